@@ -0,0 +1,183 @@
+package changes
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// file extensions of the exported content of a changed file
+const (
+	BeforeExt = ".before"
+	AfterExt  = ".after"
+)
+
+type (
+	secretsKey    struct{}
+	plaintextsKey struct{}
+)
+
+// secrets are the secret values recorded in a context
+type secrets struct {
+	mutex  sync.Mutex
+	values []string
+}
+
+// Plaintext is the decrypted content of a file encrypted by an updater - with its values masked - before and after its update
+type Plaintext struct {
+	Before []byte
+	After  []byte
+}
+
+// plaintexts are the decrypted contents of the files recorded in a context, indexed by their slash-separated path relative to the root of the repository
+type plaintexts struct {
+	mutex sync.Mutex
+	files map[string]Plaintext
+}
+
+var (
+	maskKey     []byte
+	maskKeyOnce sync.Once
+)
+
+// WithSecrets returns a context in which the secret values returned by the valuers are recorded - see RecordSecret - so that they can be masked in the exported files
+func WithSecrets(ctx context.Context) context.Context {
+	return context.WithValue(ctx, secretsKey{}, &secrets{})
+}
+
+// RecordSecret records the given secret value in the given context - if it records the secrets.
+func RecordSecret(ctx context.Context, value string) {
+	recorded, ok := ctx.Value(secretsKey{}).(*secrets)
+	if !ok || len(value) == 0 {
+		return
+	}
+	recorded.mutex.Lock()
+	defer recorded.mutex.Unlock()
+	recorded.values = append(recorded.values, value)
+}
+
+// Secrets returns the secret values recorded in the given context.
+func Secrets(ctx context.Context) []string {
+	recorded, ok := ctx.Value(secretsKey{}).(*secrets)
+	if !ok {
+		return nil
+	}
+	recorded.mutex.Lock()
+	defer recorded.mutex.Unlock()
+	return append([]string(nil), recorded.values...)
+}
+
+// WithPlaintexts returns a context in which the updaters of encrypted files - such as sops - record the decrypted content of the files they change - see RecordPlaintext -
+// so that they can be exported in plaintext instead of encrypted.
+func WithPlaintexts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, plaintextsKey{}, &plaintexts{files: make(map[string]Plaintext)})
+}
+
+// RecordsPlaintexts returns true if the given context records the decrypted content of the files - so that the updaters only mask it when it is exported.
+func RecordsPlaintexts(ctx context.Context) bool {
+	_, ok := ctx.Value(plaintextsKey{}).(*plaintexts)
+	return ok
+}
+
+// RecordPlaintext records the decrypted content - with its values masked, see Mask - of the given file, relative to the root of the repository,
+// before and after its update, in the given context - if it records the plaintexts.
+func RecordPlaintext(ctx context.Context, file string, before, after []byte) {
+	recorded, ok := ctx.Value(plaintextsKey{}).(*plaintexts)
+	if !ok {
+		return
+	}
+	recorded.mutex.Lock()
+	defer recorded.mutex.Unlock()
+	recorded.files[filepath.ToSlash(file)] = Plaintext{Before: before, After: after}
+}
+
+// Plaintexts returns the decrypted contents of the files recorded in the given context, indexed by their slash-separated path relative to the root of the repository.
+func Plaintexts(ctx context.Context) map[string]Plaintext {
+	recorded, ok := ctx.Value(plaintextsKey{}).(*plaintexts)
+	if !ok {
+		return nil
+	}
+	recorded.mutex.Lock()
+	defer recorded.mutex.Unlock()
+	files := make(map[string]Plaintext, len(recorded.files))
+	for file, plaintext := range recorded.files {
+		files[file] = plaintext
+	}
+	return files
+}
+
+// Export writes the content of the given file - relative to the root of the repository - before and after its update,
+// to the <file>.before and <file>.after files of the given directory, with the given secret values masked.
+// A nil before content means that the file has been created, and a nil after content that it has been deleted: only the other content is written.
+func Export(dir, file string, before, after []byte, secretValues []string) error {
+	filePath := filepath.Join(dir, filepath.Clean(string(filepath.Separator)+file))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create the export directory for file %s: %w", file, err)
+	}
+	if before != nil {
+		if err := os.WriteFile(filePath+BeforeExt, MaskSecrets(before, secretValues), 0600); err != nil {
+			return fmt.Errorf("failed to export the original content of file %s: %w", file, err)
+		}
+	}
+	if after != nil {
+		if err := os.WriteFile(filePath+AfterExt, MaskSecrets(after, secretValues), 0600); err != nil {
+			return fmt.Errorf("failed to export the updated content of file %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// MaskSecrets returns the given content with all the occurrences of the given secret values masked - see Mask.
+// The lines of the multi-line secrets are also masked one by one, as they may be indented in the content - such as in a YAML block scalar.
+func MaskSecrets(content []byte, secretValues []string) []byte {
+	var values []string
+	for _, secret := range secretValues {
+		values = append(values, secret)
+		if lines := strings.Split(secret, "\n"); len(lines) > 1 {
+			for _, line := range lines {
+				if line = strings.TrimSpace(line); len(line) > 0 {
+					values = append(values, line)
+				}
+			}
+		}
+	}
+	// the longest values first, so that a value containing another one is masked as a whole
+	sort.SliceStable(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	masked := string(content)
+	for _, value := range values {
+		if len(value) > 0 {
+			masked = strings.ReplaceAll(masked, value, Mask(value))
+		}
+	}
+	return []byte(masked)
+}
+
+// Mask returns a masked representation of a secret value, to export the content of encrypted files without their secrets.
+// The same value always has the same masked representation during a run - so that the changed values can be spotted -
+// but it can't be used to guess the value: it is based on a HMAC with a random key.
+func Mask(value string) string {
+	maskKeyOnce.Do(func() {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err == nil {
+			maskKey = key
+		}
+	})
+	if maskKey == nil {
+		// no random key: all the values are masked the same way
+		return "[MASKED]"
+	}
+	mac := hmac.New(sha256.New, maskKey)
+	mac.Write([]byte(value))
+	return "[MASKED:" + hex.EncodeToString(mac.Sum(nil))[:12] + "]"
+}
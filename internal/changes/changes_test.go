@@ -0,0 +1,147 @@
+package changes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		file          string
+		before        []byte
+		after         []byte
+		secrets       []string
+		expectedFiles map[string]string
+	}{
+		{
+			name:   "changed file",
+			file:   "config/app.yaml",
+			before: []byte("version: 1\n"),
+			after:  []byte("version: 2\n"),
+			expectedFiles: map[string]string{
+				"config/app.yaml.before": "version: 1\n",
+				"config/app.yaml.after":  "version: 2\n",
+			},
+		},
+		{
+			name:  "created file",
+			file:  "VERSION",
+			after: []byte("v2\n"),
+			expectedFiles: map[string]string{
+				"VERSION.after": "v2\n",
+			},
+		},
+		{
+			name:   "file outside the repository",
+			file:   "../../etc/passwd",
+			before: []byte("before"),
+			after:  []byte("after"),
+			expectedFiles: map[string]string{
+				"etc/passwd.before": "before",
+				"etc/passwd.after":  "after",
+			},
+		},
+		{
+			name:   "deleted file",
+			file:   "VERSION",
+			before: []byte("v1\n"),
+			expectedFiles: map[string]string{
+				"VERSION.before": "v1\n",
+			},
+		},
+		{
+			name:    "masked secrets",
+			file:    "secrets.yaml",
+			before:  []byte("token: old-token\n"),
+			after:   []byte("token: new-token\n"),
+			secrets: []string{"new-token", "old-token"},
+			expectedFiles: map[string]string{
+				"secrets.yaml.before": "token: " + Mask("old-token") + "\n",
+				"secrets.yaml.after":  "token: " + Mask("new-token") + "\n",
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			err := Export(dir, test.file, test.before, test.after, test.secrets)
+			require.NoError(t, err)
+
+			var actualFiles []string
+			err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				relPath, err := filepath.Rel(dir, path)
+				actualFiles = append(actualFiles, filepath.ToSlash(relPath))
+				return err
+			})
+			require.NoError(t, err)
+			assert.Len(t, actualFiles, len(test.expectedFiles))
+			for filename, expectedContent := range test.expectedFiles {
+				actualContent, err := os.ReadFile(filepath.Join(dir, filename))
+				require.NoErrorf(t, err, "can't read file %s", filename)
+				assert.Equalf(t, expectedContent, string(actualContent), "file %s doesn't match", filename)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	t.Parallel()
+
+	masked := Mask("s3cret")
+	assert.Regexp(t, `^\[MASKED:[0-9a-f]{12}\]$`, masked)
+	assert.NotContains(t, masked, "s3cret")
+	assert.Equal(t, masked, Mask("s3cret"), "the same value should always be masked the same way")
+	assert.NotEqual(t, masked, Mask("an0ther-s3cret"), "different values should be masked differently")
+}
+
+func TestMaskSecrets(t *testing.T) {
+	t.Parallel()
+
+	content := "password: s3cret\nkey: |\n  line-1\n  line-2\npasswords: s3cret-and-more\n"
+	masked := string(MaskSecrets([]byte(content), []string{"s3cret", "line-1\nline-2", "s3cret-and-more"}))
+	for _, secret := range []string{"s3cret", "line-1", "line-2"} {
+		assert.NotContains(t, masked, secret)
+	}
+	assert.Contains(t, masked, "passwords: "+Mask("s3cret-and-more")+"\n")
+	assert.Contains(t, masked, "password: "+Mask("s3cret")+"\n")
+}
+
+func TestSecrets(t *testing.T) {
+	t.Parallel()
+
+	RecordSecret(context.Background(), "ignored")
+	assert.Empty(t, Secrets(context.Background()))
+
+	ctx := WithSecrets(context.Background())
+	RecordSecret(ctx, "s3cret")
+	RecordSecret(ctx, "")
+	assert.Equal(t, []string{"s3cret"}, Secrets(ctx))
+}
+
+func TestPlaintexts(t *testing.T) {
+	t.Parallel()
+
+	RecordPlaintext(context.Background(), "ignored.yaml", []byte("before"), []byte("after"))
+	assert.False(t, RecordsPlaintexts(context.Background()))
+	assert.Empty(t, Plaintexts(context.Background()))
+
+	ctx := WithPlaintexts(context.Background())
+	assert.True(t, RecordsPlaintexts(ctx))
+	RecordPlaintext(ctx, filepath.Join("config", "secrets.yaml"), []byte("before"), []byte("after"))
+	assert.Equal(t, map[string]Plaintext{
+		"config/secrets.yaml": {Before: []byte("before"), After: []byte("after")},
+	}, Plaintexts(ctx))
+}
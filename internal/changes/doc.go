@@ -0,0 +1,2 @@
+// Package changes exports the content of the files changed by the updaters - before and after their update - to a directory, for debugging.
+package changes
@@ -0,0 +1,2 @@
+// Package eol provides functions to detect and convert the line endings (LF or CRLF) of file contents.
+package eol
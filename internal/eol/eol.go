@@ -0,0 +1,74 @@
+package eol
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Mode defines how the line endings of an updated content are written.
+type Mode string
+
+// definition of the supported line endings modes
+const (
+	// Preserve converts the line endings to the dominant line ending of the original content.
+	Preserve Mode = ""
+	// LF converts all line endings to LF.
+	LF Mode = "lf"
+	// CRLF converts all line endings to CRLF.
+	CRLF Mode = "crlf"
+)
+
+var (
+	lf   = []byte("\n")
+	crlf = []byte("\r\n")
+)
+
+// ParseMode parses the string representation of a line endings mode: "preserve", "lf" or "crlf".
+// An empty string is the same as "preserve".
+func ParseMode(mode string) (Mode, error) {
+	switch strings.ToLower(mode) {
+	case "", "preserve":
+		return Preserve, nil
+	case "lf":
+		return LF, nil
+	case "crlf":
+		return CRLF, nil
+	default:
+		return Preserve, fmt.Errorf("invalid eol mode %s: must be one of preserve, lf or crlf", mode)
+	}
+}
+
+// Detect returns the dominant line ending of the given content: CRLF if most lines end with CRLF, and LF otherwise.
+func Detect(data []byte) Mode {
+	var (
+		crlfCount = bytes.Count(data, crlf)
+		lfCount   = bytes.Count(data, lf) - crlfCount
+	)
+	if crlfCount > lfCount {
+		return CRLF
+	}
+	return LF
+}
+
+// Convert converts all line endings of the given content to the given mode - which must be either LF or CRLF.
+func Convert(data []byte, mode Mode) []byte {
+	normalized := bytes.ReplaceAll(data, crlf, lf)
+	if mode == CRLF {
+		return bytes.ReplaceAll(normalized, lf, crlf)
+	}
+	return normalized
+}
+
+// Apply converts the line endings of the updated content, based on the given mode and the original content.
+// With the Preserve mode, the updated content will use the dominant line ending of the original content.
+func Apply(mode Mode, original, updated []byte) []byte {
+	if mode == Preserve {
+		mode = Detect(original)
+		if mode == LF && !bytes.Contains(updated, crlf) {
+			// nothing to convert - let's not touch the content
+			return updated
+		}
+	}
+	return Convert(updated, mode)
+}
@@ -0,0 +1,110 @@
+package eol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		mode             string
+		expected         Mode
+		expectedErrorMsg string
+	}{
+		{
+			name:     "empty input",
+			expected: Preserve,
+		},
+		{
+			name:     "preserve",
+			mode:     "preserve",
+			expected: Preserve,
+		},
+		{
+			name:     "lf",
+			mode:     "lf",
+			expected: LF,
+		},
+		{
+			name:     "uppercase crlf",
+			mode:     "CRLF",
+			expected: CRLF,
+		},
+		{
+			name:             "invalid input",
+			mode:             "cr",
+			expectedErrorMsg: "invalid eol mode cr: must be one of preserve, lf or crlf",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := ParseMode(test.mode)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		mode     Mode
+		original string
+		updated  string
+		expected string
+	}{
+		{
+			name:     "preserve crlf",
+			original: "key: old\r\nother: value\r\n",
+			updated:  "key: new\nother: value\n",
+			expected: "key: new\r\nother: value\r\n",
+		},
+		{
+			name:     "preserve dominant crlf in mixed content",
+			original: "key: old\r\nother: value\r\nlast: value\n",
+			updated:  "key: new\nother: value\nlast: value\n",
+			expected: "key: new\r\nother: value\r\nlast: value\r\n",
+		},
+		{
+			name:     "preserve lf",
+			original: "key: old\nother: value\n",
+			updated:  "key: new\nother: value\n",
+			expected: "key: new\nother: value\n",
+		},
+		{
+			name:     "force lf",
+			mode:     LF,
+			original: "key: old\r\nother: value\r\n",
+			updated:  "key: new\r\nother: value\r\n",
+			expected: "key: new\nother: value\n",
+		},
+		{
+			name:     "force crlf",
+			mode:     CRLF,
+			original: "key: old\nother: value\n",
+			updated:  "key: new\nother: value\r\n",
+			expected: "key: new\r\nother: value\r\n",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual := Apply(test.mode, []byte(test.original), []byte(test.updated))
+			assert.Equal(t, test.expected, string(actual))
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// Package jsonschema provides a validator for JSON documents, supporting the most common keywords of the JSON Schema specification.
+//
+// Supported keywords: type, enum, const, properties, required, additionalProperties, patternProperties, items, minItems, maxItems, uniqueItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, minProperties, maxProperties,
+// allOf, anyOf, oneOf, not, and local $ref - such as "#/definitions/foo" or "#/$defs/foo". Other keywords - such as format - are ignored.
+package jsonschema
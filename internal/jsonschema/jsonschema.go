@@ -0,0 +1,436 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a parsed JSON Schema
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+	// secret is true if the values of the validated document must not be in the errors
+	secret bool
+}
+
+// Parse parses the given JSON Schema
+func Parse(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	switch root.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return nil, fmt.Errorf("invalid JSON schema: must be an object or a boolean, got %s", typeOf(root))
+	}
+
+	s := &Schema{
+		root:     root,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	if err := s.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate validates the given JSON document against the schema, and returns the validation errors - prefixed by the JSON pointer of the invalid value.
+// It returns nil if the document is valid.
+func (s *Schema) Validate(data []byte) ([]string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+	return s.validate(s.root, doc, "", 0), nil
+}
+
+// ValidateSecret validates the given secret JSON document against the schema, just like Validate - but without the values of the document in the errors.
+func (s *Schema) ValidateSecret(data []byte) ([]string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		// the syntax errors contain the invalid characters of the document
+		return nil, errors.New("invalid JSON document")
+	}
+	secret := *s
+	secret.secret = true
+	return secret.validate(secret.root, doc, "", 0), nil
+}
+
+// maxRefDepth prevents infinite recursions with recursive $ref
+const maxRefDepth = 64
+
+func (s *Schema) validate(schema, doc interface{}, pointer string, depth int) []string {
+	if depth > maxRefDepth {
+		return []string{location(pointer) + ": too many nested $ref"}
+	}
+
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			return []string{location(pointer) + ": no value is allowed"}
+		}
+		return nil
+	case map[string]interface{}:
+		if ref, ok := schema["$ref"].(string); ok {
+			target, err := s.resolve(ref)
+			if err != nil {
+				return []string{location(pointer) + ": " + err.Error()}
+			}
+			return s.validate(target, doc, pointer, depth+1)
+		}
+		var errs []string
+		errs = append(errs, s.validateGeneric(schema, doc, pointer)...)
+		errs = append(errs, s.validateComposition(schema, doc, pointer, depth)...)
+		switch doc := doc.(type) {
+		case map[string]interface{}:
+			errs = append(errs, s.validateObject(schema, doc, pointer, depth)...)
+		case []interface{}:
+			errs = append(errs, s.validateArray(schema, doc, pointer, depth)...)
+		case string:
+			errs = append(errs, s.validateString(schema, doc, pointer)...)
+		case float64:
+			errs = append(errs, validateNumber(schema, doc, pointer)...)
+		}
+		return errs
+	default:
+		return nil
+	}
+}
+
+func (s *Schema) validateGeneric(schema map[string]interface{}, doc interface{}, pointer string) []string {
+	var errs []string
+	if types, found := schema["type"]; found {
+		var allowed []string
+		switch types := types.(type) {
+		case string:
+			allowed = []string{types}
+		case []interface{}:
+			for _, t := range types {
+				if t, ok := t.(string); ok {
+					allowed = append(allowed, t)
+				}
+			}
+		}
+		if !hasType(doc, allowed) {
+			errs = append(errs, fmt.Sprintf("%s: expected %s, got %s", location(pointer), strings.Join(allowed, " or "), typeOf(doc)))
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		var found bool
+		for _, value := range enum {
+			if equal(value, doc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: must be one of %s", location(pointer), toJSON(enum)))
+		}
+	}
+	if constValue, found := schema["const"]; found && !equal(constValue, doc) {
+		errs = append(errs, fmt.Sprintf("%s: must be %s", location(pointer), toJSON(constValue)))
+	}
+	return errs
+}
+
+func (s *Schema) validateComposition(schema map[string]interface{}, doc interface{}, pointer string, depth int) []string {
+	var errs []string
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, subSchema := range allOf {
+			errs = append(errs, s.validate(subSchema, doc, pointer, depth+1)...)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && s.countValid(anyOf, doc, pointer, depth) == 0 {
+		errs = append(errs, fmt.Sprintf("%s: must match at least one of the anyOf schemas", location(pointer)))
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if count := s.countValid(oneOf, doc, pointer, depth); count != 1 {
+			errs = append(errs, fmt.Sprintf("%s: must match exactly one of the oneOf schemas, matched %d", location(pointer), count))
+		}
+	}
+	if not, found := schema["not"]; found && len(s.validate(not, doc, pointer, depth+1)) == 0 {
+		errs = append(errs, fmt.Sprintf("%s: must not match the not schema", location(pointer)))
+	}
+	return errs
+}
+
+func (s *Schema) countValid(schemas []interface{}, doc interface{}, pointer string, depth int) int {
+	var count int
+	for _, subSchema := range schemas {
+		if len(s.validate(subSchema, doc, pointer, depth+1)) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Schema) validateObject(schema, doc map[string]interface{}, pointer string, depth int) []string {
+	var errs []string
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, found := doc[name]; !found {
+					errs = append(errs, fmt.Sprintf("%s: missing required property %q", location(pointer), name))
+				}
+			}
+		}
+	}
+	if minProperties, ok := schema["minProperties"].(float64); ok && float64(len(doc)) < minProperties {
+		errs = append(errs, fmt.Sprintf("%s: must have at least %v properties", location(pointer), minProperties))
+	}
+	if maxProperties, ok := schema["maxProperties"].(float64); ok && float64(len(doc)) > maxProperties {
+		errs = append(errs, fmt.Sprintf("%s: must have at most %v properties", location(pointer), maxProperties))
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	additionalProperties, hasAdditionalProperties := schema["additionalProperties"]
+
+	patterns := make([]string, 0, len(patternProperties))
+	for pattern := range patternProperties {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	names := make([]string, 0, len(doc))
+	for name := range doc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPointer := pointer + "/" + escapePointer(name)
+		matched := false
+		if propertySchema, found := properties[name]; found {
+			matched = true
+			errs = append(errs, s.validate(propertySchema, doc[name], childPointer, depth+1)...)
+		}
+		for _, pattern := range patterns {
+			if s.patterns[pattern].MatchString(name) {
+				matched = true
+				errs = append(errs, s.validate(patternProperties[pattern], doc[name], childPointer, depth+1)...)
+			}
+		}
+		if matched || !hasAdditionalProperties {
+			continue
+		}
+		if allowed, ok := additionalProperties.(bool); ok && !allowed {
+			errs = append(errs, fmt.Sprintf("%s: additional property %q is not allowed", location(pointer), name))
+			continue
+		}
+		errs = append(errs, s.validate(additionalProperties, doc[name], childPointer, depth+1)...)
+	}
+	return errs
+}
+
+func (s *Schema) validateArray(schema map[string]interface{}, doc []interface{}, pointer string, depth int) []string {
+	var errs []string
+	if minItems, ok := schema["minItems"].(float64); ok && float64(len(doc)) < minItems {
+		errs = append(errs, fmt.Sprintf("%s: must have at least %v items", location(pointer), minItems))
+	}
+	if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(doc)) > maxItems {
+		errs = append(errs, fmt.Sprintf("%s: must have at most %v items", location(pointer), maxItems))
+	}
+	if uniqueItems, ok := schema["uniqueItems"].(bool); ok && uniqueItems {
+	loop:
+		for i := range doc {
+			for j := i + 1; j < len(doc); j++ {
+				if equal(doc[i], doc[j]) {
+					errs = append(errs, fmt.Sprintf("%s: items %d and %d must be unique", location(pointer), i, j))
+					break loop
+				}
+			}
+		}
+	}
+	if items, found := schema["items"]; found {
+		for i, item := range doc {
+			errs = append(errs, s.validate(items, item, pointer+"/"+strconv.Itoa(i), depth+1)...)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateString(schema map[string]interface{}, doc, pointer string) []string {
+	var (
+		errs   []string
+		length = float64(utf8.RuneCountInString(doc))
+	)
+	if minLength, ok := schema["minLength"].(float64); ok && length < minLength {
+		errs = append(errs, fmt.Sprintf("%s: must be at least %v characters long", location(pointer), minLength))
+	}
+	if maxLength, ok := schema["maxLength"].(float64); ok && length > maxLength {
+		errs = append(errs, fmt.Sprintf("%s: must be at most %v characters long", location(pointer), maxLength))
+	}
+	if pattern, ok := schema["pattern"].(string); ok && !s.patterns[pattern].MatchString(doc) {
+		if s.secret {
+			errs = append(errs, fmt.Sprintf("%s: secret value doesn't match pattern %s", location(pointer), pattern))
+		} else {
+			errs = append(errs, fmt.Sprintf("%s: %q doesn't match pattern %s", location(pointer), doc, pattern))
+		}
+	}
+	return errs
+}
+
+func validateNumber(schema map[string]interface{}, doc float64, pointer string) []string {
+	var errs []string
+	if minimum, ok := schema["minimum"].(float64); ok && doc < minimum {
+		errs = append(errs, fmt.Sprintf("%s: must be >= %v", location(pointer), minimum))
+	}
+	if maximum, ok := schema["maximum"].(float64); ok && doc > maximum {
+		errs = append(errs, fmt.Sprintf("%s: must be <= %v", location(pointer), maximum))
+	}
+	if exclusiveMinimum, ok := schema["exclusiveMinimum"].(float64); ok && doc <= exclusiveMinimum {
+		errs = append(errs, fmt.Sprintf("%s: must be > %v", location(pointer), exclusiveMinimum))
+	}
+	if exclusiveMaximum, ok := schema["exclusiveMaximum"].(float64); ok && doc >= exclusiveMaximum {
+		errs = append(errs, fmt.Sprintf("%s: must be < %v", location(pointer), exclusiveMaximum))
+	}
+	if multipleOf, ok := schema["multipleOf"].(float64); ok && multipleOf > 0 {
+		if quotient := doc / multipleOf; math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			errs = append(errs, fmt.Sprintf("%s: must be a multiple of %v", location(pointer), multipleOf))
+		}
+	}
+	return errs
+}
+
+// resolve returns the sub-schema referenced by the given local $ref, such as "#/definitions/foo"
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %s: only local references are supported", ref)
+	}
+	current := s.root
+	for _, token := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+		if len(token) == 0 {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, found := node[token]
+			if !found {
+				return nil, fmt.Errorf("invalid $ref %s: %s not found", ref, token)
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("invalid $ref %s: %s not found", ref, token)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("invalid $ref %s: %s not found", ref, token)
+		}
+	}
+	return current, nil
+}
+
+// compilePatterns compiles all the patterns of the schema - so that an invalid pattern is reported when parsing the schema
+func (s *Schema) compilePatterns(node interface{}) error {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		if pattern, ok := node["pattern"].(string); ok {
+			if err := s.compilePattern(pattern); err != nil {
+				return err
+			}
+		}
+		if patternProperties, ok := node["patternProperties"].(map[string]interface{}); ok {
+			for pattern := range patternProperties {
+				if err := s.compilePattern(pattern); err != nil {
+					return err
+				}
+			}
+		}
+		for _, child := range node {
+			if err := s.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range node {
+			if err := s.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) compilePattern(pattern string) error {
+	if _, found := s.patterns[pattern]; found {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid JSON schema: invalid pattern %s: %w", pattern, err)
+	}
+	s.patterns[pattern] = re
+	return nil
+}
+
+func hasType(doc interface{}, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "integer":
+			if number, ok := doc.(float64); ok && number == math.Trunc(number) {
+				return true
+			}
+		default:
+			if typeOf(doc) == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of the given decoded JSON value
+func typeOf(doc interface{}) string {
+	switch doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", doc)
+	}
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func toJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// location returns the JSON pointer of a value - or "/" for the root document
+func location(pointer string) string {
+	if len(pointer) == 0 {
+		return "/"
+	}
+	return pointer
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,137 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		schema         string
+		doc            string
+		expectedErrors []string
+	}{
+		{
+			name:   "boolean schema",
+			schema: `false`,
+			doc:    `{}`,
+			expectedErrors: []string{
+				"/: no value is allowed",
+			},
+		},
+		{
+			name:   "enum and const",
+			schema: `{"properties": {"env": {"enum": ["dev", "prod"]}, "version": {"const": 2}}}`,
+			doc:    `{"env": "staging", "version": 1}`,
+			expectedErrors: []string{
+				`/env: must be one of ["dev","prod"]`,
+				"/version: must be 2",
+			},
+		},
+		{
+			name:   "numbers",
+			schema: `{"items": {"type": "number", "exclusiveMinimum": 0, "exclusiveMaximum": 100, "multipleOf": 0.5}}`,
+			doc:    `[0, 50.5, 100, 12.3]`,
+			expectedErrors: []string{
+				"/0: must be > 0",
+				"/2: must be < 100",
+				"/3: must be a multiple of 0.5",
+			},
+		},
+		{
+			name:   "arrays",
+			schema: `{"type": "array", "minItems": 4, "maxItems": 2, "uniqueItems": true}`,
+			doc:    `["a", "b", "a"]`,
+			expectedErrors: []string{
+				"/: must have at least 4 items",
+				"/: must have at most 2 items",
+				"/: items 0 and 2 must be unique",
+			},
+		},
+		{
+			name:   "objects",
+			schema: `{"minProperties": 1, "maxProperties": 2, "patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": {"type": "integer"}}`,
+			doc:    `{"x-name": 1, "count": "one", "a/b": 3}`,
+			expectedErrors: []string{
+				"/: must have at most 2 properties",
+				"/count: expected integer, got string",
+				"/x-name: expected string, got number",
+			},
+		},
+		{
+			name:   "composition",
+			schema: `{"properties": {"all": {"allOf": [{"type": "string"}, {"maxLength": 2}]}, "any": {"anyOf": [{"type": "string"}, {"type": "boolean"}]}, "one": {"oneOf": [{"type": "number"}, {"type": "integer"}]}, "not": {"not": {"type": "null"}}}}`,
+			doc:    `{"all": "abc", "any": 1, "one": 1, "not": null}`,
+			expectedErrors: []string{
+				"/all: must be at most 2 characters long",
+				"/any: must match at least one of the anyOf schemas",
+				"/not: must not match the not schema",
+				"/one: must match exactly one of the oneOf schemas, matched 2",
+			},
+		},
+		{
+			name:   "local references",
+			schema: `{"definitions": {"node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}, "name": {"type": "string"}}}}, "$ref": "#/definitions/node"}`,
+			doc:    `{"name": "root", "children": [{"name": "child", "children": [{"name": 42}]}]}`,
+			expectedErrors: []string{
+				"/children/0/children/0/name: expected string, got number",
+			},
+		},
+		{
+			name:   "remote reference",
+			schema: `{"$ref": "https://example.com/schema.json"}`,
+			doc:    `{}`,
+			expectedErrors: []string{
+				"/: unsupported $ref https://example.com/schema.json: only local references are supported",
+			},
+		},
+		{
+			name:   "valid document",
+			schema: `{"type": "object", "required": ["name"], "properties": {"name": {"type": "string", "format": "hostname"}}}`,
+			doc:    `{"name": "example.com"}`,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			schema, err := Parse([]byte(test.schema))
+			require.NoError(t, err)
+			actual, err := schema.Validate([]byte(test.doc))
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedErrors, actual)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	_, err := Parse([]byte(`{"properties": {"name": {"pattern": "[a-z"}}}`))
+	require.EqualError(t, err, "invalid JSON schema: invalid pattern [a-z: error parsing regexp: missing closing ]: `[a-z`")
+
+	_, err = Parse([]byte(`["type", "string"]`))
+	require.EqualError(t, err, "invalid JSON schema: must be an object or a boolean, got array")
+}
+
+func TestValidateSecret(t *testing.T) {
+	t.Parallel()
+	schema, err := Parse([]byte(`{"properties": {"token": {"type": "string", "pattern": "^[a-f0-9]+$"}}}`))
+	require.NoError(t, err)
+
+	actual, err := schema.ValidateSecret([]byte(`{"token": "s3cret"}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/token: secret value doesn't match pattern ^[a-f0-9]+$"}, actual)
+
+	_, err = schema.ValidateSecret([]byte(`s3cret`))
+	require.EqualError(t, err, "invalid JSON document")
+
+	// the schema is not changed
+	actual, err = schema.Validate([]byte(`{"token": "s3cret"}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`/token: "s3cret" doesn't match pattern ^[a-f0-9]+$`}, actual)
+}
@@ -0,0 +1,2 @@
+// Package missingkey defines how an updater handles the files - matched by a glob pattern - which don't contain the key to update.
+package missingkey
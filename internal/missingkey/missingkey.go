@@ -0,0 +1,38 @@
+package missingkey
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Strategy defines how the files which don't contain the key to update are handled.
+type Strategy string
+
+// definition of the supported strategies
+const (
+	// Default keeps the implicit behavior of each updater.
+	Default Strategy = ""
+	// Skip only updates the files which already contain the key.
+	Skip Strategy = "skip"
+	// Create adds the key to all the files.
+	Create Strategy = "create"
+	// Error fails the update if one of the files doesn't contain the key.
+	Error Strategy = "error"
+)
+
+// ParseStrategy parses the string representation of a strategy: "skip", "create" or "error".
+// An empty string keeps the default behavior of the updater.
+func ParseStrategy(strategy string) (Strategy, error) {
+	switch strings.ToLower(strategy) {
+	case "":
+		return Default, nil
+	case "skip":
+		return Skip, nil
+	case "create":
+		return Create, nil
+	case "error":
+		return Error, nil
+	default:
+		return Default, fmt.Errorf("invalid missing-key-strategy %s: must be one of skip, create or error", strategy)
+	}
+}
@@ -0,0 +1,37 @@
+package missingkey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStrategy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		strategy         string
+		expected         Strategy
+		expectedErrorMsg string
+	}{
+		{strategy: "", expected: Default},
+		{strategy: "skip", expected: Skip},
+		{strategy: "Create", expected: Create},
+		{strategy: "ERROR", expected: Error},
+		{strategy: "ignore", expectedErrorMsg: "invalid missing-key-strategy ignore: must be one of skip, create or error"},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.strategy, func(t *testing.T) {
+			t.Parallel()
+			actual, err := ParseStrategy(test.strategy)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,2 @@
+// Package monotonic provides a guard that ensures a value is only updated if the new value is greater than the current one - to prevent downgrades.
+package monotonic
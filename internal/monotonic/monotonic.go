@@ -0,0 +1,75 @@
+package monotonic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Mode defines how the current and new values are compared.
+type Mode string
+
+// definition of the supported comparison modes
+const (
+	// None disables the guard: the new value is always written.
+	None Mode = ""
+	// SemVer compares the values as semantic versions - with an optional "v" prefix.
+	SemVer Mode = "semver"
+	// Numeric compares the values as numbers.
+	Numeric Mode = "numeric"
+	// Lexical compares the values as strings.
+	Lexical Mode = "lexical"
+)
+
+// ParseMode parses the string representation of a comparison mode: "semver", "numeric" or "lexical".
+// An empty string disables the guard.
+func ParseMode(mode string) (Mode, error) {
+	switch strings.ToLower(mode) {
+	case "":
+		return None, nil
+	case "semver":
+		return SemVer, nil
+	case "numeric":
+		return Numeric, nil
+	case "lexical":
+		return Lexical, nil
+	default:
+		return None, fmt.Errorf("invalid monotonic mode %s: must be one of semver, numeric or lexical", mode)
+	}
+}
+
+// IsGreater returns true if the new value is strictly greater than the current value, using the given comparison mode.
+// It returns an error if one of the values can't be parsed for the given mode.
+func IsGreater(mode Mode, current, next string) (bool, error) {
+	current, next = strings.TrimSpace(current), strings.TrimSpace(next)
+	switch mode {
+	case None:
+		return true, nil
+	case SemVer:
+		currentVersion, err := semver.NewVersion(current)
+		if err != nil {
+			return false, fmt.Errorf("invalid current semver value %q: %w", current, err)
+		}
+		nextVersion, err := semver.NewVersion(next)
+		if err != nil {
+			return false, fmt.Errorf("invalid new semver value %q: %w", next, err)
+		}
+		return nextVersion.GreaterThan(currentVersion), nil
+	case Numeric:
+		currentNumber, err := strconv.ParseFloat(current, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid current numeric value %q: %w", current, err)
+		}
+		nextNumber, err := strconv.ParseFloat(next, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid new numeric value %q: %w", next, err)
+		}
+		return nextNumber > currentNumber, nil
+	case Lexical:
+		return next > current, nil
+	default:
+		return false, fmt.Errorf("unsupported monotonic mode %s", mode)
+	}
+}
@@ -0,0 +1,99 @@
+package monotonic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsGreater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		mode             Mode
+		current          string
+		next             string
+		expected         bool
+		expectedErrorMsg string
+	}{
+		{
+			name:     "no guard",
+			mode:     None,
+			current:  "2.0.0",
+			next:     "1.0.0",
+			expected: true,
+		},
+		{
+			name:     "semver upgrade",
+			mode:     SemVer,
+			current:  "v1.9.0",
+			next:     "v1.10.0",
+			expected: true,
+		},
+		{
+			name:     "semver same",
+			mode:     SemVer,
+			current:  "1.2.3",
+			next:     "v1.2.3",
+			expected: false,
+		},
+		{
+			name:     "semver downgrade",
+			mode:     SemVer,
+			current:  "1.2.3",
+			next:     "1.2.3-rc.1",
+			expected: false,
+		},
+		{
+			name:             "semver invalid value",
+			mode:             SemVer,
+			current:          "latest",
+			next:             "1.2.3",
+			expectedErrorMsg: `invalid current semver value "latest": Invalid Semantic Version`,
+		},
+		{
+			name:     "numeric upgrade",
+			mode:     Numeric,
+			current:  "9",
+			next:     "10\n",
+			expected: true,
+		},
+		{
+			name:     "numeric downgrade",
+			mode:     Numeric,
+			current:  "10",
+			next:     "9.5",
+			expected: false,
+		},
+		{
+			name:     "lexical upgrade",
+			mode:     Lexical,
+			current:  "2023-01-02",
+			next:     "2023-01-10",
+			expected: true,
+		},
+		{
+			name:     "lexical downgrade",
+			mode:     Lexical,
+			current:  "b",
+			next:     "a",
+			expected: false,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := IsGreater(test.mode, test.current, test.next)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.False(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
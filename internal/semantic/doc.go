@@ -0,0 +1,2 @@
+// Package semantic compares YAML or JSON contents by their data - ignoring their formatting, such as the order of the keys, the indentation or the quotes.
+package semantic
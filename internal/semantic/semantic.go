@@ -0,0 +1,49 @@
+package semantic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Equal returns true if both YAML contents have the same data, even if they are formatted differently.
+// All the documents of a multi-document content are compared, in order. JSON contents are supported too, JSON being a subset of YAML.
+// It returns an error if one of the contents can't be parsed.
+func Equal(a, b []byte) (bool, error) {
+	if bytes.Equal(a, b) {
+		return true, nil
+	}
+
+	aDocuments, err := decode(a)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse the original content: %w", err)
+	}
+	bDocuments, err := decode(b)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse the updated content: %w", err)
+	}
+	return reflect.DeepEqual(aDocuments, bDocuments), nil
+}
+
+// decode returns the data of all the documents of the given content
+func decode(content []byte) ([]interface{}, error) {
+	var (
+		documents []interface{}
+		decoder   = yaml.NewDecoder(bytes.NewReader(content))
+	)
+	for {
+		var document interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+}
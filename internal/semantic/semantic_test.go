@@ -0,0 +1,88 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEqual(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		a                string
+		b                string
+		expected         bool
+		expectedErrorMsg string
+	}{
+		{
+			name:     "same content",
+			a:        "app:\n  version: 1.2.3\n",
+			b:        "app:\n  version: 1.2.3\n",
+			expected: true,
+		},
+		{
+			name:     "reordered keys",
+			a:        "name: app\nversion: 1.2.3\nimage:\n  repository: app\n  tag: latest\n",
+			b:        "image:\n  tag: latest\n  repository: app\nversion: 1.2.3\nname: app\n",
+			expected: true,
+		},
+		{
+			name:     "whitespace, quotes and comments",
+			a:        "app:\n  # the version\n  version: \"1.2.3\"\n  ports: [80, 443]\n",
+			b:        "app:\n    version: '1.2.3'\n    ports:\n      - 80\n      - 443\n\n",
+			expected: true,
+		},
+		{
+			name:     "json and yaml",
+			a:        `{"app": {"version": "1.2.3"}}`,
+			b:        "app:\n  version: 1.2.3\n",
+			expected: true,
+		},
+		{
+			name:     "different value",
+			a:        "app:\n  version: 1.2.3\n",
+			b:        "app:\n  version: 1.2.4\n",
+			expected: false,
+		},
+		{
+			name:     "different type",
+			a:        "app:\n  port: 8080\n",
+			b:        "app:\n  port: \"8080\"\n",
+			expected: false,
+		},
+		{
+			name:     "reordered list items",
+			a:        "items:\n  - a\n  - b\n",
+			b:        "items:\n  - b\n  - a\n",
+			expected: false,
+		},
+		{
+			name:     "multiple documents",
+			a:        "name: a\n---\nname: b\n",
+			b:        "name: a\n---\nname: c\n",
+			expected: false,
+		},
+		{
+			name:             "invalid content",
+			a:                "app:\n  version: 1.2.3\n",
+			b:                "app: [1.2.3\n",
+			expectedErrorMsg: "failed to parse the updated content: yaml: line 1: did not find expected ',' or ']'",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := Equal([]byte(test.a), []byte(test.b))
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
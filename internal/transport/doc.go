@@ -0,0 +1,2 @@
+// Package transport provides the HTTP transport used for all outbound calls - to the GitHub/GitLab APIs, the git remotes, and the network valuers - with an optional proxy and custom CA bundle.
+package transport
@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// Options holds the configuration of the HTTP transport.
+type Options struct {
+	// ProxyURL is the URL of the proxy used for all outbound calls. If empty, the proxy is read from the HTTP_PROXY / HTTPS_PROXY / NO_PROXY env vars.
+	ProxyURL string
+	// CACertPath is the path to a PEM-encoded CA bundle, trusted in addition to the system's CAs.
+	CACertPath string
+}
+
+var (
+	defaultTransportMutex sync.RWMutex
+	defaultTransport      http.RoundTripper = http.DefaultTransport
+)
+
+// New returns a new HTTP transport configured with the given options.
+func New(opts Options) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if len(opts.ProxyURL) > 0 {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %s: %w", opts.ProxyURL, err)
+		}
+		if len(proxyURL.Scheme) == 0 || len(proxyURL.Host) == 0 {
+			return nil, fmt.Errorf("invalid proxy URL %s: must be an absolute URL, such as http://proxy.example.com:3128", opts.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if len(opts.CACertPath) > 0 {
+		caCerts, err := os.ReadFile(opts.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle %s: %w", opts.CACertPath, err)
+		}
+		certPool, err := x509.SystemCertPool()
+		if err != nil || certPool == nil {
+			certPool = x509.NewCertPool()
+		}
+		if !certPool.AppendCertsFromPEM(caCerts) {
+			return nil, errors.New("failed to parse CA bundle " + opts.CACertPath + ": no PEM-encoded certificate found")
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    certPool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return transport, nil
+}
+
+// SetDefault sets the transport returned by Default - it should be called once at startup, before any outbound call.
+func SetDefault(transport http.RoundTripper) {
+	defaultTransportMutex.Lock()
+	defer defaultTransportMutex.Unlock()
+	defaultTransport = transport
+}
+
+// Default returns the transport that should be used for all outbound calls.
+func Default() http.RoundTripper {
+	defaultTransportMutex.RLock()
+	defer defaultTransportMutex.RUnlock()
+	return defaultTransport
+}
+
+// DefaultClient returns a new HTTP client using the default transport.
+func DefaultClient() *http.Client {
+	return &http.Client{Transport: Default()}
+}
@@ -0,0 +1,93 @@
+package transport
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWithProxy(t *testing.T) {
+	t.Parallel()
+
+	var proxiedURLs []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxy receives the absolute URL of the target
+		proxiedURLs = append(proxiedURLs, r.URL.String())
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	transport, err := New(Options{ProxyURL: proxy.URL})
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: transport}).Get("http://gitlab.internal.example/api/v4/projects")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"http://gitlab.internal.example/api/v4/projects"}, proxiedURLs)
+}
+
+func TestNewWithCACert(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// without the CA bundle, the server's certificate is not trusted
+	transport, err := New(Options{})
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	require.Error(t, err)
+
+	caCertPath := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(caCertPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	}), 0644)
+	require.NoError(t, err)
+
+	transport, err = New(Options{CACertPath: caCertPath})
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestNewWithInvalidOptions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		options          Options
+		expectedErrorMsg string
+	}{
+		{
+			name:             "relative proxy URL",
+			options:          Options{ProxyURL: "proxy.example.com"},
+			expectedErrorMsg: "invalid proxy URL proxy.example.com: must be an absolute URL, such as http://proxy.example.com:3128",
+		},
+		{
+			name:             "missing CA bundle",
+			options:          Options{CACertPath: "testdata/does-not-exists.pem"},
+			expectedErrorMsg: "failed to read CA bundle testdata/does-not-exists.pem: open testdata/does-not-exists.pem: no such file or directory",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := New(test.options)
+			require.EqualError(t, err, test.expectedErrorMsg)
+			assert.Nil(t, actual)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// IsJSON returns true if the file is a JSON document - based on its extension or its content
+func IsJSON(filePath string, data []byte) bool {
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+// EncodeJSON writes the given node as JSON, with the given indentation - preserving the order of the keys
+func EncodeJSON(buffer *bytes.Buffer, node *yamlv3.Node, indent int) error {
+	return encodeJSON(buffer, node, indent, 0)
+}
+
+func encodeJSON(buffer *bytes.Buffer, node *yamlv3.Node, indent, depth int) error {
+	var (
+		newLine     = "\n" + strings.Repeat(" ", indent*(depth+1))
+		closingLine = "\n" + strings.Repeat(" ", indent*depth)
+	)
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		if len(node.Content) == 0 {
+			buffer.WriteString("{}")
+			return nil
+		}
+		buffer.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(newLine)
+			if err := encodeJSONString(buffer, node.Content[i].Value); err != nil {
+				return err
+			}
+			buffer.WriteString(": ")
+			if err := encodeJSON(buffer, node.Content[i+1], indent, depth+1); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString(closingLine + "}")
+	case yamlv3.SequenceNode:
+		if len(node.Content) == 0 {
+			buffer.WriteString("[]")
+			return nil
+		}
+		buffer.WriteString("[")
+		for i, child := range node.Content {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(newLine)
+			if err := encodeJSON(buffer, child, indent, depth+1); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString(closingLine + "]")
+	case yamlv3.ScalarNode:
+		switch node.Tag {
+		case "!!int", "!!float", "!!bool", "!!null":
+			buffer.WriteString(node.Value)
+		default:
+			return encodeJSONString(buffer, node.Value)
+		}
+	case yamlv3.AliasNode:
+		return encodeJSON(buffer, node.Alias, indent, depth)
+	default:
+		return fmt.Errorf("unsupported node kind %v", node.Kind)
+	}
+	return nil
+}
+
+func encodeJSONString(buffer *bytes.Buffer, s string) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	buffer.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+	return nil
+}
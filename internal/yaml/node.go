@@ -0,0 +1,60 @@
+package yaml
+
+import (
+	"fmt"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// SetValue sets the value of the (nested) field in the given mapping node, creating any missing mapping on the way.
+// It returns true if the value has changed.
+func SetValue(node *yamlv3.Node, path []string, value string) (bool, error) {
+	if node.Kind != yamlv3.MappingNode {
+		return false, fmt.Errorf("can't set %s on a non-mapping node", path[0])
+	}
+
+	var valueNode *yamlv3.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == path[0] {
+			valueNode = node.Content[i+1]
+			break
+		}
+	}
+	if valueNode == nil {
+		valueNode = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+		if len(path) == 1 {
+			valueNode = &yamlv3.Node{Kind: yamlv3.ScalarNode}
+		}
+		node.Content = append(node.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: path[0]}, valueNode)
+	}
+
+	if len(path) > 1 {
+		return SetValue(valueNode, path[1:], value)
+	}
+	if valueNode.Kind != yamlv3.ScalarNode {
+		return false, fmt.Errorf("%s is not a scalar value", path[0])
+	}
+	if valueNode.Value == value && len(valueNode.Tag) > 0 {
+		return false, nil
+	}
+	switch valueNode.Tag {
+	case "", "!!str":
+		// keep the quotes of the existing value
+		style := valueNode.Style
+		valueNode.SetString(value)
+		if style == yamlv3.DoubleQuotedStyle || style == yamlv3.SingleQuotedStyle {
+			valueNode.Style = style
+		}
+	default:
+		// keep the type of the existing value - such as an int or a bool - only if the new value has the same type
+		if (&yamlv3.Node{Kind: yamlv3.ScalarNode, Value: value}).ShortTag() == valueNode.ShortTag() {
+			valueNode.Value = value
+			break
+		}
+		valueNode.SetString(value)
+		if valueNode.Style != yamlv3.LiteralStyle {
+			valueNode.Style = 0
+		}
+	}
+	return true, nil
+}
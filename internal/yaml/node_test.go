@@ -0,0 +1,118 @@
+package yaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+func TestSetValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		content         string
+		path            string
+		value           string
+		expectedUpdated bool
+		expectedYAML    string
+		expectedJSON    string
+	}{
+		{
+			name:            "int field set to an int",
+			content:         "a: 30\n",
+			path:            "a",
+			value:           "60",
+			expectedUpdated: true,
+			expectedYAML:    "a: 60\n",
+			expectedJSON:    "{\n  \"a\": 60\n}",
+		},
+		{
+			name:            "int field set to a string",
+			content:         "a: 30\n",
+			path:            "a",
+			value:           "thirty",
+			expectedUpdated: true,
+			expectedYAML:    "a: thirty\n",
+			expectedJSON:    "{\n  \"a\": \"thirty\"\n}",
+		},
+		{
+			name:            "bool field set to a bool",
+			content:         "enabled: true\n",
+			path:            "enabled",
+			value:           "false",
+			expectedUpdated: true,
+			expectedYAML:    "enabled: false\n",
+			expectedJSON:    "{\n  \"enabled\": false\n}",
+		},
+		{
+			name:            "bool field set to a string",
+			content:         "enabled: true\n",
+			path:            "enabled",
+			value:           "maybe",
+			expectedUpdated: true,
+			expectedYAML:    "enabled: maybe\n",
+			expectedJSON:    "{\n  \"enabled\": \"maybe\"\n}",
+		},
+		{
+			name:            "null field set to a string",
+			content:         "url: null\n",
+			path:            "url",
+			value:           "http://x",
+			expectedUpdated: true,
+			expectedYAML:    "url: http://x\n",
+			expectedJSON:    "{\n  \"url\": \"http://x\"\n}",
+		},
+		{
+			name:            "string field set to a number",
+			content:         "version: \"1.0\"\n",
+			path:            "version",
+			value:           "2",
+			expectedUpdated: true,
+			expectedYAML:    "version: \"2\"\n",
+			expectedJSON:    "{\n  \"version\": \"2\"\n}",
+		},
+		{
+			name:            "missing nested field",
+			content:         "a: 1\n",
+			path:            "b.c",
+			value:           "value",
+			expectedUpdated: true,
+			expectedYAML:    "a: 1\nb:\n    c: value\n",
+			expectedJSON:    "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": \"value\"\n  }\n}",
+		},
+		{
+			name:         "unchanged value",
+			content:      "a: 30\n",
+			path:         "a",
+			value:        "30",
+			expectedYAML: "a: 30\n",
+			expectedJSON: "{\n  \"a\": 30\n}",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			var rootNode yamlv3.Node
+			require.NoError(t, yamlv3.Unmarshal([]byte(test.content), &rootNode))
+
+			updated, err := SetValue(rootNode.Content[0], strings.Split(test.path, "."), test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedUpdated, updated)
+
+			yamlContent, err := yamlv3.Marshal(&rootNode)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedYAML, string(yamlContent))
+
+			var jsonContent bytes.Buffer
+			require.NoError(t, EncodeJSON(&jsonContent, rootNode.Content[0], 2))
+			assert.Equal(t, test.expectedJSON, jsonContent.String())
+		})
+	}
+}
@@ -11,8 +11,12 @@ import (
 	"time"
 
 	"github.com/dailymotion-oss/octopilot/internal/git"
+	"github.com/dailymotion-oss/octopilot/internal/transport"
 	"github.com/dailymotion-oss/octopilot/repository"
 	"github.com/dailymotion-oss/octopilot/update"
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 )
@@ -26,11 +30,19 @@ var (
 )
 
 var options struct {
-	updates []string
-	repos   []string
+	updates            []string
+	repos              []string
+	updatesFile        string
+	updatesFileSkipBad bool
 	repository.UpdateOptions
-	logLevel    string
-	failOnError bool
+	transport        transport.Options
+	prCreateDelay    time.Duration
+	prCreateJitter   time.Duration
+	rollbackManifest string
+	planFile         string
+	sarifReport      string
+	logLevel         string
+	failOnError      bool
 }
 
 func init() {
@@ -39,6 +51,8 @@ func init() {
 	assert(pflag.CommandLine.SetAnnotation("update", "mandatory", []string{"true"}))
 	pflag.StringArrayVarP(&options.repos, "repo", "r", nil, `A repository to update, defined either statically in the form "org/repo", or dynamically with the "discover-from" prefix - see the online documentation for more details.`)
 	assert(pflag.CommandLine.SetAnnotation("repo", "mandatory", []string{"true"}))
+	pflag.StringVar(&options.updatesFile, "updates-file", "", `Path to a CSV - or TSV, with the ".tsv" extension - file defining the updates, with one update per row and the "repo", "file", "key" and "value" columns - and an optional "updater" column. The updates are grouped by repository, in a single Pull Request per repository. Can't be used with the --update and --repo flags.`)
+	pflag.BoolVar(&options.updatesFileSkipBad, "updates-file-skip-invalid-rows", false, "Skip - and log - the invalid rows of the --updates-file, instead of failing.")
 	pflag.StringVar(&options.GitHub.AuthMethod, "github-auth-method", "token", `Mandatory GitHub authentication method: either "token" or "app" - see the online documentation for more details.`)
 	assert(pflag.CommandLine.SetAnnotation("github-auth-method", "mandatory", []string{"true"}))
 
@@ -50,6 +64,10 @@ func init() {
 	pflag.StringVar(&options.GitHub.PrivateKeyPath, "github-privatekey-path", os.Getenv("GITHUB_PRIVATEKEY_PATH"), "For the `app` GitHub auth method, contains the GitHubApp Private key file path `/some/key.pem` (used if the github-privatekey is empty). Default to the GITHUB_PRIVATEKEY_PATH env var.")
 	pflag.StringVar(&options.GitHub.URL, "github-url", repository.PublicGithubURL, `GitHub server URL`)
 
+	// GitLab flags
+	pflag.StringVar(&options.GitLab.Token, "gitlab-token", os.Getenv("GITLAB_TOKEN"), `This is the GitLab token - required to update repositories hosted on GitLab. Default to the GITLAB_TOKEN env var.`)
+	pflag.StringVar(&options.GitLab.URL, "gitlab-url", repository.PublicGitLabURL, `GitLab server URL. Repositories with a matching host - or with the "provider=gitlab" parameter - are updated through GitLab.`)
+
 	// pull-request flags
 	pflag.StringVar(&options.GitHub.PullRequest.Title, "pr-title", "", "The title of the Pull Request to create. Default to the commit title.")
 	pflag.StringVar(&options.GitHub.PullRequest.TitleUpdateOperation, "pr-title-update-operation", "", `The type of operation when updating the PR's title: "ignore" (keep old value), "replace", "prepend" or "append". Default is: "ignore" for "append" strategy, "replace" for "reset" strategy, and not applicable for "recreate" strategy.`)
@@ -58,6 +76,8 @@ func init() {
 	pflag.StringArrayVar(&options.GitHub.PullRequest.Comments, "pr-comment", []string{}, "List of comments to add to the Pull Request.")
 	pflag.StringSliceVar(&options.GitHub.PullRequest.Labels, "pr-labels", []string{"octopilot-update"}, "List of labels set on the pull requests, and used to find existing pull requests to update.")
 	pflag.StringVar(&options.GitHub.PullRequest.BaseBranch, "pr-base-branch", "master", "Name of the branch used as a base when creating pull requests.")
+	pflag.StringSliceVar(&options.BaseBranches, "base-branches", nil, `Names of the branches against which the updaters are run - such as "release/1.0,release/1.1" - to backport the changes. Each branch is cloned and updated separately, with its own pull request, from a branch whose name contains the name of the base branch. Overrides the --pr-base-branch flag and the "branch" repository param.`)
+	pflag.StringVar(&options.GitHub.PullRequest.Repository, "pr-repo", "", `Optional "owner/name" of the repository in which the Pull Requests are created, if it's not the updated repository - which must then be a fork of it. The changes are pushed to a branch of the updated repository (the fork), and the Pull Requests are opened in this upstream repository. Only supported for GitHub.`)
 	pflag.BoolVar(&options.GitHub.PullRequest.Draft, "pr-draft", false, `Create "draft" Pull Requests, instead of regular ones. It means that the PRs can't be merged until marked as "ready for review".`)
 	pflag.BoolVar(&options.GitHub.PullRequest.Merge.Enabled, "pr-merge", false, `Automatically merge the Pull Requests created. It will wait until the PRs are "mergeable" before merging them.`)
 	pflag.StringVar(&options.GitHub.PullRequest.Merge.Method, "pr-merge-method", "merge", `If auto-merge is enabled, the PRs will be merged with this method. Can be either "merge", "squash", or "rebase".`)
@@ -67,6 +87,10 @@ func init() {
 	pflag.DurationVar(&options.GitHub.PullRequest.Merge.PollTimeout, "pr-merge-poll-timeout", 10*time.Minute, "If auto-merge is enabled, this is the maximum duration to wait for a Pull Request to be mergeable.")
 	pflag.DurationVar(&options.GitHub.PullRequest.Merge.PollInterval, "pr-merge-poll-interval", 30*time.Second, "If auto-merge is enabled, this is the duration to wait for between each GitHub API call to check if a PR is mergeable.")
 	pflag.IntVar(&options.GitHub.PullRequest.Merge.RetryCount, "pr-merge-retry-count", 3, "If auto-merge is enabled, this is the number of times to retry the merge operation in case of merge failure.")
+	pflag.BoolVar(&options.GitHub.PullRequest.Release.OnMerge, "create-release-on-merge", false, `Create a GitHub - or GitLab - Release once the Pull Requests are merged, with the tag defined by the --release-tag flag. The tag is recorded in the PR body, and the releases of the merged PRs are created by the next run - or right after the merge if auto-merge is enabled.`)
+	pflag.StringVar(&options.GitHub.PullRequest.Release.Tag, "release-tag", "", `If releases on merge are enabled, this is the tag of the release to create - such as 'v{{ readFile "VERSION" | trim }}'. It is executed as a template when the PR is created or updated.`)
+	pflag.DurationVar(&options.prCreateDelay, "pr-create-delay", 0, "Minimum duration to wait between 2 Pull Request creations - across all the repositories - to avoid flooding the reviewers with notifications. Default to 0 (no delay).")
+	pflag.DurationVar(&options.prCreateJitter, "pr-create-jitter", 0, "Maximum random duration added to the --pr-create-delay value between 2 Pull Request creations.")
 
 	// git-related flags
 	pflag.StringVar(&options.UpdateOptions.Git.CloneDir, "git-clone-dir", temporaryDirectory(), "Directory used to clone the repositories.")
@@ -82,10 +106,22 @@ func init() {
 	pflag.StringVar(&options.UpdateOptions.Git.BranchPrefix, "git-branch-prefix", "octopilot-", "Prefix of the new branch to create.")
 	pflag.StringVar(&options.UpdateOptions.Git.SigningKeyPath, "git-signing-key-path", os.Getenv("GIT_SIGNING_KEY_PATH"), "Path to the private key file to sign commits or tags (e.g. `/some/key.pgp`). Default to the GIT_SIGNING_KEY_PATH env var.")
 	pflag.StringVar(&options.UpdateOptions.Git.SigningKeyPassphrase, "git-signing-key-passphrase", os.Getenv("GIT_SIGNING_KEY_PASSPHRASE"), "Passphrase to decrypt the signing key. Default to the GIT_SIGNING_KEY_PASSPHRASE env var.")
+	pflag.StringVar(&options.UpdateOptions.Git.AuditLogFile, "git-audit-log-file", "", "Path - relative to the root of the repository - of an audit log file, such as `.octopilot-audit.log`. If set, a line will be appended to this file for each updater that changed the repository, and committed with the changes.")
+	pflag.StringVar(&options.UpdateOptions.Git.AuditLogRunID, "git-audit-log-run-id", xid.New().String(), "ID of the run, recorded in the audit log. Default to a random ID, shared by all the repositories updated in the same run.")
+	pflag.IntVar(&options.UpdateOptions.Git.PushRetryCount, "git-push-retry-count", 3, `Number of times to retry a git push rejected because the remote branch has been updated - with the "append" strategy. The changes are applied again on top of the updated branch before each retry.`)
+	pflag.DurationVar(&options.UpdateOptions.Git.PushRetryInterval, "git-push-retry-interval", 1*time.Second, "Duration to wait before retrying a rejected git push. It is doubled after each retry.")
 
 	pflag.StringVar(&options.Strategy, "strategy", "reset", `Strategy to use when creating/updating the Pull Requests: either "reset" (reset any existing PR from the current base branch), "append" (append new commit to any existing PR) or "recreate" (always create a new PR).`)
+	pflag.IntVar(&options.MinChangedFiles, "min-changed-files", 0, "Minimum number of files changed by the updaters to create/update a Pull Request. If fewer files are changed, the repository is skipped. Default to 0 (no minimum).")
+	pflag.BoolVar(&options.RevertBelowMinChangedFiles, "min-changed-files-revert", false, "Revert the changes in the local cloned repository if fewer files than the --min-changed-files value are changed.")
+	pflag.StringVar(&options.rollbackManifest, "rollback-manifest", "", "Path to a JSON file recording the Pull Requests, branches and commits created or updated by the run - so that they can be rolled back later with the \"rollback\" command, which reads it.")
+	pflag.StringVar(&options.planFile, "plan-file", "", "Path to the JSON plan file written by the \"plan\" command - with the changes planned on each repository and the values used - and read by the \"apply\" command, which applies exactly these changes.")
+	pflag.StringVar(&options.sarifReport, "sarif-report", "", "Path to a SARIF file recording the files - and lines - changed by each updater in each repository, for the code scanning dashboards of the security tooling. Each updater type is a rule, such as octopilot/sops. The changed values are never recorded.")
+	pflag.StringVar(&options.ExportChangesDir, "export-changes", "", "Path to a directory in which the content of each file changed by an updater is exported - before and after the update - as <owner>/<repo>/updater-<n>/<file>.before and <file>.after files, for debugging. The sops and ansible-vault files are exported decrypted, with their values masked, and the other secret values are masked. Disabled by default.")
 	pflag.BoolVar(&options.KeepFiles, "keep-files", false, "Keep the cloned repositories on disk. If false, the files will be deleted at the end of the process.")
 	pflag.BoolVarP(&options.DryRun, "dry-run", "n", false, `Don't perform any operation on the remote git repository: all operations will be done in the local cloned repository. You should also set the "--keep-files" flag to keep the files and inspect the changes in the local repository.`)
+	pflag.StringVar(&options.transport.ProxyURL, "http-proxy", "", "URL of the proxy used for all outbound HTTP calls: GitHub/GitLab APIs, git remotes, and valuers. Default to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars.")
+	pflag.StringVar(&options.transport.CACertPath, "ca-cert", "", "Path to a PEM-encoded CA bundle, trusted in addition to the system's CAs for all outbound HTTPS calls.")
 	pflag.StringVar(&options.logLevel, "log-level", "info", "Log level. Supported values: trace, debug, info, warning, error, fatal, panic.")
 
 	pflag.BoolVar(&options.failOnError, "fail-on-error", false, "Exit with error code 1 if any repository update fails.")
@@ -96,6 +132,10 @@ func init() {
 	pflag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Octopilot v%s - Documentation at https://dailymotion-oss.github.io/octopilot/v%s/\n", buildVersion, buildVersion)
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s rollback --rollback-manifest=PATH [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s plan --plan-file=PATH [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s apply --plan-file=PATH [flags]\n", os.Args[0])
 		pflag.PrintDefaults()
 	}
 }
@@ -106,33 +146,38 @@ func main() {
 	printHelpOrVersion()
 	setLogLevel()
 	checkMandatoryFlags()
+	setHTTPTransport()
+	options.GitHub.PullRequest.CreatePacer = repository.NewCreationPacer(options.prCreateDelay, options.prCreateJitter)
 
-	logrus.WithField("updates", options.updates).Trace("Parsing updates")
-	updaters, err := update.Parse(options.updates)
-	if err != nil {
-		logrus.
-			WithError(err).
-			WithField("updates", options.updates).
-			Fatal("Failed to parse updates")
+	if pflag.Arg(0) == "rollback" {
+		rollback(ctx)
+		return
+	}
+	if len(options.rollbackManifest) > 0 {
+		options.RollbackManifest = repository.NewRollbackManifest(options.Git.AuditLogRunID)
+	}
+	if len(options.sarifReport) > 0 {
+		options.SARIFReport = repository.NewSARIFReport(buildVersion)
 	}
-	logrus.WithField("updaters", updaters).Debug("Updaters ready")
 
-	logrus.WithField("repos", options.repos).Trace("Parsing repositories")
-	repositories, err := repository.Parse(ctx, options.repos, options.GitHub)
-	if err != nil {
-		logrus.
-			WithError(err).
-			WithField("repos", options.repos).
-			Fatal("Failed to parse repos")
+	var jobs []repositoryUpdate
+	switch {
+	case pflag.Arg(0) == "plan":
+		jobs = planUpdates(ctx)
+	case pflag.Arg(0) == "apply":
+		jobs = applyPlan()
+	case len(options.updatesFile) > 0:
+		jobs = parseUpdatesFile(ctx)
+	default:
+		jobs = parseUpdatesAndRepos(ctx, update.Parse)
 	}
-	logrus.WithField("repositories", repositories).Debug("Repositories ready")
 
-	logrus.WithField("repositories-count", len(repositories)).Trace("Starting updates")
+	logrus.WithField("repositories-count", len(jobs)).Trace("Starting updates")
 	var wg sync.WaitGroup
-	errors := make(chan error, len(repositories))
-	for _, repo := range repositories {
+	errors := make(chan error, len(jobs))
+	for _, job := range jobs {
 		wg.Add(1)
-		go func(repo repository.Repository) {
+		go func(repo repository.Repository, updaters []update.Updater) {
 			defer wg.Done()
 			logrus.WithField("repository", repo.FullName()).Trace("Starting repository update")
 			updated, err := repo.Update(ctx, updaters, options.UpdateOptions)
@@ -149,17 +194,199 @@ func main() {
 				return
 			}
 			logrus.WithField("repository", repo.FullName()).Info("Repository update finished")
-		}(repo)
+		}(job.repo, job.updaters)
 	}
 	wg.Wait()
 	close(errors)
-	logrus.WithField("repositories-count", len(repositories)).Info("Updates finished")
+	logrus.WithField("repositories-count", len(jobs)).Info("Updates finished")
+
+	if pflag.Arg(0) == "plan" {
+		if err := options.Plan.Write(options.planFile); err != nil {
+			logrus.WithError(err).Fatal("Failed to write the plan")
+		}
+		logrus.WithField("path", options.planFile).Info("Plan written")
+	}
+
+	if options.RollbackManifest != nil {
+		if err := options.RollbackManifest.Write(options.rollbackManifest); err != nil {
+			logrus.WithError(err).Fatal("Failed to write the rollback manifest")
+		}
+		logrus.WithField("path", options.rollbackManifest).Info("Rollback manifest written")
+	}
+
+	if options.SARIFReport != nil {
+		if err := options.SARIFReport.Write(options.sarifReport); err != nil {
+			logrus.WithError(err).Fatal("Failed to write the SARIF report")
+		}
+		logrus.WithField("path", options.sarifReport).Info("SARIF report written")
+	}
 
 	if options.failOnError && len(errors) > 0 {
 		logrus.Fatal("Some repository updates failed")
 	}
 }
 
+// repositoryUpdate is a repository to update, with the updaters to run on it
+type repositoryUpdate struct {
+	repo     repository.Repository
+	updaters []update.Updater
+}
+
+// parseUpdatesAndRepos parses the --update and --repo flags - with the given parse function: the same updaters are run on all the repositories
+func parseUpdatesAndRepos(ctx context.Context, parse func([]string) ([]update.Updater, error)) []repositoryUpdate {
+	logrus.WithField("updates", options.updates).Trace("Parsing updates")
+	updaters, err := parse(options.updates)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("updates", options.updates).
+			Fatal("Failed to parse updates")
+	}
+	logrus.WithField("updaters", updaters).Debug("Updaters ready")
+
+	logrus.WithField("repos", options.repos).Trace("Parsing repositories")
+	repositories, err := repository.Parse(ctx, options.repos, options.GitHub)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("repos", options.repos).
+			Fatal("Failed to parse repos")
+	}
+	logrus.WithField("repositories", repositories).Debug("Repositories ready")
+
+	jobs := make([]repositoryUpdate, 0, len(repositories))
+	for _, repo := range repositories {
+		jobs = append(jobs, repositoryUpdate{repo: repo, updaters: updaters})
+	}
+	return jobs
+}
+
+// parseUpdatesFile parses the --updates-file: each repository defined in the file is updated with its own updaters
+func parseUpdatesFile(ctx context.Context) []repositoryUpdate {
+	if len(options.updates) > 0 || len(options.repos) > 0 {
+		logrus.Fatal("The --updates-file flag can't be used with the --update and --repo flags")
+	}
+
+	logrus.WithField("updates-file", options.updatesFile).Trace("Parsing updates file")
+	file, err := os.Open(options.updatesFile)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("updates-file", options.updatesFile).
+			Fatal("Failed to open the updates file")
+	}
+	defer file.Close()
+
+	tableOptions := update.TableOptions{
+		Comma:           ',',
+		SkipInvalidRows: options.updatesFileSkipBad,
+	}
+	if strings.EqualFold(filepath.Ext(options.updatesFile), ".tsv") {
+		tableOptions.Comma = '\t'
+	}
+	tableUpdates, err := update.ParseTable(file, tableOptions)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("updates-file", options.updatesFile).
+			Fatal("Failed to parse the updates file")
+	}
+
+	var jobs []repositoryUpdate
+	for _, tableUpdate := range tableUpdates {
+		repositories, err := repository.Parse(ctx, []string{tableUpdate.Repository}, options.GitHub)
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("repo", tableUpdate.Repository).
+				Fatal("Failed to parse repos")
+		}
+		for _, repo := range repositories {
+			jobs = append(jobs, repositoryUpdate{repo: repo, updaters: tableUpdate.Updaters})
+		}
+	}
+	logrus.WithField("repositories-count", len(jobs)).Debug("Repositories ready")
+	return jobs
+}
+
+// planUpdates records the changes that the updaters would make on the repositories in a plan - without pushing anything
+func planUpdates(ctx context.Context) []repositoryUpdate {
+	if len(options.planFile) == 0 {
+		logrus.Fatal("Missing the --plan-file flag, with the path to the plan to write")
+	}
+	if len(options.updatesFile) > 0 {
+		logrus.Fatal("The --updates-file flag can't be used with the plan command")
+	}
+	if len(options.BaseBranches) > 0 {
+		logrus.Fatal("The --base-branches flag can't be used with the plan command")
+	}
+
+	options.Plan = repository.NewPlan(options.Git.AuditLogRunID, options.updates)
+	options.DryRun = true
+	return parseUpdatesAndRepos(ctx, func([]string) ([]update.Updater, error) {
+		return options.Plan.Updaters()
+	})
+}
+
+// applyPlan returns the repositories of the plan written by a previous run, with the updaters replaying the values recorded in the plan
+func applyPlan() []repositoryUpdate {
+	if len(options.planFile) == 0 {
+		logrus.Fatal("Missing the --plan-file flag, with the path to the plan written by the plan command")
+	}
+	if len(options.updates) > 0 || len(options.repos) > 0 || len(options.updatesFile) > 0 {
+		logrus.Fatal("The --update, --repo and --updates-file flags can't be used with the apply command: the updates and repositories are read from the plan")
+	}
+	if len(options.BaseBranches) > 0 {
+		logrus.Fatal("The --base-branches flag can't be used with the apply command")
+	}
+
+	plan, err := repository.ReadPlan(options.planFile)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to read the plan")
+	}
+	options.Plan = plan
+
+	updaters, err := plan.Updaters()
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("updates", plan.Updates).
+			Fatal("Failed to parse the updates of the plan")
+	}
+
+	repositories := plan.Repositories()
+	logrus.WithFields(logrus.Fields{
+		"run-id":             plan.RunID,
+		"repositories-count": len(repositories),
+	}).Info("Applying plan")
+	jobs := make([]repositoryUpdate, 0, len(repositories))
+	for _, repo := range repositories {
+		jobs = append(jobs, repositoryUpdate{repo: repo, updaters: updaters})
+	}
+	return jobs
+}
+
+// rollback rolls back the changes recorded in the rollback manifest of a previous run
+func rollback(ctx context.Context) {
+	if len(options.rollbackManifest) == 0 {
+		logrus.Fatal("Missing the --rollback-manifest flag, with the path to the rollback manifest written by a previous run")
+	}
+	manifest, err := repository.ReadRollbackManifest(options.rollbackManifest)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to read the rollback manifest")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"run-id":             manifest.RunID,
+		"repositories-count": len(manifest.Entries),
+	}).Info("Starting rollback")
+	err = repository.Rollback(ctx, manifest, options.UpdateOptions)
+	if err != nil {
+		logrus.WithError(err).Fatal("Rollback failed")
+	}
+	logrus.WithField("repositories-count", len(manifest.Entries)).Info("Rollback finished")
+}
+
 func checkMandatoryFlags() {
 	var missingFlags []string
 	pflag.CommandLine.VisitAll(func(flag *pflag.Flag) {
@@ -188,6 +415,20 @@ func checkMandatoryFlags() {
 	logrus.WithField("missing-flags", missingFlags).Fatal("Mandatory fields not defined")
 }
 
+func setHTTPTransport() {
+	httpTransport, err := transport.New(options.transport)
+	if err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Invalid HTTP transport configuration")
+	}
+	transport.SetDefault(httpTransport)
+	// also use it for the git remotes
+	gitClient := githttp.NewClient(transport.DefaultClient())
+	gitclient.InstallProtocol("https", gitClient)
+	gitclient.InstallProtocol("http", gitClient)
+}
+
 func setLogLevel() {
 	level, err := logrus.ParseLevel(options.logLevel)
 	if err != nil {
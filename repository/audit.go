@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dailymotion-oss/octopilot/update"
+)
+
+// appendAuditLog appends a line for each updater which changed the repository to the audit log file - relative to the repository root.
+// Each line records the timestamp, the run ID, and the updater - with the keys/files it targets, but never the values.
+// The file is opened in append mode and each line is written with a single call, so that it is safe for concurrent appends.
+func appendAuditLog(repoPath, auditLogFile, runID string, updaters []update.Updater, now time.Time) error {
+	filePath := filepath.Join(repoPath, auditLogFile)
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create the parent directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	lines := new(strings.Builder)
+	for _, updater := range updaters {
+		fmt.Fprintf(lines, "timestamp=%s run_id=%s updater=%s\n",
+			now.UTC().Format(time.RFC3339),
+			strconv.Quote(runID),
+			strconv.Quote(updater.String()),
+		)
+	}
+
+	_, err = file.WriteString(lines.String())
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFileUpdater is a test updater that writes a fixed content to a file
+type writeFileUpdater struct {
+	file    string
+	content string
+}
+
+func (u *writeFileUpdater) Update(_ context.Context, repoPath string) (bool, error) {
+	filePath := filepath.Join(repoPath, u.file)
+	if data, err := os.ReadFile(filePath); err == nil && string(data) == u.content {
+		return false, nil
+	}
+	return true, os.WriteFile(filePath, []byte(u.content), 0644)
+}
+
+func (u *writeFileUpdater) Message() (string, string) {
+	return "Update " + u.file, ""
+}
+
+func (u *writeFileUpdater) String() string {
+	return "WriteFile[file=" + u.file + "]"
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Parallel()
+
+	repoPath := t.TempDir()
+	gitRepo, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+	workTree, err := gitRepo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "version.txt"), []byte("v1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".octopilot-audit.log"), []byte("previous entry\n"), 0644))
+	_, err = workTree.Add(".")
+	require.NoError(t, err)
+	_, err = workTree.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	options := UpdateOptions{
+		Git: GitOptions{
+			StageAllChanged: true,
+			AuthorName:      "test",
+			AuthorEmail:     "test@example.com",
+			CommitterName:   "test",
+			CommitterEmail:  "test@example.com",
+			CommitTitle:     "update",
+			AuditLogFile:    ".octopilot-audit.log",
+			AuditLogRunID:   "run-42",
+		},
+	}
+	updaters := []update.Updater{
+		&writeFileUpdater{file: "version.txt", content: "v2"},
+		&writeFileUpdater{file: "version.txt", content: "v2"}, // no changes, so not in the audit log
+	}
+
+	repo := Repository{Owner: "owner", Name: "repo"}
+	updated, err := repo.runUpdaters(context.Background(), updaters, repoPath, options)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	committed, err := commitChanges(context.Background(), gitRepo, options)
+	require.NoError(t, err)
+	require.True(t, committed)
+
+	status, err := workTree.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), "the audit log should be committed with the changes: %s", status.String())
+
+	data, err := os.ReadFile(filepath.Join(repoPath, ".octopilot-audit.log"))
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^previous entry\ntimestamp=\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z run_id="run-42" updater="WriteFile\[file=version.txt\]"\n$`), string(data))
+}
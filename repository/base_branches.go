@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/sirupsen/logrus"
+)
+
+// characters which can't be used in the paths and branch names derived from a base branch name
+var unsafeBranchCharsRegexp = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// updateBaseBranches runs the updaters against each of the base branches of the options - for example to backport a change to multiple release branches.
+// Each base branch is cloned in its own directory, and gets its own pull request - from a branch scoped to the base branch.
+// A failure on a base branch doesn't prevent the other ones from being updated: all the errors are returned together.
+func (r Repository) updateBaseBranches(ctx context.Context, updaters []update.Updater, options UpdateOptions, provider Provider) (bool, error) {
+	var (
+		repoUpdated bool
+		updated     []string
+		unchanged   []string
+		failed      []string
+		errs        []string
+	)
+	for _, baseBranch := range options.BaseBranches {
+		branchRepo, branchOptions := r.forBaseBranch(baseBranch, options)
+		logrus.WithFields(logrus.Fields{
+			"repository":  r.FullName(),
+			"base-branch": baseBranch,
+		}).Debug("Updating base branch")
+
+		branchUpdated, err := branchRepo.update(ctx, updaters, branchOptions, provider)
+		switch {
+		case err != nil:
+			logrus.WithFields(logrus.Fields{
+				"repository":  r.FullName(),
+				"base-branch": baseBranch,
+			}).WithError(err).Error("Failed to update base branch")
+			failed = append(failed, baseBranch)
+			errs = append(errs, fmt.Sprintf("base branch %s: %s", baseBranch, err))
+		case branchUpdated:
+			updated = append(updated, baseBranch)
+		default:
+			unchanged = append(unchanged, baseBranch)
+		}
+		repoUpdated = repoUpdated || branchUpdated
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"updated":    updated,
+		"unchanged":  unchanged,
+		"failed":     failed,
+	}).Info("Base branches updates finished")
+
+	if len(errs) > 0 {
+		return repoUpdated, fmt.Errorf("failed to update %d out of %d base branches of repository %s: %s", len(errs), len(options.BaseBranches), r.FullName(), strings.Join(errs, "; "))
+	}
+	return repoUpdated, nil
+}
+
+// forBaseBranch returns the repository and options to update the given base branch:
+// the base branch is cloned - in a dedicated directory - and used as the base of the pull request,
+// from a branch whose name contains the name of the base branch, so that each base branch gets its own pull request.
+func (r Repository) forBaseBranch(baseBranch string, options UpdateOptions) (Repository, UpdateOptions) {
+	params := make(map[string]string, len(r.Params)+1)
+	for key, value := range r.Params {
+		params[key] = value
+	}
+	params["branch"] = baseBranch
+	r.Params = params
+
+	safeName := strings.Trim(unsafeBranchCharsRegexp.ReplaceAllString(baseBranch, "-"), "-")
+	options.BaseBranches = nil
+	options.GitHub.PullRequest.BaseBranch = baseBranch
+	options.Git.BranchPrefix = fmt.Sprintf("%s%s-", options.Git.BranchPrefix, safeName)
+	options.Git.CloneDir = filepath.Join(options.Git.CloneDir, "base-branches", safeName)
+	if len(options.ExportChangesDir) > 0 {
+		options.ExportChangesDir = filepath.Join(options.ExportChangesDir, safeName)
+	}
+	return r, options
+}
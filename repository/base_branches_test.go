@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initLocalRepositoryWithBranches returns a local repository with the given branches - all pointing to the initial commit
+func initLocalRepositoryWithBranches(t *testing.T, files map[string]string, branches ...string) string {
+	t.Helper()
+
+	repoPath := initLocalRepository(t, files)
+	gitRepo, err := git.PlainOpen(repoPath)
+	require.NoError(t, err)
+	head, err := gitRepo.Head()
+	require.NoError(t, err)
+	for _, branch := range branches {
+		require.NoError(t, gitRepo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), head.Hash())))
+	}
+	return repoPath
+}
+
+func TestUpdateBaseBranches(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                string
+		baseBranches        []string
+		expectedUpdated     bool
+		expectedCreatedFrom []string
+		expectedErrorMsg    string
+	}{
+		{
+			name:            "pull requests against 3 base branches",
+			baseBranches:    []string{"release/1.0", "release/1.1", "main"},
+			expectedUpdated: true,
+			expectedCreatedFrom: []string{
+				`release/1.0 <- octopilot-release-1.0-[a-z0-9]{20}`,
+				`release/1.1 <- octopilot-release-1.1-[a-z0-9]{20}`,
+				`main <- octopilot-main-[a-z0-9]{20}`,
+			},
+		},
+		{
+			name:            "missing base branch",
+			baseBranches:    []string{"release/1.0", "release/0.9", "main"},
+			expectedUpdated: true,
+			expectedCreatedFrom: []string{
+				`release/1.0 <- octopilot-release-1.0-[a-z0-9]{20}`,
+				`main <- octopilot-main-[a-z0-9]{20}`,
+			},
+			expectedErrorMsg: "failed to update 1 out of 3 base branches of repository owner/repo: base branch release/0.9: ",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			provider := &localProvider{
+				path: initLocalRepositoryWithBranches(t, map[string]string{"a.txt": "a1"}, "release/1.0", "release/1.1", "main"),
+			}
+			repo := Repository{Owner: "owner", Name: "repo", Params: map[string]string{}}
+			cloneDir := t.TempDir()
+			options := UpdateOptions{
+				Strategy:     "recreate",
+				BaseBranches: test.baseBranches,
+				Git: GitOptions{
+					CloneDir:        cloneDir,
+					StageAllChanged: true,
+					StagePatterns:   []string{"*.txt"},
+					AuthorName:      "test",
+					AuthorEmail:     "test@example.com",
+					CommitterName:   "test",
+					CommitterEmail:  "test@example.com",
+					CommitTitle:     "update",
+					BranchPrefix:    "octopilot-",
+				},
+				GitHub: GitHubOptions{
+					PullRequest: PullRequestOptions{Title: "update", Body: "update", BaseBranch: "master"},
+				},
+			}
+
+			updated, err := repo.updateBaseBranches(context.Background(), []update.Updater{
+				&writeFileUpdater{file: "a.txt", content: "a2"},
+			}, options, provider)
+			if len(test.expectedErrorMsg) > 0 {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedErrorMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedUpdated, updated)
+			require.Len(t, provider.createdFrom, len(test.expectedCreatedFrom))
+			for i, expected := range test.expectedCreatedFrom {
+				assert.Regexp(t, regexp.MustCompile("^"+expected+"$"), provider.createdFrom[i])
+			}
+
+			// the base branches are not modified: the changes are pushed to the head branches
+			gitRepo, err := git.PlainOpen(provider.path)
+			require.NoError(t, err)
+			for _, baseBranch := range test.baseBranches {
+				ref, err := gitRepo.Reference(plumbing.NewBranchReferenceName(baseBranch), true)
+				if err != nil {
+					continue
+				}
+				commit, err := gitRepo.CommitObject(ref.Hash())
+				require.NoError(t, err)
+				assert.Equal(t, "initial commit", commit.Message)
+			}
+
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dailymotion-oss/octopilot/internal/changes"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// fileContent is the content of a file of the repository - which may not exist
+type fileContent struct {
+	data   string
+	exists bool
+}
+
+// fileChange is a file changed by an updater, with its content before and after the update
+type fileChange struct {
+	file   string
+	before fileContent
+	after  fileContent
+}
+
+// snapshotChangedFiles returns the content of the files already changed in the repository cloned at the given path - before an updater runs -
+// so that only the changes of the updater are recorded - see updaterChanges. The other files still have their content of the HEAD commit.
+func snapshotChangedFiles(repoPath string, gitOpts GitOptions) (map[string]fileContent, error) {
+	gitRepo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository %s: %w", repoPath, err)
+	}
+	changedFiles, err := listChangedFiles(gitRepo, gitOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the changed files: %w", err)
+	}
+	contents := make(map[string]fileContent, len(changedFiles))
+	for _, file := range changedFiles {
+		if contents[file], err = readWorktreeFile(repoPath, file); err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+// updaterChanges returns the files changed by an updater in the repository cloned at the given path - compared to the given snapshot,
+// taken before the updater ran. The files which are not in the snapshot are compared to their content in the HEAD commit.
+func updaterChanges(repoPath string, gitOpts GitOptions, before map[string]fileContent) ([]fileChange, error) {
+	gitRepo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository %s: %w", repoPath, err)
+	}
+	changedFiles, err := listChangedFiles(gitRepo, gitOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the changed files: %w", err)
+	}
+	head, err := gitRepo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the HEAD of git repository %s: %w", repoPath, err)
+	}
+	headCommit, err := gitRepo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the HEAD commit: %w", err)
+	}
+
+	// the files changed back to their HEAD content are not changed anymore - but have been changed by the updater
+	files := changedFiles
+	for file := range before {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var fileChanges []fileChange
+	for i, file := range files {
+		if i > 0 && files[i-1] == file {
+			continue
+		}
+		oldContent, found := before[file]
+		if !found {
+			if oldContent, err = readCommitFile(headCommit, file); err != nil {
+				return nil, err
+			}
+		}
+		newContent, err := readWorktreeFile(repoPath, file)
+		if err != nil {
+			return nil, err
+		}
+		if oldContent == newContent {
+			continue
+		}
+		fileChanges = append(fileChanges, fileChange{
+			file:   file,
+			before: oldContent,
+			after:  newContent,
+		})
+	}
+	return fileChanges, nil
+}
+
+// exportChanges exports the given files changed by the updater with the given index to the given directory - with the secret values recorded in the context masked.
+// The encrypted files whose decrypted content has been recorded in the context by the updater are exported in plaintext - with their values masked.
+func (r Repository) exportChanges(ctx context.Context, dir string, updaterIndex int, fileChanges []fileChange) error {
+	exportDir := r.exportChangesDir(dir, updaterIndex)
+	secrets := changes.Secrets(ctx)
+	plaintexts := changes.Plaintexts(ctx)
+	for _, change := range fileChanges {
+		var before, after []byte
+		if change.before.exists {
+			before = []byte(change.before.data)
+		}
+		if change.after.exists {
+			after = []byte(change.after.data)
+		}
+		if plaintext, found := plaintexts[change.file]; found && change.before.exists && change.after.exists {
+			before, after = plaintext.Before, plaintext.After
+		}
+		if err := changes.Export(exportDir, change.file, before, after, secrets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportChangesDir returns the directory in which the changes of the updater with the given index are exported: <dir>/<owner>/<name>/updater-<n>
+func (r Repository) exportChangesDir(dir string, updaterIndex int) string {
+	return filepath.Join(dir, r.Owner, r.Name, fmt.Sprintf("updater-%d", updaterIndex+1))
+}
+
+func readWorktreeFile(repoPath, file string) (fileContent, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, file))
+	if os.IsNotExist(err) {
+		return fileContent{}, nil
+	}
+	if err != nil {
+		return fileContent{}, fmt.Errorf("failed to read file %s: %w", file, err)
+	}
+	return fileContent{data: string(data), exists: true}, nil
+}
+
+func readCommitFile(commit *object.Commit, file string) (fileContent, error) {
+	f, err := commit.File(filepath.ToSlash(file))
+	if err == object.ErrFileNotFound {
+		return fileContent{}, nil
+	}
+	if err != nil {
+		return fileContent{}, fmt.Errorf("failed to read file %s from commit %s: %w", file, commit.Hash, err)
+	}
+	data, err := f.Contents()
+	if err != nil {
+		return fileContent{}, fmt.Errorf("failed to read file %s from commit %s: %w", file, commit.Hash, err)
+	}
+	return fileContent{data: data, exists: true}, nil
+}
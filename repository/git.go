@@ -5,9 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,15 +16,13 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/sirupsen/logrus"
 )
 
-func cloneGitRepository(ctx context.Context, repo Repository, localPath string, options GitHubOptions) (*git.Repository, error) {
-	gitURL, err := url.JoinPath(options.URL, repo.GitFullName())
+func cloneGitRepository(ctx context.Context, repo Repository, localPath string, provider Provider) (*git.Repository, error) {
+	gitURL, err := provider.gitURL(repo)
 	if err != nil {
-		// likely the Url passed is malformed
-		return nil, fmt.Errorf("invalid github url format: %w", err)
+		return nil, err
 	}
 
 	branch := "HEAD"
@@ -38,18 +36,15 @@ func cloneGitRepository(ctx context.Context, repo Repository, localPath string,
 		"local-path":    localPath,
 	}).Trace("Cloning git repository")
 
-	_, token, err := githubClient(ctx, options)
+	auth, err := provider.gitAuth(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create github client: %w", err)
+		return nil, err
 	}
 
 	gitRepo, err := git.PlainCloneContext(ctx, localPath, false, &git.CloneOptions{
 		ReferenceName: referenceName,
 		URL:           gitURL,
-		Auth: &http.BasicAuth{
-			Username: "x-access-token", // For GitHub Apps, the username must be `x-access-token`. For Personal Tokens, it doesn't matter.
-			Password: token,
-		},
+		Auth:          auth,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone git repository from %s to %s: %w", gitURL, localPath, err)
@@ -64,6 +59,70 @@ func cloneGitRepository(ctx context.Context, repo Repository, localPath string,
 	return gitRepo, nil
 }
 
+// countChangedFiles returns the number of files changed in the worktree - new, modified or deleted.
+// The audit log file is ignored, because it is changed with any other file.
+func countChangedFiles(gitRepo *git.Repository, options GitOptions) (int, error) {
+	changedFiles, err := listChangedFiles(gitRepo, options)
+	if err != nil {
+		return 0, err
+	}
+	return len(changedFiles), nil
+}
+
+// listChangedFiles returns the sorted paths of the files changed in the worktree - new, modified or deleted.
+// The audit log file is ignored, because it is changed with any other file.
+func listChangedFiles(gitRepo *git.Repository, options GitOptions) ([]string, error) {
+	workTree, err := gitRepo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+
+	status, err := workTree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the worktree status: %w", err)
+	}
+
+	var changedFiles []string
+	for filePath, fileStatus := range status {
+		if filePath == filepath.ToSlash(filepath.Clean(options.AuditLogFile)) {
+			continue
+		}
+		if fileStatus.Worktree == git.Unmodified && fileStatus.Staging == git.Unmodified {
+			continue
+		}
+		changedFiles = append(changedFiles, filePath)
+	}
+	sort.Strings(changedFiles)
+	return changedFiles, nil
+}
+
+// revertChanges discards all the changes in the worktree - including the new files.
+func revertChanges(gitRepo *git.Repository) error {
+	workTree, err := gitRepo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+
+	head, err := gitRepo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	err = workTree.Reset(&git.ResetOptions{
+		Commit: head.Hash(),
+		Mode:   git.HardReset,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset worktree: %w", err)
+	}
+
+	err = workTree.Clean(&git.CleanOptions{Dir: true})
+	if err != nil {
+		return fmt.Errorf("failed to clean worktree: %w", err)
+	}
+	return nil
+}
+
 type switchBranchOptions struct {
 	BranchName   string
 	CreateBranch bool
@@ -131,6 +190,14 @@ func commitChanges(_ context.Context, gitRepo *git.Repository, options UpdateOpt
 		"status":          status.String(),
 	}).Debug("Git status")
 
+	if len(options.Git.AuditLogFile) > 0 {
+		// the audit log may be a new file, which wouldn't be committed with the "stage all changed" option
+		_, err = workTree.Add(options.Git.AuditLogFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to stage audit log file %s: %w", options.Git.AuditLogFile, err)
+		}
+	}
+
 	for _, pattern := range options.Git.StagePatterns {
 		err = workTree.AddGlob(pattern)
 		if err != nil {
@@ -179,6 +246,9 @@ func commitChanges(_ context.Context, gitRepo *git.Repository, options UpdateOpt
 		"commit":          commit.String(),
 	}).Debug("Git commit")
 
+	if err = options.Plan.checkDiff(gitRepo, rootPath); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
@@ -209,8 +279,60 @@ func parseSigningKey(signingKeyPath, signingKeyPassphrase string) (*openpgp.Enti
 	return signingKey, nil
 }
 
+// resetToRemoteBranch fetches the given branch from the remote repository, and resets the local branch and the worktree to it - discarding any local commits and changes.
+func resetToRemoteBranch(ctx context.Context, gitRepo *git.Repository, provider Provider, branchName string) error {
+	auth, err := provider.gitAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	remoteRefName := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branchName)
+	err = gitRepo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:%s", branchName, remoteRefName)),
+		},
+		Auth: auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch branch %s: %w", branchName, err)
+	}
+
+	remoteRef, err := gitRepo.Reference(remoteRefName, true)
+	if err != nil {
+		return fmt.Errorf("failed to resolve reference %s: %w", remoteRefName, err)
+	}
+
+	workTree, err := gitRepo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+	err = workTree.Reset(&git.ResetOptions{
+		Commit: remoteRef.Hash(),
+		Mode:   git.HardReset,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset worktree to %s: %w", remoteRef.Hash(), err)
+	}
+	err = workTree.Clean(&git.CleanOptions{Dir: true})
+	if err != nil {
+		return fmt.Errorf("failed to clean worktree: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository-name": filepath.Base(workTree.Filesystem.Root()),
+		"branch":          branchName,
+		"commit":          remoteRef.Hash().String(),
+	}).Debug("Reset git branch to the remote branch")
+	return nil
+}
+
+// isNonFastForwardError returns true if the given push error is a rejection because the remote branch has commits that are not in the local branch.
+func isNonFastForwardError(err error) bool {
+	return errors.Is(err, git.ErrNonFastForwardUpdate)
+}
+
 type pushOptions struct {
-	GitHubOpts GitHubOptions
+	Provider   Provider
 	BranchName string
 	ForcePush  bool
 }
@@ -231,9 +353,9 @@ func pushChanges(ctx context.Context, gitRepo *git.Repository, opts pushOptions)
 		refSpec = fmt.Sprintf("+%s", refSpec)
 	}
 
-	_, token, err := githubClient(ctx, opts.GitHubOpts)
+	auth, err := opts.Provider.gitAuth(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create github client: %w", err)
+		return err
 	}
 
 	logrus.WithFields(logrus.Fields{
@@ -245,12 +367,13 @@ func pushChanges(ctx context.Context, gitRepo *git.Repository, opts pushOptions)
 		RefSpecs: []config.RefSpec{
 			config.RefSpec(refSpec),
 		},
-		Auth: &http.BasicAuth{
-			Username: "x-access-token", // For GitHub Apps, the username must be `x-access-token`. For Personal Tokens, it doesn't matter.
-			Password: token,
-		},
+		Auth: auth,
 	})
 	if err != nil {
+		// go-git doesn't wrap its sentinel error when the remote branch has commits that are not in the local branch
+		if !errors.Is(err, git.ErrNonFastForwardUpdate) && strings.HasPrefix(err.Error(), git.ErrNonFastForwardUpdate.Error()+":") {
+			err = fmt.Errorf("%w: %s", git.ErrNonFastForwardUpdate, strings.TrimPrefix(err.Error(), git.ErrNonFastForwardUpdate.Error()+": "))
+		}
 		return fmt.Errorf("failed to push branch %s to %s: %w", opts.BranchName, repoName, err)
 	}
 
@@ -4,14 +4,46 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/bradleyfalzon/ghinstallation"
+	"github.com/dailymotion-oss/octopilot/internal/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/google/go-github/v36/github"
 	"github.com/sirupsen/logrus"
 	"github.com/ybbus/httpretry"
 	"golang.org/x/oauth2"
 )
 
+// githubProvider is the provider for repositories hosted on GitHub - or GitHub Enterprise.
+type githubProvider struct {
+	options GitHubOptions
+}
+
+func (p *githubProvider) name() string {
+	return GitHubProvider
+}
+
+func (p *githubProvider) gitURL(repo Repository) (string, error) {
+	gitURL, err := url.JoinPath(p.options.URL, repo.GitFullName())
+	if err != nil {
+		// likely the Url passed is malformed
+		return "", fmt.Errorf("invalid github url format: %w", err)
+	}
+	return gitURL, nil
+}
+
+func (p *githubProvider) gitAuth(ctx context.Context) (*githttp.BasicAuth, error) {
+	_, token, err := githubClient(ctx, p.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create github client: %w", err)
+	}
+	return &githttp.BasicAuth{
+		Username: "x-access-token", // For GitHub Apps, the username must be `x-access-token`. For Personal Tokens, it doesn't matter.
+		Password: token,
+	}, nil
+}
+
 func githubClient(ctx context.Context, ghOptions GitHubOptions) (*github.Client, string, error) {
 	var (
 		httpClient *http.Client
@@ -46,12 +78,13 @@ func githubClient(ctx context.Context, ghOptions GitHubOptions) (*github.Client,
 
 func githubTokenClient(ctx context.Context, token string) (*http.Client, string, error) { //nolint: unparam // the returned error is not used, but we need it for the method signature
 	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, transport.DefaultClient())
 	return oauth2.NewClient(ctx, tokenSource), token, nil
 }
 
 func githubAppClient(ctx context.Context, appID int64, installationID int64, privateKey string, privateKeyPath string) (*http.Client, string, error) {
 	var (
-		tr  = http.DefaultTransport
+		tr  = transport.Default()
 		itr *ghinstallation.Transport
 		err error
 	)
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/internal/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/sirupsen/logrus"
+	"github.com/ybbus/httpretry"
+)
+
+// gitlabProvider is the provider for repositories hosted on GitLab - either gitlab.com or a self-managed instance.
+// It uses the GitLab REST API v4.
+type gitlabProvider struct {
+	options GitLabOptions
+}
+
+func (p *gitlabProvider) name() string {
+	return GitLabProvider
+}
+
+func (p *gitlabProvider) gitURL(repo Repository) (string, error) {
+	gitURL, err := url.JoinPath(p.options.URL, repo.GitFullName())
+	if err != nil {
+		// likely the Url passed is malformed
+		return "", fmt.Errorf("invalid gitlab url format: %w", err)
+	}
+	return gitURL, nil
+}
+
+func (p *gitlabProvider) gitAuth(_ context.Context) (*githttp.BasicAuth, error) {
+	if len(p.options.Token) == 0 {
+		return nil, errors.New("missing GitLab token")
+	}
+	return &githttp.BasicAuth{
+		Username: "oauth2", // GitLab accepts any username with a personal/project/group access token, but oauth2 is the documented one.
+		Password: p.options.Token,
+	}, nil
+}
+
+// gitlabAPIError is the error returned for an unexpected status code of the GitLab API
+type gitlabAPIError struct {
+	method     string
+	url        string
+	statusCode int
+	body       string
+}
+
+func (e *gitlabAPIError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status code %d: %s", e.method, e.url, e.statusCode, e.body)
+}
+
+// errIsGitLabNotFound returns true if the given error is a "not found" response of the GitLab API
+func errIsGitLabNotFound(err error) bool {
+	var apiErr *gitlabAPIError
+	return errors.As(err, &apiErr) && apiErr.statusCode == http.StatusNotFound
+}
+
+// projectPath returns the API path of the given repository - with its URL-encoded full name as the project ID
+func (p *gitlabProvider) projectPath(repo Repository) string {
+	return fmt.Sprintf("projects/%s", url.PathEscape(repo.FullName()))
+}
+
+// do sends a request to the GitLab API, and decodes the JSON response into the given result - if not nil
+func (p *gitlabProvider) do(ctx context.Context, method, path string, query url.Values, payload, result interface{}) error {
+	if len(p.options.Token) == 0 {
+		return errors.New("missing GitLab token")
+	}
+
+	apiURL := fmt.Sprintf("%s/api/v4/%s", strings.TrimSuffix(p.options.URL, "/"), path)
+	if len(query) > 0 {
+		apiURL = fmt.Sprintf("%s?%s", apiURL, query.Encode())
+	}
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode request payload: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", p.options.Token)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"method": method,
+		"url":    apiURL,
+	}).Trace("Sending GitLab API request")
+	resp, err := httpretry.NewCustomClient(transport.DefaultClient()).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request to %s: %w", method, apiURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &gitlabAPIError{
+			method:     method,
+			url:        apiURL,
+			statusCode: resp.StatusCode,
+			body:       strings.TrimSpace(string(respBody)),
+		}
+	}
+
+	if result == nil {
+		return nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, apiURL, err)
+	}
+	return nil
+}
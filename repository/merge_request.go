@@ -0,0 +1,382 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// gitlabMergeRequest is the subset of the GitLab Merge Request API representation used by octopilot
+type gitlabMergeRequest struct {
+	IID                 int      `json:"iid"`
+	WebURL              string   `json:"web_url"`
+	Title               string   `json:"title"`
+	Description         string   `json:"description"`
+	SourceBranch        string   `json:"source_branch"`
+	Labels              []string `json:"labels"`
+	State               string   `json:"state"`
+	DetailedMergeStatus string   `json:"detailed_merge_status"`
+	SHA                 string   `json:"sha"`
+	MergeCommitSHA      string   `json:"merge_commit_sha"`
+	SquashCommitSHA     string   `json:"squash_commit_sha"`
+}
+
+func (mr gitlabMergeRequest) toPullRequest() *PullRequest {
+	pr := &PullRequest{
+		Number:     mr.IID,
+		URL:        mr.WebURL,
+		Title:      mr.Title,
+		Body:       mr.Description,
+		HeadBranch: mr.SourceBranch,
+		Labels:     mr.Labels,
+	}
+	if mr.State == "merged" {
+		// fast-forward merges don't create a merge commit: the merged commit is the head of the merge request
+		switch {
+		case len(mr.MergeCommitSHA) > 0:
+			pr.MergeCommit = mr.MergeCommitSHA
+		case len(mr.SquashCommitSHA) > 0:
+			pr.MergeCommit = mr.SquashCommitSHA
+		default:
+			pr.MergeCommit = mr.SHA
+		}
+	}
+	return pr
+}
+
+func (p *gitlabProvider) findMatchingPullRequest(ctx context.Context, r Repository, options PullRequestOptions) (*PullRequest, error) {
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"labels":     options.Labels,
+	}).Trace("Looking for existing Merge Requests")
+
+	query := url.Values{}
+	query.Set("state", "opened")
+	if len(options.BaseBranch) > 0 {
+		query.Set("target_branch", options.BaseBranch)
+	}
+	if len(options.Labels) > 0 {
+		query.Set("labels", strings.Join(options.Labels, ","))
+	}
+
+	var mrs []gitlabMergeRequest
+	err := p.do(ctx, http.MethodGet, p.projectPath(r)+"/merge_requests", query, nil, &mrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list opened Merge Requests for repository %s: %w", r.FullName(), err)
+	}
+
+	for _, mr := range mrs {
+		pr := mr.toPullRequest()
+		if pr.hasLabels(options.Labels) {
+			logrus.WithFields(logrus.Fields{
+				"repository":    r.FullName(),
+				"labels":        options.Labels,
+				"merge-request": pr.URL,
+			}).Info("Found existing Merge Request")
+			return pr, nil
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"labels":     options.Labels,
+	}).Debug("No existing Merge Request found")
+	return nil, nil
+}
+
+func (p *gitlabProvider) createPullRequest(ctx context.Context, r Repository, options PullRequestOptions, branchName string) (*PullRequest, error) {
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+	}).Trace("Creating new Merge Request")
+
+	title := options.Title
+	if options.Draft {
+		title = fmt.Sprintf("Draft: %s", title)
+	}
+
+	var mr gitlabMergeRequest
+	err := p.do(ctx, http.MethodPost, p.projectPath(r)+"/merge_requests", nil, map[string]interface{}{
+		"source_branch": branchName,
+		"target_branch": options.BaseBranch,
+		"title":         title,
+		"description":   options.Body,
+		"labels":        strings.Join(options.Labels, ","),
+	}, &mr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a new Merge Request for repository %s: %w", r.FullName(), err)
+	}
+	pr := mr.toPullRequest()
+
+	logrus.WithFields(logrus.Fields{
+		"repository":    r.FullName(),
+		"merge-request": pr.URL,
+	}).Info("New Merge Request created")
+
+	err = p.addPullRequestComments(ctx, r, options, pr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comments to Merge Request %s: %w", pr.URL, err)
+	}
+
+	return pr, nil
+}
+
+func (p *gitlabProvider) updatePullRequest(ctx context.Context, r Repository, options PullRequestOptions, pr *PullRequest) (*PullRequest, error) {
+	needUpdate := applyPullRequestUpdateOperations(options, pr)
+	hasLabels := pr.hasLabels(options.Labels)
+	if needUpdate || !hasLabels {
+		logrus.WithFields(logrus.Fields{
+			"repository":    r.FullName(),
+			"merge-request": pr.URL,
+		}).Trace("Updating existing Merge Request")
+		payload := map[string]interface{}{
+			"title":       pr.Title,
+			"description": pr.Body,
+		}
+		if !hasLabels {
+			payload["add_labels"] = strings.Join(options.Labels, ",")
+		}
+		var mr gitlabMergeRequest
+		err := p.do(ctx, http.MethodPut, fmt.Sprintf("%s/merge_requests/%d", p.projectPath(r), pr.Number), nil, payload, &mr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update Merge Request %s: %w", pr.URL, err)
+		}
+		pr = mr.toPullRequest()
+		logrus.WithFields(logrus.Fields{
+			"repository":    r.FullName(),
+			"merge-request": pr.URL,
+		}).Info("Merge Request updated")
+	} else {
+		logrus.WithFields(logrus.Fields{
+			"repository":    r.FullName(),
+			"merge-request": pr.URL,
+		}).Debug("No need to update the Merge Request")
+	}
+
+	err := p.addPullRequestComments(ctx, r, options, pr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comments to Merge Request %s: %w", pr.URL, err)
+	}
+
+	return pr, nil
+}
+
+func (p *gitlabProvider) addPullRequestComments(ctx context.Context, r Repository, options PullRequestOptions, pr *PullRequest) error {
+	for i, comment := range options.Comments {
+		logrus.WithFields(logrus.Fields{
+			"repository":    r.FullName(),
+			"merge-request": pr.URL,
+			"comment":       i,
+		}).Trace("Adding a comment to the Merge Request")
+
+		err := p.do(ctx, http.MethodPost, fmt.Sprintf("%s/merge_requests/%d/notes", p.projectPath(r), pr.Number), nil, map[string]interface{}{
+			"body": comment,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to add comment on MR %s: %w", pr.URL, err)
+		}
+
+		if len(options.Comments) > 1 && i < len(options.Comments)-1 {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+func (p *gitlabProvider) closePullRequest(ctx context.Context, r Repository, pr *PullRequest) error {
+	err := p.do(ctx, http.MethodPut, fmt.Sprintf("%s/merge_requests/%d", p.projectPath(r), pr.Number), nil, map[string]interface{}{
+		"state_event": "close",
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to close Merge Request %s: %w", pr.URL, err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository":    r.FullName(),
+		"merge-request": pr.URL,
+	}).Info("Merge Request closed")
+	return nil
+}
+
+func (p *gitlabProvider) deleteBranch(ctx context.Context, r Repository, branchName string) error {
+	err := p.do(ctx, http.MethodDelete, fmt.Sprintf("%s/repository/branches/%s", p.projectPath(r), url.PathEscape(branchName)), nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete branch %s of repository %s: %w", branchName, r.FullName(), err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"branch":     branchName,
+	}).Info("Branch deleted")
+	return nil
+}
+
+func (p *gitlabProvider) findMergedPullRequests(ctx context.Context, r Repository, options PullRequestOptions) ([]*PullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "merged")
+	query.Set("order_by", "updated_at")
+	query.Set("sort", "desc")
+	if len(options.BaseBranch) > 0 {
+		query.Set("target_branch", options.BaseBranch)
+	}
+	if len(options.Labels) > 0 {
+		query.Set("labels", strings.Join(options.Labels, ","))
+	}
+
+	var mrs []gitlabMergeRequest
+	err := p.do(ctx, http.MethodGet, p.projectPath(r)+"/merge_requests", query, nil, &mrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merged Merge Requests for repository %s: %w", r.FullName(), err)
+	}
+
+	var prs []*PullRequest
+	for _, mr := range mrs {
+		pr := mr.toPullRequest()
+		if len(pr.MergeCommit) > 0 && pr.hasLabels(options.Labels) {
+			prs = append(prs, pr)
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"labels":     options.Labels,
+		"count":      len(prs),
+	}).Debug("Found merged Merge Requests")
+	return prs, nil
+}
+
+func (p *gitlabProvider) createRelease(ctx context.Context, r Repository, _ PullRequestOptions, release Release) (bool, error) {
+	releasesPath := p.projectPath(r) + "/releases"
+	err := p.do(ctx, http.MethodGet, releasesPath+"/"+url.PathEscape(release.Tag), nil, nil, nil)
+	if err == nil {
+		return false, nil
+	}
+	if !errIsGitLabNotFound(err) {
+		return false, fmt.Errorf("failed to retrieve GitLab Release %s of repository %s: %w", release.Tag, r.FullName(), err)
+	}
+
+	var glRelease struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+	err = p.do(ctx, http.MethodPost, releasesPath, nil, map[string]interface{}{
+		"tag_name":    release.Tag,
+		"ref":         release.Commit,
+		"name":        release.Name,
+		"description": release.Body,
+	}, &glRelease)
+	if err != nil {
+		return false, fmt.Errorf("failed to create GitLab Release %s of repository %s: %w", release.Tag, r.FullName(), err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"release":    glRelease.Links.Self,
+		"commit":     release.Commit,
+	}).Info("Release created")
+	return true, nil
+}
+
+// mergePullRequest waits until the GitLab Merge Request is mergeable - based on its detailed merge status - and merges it.
+// The "merge" and "rebase" merge methods both use the project's merge method, while "squash" squashes the commits.
+func (p *gitlabProvider) mergePullRequest(ctx context.Context, r Repository, options PullRequestOptions, pr *PullRequest) error {
+	return p.mergeGitLabMergeRequest(ctx, r, options, pr)
+}
+
+func (p *gitlabProvider) mergeGitLabMergeRequest(ctx context.Context, r Repository, options PullRequestOptions, pr *PullRequest, retryCounts ...int) error {
+	var (
+		startTime  = time.Now()
+		mrPath     = fmt.Sprintf("%s/merge_requests/%d", p.projectPath(r), pr.Number)
+		retryCount = 0
+	)
+	if len(retryCounts) > 0 && retryCounts[0] > 0 {
+		retryCount = retryCounts[0]
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository":    r.FullName(),
+		"merge-request": pr.URL,
+		"timeout":       options.Merge.PollTimeout.String(),
+		"retry":         retryCount,
+	}).Trace("Starting Merge Request merge process")
+
+	for {
+		var mr gitlabMergeRequest
+		err := p.do(ctx, http.MethodGet, mrPath, nil, nil, &mr)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve status of Merge Request %s: %w", pr.URL, err)
+		}
+		if mr.State == "merged" {
+			logrus.WithFields(logrus.Fields{
+				"repository":    r.FullName(),
+				"merge-request": pr.URL,
+				"retry":         retryCount,
+			}).Info("Merge Request has already been merged")
+			return nil
+		}
+
+		switch mr.DetailedMergeStatus {
+		case "mergeable":
+			payload := map[string]interface{}{
+				"squash": options.Merge.Method == "squash",
+			}
+			if len(options.Merge.CommitMessage) > 0 {
+				payload["merge_commit_message"] = options.Merge.CommitMessage
+			}
+			if len(options.Merge.SHA) > 0 {
+				payload["sha"] = options.Merge.SHA
+			}
+			err = p.do(ctx, http.MethodPut, mrPath+"/merge", nil, payload, nil)
+			if err != nil && shouldRetryGitLabMerge(err) {
+				if retryCount >= options.Merge.RetryCount {
+					return fmt.Errorf("failed to merge Merge Request %s after %d retries (max retry count is set to %d): %w", pr.URL, retryCount, options.Merge.RetryCount, err)
+				}
+				logrus.WithFields(logrus.Fields{
+					"repository":    r.FullName(),
+					"merge-request": pr.URL,
+					"retry":         retryCount,
+				}).WithError(err).Warningf("Failed to merge Merge Request - will retry in %s", options.Merge.PollInterval.String())
+				time.Sleep(options.Merge.PollInterval)
+				retryCount++
+				return p.mergeGitLabMergeRequest(ctx, r, options, pr, retryCount)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to merge Merge Request %s: %w", pr.URL, err)
+			}
+			logrus.WithFields(logrus.Fields{
+				"repository":    r.FullName(),
+				"merge-request": pr.URL,
+				"retry":         retryCount,
+			}).Info("Merge Request merged")
+			return nil
+		case "conflict", "not_open", "need_rebase":
+			return fmt.Errorf("merge request %s is not mergeable: %s", pr.URL, mr.DetailedMergeStatus)
+		}
+
+		if time.Since(startTime) > options.Merge.PollTimeout {
+			return fmt.Errorf("timeout after %s waiting for Merge Request %s mergeable status", options.Merge.PollTimeout.String(), pr.URL)
+		}
+		logrus.WithFields(logrus.Fields{
+			"repository":    r.FullName(),
+			"merge-request": pr.URL,
+			"merge-status":  mr.DetailedMergeStatus,
+		}).Tracef("Waiting %s until next GitLab request...", options.Merge.PollInterval.String())
+		time.Sleep(options.Merge.PollInterval)
+	}
+}
+
+// shouldRetryGitLabMerge returns true if the merge of a Merge Request failed because it is not mergeable yet
+// - GitLab may still report a Merge Request as mergeable while its pipeline is starting, or right after its target branch has been updated.
+func shouldRetryGitLabMerge(err error) bool {
+	var apiErr *gitlabAPIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.statusCode == http.StatusMethodNotAllowed || apiErr.statusCode == http.StatusNotAcceptable
+}
@@ -16,15 +16,26 @@ const (
 	AppendUpdateOperation  = "append"
 
 	PublicGithubURL = "https://github.com"
+	PublicGitLabURL = "https://gitlab.com"
 )
 
 // UpdateOptions is the options entrypoint for a git repo update
 type UpdateOptions struct {
-	DryRun    bool
-	KeepFiles bool
-	Git       GitOptions
-	GitHub    GitHubOptions
-	Strategy  string
+	DryRun                     bool
+	KeepFiles                  bool
+	MinChangedFiles            int
+	RevertBelowMinChangedFiles bool
+	Git                        GitOptions
+	GitHub                     GitHubOptions
+	GitLab                     GitLabOptions
+	Strategy                   string
+	RollbackManifest           *RollbackManifest
+	Plan                       *Plan
+	SARIFReport                *SARIFReport
+	// ExportChangesDir is the directory in which the content of the changed files - before and after each updater - is exported. Disabled if empty.
+	ExportChangesDir string
+	// BaseBranches are the branches against which the updaters are run - each one with its own pull request - instead of the single base branch of the pull requests options
+	BaseBranches []string
 }
 
 // GitOptions holds all the options required to perform git operations: clone, commit, ...
@@ -42,6 +53,10 @@ type GitOptions struct {
 	BranchPrefix         string
 	SigningKeyPath       string
 	SigningKeyPassphrase string
+	AuditLogFile         string
+	AuditLogRunID        string
+	PushRetryCount       int
+	PushRetryInterval    time.Duration
 }
 
 // GitHubOptions holds all the options required to perform github operations: auth, PRs, ...
@@ -60,6 +75,13 @@ func (o *GitHubOptions) isEnterprise() bool {
 	return o.URL != PublicGithubURL
 }
 
+// GitLabOptions holds all the options required to perform gitlab operations: auth, ...
+// The pull requests (merge requests) options are shared with GitHub, and defined in the GitHubOptions.
+type GitLabOptions struct {
+	URL   string
+	Token string
+}
+
 // PullRequestOptions holds all the options required to perform github PR operations: title/body, merge, ...
 type PullRequestOptions struct {
 	Labels               []string
@@ -71,6 +93,11 @@ type PullRequestOptions struct {
 	Comments             []string
 	Draft                bool
 	Merge                PullRequestMergeOptions
+	CreatePacer          *CreationPacer
+	// Repository is the optional "owner/name" of the repository in which the pull requests are created - if it's not the updated repository.
+	// In this case, the updated repository must be a fork of it: the changes are pushed to the fork, and the pull requests are created in the upstream repository.
+	Repository string
+	Release    PullRequestReleaseOptions
 }
 
 // PullRequestMergeOptions holds all the options required to merge github PRs
@@ -85,6 +112,29 @@ type PullRequestMergeOptions struct {
 	RetryCount    int
 }
 
+// PullRequestReleaseOptions holds all the options required to create a release once a PR is merged
+type PullRequestReleaseOptions struct {
+	OnMerge bool
+	// Tag is the template of the tag of the release, executed when the PR is created or updated - and recorded in the PR body
+	Tag string
+}
+
+// pullRequestRepository returns the repository in which the pull requests of the given updated repository are created,
+// and true if it's not the updated repository - in which case the pull requests are created from the updated repository, as a fork.
+func (o PullRequestOptions) pullRequestRepository(r Repository) (Repository, bool) {
+	if len(o.Repository) == 0 {
+		return r, false
+	}
+	target, valid := r.withFullName(o.Repository)
+	if !valid {
+		return r, false
+	}
+	if strings.EqualFold(target.FullName(), r.FullName()) {
+		return r, false
+	}
+	return target, true
+}
+
 func (o *GitOptions) setDefaultValues(updaters []update.Updater, tplExecutorFunc templateExecutor) error {
 	if len(updaters) == 1 {
 		title, body := updaters[0].Message()
@@ -151,7 +201,7 @@ func (o *GitHubOptions) setDefaultValues(git GitOptions, tplExecutorFunc templat
 	}
 	o.PullRequest.Body = prBody
 
-	return nil
+	return o.PullRequest.setReleaseTag(tplExecutorFunc)
 }
 
 func (o *GitHubOptions) setDefaultUpdateOperation(defaultUpdateOperation string) {
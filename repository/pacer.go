@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// CreationPacer spreads the creation of pull requests over time, to avoid flooding the reviewers with notifications.
+// It is shared by all the repositories updated in the same run: each creation waits for the delay - plus a random jitter - after the previous one.
+// A nil pacer doesn't wait.
+type CreationPacer struct {
+	delay  time.Duration
+	jitter time.Duration
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+// NewCreationPacer returns a new pacer with the given minimum delay between 2 pull request creations, and the given maximum random jitter added to the delay.
+// It returns nil if both are zero.
+func NewCreationPacer(delay, jitter time.Duration) *CreationPacer {
+	if delay <= 0 && jitter <= 0 {
+		return nil
+	}
+	return &CreationPacer{
+		delay:  delay,
+		jitter: jitter,
+	}
+}
+
+// wait reserves the next creation slot, and blocks until it's time to use it - or the context is done.
+func (p *CreationPacer) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	now := time.Now()
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	interval := p.delay
+	if p.jitter > 0 {
+		interval += time.Duration(rand.Int63n(int64(p.jitter))) //nolint: gosec // no need for a secure random number here
+	}
+	p.next = slot.Add(interval)
+	p.mutex.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,431 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// redactedSecret replaces the secret values in the diffs of a plan
+const redactedSecret = "[REDACTED]"
+
+// Plan records the changes that the updaters would make on each repository - with the values they used - without pushing anything.
+// It is written by a "plan" run, and consumed by an "apply" run: the apply run replays the recorded values - instead of resolving them again -
+// and fails for the repositories whose changes don't match the plan, so that what has been reviewed is what is applied.
+// The secret values are never written to the plan: they are resolved again by the apply run, and must match the digest recorded in the plan.
+type Plan struct {
+	RunID     string      `json:"runId"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Updates   []string    `json:"updates"`
+	Entries   []PlanEntry `json:"entries"`
+
+	// applying is true when the plan is consumed by an apply run, and false when it is recorded by a plan run
+	applying bool
+	// entries are the entries of the repositories being updated, indexed by the path of their clone
+	entries map[string]*PlanEntry
+	// secrets are the secret values used in each repository - indexed by the path of their clone - to redact them from the diffs
+	secrets map[string][]string
+	mutex   sync.Mutex
+}
+
+// PlanEntry is the record of the changes planned on a single repository.
+type PlanEntry struct {
+	Host   string            `json:"host,omitempty"`
+	Owner  string            `json:"owner"`
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params,omitempty"`
+	// BaseCommit is the commit on top of which the changes are made - the apply run fails if it is not the same
+	BaseCommit   string      `json:"baseCommit"`
+	ChangedFiles []string    `json:"changedFiles"`
+	Diff         string      `json:"diff,omitempty"`
+	Values       []PlanValue `json:"values,omitempty"`
+}
+
+// PlanValue is a value used by one of the updates of the plan.
+type PlanValue struct {
+	// Update is the index of the update in the updates of the plan
+	Update int    `json:"update"`
+	Value  string `json:"value,omitempty"`
+	// Secret is true if the value is a secret: it is not recorded, but resolved again from its reference - and must match its digest
+	Secret    bool   `json:"secret,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	// Digest is the salted SHA-256 digest of the secret value
+	Digest string `json:"digest,omitempty"`
+	Salt   string `json:"salt,omitempty"`
+}
+
+// NewPlan returns a new empty plan for the given run and updates - as defined on the CLI.
+func NewPlan(runID string, updates []string) *Plan {
+	return &Plan{
+		RunID:     runID,
+		CreatedAt: time.Now().UTC(),
+		Updates:   updates,
+		entries:   make(map[string]*PlanEntry),
+		secrets:   make(map[string][]string),
+	}
+}
+
+// ReadPlan reads the plan written to the given file, so that it can be applied.
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan %s: %w", path, err)
+	}
+	plan := &Plan{}
+	if err = json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	plan.applying = true
+	plan.entries = make(map[string]*PlanEntry)
+	plan.secrets = make(map[string][]string)
+	return plan, nil
+}
+
+// Write writes the plan to the given file, as JSON. Only the repositories with changes are written, sorted by repository, so that the plan is stable.
+func (p *Plan) Write(path string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.Entries = nil
+	for _, entry := range p.entries {
+		if len(entry.ChangedFiles) > 0 {
+			p.Entries = append(p.Entries, *entry)
+		}
+	}
+	sort.SliceStable(p.Entries, func(i, j int) bool {
+		return p.Entries[i].repository().FullName() < p.Entries[j].repository().FullName()
+	})
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan %s: %w", path, err)
+	}
+	return nil
+}
+
+// Updaters returns the updaters of the plan, with valuers recording - or replaying - the values used in each repository.
+func (p *Plan) Updaters() ([]update.Updater, error) {
+	return update.ParseWithValuerWrapper(p.Updates, func(index int, valueStr string, valuer value.Valuer) value.Valuer {
+		return &planValuer{
+			plan:      p,
+			index:     index,
+			reference: valueStr,
+			valuer:    valuer,
+		}
+	})
+}
+
+// Repositories returns the repositories with changes in the plan - to apply them.
+func (p *Plan) Repositories() []Repository {
+	repositories := make([]Repository, 0, len(p.Entries))
+	for _, entry := range p.Entries {
+		repositories = append(repositories, entry.repository())
+	}
+	return repositories
+}
+
+// register starts recording - or applying - the plan for the given repository, cloned at the given path.
+// A nil plan doesn't do anything.
+func (p *Plan) register(r Repository, repoPath string) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.applying {
+		p.entries[repoPath] = &PlanEntry{
+			Host:   r.Host,
+			Owner:  r.Owner,
+			Name:   r.Name,
+			Params: r.Params,
+		}
+		return nil
+	}
+
+	for i := range p.Entries {
+		if p.Entries[i].repository().FullName() == r.FullName() {
+			p.entries[repoPath] = &p.Entries[i]
+			return nil
+		}
+	}
+	return fmt.Errorf("repository %s has no changes in the plan", r.FullName())
+}
+
+// checkChanges records - or verifies, when applying the plan - the base commit and the files changed by the updaters in the given repository.
+// The diff of the changes is verified later, once they are committed - see checkDiff.
+// A nil plan doesn't do anything.
+func (p *Plan) checkChanges(r Repository, repoPath string, gitOpts GitOptions) error {
+	if p == nil {
+		return nil
+	}
+	entry, err := p.entry(repoPath)
+	if err != nil {
+		return err
+	}
+
+	gitRepo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open git repository %s: %w", repoPath, err)
+	}
+	head, err := gitRepo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get the HEAD of git repository %s: %w", repoPath, err)
+	}
+	changedFiles, err := listChangedFiles(gitRepo, gitOpts)
+	if err != nil {
+		return fmt.Errorf("failed to list changed files: %w", err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.applying {
+		entry.BaseCommit = head.Hash().String()
+		entry.ChangedFiles = changedFiles
+		return nil
+	}
+
+	if baseCommit := head.Hash().String(); baseCommit != entry.BaseCommit {
+		return fmt.Errorf("the changes of repository %s are not applied on top of the planned commit %s but on %s: the repository has changed since the plan", r.FullName(), entry.BaseCommit, baseCommit)
+	}
+	if !reflect.DeepEqual(changedFiles, entry.ChangedFiles) {
+		return fmt.Errorf("the files changed in repository %s don't match the plan: changed %v instead of %v", r.FullName(), changedFiles, entry.ChangedFiles)
+	}
+	return nil
+}
+
+// recordDiff records the diff of the commit created by the updaters in the given repository - with the secret values redacted.
+// A nil plan - or a plan being applied - doesn't record anything.
+func (p *Plan) recordDiff(repoPath string) error {
+	if p == nil || p.applying {
+		return nil
+	}
+	entry, err := p.entry(repoPath)
+	if err != nil {
+		return err
+	}
+	if len(entry.BaseCommit) == 0 {
+		// the updaters didn't run
+		return nil
+	}
+
+	gitRepo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open git repository %s: %w", repoPath, err)
+	}
+	head, err := gitRepo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get the HEAD of git repository %s: %w", repoPath, err)
+	}
+	if head.Hash().String() == entry.BaseCommit {
+		// no commit has been created - not enough changed files, or no changes to commit
+		p.mutex.Lock()
+		entry.ChangedFiles = nil
+		p.mutex.Unlock()
+		return nil
+	}
+	diff, err := p.diff(gitRepo, repoPath, entry.BaseCommit, head.Hash())
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	entry.Diff = diff
+	return nil
+}
+
+// checkDiff verifies - when applying the plan - that the diff of the commit created by the updaters in the given repository is the planned one.
+// It must be called after the changes are committed, and before they are pushed.
+// A nil plan - or a plan being recorded - doesn't check anything.
+func (p *Plan) checkDiff(gitRepo *git.Repository, repoPath string) error {
+	if p == nil || !p.applying {
+		return nil
+	}
+	entry, err := p.entry(repoPath)
+	if err != nil {
+		return err
+	}
+
+	head, err := gitRepo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get the HEAD of git repository %s: %w", repoPath, err)
+	}
+	diff, err := p.diff(gitRepo, repoPath, entry.BaseCommit, head.Hash())
+	if err != nil {
+		return err
+	}
+	if diff != entry.Diff {
+		return errors.New("the diff of the changes doesn't match the plan")
+	}
+	return nil
+}
+
+// diff returns the diff between the given commits of the given repository - with the secret values redacted
+func (p *Plan) diff(gitRepo *git.Repository, repoPath string, baseHash string, headHash plumbing.Hash) (string, error) {
+	headCommit, err := gitRepo.CommitObject(headHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the HEAD commit: %w", err)
+	}
+	baseCommit, err := gitRepo.CommitObject(plumbing.NewHash(baseHash))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the base commit %s: %w", baseHash, err)
+	}
+	patch, err := baseCommit.Patch(headCommit)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute the diff of the changes: %w", err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	diff := patch.String()
+	for _, secret := range p.secrets[repoPath] {
+		if len(secret) > 0 {
+			diff = strings.ReplaceAll(diff, secret, redactedSecret)
+		}
+	}
+	return diff, nil
+}
+
+func (p *Plan) entry(repoPath string) (*PlanEntry, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	entry, found := p.entries[repoPath]
+	if !found {
+		return nil, fmt.Errorf("no plan entry for the repository cloned at %s", repoPath)
+	}
+	return entry, nil
+}
+
+func (e PlanEntry) repository() Repository {
+	return Repository{
+		Host:   e.Host,
+		Owner:  e.Owner,
+		Name:   e.Name,
+		Params: e.Params,
+	}
+}
+
+// planValuer is a valuer which records its values in a plan - or replays the values recorded in the plan, when the plan is applied
+type planValuer struct {
+	plan      *Plan
+	index     int
+	reference string
+	valuer    value.Valuer
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (v *planValuer) Value(ctx context.Context, repoPath string) (string, error) {
+	entry, err := v.plan.entry(repoPath)
+	if err != nil {
+		return "", err
+	}
+	secret := value.IsSecret(v.valuer)
+
+	if !v.plan.applying {
+		val, err := v.valuer.Value(ctx, repoPath)
+		if err != nil {
+			return "", err
+		}
+		planValue := PlanValue{
+			Update: v.index,
+			Value:  val,
+		}
+		if secret {
+			salt, err := newSalt()
+			if err != nil {
+				return "", err
+			}
+			planValue = PlanValue{
+				Update:    v.index,
+				Secret:    true,
+				Reference: v.reference,
+				Digest:    digest(salt, val),
+				Salt:      salt,
+			}
+		}
+
+		v.plan.mutex.Lock()
+		defer v.plan.mutex.Unlock()
+		if secret {
+			v.plan.secrets[repoPath] = append(v.plan.secrets[repoPath], val)
+		}
+		for i := range entry.Values {
+			if entry.Values[i].Update == v.index {
+				entry.Values[i] = planValue
+				return val, nil
+			}
+		}
+		entry.Values = append(entry.Values, planValue)
+		return val, nil
+	}
+
+	v.plan.mutex.Lock()
+	var (
+		planValue PlanValue
+		found     bool
+	)
+	for _, entryValue := range entry.Values {
+		if entryValue.Update == v.index {
+			planValue, found = entryValue, true
+			break
+		}
+	}
+	v.plan.mutex.Unlock()
+	if !found {
+		return "", fmt.Errorf("no value planned for update %q", v.plan.Updates[v.index])
+	}
+	if !planValue.Secret {
+		return planValue.Value, nil
+	}
+
+	// secrets are resolved again - from the same reference, and must not have changed since the plan
+	val, err := v.valuer.Value(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+	if digest(planValue.Salt, val) != planValue.Digest {
+		return "", fmt.Errorf("the secret value %s of update %q has changed since the plan", planValue.Reference, v.plan.Updates[v.index])
+	}
+	v.plan.mutex.Lock()
+	v.plan.secrets[repoPath] = append(v.plan.secrets[repoPath], val)
+	v.plan.mutex.Unlock()
+	return val, nil
+}
+
+// Sensitive returns true if the wrapped valuer returns a secret
+func (v *planValuer) Sensitive() bool {
+	return value.IsSecret(v.valuer)
+}
+
+func newSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate a salt: %w", err)
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+func digest(salt, val string) string {
+	sum := sha256.Sum256([]byte(salt + val))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
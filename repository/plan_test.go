@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubValuer is a test valuer returning a fixed value - which can be flagged as a secret
+type stubValuer struct {
+	value  string
+	secret bool
+}
+
+func (v stubValuer) Value(_ context.Context, _ string) (string, error) {
+	return v.value, nil
+}
+
+func (v stubValuer) Sensitive() bool {
+	return v.secret
+}
+
+// valueFileUpdater is a test updater that writes the value returned by its valuer to a file
+type valueFileUpdater struct {
+	file   string
+	valuer value.Valuer
+}
+
+func (u *valueFileUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	val, err := u.valuer.Value(ctx, repoPath)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(filepath.Join(repoPath, u.file), []byte(val+"\n"), 0644)
+}
+
+func (u *valueFileUpdater) Message() (string, string) {
+	return "Update " + u.file, ""
+}
+
+func (u *valueFileUpdater) String() string {
+	return "ValueFile[file=" + u.file + "]"
+}
+
+// planUpdaters returns the test updaters of the plan - the first one writes a version, and the second one a secret
+func planUpdaters(plan *Plan, version, secret string) []update.Updater {
+	return []update.Updater{
+		&valueFileUpdater{
+			file:   "version.txt",
+			valuer: &planValuer{plan: plan, index: 0, reference: plan.Updates[0], valuer: stubValuer{value: version}},
+		},
+		&valueFileUpdater{
+			file:   "password.txt",
+			valuer: &planValuer{plan: plan, index: 1, reference: "stub(secret=password)", valuer: stubValuer{value: secret, secret: true}},
+		},
+	}
+}
+
+// runPlan runs the updaters on the repository, the same way as Repository.Update
+func runPlan(t *testing.T, plan *Plan, updaters []update.Updater, provider *localProvider, dryRun bool) (bool, error) {
+	t.Helper()
+
+	repo := Repository{Owner: "owner", Name: "repo", Params: map[string]string{}}
+	clonePath := t.TempDir()
+	options := UpdateOptions{
+		DryRun: dryRun,
+		Plan:   plan,
+		Git: GitOptions{
+			StageAllChanged: true,
+			AuthorName:      "test",
+			AuthorEmail:     "test@example.com",
+			CommitterName:   "test",
+			CommitterEmail:  "test@example.com",
+			CommitTitle:     "update",
+			BranchPrefix:    "octopilot-test",
+		},
+		GitHub: GitHubOptions{
+			PullRequest: PullRequestOptions{Title: "update", Body: "update"},
+		},
+	}
+	if err := plan.register(repo, clonePath); err != nil {
+		return false, err
+	}
+	strategy := &RecreateStrategy{
+		Repository: repo,
+		RepoPath:   clonePath,
+		Updaters:   updaters,
+		Provider:   provider,
+		Options:    options,
+	}
+	updated, _, err := strategy.Run(context.Background())
+	if err != nil {
+		return false, err
+	}
+	if err = plan.recordDiff(clonePath); err != nil {
+		return false, err
+	}
+
+	if updated {
+		data, err := os.ReadFile(filepath.Join(clonePath, "version.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "v2\n", string(data), "the applied version should be the planned one")
+	}
+	return updated, nil
+}
+
+// writeTestPlan runs a plan on a new local repository, and returns the repository and the path of the plan file
+func writeTestPlan(t *testing.T) (string, string) {
+	t.Helper()
+
+	originPath := initLocalRepository(t, map[string]string{"version.txt": "v1\n", "password.txt": "old\n"})
+	plan := NewPlan("run-1", []string{"version", "password"})
+	updated, err := runPlan(t, plan, planUpdaters(plan, "v2", "s3cret"), &localProvider{path: originPath}, true)
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, plan.Write(planPath))
+	return originPath, planPath
+}
+
+func TestPlanApply(t *testing.T) {
+	t.Parallel()
+
+	originPath, planPath := writeTestPlan(t)
+	data, err := os.ReadFile(planPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cret")
+
+	plan, err := ReadPlan(planPath)
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", plan.RunID)
+	require.Len(t, plan.Entries, 1)
+	entry := plan.Entries[0]
+	assert.Equal(t, "owner/repo", entry.repository().FullName())
+	assert.Equal(t, []string{"password.txt", "version.txt"}, entry.ChangedFiles)
+	assert.Contains(t, entry.Diff, "-v1\n+v2\n")
+	assert.Contains(t, entry.Diff, "-old\n+"+redactedSecret+"\n")
+	require.Len(t, entry.Values, 2)
+	assert.Equal(t, PlanValue{Update: 0, Value: "v2"}, entry.Values[0])
+	assert.True(t, entry.Values[1].Secret)
+	assert.Empty(t, entry.Values[1].Value)
+	assert.Equal(t, "stub(secret=password)", entry.Values[1].Reference)
+	assert.Equal(t, digest(entry.Values[1].Salt, "s3cret"), entry.Values[1].Digest)
+
+	// the version has changed since the plan: the planned version is applied
+	provider := &localProvider{path: originPath}
+	updated, err := runPlan(t, plan, planUpdaters(plan, "v3", "s3cret"), provider, false)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, []string{"create"}, provider.pullRequests)
+}
+
+func TestPlanApplyChangedSecret(t *testing.T) {
+	t.Parallel()
+
+	originPath, planPath := writeTestPlan(t)
+	plan, err := ReadPlan(planPath)
+	require.NoError(t, err)
+
+	provider := &localProvider{path: originPath}
+	_, err = runPlan(t, plan, planUpdaters(plan, "v2", "n3w-s3cret"), provider, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `the secret value stub(secret=password) of update "password" has changed since the plan`)
+	assert.NotContains(t, err.Error(), "s3cret")
+	assert.Empty(t, provider.pullRequests)
+}
+
+func TestPlanApplyChangedRepository(t *testing.T) {
+	t.Parallel()
+
+	originPath, planPath := writeTestPlan(t)
+	plan, err := ReadPlan(planPath)
+	require.NoError(t, err)
+
+	originRepo, err := git.PlainOpen(originPath)
+	require.NoError(t, err)
+	workTree, err := originRepo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(originPath, "other.txt"), []byte("other"), 0644))
+	_, err = workTree.Add("other.txt")
+	require.NoError(t, err)
+	_, err = workTree.Commit("another commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "someone else", Email: "someone@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	provider := &localProvider{path: originPath}
+	_, err = runPlan(t, plan, planUpdaters(plan, "v2", "s3cret"), provider, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the repository has changed since the plan")
+	assert.Empty(t, provider.pullRequests)
+}
+
+func TestPlanApplyChangedDiff(t *testing.T) {
+	t.Parallel()
+
+	originPath, planPath := writeTestPlan(t)
+	plan, err := ReadPlan(planPath)
+	require.NoError(t, err)
+
+	// the same files are changed, but the version is not replayed from the plan
+	updaters := planUpdaters(plan, "v2", "s3cret")
+	updaters[0] = &valueFileUpdater{file: "version.txt", valuer: stubValuer{value: "v3"}}
+	provider := &localProvider{path: originPath}
+	_, err = runPlan(t, plan, updaters, provider, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the diff of the changes doesn't match the plan")
+	assert.Empty(t, provider.pullRequests)
+}
+
+func TestPlanValuerSensitive(t *testing.T) {
+	t.Parallel()
+
+	plan := NewPlan("run-1", []string{"version", "password"})
+	updaters := planUpdaters(plan, "v2", "s3cret")
+	assert.False(t, value.IsSecret(updaters[0].(*valueFileUpdater).valuer))
+	assert.True(t, value.IsSecret(updaters[1].(*valueFileUpdater).valuer))
+}
+
+func TestPlanApplyUnplannedRepository(t *testing.T) {
+	t.Parallel()
+
+	_, planPath := writeTestPlan(t)
+	plan, err := ReadPlan(planPath)
+	require.NoError(t, err)
+
+	err = plan.register(Repository{Owner: "owner", Name: "other"}, t.TempDir())
+	assert.EqualError(t, err, "repository owner/other has no changes in the plan")
+}
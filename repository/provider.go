@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// supported git hosting providers
+const (
+	GitHubProvider = "github"
+	GitLabProvider = "gitlab"
+)
+
+// Provider is the git hosting service of a repository - GitHub or GitLab: it provides the git authentication, and manages the pull requests.
+// A provider is instantiated for each repository, so that a single run can update repositories hosted on different services.
+type Provider interface {
+	// name returns the name of the provider: github or gitlab
+	name() string
+	// gitURL returns the URL used to clone and push to the git repository
+	gitURL(repo Repository) (string, error)
+	// gitAuth returns the credentials used to clone and push to the git repository
+	gitAuth(ctx context.Context) (*http.BasicAuth, error)
+	// findMatchingPullRequest returns the first open pull request matching the labels set in the options - or nil if there is none
+	findMatchingPullRequest(ctx context.Context, repo Repository, options PullRequestOptions) (*PullRequest, error)
+	// createPullRequest creates a new pull request from the given branch
+	createPullRequest(ctx context.Context, repo Repository, options PullRequestOptions, branchName string) (*PullRequest, error)
+	// updatePullRequest updates an existing pull request
+	updatePullRequest(ctx context.Context, repo Repository, options PullRequestOptions, pr *PullRequest) (*PullRequest, error)
+	// mergePullRequest waits until the pull request is mergeable, and merges it
+	mergePullRequest(ctx context.Context, repo Repository, options PullRequestOptions, pr *PullRequest) error
+	// closePullRequest closes the given pull request, without merging it
+	closePullRequest(ctx context.Context, repo Repository, pr *PullRequest) error
+	// deleteBranch deletes the given branch of the remote git repository
+	deleteBranch(ctx context.Context, repo Repository, branchName string) error
+	// findMergedPullRequests returns the most recently merged pull requests matching the labels set in the options
+	findMergedPullRequests(ctx context.Context, repo Repository, options PullRequestOptions) ([]*PullRequest, error)
+	// createRelease creates the given release - and its tag - and returns false if a release already exists for the tag
+	createRelease(ctx context.Context, repo Repository, options PullRequestOptions, release Release) (bool, error)
+}
+
+// PullRequest is a provider-agnostic representation of a GitHub Pull Request - or a GitLab Merge Request.
+type PullRequest struct {
+	Number     int
+	URL        string
+	Title      string
+	Body       string
+	HeadBranch string
+	Labels     []string
+	// MergeCommit is the SHA of the commit created by the merge - only set for the merged pull requests
+	MergeCommit string
+	// Created is true if the pull request - and its branch - have been created by the current run, false if they already existed
+	Created bool
+}
+
+// hasLabels returns true if the pull request has all the given labels
+func (pr *PullRequest) hasLabels(labels []string) bool {
+	matchingLabels := 0
+	for _, requiredLabel := range labels {
+		for _, label := range pr.Labels {
+			if label == requiredLabel {
+				matchingLabels++
+				break
+			}
+		}
+	}
+	return matchingLabels == len(labels)
+}
+
+// newProvider returns the provider for the given repository. It is either:
+// - explicitly set with the "provider" repository parameter
+// - or detected from the repository host: hosts matching the GitLab URL - or starting with "gitlab." - are GitLab repositories
+// - or GitHub by default
+// If the repository has a host which doesn't match the configured provider URL, it will be used as the provider URL.
+func newProvider(repo Repository, options UpdateOptions) (Provider, error) {
+	providerName := strings.ToLower(repo.Params["provider"])
+	if len(providerName) == 0 {
+		providerName = detectProvider(repo.Host, options)
+	}
+
+	switch providerName {
+	case GitHubProvider:
+		ghOptions := options.GitHub
+		if len(repo.Host) > 0 && repo.Host != hostOf(ghOptions.URL) {
+			ghOptions.URL = fmt.Sprintf("https://%s", repo.Host)
+		}
+		return &githubProvider{options: ghOptions}, nil
+	case GitLabProvider:
+		glOptions := options.GitLab
+		if len(glOptions.URL) == 0 {
+			glOptions.URL = PublicGitLabURL
+		}
+		if len(repo.Host) > 0 && repo.Host != hostOf(glOptions.URL) {
+			glOptions.URL = fmt.Sprintf("https://%s", repo.Host)
+		}
+		return &gitlabProvider{options: glOptions}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %s for repository %s (allowed values: %s, %s)", providerName, repo.FullName(), GitHubProvider, GitLabProvider)
+	}
+}
+
+func detectProvider(host string, options UpdateOptions) string {
+	if len(host) == 0 {
+		return GitHubProvider
+	}
+	gitlabURL := options.GitLab.URL
+	if len(gitlabURL) == 0 {
+		gitlabURL = PublicGitLabURL
+	}
+	if host == hostOf(gitlabURL) || strings.HasPrefix(host, "gitlab.") {
+		return GitLabProvider
+	}
+	return GitHubProvider
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,384 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProvider(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		repo             Repository
+		options          UpdateOptions
+		expected         Provider
+		expectedErrorMsg string
+	}{
+		{
+			name: "github by default",
+			repo: Repository{Owner: "dailymotion-oss", Name: "octopilot"},
+			options: UpdateOptions{
+				GitHub: GitHubOptions{URL: PublicGithubURL, Token: "gh-token"},
+			},
+			expected: &githubProvider{options: GitHubOptions{URL: PublicGithubURL, Token: "gh-token"}},
+		},
+		{
+			name: "gitlab detected from the public host",
+			repo: Repository{Host: "gitlab.com", Owner: "group", Name: "repo"},
+			options: UpdateOptions{
+				GitLab: GitLabOptions{Token: "gl-token"},
+			},
+			expected: &gitlabProvider{options: GitLabOptions{URL: PublicGitLabURL, Token: "gl-token"}},
+		},
+		{
+			name: "gitlab detected from the configured host",
+			repo: Repository{Host: "git.example.com", Owner: "group", Name: "repo"},
+			options: UpdateOptions{
+				GitLab: GitLabOptions{URL: "https://git.example.com", Token: "gl-token"},
+			},
+			expected: &gitlabProvider{options: GitLabOptions{URL: "https://git.example.com", Token: "gl-token"}},
+		},
+		{
+			name: "github enterprise from the repository host",
+			repo: Repository{Host: "github.example.com", Owner: "owner", Name: "repo"},
+			options: UpdateOptions{
+				GitHub: GitHubOptions{URL: PublicGithubURL},
+			},
+			expected: &githubProvider{options: GitHubOptions{URL: "https://github.example.com"}},
+		},
+		{
+			name: "explicit provider parameter",
+			repo: Repository{Owner: "group", Name: "repo", Params: map[string]string{"provider": "GitLab"}},
+			options: UpdateOptions{
+				GitLab: GitLabOptions{URL: "https://code.example.com"},
+			},
+			expected: &gitlabProvider{options: GitLabOptions{URL: "https://code.example.com"}},
+		},
+		{
+			name:             "unknown provider",
+			repo:             Repository{Owner: "owner", Name: "repo", Params: map[string]string{"provider": "bitbucket"}},
+			expectedErrorMsg: "unknown provider bitbucket for repository owner/repo (allowed values: github, gitlab)",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := newProvider(test.repo, test.options)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMixedProviders(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mutex    sync.Mutex
+		requests []string
+	)
+	recordRequest := func(r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.EscapedPath(), r.Header.Get("Authorization")+r.Header.Get("PRIVATE-TOKEN")))
+	}
+
+	githubServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/dailymotion-oss/octopilot/pulls":
+			_, _ = w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/dailymotion-oss/octopilot/pulls":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":1,"html_url":"https://github.example.com/dailymotion-oss/octopilot/pull/1","head":{"ref":"octopilot-branch"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/dailymotion-oss/octopilot/issues/1/labels":
+			_, _ = w.Write([]byte(`[{"name":"octopilot-update"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer githubServer.Close()
+
+	gitlabServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/api/v4/projects/group%2Fsubgroup%2Frepo/merge_requests":
+			assert.Equal(t, "opened", r.URL.Query().Get("state"))
+			assert.Equal(t, "octopilot-update", r.URL.Query().Get("labels"))
+			_, _ = w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/api/v4/projects/group%2Fsubgroup%2Frepo/merge_requests":
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "Draft: Update things", payload["title"])
+			assert.Equal(t, "octopilot-branch", payload["source_branch"])
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"iid":7,"web_url":"https://gitlab.example.com/group/subgroup/repo/-/merge_requests/7","source_branch":"octopilot-branch","labels":["octopilot-update"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer gitlabServer.Close()
+
+	options := UpdateOptions{
+		GitHub: GitHubOptions{
+			URL:        githubServer.URL,
+			AuthMethod: "token",
+			Token:      "gh-token",
+			PullRequest: PullRequestOptions{
+				Title:  "Update things",
+				Labels: []string{"octopilot-update"},
+				Draft:  true,
+			},
+		},
+		GitLab: GitLabOptions{
+			URL:   gitlabServer.URL,
+			Token: "gl-token",
+		},
+	}
+
+	repos, err := Parse(context.Background(), []string{
+		"dailymotion-oss/octopilot",
+		"group/subgroup/repo(provider=gitlab)",
+	}, options.GitHub)
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+
+	var prURLs []string
+	for _, repo := range repos {
+		provider, err := newProvider(repo, options)
+		require.NoError(t, err)
+
+		existingPR, err := provider.findMatchingPullRequest(context.Background(), repo, options.GitHub.PullRequest)
+		require.NoError(t, err)
+		assert.Nil(t, existingPR)
+
+		pr, err := provider.createPullRequest(context.Background(), repo, options.GitHub.PullRequest, "octopilot-branch")
+		require.NoError(t, err)
+		assert.Equal(t, "octopilot-branch", pr.HeadBranch)
+		prURLs = append(prURLs, pr.URL)
+	}
+
+	assert.Equal(t, []string{
+		"https://github.example.com/dailymotion-oss/octopilot/pull/1",
+		"https://gitlab.example.com/group/subgroup/repo/-/merge_requests/7",
+	}, prURLs)
+	assert.Equal(t, []string{
+		"GET /api/v3/repos/dailymotion-oss/octopilot/pulls Bearer gh-token",
+		"POST /api/v3/repos/dailymotion-oss/octopilot/pulls Bearer gh-token",
+		"POST /api/v3/repos/dailymotion-oss/octopilot/issues/1/labels Bearer gh-token",
+		"GET /api/v4/projects/group%2Fsubgroup%2Frepo/merge_requests gl-token",
+		"POST /api/v4/projects/group%2Fsubgroup%2Frepo/merge_requests gl-token",
+	}, requests)
+}
+
+func TestCrossRepositoryPullRequest(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mutex    sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/upstream-org/app/pulls":
+			// an open PR with the right labels, but from another fork
+			_, _ = w.Write([]byte(`[{"number":3,"html_url":"https://github.example.com/upstream-org/app/pull/3","head":{"ref":"octopilot-other","repo":{"full_name":"someone-else/app"}},"labels":[{"name":"octopilot-update"}]}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/upstream-org/app/pulls":
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "downstream-org:octopilot-branch", payload["head"])
+			assert.Equal(t, "main", payload["base"])
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":4,"html_url":"https://github.example.com/upstream-org/app/pull/4","head":{"ref":"octopilot-branch","repo":{"full_name":"downstream-org/app"}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/upstream-org/app/issues/4/labels":
+			_, _ = w.Write([]byte(`[{"name":"octopilot-update"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	options := UpdateOptions{
+		GitHub: GitHubOptions{
+			URL:        server.URL,
+			AuthMethod: "token",
+			Token:      "gh-token",
+			PullRequest: PullRequestOptions{
+				Title:      "Update things",
+				Labels:     []string{"octopilot-update"},
+				BaseBranch: "main",
+				Repository: "upstream-org/app",
+			},
+		},
+	}
+	repo := Repository{Owner: "downstream-org", Name: "app"}
+	provider, err := newProvider(repo, options)
+	require.NoError(t, err)
+
+	existingPR, err := provider.findMatchingPullRequest(context.Background(), repo, options.GitHub.PullRequest)
+	require.NoError(t, err)
+	assert.Nil(t, existingPR, "pull requests from other forks should be ignored")
+
+	pr, err := provider.createPullRequest(context.Background(), repo, options.GitHub.PullRequest, "octopilot-branch")
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.example.com/upstream-org/app/pull/4", pr.URL)
+	assert.Equal(t, "octopilot-branch", pr.HeadBranch)
+
+	assert.Equal(t, []string{
+		"GET /api/v3/repos/upstream-org/app/pulls",
+		"POST /api/v3/repos/upstream-org/app/pulls",
+		"POST /api/v3/repos/upstream-org/app/issues/4/labels",
+	}, requests)
+}
+
+func TestPullRequestRepositoryValidation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		repo             Repository
+		prRepo           string
+		expectedErrorMsg string
+	}{
+		{
+			name:             "invalid pull request repository",
+			repo:             Repository{Owner: "downstream-org", Name: "app"},
+			prRepo:           "upstream-org",
+			expectedErrorMsg: "invalid pull request repository upstream-org: must be in the form owner/name",
+		},
+		{
+			name:             "gitlab provider",
+			repo:             Repository{Owner: "group", Name: "app", Params: map[string]string{"provider": "gitlab"}},
+			prRepo:           "upstream-group/app",
+			expectedErrorMsg: "a different pull request repository is only supported with the github provider - repository group/app uses the gitlab provider",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			options := UpdateOptions{
+				GitHub: GitHubOptions{
+					PullRequest: PullRequestOptions{
+						Repository: test.prRepo,
+					},
+				},
+			}
+			updated, err := test.repo.Update(context.Background(), nil, options)
+			require.EqualError(t, err, test.expectedErrorMsg)
+			assert.False(t, updated)
+		})
+	}
+}
+
+func TestGitLabMergePullRequestRetry(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		mergeStatuses    []int
+		retryCount       int
+		expectedMerges   int
+		expectedErrorMsg string
+	}{
+		{
+			name:           "merged at the first attempt",
+			mergeStatuses:  []int{http.StatusOK},
+			retryCount:     3,
+			expectedMerges: 1,
+		},
+		{
+			name:           "merged once mergeable",
+			mergeStatuses:  []int{http.StatusMethodNotAllowed, http.StatusNotAcceptable, http.StatusOK},
+			retryCount:     3,
+			expectedMerges: 3,
+		},
+		{
+			name:             "still not mergeable after all the retries",
+			mergeStatuses:    []int{http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed},
+			retryCount:       2,
+			expectedMerges:   3,
+			expectedErrorMsg: "failed to merge Merge Request https://gitlab.example.com/group/repo/-/merge_requests/7 after 2 retries (max retry count is set to 2)",
+		},
+		{
+			name:             "no retry for other errors",
+			mergeStatuses:    []int{http.StatusUnprocessableEntity},
+			retryCount:       3,
+			expectedMerges:   1,
+			expectedErrorMsg: "failed to merge Merge Request https://gitlab.example.com/group/repo/-/merge_requests/7",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				mutex  sync.Mutex
+				merges int
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodGet && r.URL.EscapedPath() == "/api/v4/projects/group%2Frepo/merge_requests/7":
+					_, _ = w.Write([]byte(`{"iid":7,"state":"opened","detailed_merge_status":"mergeable"}`))
+				case r.Method == http.MethodPut && r.URL.EscapedPath() == "/api/v4/projects/group%2Frepo/merge_requests/7/merge":
+					mutex.Lock()
+					status := test.mergeStatuses[len(test.mergeStatuses)-1]
+					if merges < len(test.mergeStatuses) {
+						status = test.mergeStatuses[merges]
+					}
+					merges++
+					mutex.Unlock()
+					w.WriteHeader(status)
+					_, _ = w.Write([]byte(`{}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			provider := &gitlabProvider{options: GitLabOptions{URL: server.URL, Token: "gl-token"}}
+			options := PullRequestOptions{
+				Merge: PullRequestMergeOptions{
+					Enabled:      true,
+					PollInterval: time.Millisecond,
+					PollTimeout:  time.Second,
+					RetryCount:   test.retryCount,
+				},
+			}
+			err := provider.mergePullRequest(context.Background(), Repository{Owner: "group", Name: "repo"}, options, &PullRequest{
+				Number: 7,
+				URL:    "https://gitlab.example.com/group/repo/-/merge_requests/7",
+			})
+			if len(test.expectedErrorMsg) > 0 {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedErrorMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedMerges, merges)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v36/github"
@@ -12,28 +13,35 @@ import (
 	"github.com/zoumo/goset"
 )
 
-func (r Repository) findMatchingPullRequest(ctx context.Context, options GitHubOptions) (*github.PullRequest, error) {
+func (p *githubProvider) findMatchingPullRequest(ctx context.Context, r Repository, options PullRequestOptions) (*PullRequest, error) {
+	headRepo := r
+	r, crossRepo := options.pullRequestRepository(r)
 	logrus.WithFields(logrus.Fields{
 		"repository": r.FullName(),
-		"labels":     options.PullRequest.Labels,
+		"labels":     options.Labels,
 	}).Trace("Looking for existing Pull Requests")
-	client, _, err := githubClient(ctx, options)
+	client, _, err := githubClient(ctx, p.options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create github client: %w", err)
 	}
 	prs, _, err := client.PullRequests.List(ctx, r.Owner, r.Name, &github.PullRequestListOptions{
-		Base: options.PullRequest.BaseBranch,
+		Base: options.BaseBranch,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list opened Pull Requests for repository %s: %w", r.FullName(), err)
 	}
 
-	for _, pr := range prs {
-		if prHasLabels(pr, options.PullRequest.Labels) {
+	for _, ghPR := range prs {
+		if crossRepo && !strings.EqualFold(ghPR.GetHead().GetRepo().GetFullName(), headRepo.FullName()) {
+			// a pull request from another fork - or from a branch of the upstream repository
+			continue
+		}
+		pr := fromGitHubPullRequest(ghPR)
+		if pr.hasLabels(options.Labels) {
 			logrus.WithFields(logrus.Fields{
 				"repository":   r.FullName(),
-				"labels":       options.PullRequest.Labels,
-				"pull-request": pr.GetHTMLURL(),
+				"labels":       options.Labels,
+				"pull-request": pr.URL,
 			}).Info("Found existing Pull Request")
 			return pr, nil
 		}
@@ -41,188 +49,317 @@ func (r Repository) findMatchingPullRequest(ctx context.Context, options GitHubO
 
 	logrus.WithFields(logrus.Fields{
 		"repository": r.FullName(),
-		"labels":     options.PullRequest.Labels,
+		"labels":     options.Labels,
 	}).Debug("No existing Pull Request found")
 	return nil, nil
 }
 
-func (r Repository) createPullRequest(ctx context.Context, options GitHubOptions, branchName string) (*github.PullRequest, error) {
+func (p *githubProvider) createPullRequest(ctx context.Context, r Repository, options PullRequestOptions, branchName string) (*PullRequest, error) {
+	head := branchName
+	if target, crossRepo := options.pullRequestRepository(r); crossRepo {
+		// the branch has been pushed to the updated repository, which is a fork of the target repository
+		head = fmt.Sprintf("%s:%s", r.Owner, branchName)
+		r = target
+	}
 	logrus.WithFields(logrus.Fields{
 		"repository": r.FullName(),
 	}).Trace("Creating new Pull Request")
 
-	client, _, err := githubClient(ctx, options)
+	client, _, err := githubClient(ctx, p.options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create github client: %w", err)
 	}
-	pr, _, err := client.PullRequests.Create(ctx, r.Owner, r.Name, &github.NewPullRequest{
-		Title:               github.String(options.PullRequest.Title),
-		Base:                github.String(options.PullRequest.BaseBranch),
-		Head:                github.String(branchName),
-		Body:                github.String(options.PullRequest.Body),
+	ghPR, _, err := client.PullRequests.Create(ctx, r.Owner, r.Name, &github.NewPullRequest{
+		Title:               github.String(options.Title),
+		Base:                github.String(options.BaseBranch),
+		Head:                github.String(head),
+		Body:                github.String(options.Body),
 		MaintainerCanModify: github.Bool(true),
-		Draft:               github.Bool(options.PullRequest.Draft),
+		Draft:               github.Bool(options.Draft),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a new Pull Request for repository %s: %w", r.FullName(), err)
 	}
+	pr := fromGitHubPullRequest(ghPR)
 
 	logrus.WithFields(logrus.Fields{
 		"repository":   r.FullName(),
-		"pull-request": pr.GetHTMLURL(),
+		"pull-request": pr.URL,
 	}).Info("New Pull Request created")
 
-	err = r.ensurePullRequestLabels(ctx, options, pr)
+	err = p.ensurePullRequestLabels(ctx, r, options, pr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to ensure that Pull Request %s has the right labels: %w", pr.GetHTMLURL(), err)
+		return nil, fmt.Errorf("failed to ensure that Pull Request %s has the right labels: %w", pr.URL, err)
 	}
 
-	err = r.addPullRequestComments(ctx, options, pr)
+	err = p.addPullRequestComments(ctx, r, options, pr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add comments to Pull Request %s: %w", pr.GetHTMLURL(), err)
+		return nil, fmt.Errorf("failed to add comments to Pull Request %s: %w", pr.URL, err)
 	}
 
 	return pr, nil
 }
 
-func (r Repository) updatePullRequest(ctx context.Context, options GitHubOptions, pr *github.PullRequest) (*github.PullRequest, error) {
-	var needUpdate bool
-	client, _, err := githubClient(ctx, options)
+func (p *githubProvider) updatePullRequest(ctx context.Context, r Repository, options PullRequestOptions, pr *PullRequest) (*PullRequest, error) {
+	r, _ = options.pullRequestRepository(r)
+	client, _, err := githubClient(ctx, p.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create github client: %w", err)
+	}
+
+	if applyPullRequestUpdateOperations(options, pr) {
+		logrus.WithFields(logrus.Fields{
+			"repository":   r.FullName(),
+			"pull-request": pr.URL,
+		}).Trace("Updating existing Pull Request")
+		ghPR, _, err := client.PullRequests.Edit(ctx, r.Owner, r.Name, pr.Number, &github.PullRequest{
+			Title: github.String(pr.Title),
+			Body:  github.String(pr.Body),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update Pull Request %s: %w", pr.URL, err)
+		}
+		pr = fromGitHubPullRequest(ghPR)
+		logrus.WithFields(logrus.Fields{
+			"repository":   r.FullName(),
+			"pull-request": pr.URL,
+		}).Info("Pull Request updated")
+	} else {
+		logrus.WithFields(logrus.Fields{
+			"repository":   r.FullName(),
+			"pull-request": pr.URL,
+		}).Debug("No need to update the Pull Request")
+	}
+
+	err = p.ensurePullRequestLabels(ctx, r, options, pr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure that Pull Request %s has the right labels: %w", pr.URL, err)
+	}
+
+	err = p.addPullRequestComments(ctx, r, options, pr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comments to Pull Request %s: %w", pr.URL, err)
+	}
+
+	return pr, nil
+}
+
+func (p *githubProvider) closePullRequest(ctx context.Context, r Repository, pr *PullRequest) error {
+	client, _, err := githubClient(ctx, p.options)
+	if err != nil {
+		return fmt.Errorf("failed to create github client: %w", err)
+	}
+
+	_, _, err = client.PullRequests.Edit(ctx, r.Owner, r.Name, pr.Number, &github.PullRequest{
+		State: github.String("closed"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to close Pull Request %s: %w", pr.URL, err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository":   r.FullName(),
+		"pull-request": pr.URL,
+	}).Info("Pull Request closed")
+	return nil
+}
+
+func (p *githubProvider) deleteBranch(ctx context.Context, r Repository, branchName string) error {
+	client, _, err := githubClient(ctx, p.options)
+	if err != nil {
+		return fmt.Errorf("failed to create github client: %w", err)
+	}
+
+	_, err = client.Git.DeleteRef(ctx, r.Owner, r.Name, "heads/"+branchName)
+	if err != nil {
+		return fmt.Errorf("failed to delete branch %s of repository %s: %w", branchName, r.FullName(), err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"branch":     branchName,
+	}).Info("Branch deleted")
+	return nil
+}
+
+func (p *githubProvider) findMergedPullRequests(ctx context.Context, r Repository, options PullRequestOptions) ([]*PullRequest, error) {
+	headRepo := r
+	r, crossRepo := options.pullRequestRepository(r)
+	client, _, err := githubClient(ctx, p.options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create github client: %w", err)
 	}
+	ghPRs, _, err := client.PullRequests.List(ctx, r.Owner, r.Name, &github.PullRequestListOptions{
+		State:     "closed",
+		Base:      options.BaseBranch,
+		Sort:      "updated",
+		Direction: "desc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list closed Pull Requests for repository %s: %w", r.FullName(), err)
+	}
 
-	if len(options.PullRequest.Title) > 0 {
-		switch options.PullRequest.TitleUpdateOperation {
+	var prs []*PullRequest
+	for _, ghPR := range ghPRs {
+		if crossRepo && !strings.EqualFold(ghPR.GetHead().GetRepo().GetFullName(), headRepo.FullName()) {
+			continue
+		}
+		pr := fromGitHubPullRequest(ghPR)
+		if len(pr.MergeCommit) > 0 && pr.hasLabels(options.Labels) {
+			prs = append(prs, pr)
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"labels":     options.Labels,
+		"count":      len(prs),
+	}).Debug("Found merged Pull Requests")
+	return prs, nil
+}
+
+func (p *githubProvider) createRelease(ctx context.Context, r Repository, options PullRequestOptions, release Release) (bool, error) {
+	r, _ = options.pullRequestRepository(r)
+	client, _, err := githubClient(ctx, p.options)
+	if err != nil {
+		return false, fmt.Errorf("failed to create github client: %w", err)
+	}
+
+	_, _, err = client.Repositories.GetReleaseByTag(ctx, r.Owner, r.Name, release.Tag)
+	if err == nil {
+		return false, nil
+	}
+	if !errIsStatusNotFound(err) {
+		return false, fmt.Errorf("failed to retrieve GitHub Release %s of repository %s: %w", release.Tag, r.FullName(), err)
+	}
+
+	ghRelease, _, err := client.Repositories.CreateRelease(ctx, r.Owner, r.Name, &github.RepositoryRelease{
+		TagName:         github.String(release.Tag),
+		TargetCommitish: github.String(release.Commit),
+		Name:            github.String(release.Name),
+		Body:            github.String(release.Body),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create GitHub Release %s of repository %s: %w", release.Tag, r.FullName(), err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"release":    ghRelease.GetHTMLURL(),
+		"commit":     release.Commit,
+	}).Info("Release created")
+	return true, nil
+}
+
+// applyPullRequestUpdateOperations updates the title and body of the given pull request, based on the update operations defined in the options.
+// It returns true if the pull request has been changed - and needs to be updated.
+func applyPullRequestUpdateOperations(options PullRequestOptions, pr *PullRequest) bool {
+	var needUpdate bool
+	if len(options.Title) > 0 {
+		switch options.TitleUpdateOperation {
 		case IgnoreUpdateOperation:
 			// nothing to do
 		case ReplaceUpdateOperation:
-			pr.Title = github.String(options.PullRequest.Title)
+			pr.Title = options.Title
 			needUpdate = true
 		case PrependUpdateOperation:
-			pr.Title = github.String(fmt.Sprintf("%s %s", options.PullRequest.Title, pr.GetTitle()))
+			pr.Title = fmt.Sprintf("%s %s", options.Title, pr.Title)
 			needUpdate = true
 		case AppendUpdateOperation:
-			pr.Title = github.String(fmt.Sprintf("%s %s", pr.GetTitle(), options.PullRequest.Title))
+			pr.Title = fmt.Sprintf("%s %s", pr.Title, options.Title)
 			needUpdate = true
 		}
 	}
-	if len(options.PullRequest.Body) > 0 {
-		switch options.PullRequest.BodyUpdateOperation {
+	if len(options.Body) > 0 {
+		switch options.BodyUpdateOperation {
 		case IgnoreUpdateOperation:
 			// nothing to do
 		case ReplaceUpdateOperation:
-			pr.Body = github.String(options.PullRequest.Body)
+			pr.Body = options.Body
 			needUpdate = true
 		case PrependUpdateOperation:
-			pr.Body = github.String(fmt.Sprintf("%s\n\n%s", options.PullRequest.Body, pr.GetBody()))
+			pr.Body = fmt.Sprintf("%s\n\n%s", options.Body, pr.Body)
 			needUpdate = true
 		case AppendUpdateOperation:
-			pr.Body = github.String(fmt.Sprintf("%s\n\n%s", pr.GetBody(), options.PullRequest.Body))
+			pr.Body = fmt.Sprintf("%s\n\n%s", pr.Body, options.Body)
 			needUpdate = true
 		}
 	}
-
-	if needUpdate {
-		logrus.WithFields(logrus.Fields{
-			"repository":   r.FullName(),
-			"pull-request": pr.GetHTMLURL(),
-		}).Trace("Updating existing Pull Request")
-		prURL := pr.GetHTMLURL()
-		pr, _, err = client.PullRequests.Edit(ctx, r.Owner, r.Name, pr.GetNumber(), pr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to update Pull Request %s: %w", prURL, err)
+	if tag, found := releaseTag(options.Body); found {
+		// the release tag must be up-to-date, even if the body is not updated
+		if body := withReleaseTag(pr.Body, tag); body != pr.Body {
+			pr.Body = body
+			needUpdate = true
 		}
-		logrus.WithFields(logrus.Fields{
-			"repository":   r.FullName(),
-			"pull-request": pr.GetHTMLURL(),
-		}).Info("Pull Request updated")
-	} else {
-		logrus.WithFields(logrus.Fields{
-			"repository":   r.FullName(),
-			"pull-request": pr.GetHTMLURL(),
-		}).Debug("No need to update the Pull Request")
 	}
-
-	err = r.ensurePullRequestLabels(ctx, options, pr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to ensure that Pull Request %s has the right labels: %w", pr.GetHTMLURL(), err)
-	}
-
-	err = r.addPullRequestComments(ctx, options, pr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to add comments to Pull Request %s: %w", pr.GetHTMLURL(), err)
-	}
-
-	return pr, nil
+	return needUpdate
 }
 
-func (r Repository) ensurePullRequestLabels(ctx context.Context, options GitHubOptions, pr *github.PullRequest) error {
-	if prHasLabels(pr, options.PullRequest.Labels) {
+func (p *githubProvider) ensurePullRequestLabels(ctx context.Context, r Repository, options PullRequestOptions, pr *PullRequest) error {
+	if pr.hasLabels(options.Labels) {
 		logrus.WithFields(logrus.Fields{
 			"repository":   r.FullName(),
-			"pull-request": pr.GetHTMLURL(),
+			"pull-request": pr.URL,
 		}).Debug("No labels to add to the Pull Request")
 		return nil
 	}
 
-	client, _, err := githubClient(ctx, options)
+	client, _, err := githubClient(ctx, p.options)
 	if err != nil {
 		return fmt.Errorf("failed to create github client: %w", err)
 	}
 	logrus.WithFields(logrus.Fields{
 		"repository":   r.FullName(),
-		"pull-request": pr.GetHTMLURL(),
+		"pull-request": pr.URL,
 	}).Trace("Adding labels to Pull Request")
-	_, _, err = client.Issues.AddLabelsToIssue(ctx, r.Owner, r.Name, pr.GetNumber(), options.PullRequest.Labels)
+	_, _, err = client.Issues.AddLabelsToIssue(ctx, r.Owner, r.Name, pr.Number, options.Labels)
 	if err != nil {
-		return fmt.Errorf("failed to add labels %v on PR %s: %w", options.PullRequest.Labels, pr.GetHTMLURL(), err)
+		return fmt.Errorf("failed to add labels %v on PR %s: %w", options.Labels, pr.URL, err)
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"repository":   r.FullName(),
-		"pull-request": pr.GetHTMLURL(),
+		"pull-request": pr.URL,
 	}).Debug("Labels added to Pull Request")
 	return nil
 }
 
-func (r Repository) addPullRequestComments(ctx context.Context, options GitHubOptions, pr *github.PullRequest) error {
-	if len(options.PullRequest.Comments) == 0 {
+func (p *githubProvider) addPullRequestComments(ctx context.Context, r Repository, options PullRequestOptions, pr *PullRequest) error {
+	if len(options.Comments) == 0 {
 		logrus.WithFields(logrus.Fields{
 			"repository":   r.FullName(),
-			"pull-request": pr.GetHTMLURL(),
+			"pull-request": pr.URL,
 		}).Debug("No comments to add to the Pull Request")
 		return nil
 	}
 
-	client, _, err := githubClient(ctx, options)
+	client, _, err := githubClient(ctx, p.options)
 	if err != nil {
 		return fmt.Errorf("failed to create github client: %w", err)
 	}
-	for i, comment := range options.PullRequest.Comments {
+	for i, comment := range options.Comments {
 		logrus.WithFields(logrus.Fields{
 			"repository":   r.FullName(),
-			"pull-request": pr.GetHTMLURL(),
+			"pull-request": pr.URL,
 			"comment":      i,
 		}).Trace("Adding a comment to the Pull Request")
 
-		_, _, err := client.Issues.CreateComment(ctx, r.Owner, r.Name, pr.GetNumber(), &github.IssueComment{
+		_, _, err := client.Issues.CreateComment(ctx, r.Owner, r.Name, pr.Number, &github.IssueComment{
 			Body: github.String(comment),
 		})
 		if err != nil {
-			return fmt.Errorf("failed to add labels %v on PR %s: %w", options.PullRequest.Labels, pr.GetHTMLURL(), err)
+			return fmt.Errorf("failed to add labels %v on PR %s: %w", options.Labels, pr.URL, err)
 		}
 
 		logrus.WithFields(logrus.Fields{
 			"repository":   r.FullName(),
-			"pull-request": pr.GetHTMLURL(),
+			"pull-request": pr.URL,
 			"comment":      i,
 		}).Debug("Comment added to the Pull Request")
 
-		if len(options.PullRequest.Comments) > 1 && i < len(options.PullRequest.Comments)-1 {
+		if len(options.Comments) > 1 && i < len(options.Comments)-1 {
 			logrus.WithFields(logrus.Fields{
 				"repository":   r.FullName(),
-				"pull-request": pr.GetHTMLURL(),
+				"pull-request": pr.URL,
 				"comment":      i,
 			}).Trace("Sleeping a little before adding next comment, for rate limiting...")
 			time.Sleep(500 * time.Millisecond)
@@ -232,30 +369,35 @@ func (r Repository) addPullRequestComments(ctx context.Context, options GitHubOp
 	return nil
 }
 
-func (r Repository) mergePullRequest(ctx context.Context, options GitHubOptions, pr *github.PullRequest, retryCounts ...int) error {
+func (p *githubProvider) mergePullRequest(ctx context.Context, r Repository, options PullRequestOptions, pr *PullRequest) error {
+	r, _ = options.pullRequestRepository(r)
+	return p.mergeGitHubPullRequest(ctx, r, options, pr.Number)
+}
+
+func (p *githubProvider) mergeGitHubPullRequest(ctx context.Context, r Repository, options PullRequestOptions, prNumber int, retryCounts ...int) error {
 	var (
-		prURL      = pr.GetHTMLURL()
+		prURL      = fmt.Sprintf("%s/%s/pull/%d", p.options.URL, r.FullName(), prNumber)
 		retryCount = 0
 	)
-	client, _, err := githubClient(ctx, options)
+	client, _, err := githubClient(ctx, p.options)
 	if err != nil {
 		return fmt.Errorf("failed to create github client: %w", err)
 	}
 	if len(retryCounts) > 0 && retryCounts[0] > 0 {
 		retryCount = retryCounts[0]
 	}
-	if retryCount >= options.PullRequest.Merge.RetryCount {
-		return fmt.Errorf("failed to merge Pull Request %s after %d retries (max retry count is set to %d)", prURL, retryCount, options.PullRequest.Merge.RetryCount)
+	if retryCount >= options.Merge.RetryCount {
+		return fmt.Errorf("failed to merge Pull Request %s after %d retries (max retry count is set to %d)", prURL, retryCount, options.Merge.RetryCount)
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"repository":   r.FullName(),
 		"pull-request": prURL,
-		"timeout":      options.PullRequest.Merge.PollTimeout.String(),
+		"timeout":      options.Merge.PollTimeout.String(),
 		"retry":        retryCount,
 	}).Trace("Starting Pull Request merge process")
 
-	err = r.waitUntilPullRequestIsMergeable(ctx, options, pr)
+	err = p.waitUntilPullRequestIsMergeable(ctx, r, options, prNumber)
 	if err != nil {
 		return fmt.Errorf("failed to wait until Pull Request %s is mergeable: %w", prURL, err)
 	}
@@ -265,10 +407,11 @@ func (r Repository) mergePullRequest(ctx context.Context, options GitHubOptions,
 		"pull-request": prURL,
 		"retry":        retryCount,
 	}).Trace("Getting Pull Request status")
-	pr, _, err = client.PullRequests.Get(ctx, r.Owner, r.Name, pr.GetNumber())
+	pr, _, err := client.PullRequests.Get(ctx, r.Owner, r.Name, prNumber)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve status of Pull Request %s: %w", prURL, err)
 	}
+	prURL = pr.GetHTMLURL()
 	if pr.GetMerged() {
 		logrus.WithFields(logrus.Fields{
 			"repository":   r.FullName(),
@@ -283,10 +426,10 @@ func (r Repository) mergePullRequest(ctx context.Context, options GitHubOptions,
 		"pull-request": prURL,
 		"retry":        retryCount,
 	}).Trace("Merging Pull Request")
-	res, resp, err := client.PullRequests.Merge(ctx, r.Owner, r.Name, pr.GetNumber(), options.PullRequest.Merge.CommitMessage, &github.PullRequestOptions{
-		MergeMethod: options.PullRequest.Merge.Method,
-		CommitTitle: options.PullRequest.Merge.CommitTitle,
-		SHA:         options.PullRequest.Merge.SHA,
+	res, resp, err := client.PullRequests.Merge(ctx, r.Owner, r.Name, prNumber, options.Merge.CommitMessage, &github.PullRequestOptions{
+		MergeMethod: options.Merge.Method,
+		CommitTitle: options.Merge.CommitTitle,
+		SHA:         options.Merge.SHA,
 	})
 	if err != nil && shouldRetryMerge(resp, err) {
 		logrus.WithFields(logrus.Fields{
@@ -295,7 +438,7 @@ func (r Repository) mergePullRequest(ctx context.Context, options GitHubOptions,
 			"retry":        retryCount,
 		}).WithError(err).Warning("Failed to merge Pull Request - will retry")
 		retryCount++
-		err = r.mergePullRequest(ctx, options, pr, retryCount)
+		err = p.mergeGitHubPullRequest(ctx, r, options, prNumber, retryCount)
 		if err == nil {
 			return nil
 		}
@@ -315,14 +458,19 @@ func (r Repository) mergePullRequest(ctx context.Context, options GitHubOptions,
 	return nil
 }
 
-func (r Repository) waitUntilPullRequestIsMergeable(ctx context.Context, options GitHubOptions, pr *github.PullRequest) error {
+func (p *githubProvider) waitUntilPullRequestIsMergeable(ctx context.Context, r Repository, options PullRequestOptions, prNumber int) error {
 	var startTime = time.Now()
 
-	client, _, err := githubClient(ctx, options)
+	client, _, err := githubClient(ctx, p.options)
 	if err != nil {
 		return fmt.Errorf("failed to create github client: %w", err)
 	}
 
+	pr := &github.PullRequest{
+		Number:  github.Int(prNumber),
+		HTMLURL: github.String(fmt.Sprintf("%s/%s/pull/%d", p.options.URL, r.FullName(), prNumber)),
+	}
+
 	// first, ensure PR is mergeable
 	// https://developer.github.com/v3/git/#checking-mergeability-of-pull-requests
 	for {
@@ -358,8 +506,8 @@ func (r Repository) waitUntilPullRequestIsMergeable(ctx context.Context, options
 			break
 		}
 
-		if time.Since(startTime) > options.PullRequest.Merge.PollTimeout {
-			return fmt.Errorf("timeout after %s waiting for Pull Request %s mergeable status", options.PullRequest.Merge.PollTimeout.String(), pr.GetHTMLURL())
+		if time.Since(startTime) > options.Merge.PollTimeout {
+			return fmt.Errorf("timeout after %s waiting for Pull Request %s mergeable status", options.Merge.PollTimeout.String(), pr.GetHTMLURL())
 		}
 
 		logrus.WithFields(logrus.Fields{
@@ -370,8 +518,8 @@ func (r Repository) waitUntilPullRequestIsMergeable(ctx context.Context, options
 		logrus.WithFields(logrus.Fields{
 			"repository":   r.FullName(),
 			"pull-request": pr.GetHTMLURL(),
-		}).Tracef("Waiting %s until next GitHub request...", options.PullRequest.Merge.PollInterval.String())
-		time.Sleep(options.PullRequest.Merge.PollInterval)
+		}).Tracef("Waiting %s until next GitHub request...", options.Merge.PollInterval.String())
+		time.Sleep(options.Merge.PollInterval)
 	}
 
 	// then, ensure the status(es) are success
@@ -420,8 +568,8 @@ func (r Repository) waitUntilPullRequestIsMergeable(ctx context.Context, options
 			break
 		}
 
-		if time.Since(startTime) > options.PullRequest.Merge.PollTimeout {
-			return fmt.Errorf("timeout after %s waiting for Pull Request %s statuses checks", options.PullRequest.Merge.PollTimeout.String(), pr.GetHTMLURL())
+		if time.Since(startTime) > options.Merge.PollTimeout {
+			return fmt.Errorf("timeout after %s waiting for Pull Request %s statuses checks", options.Merge.PollTimeout.String(), pr.GetHTMLURL())
 		}
 
 		logrus.WithFields(logrus.Fields{
@@ -433,24 +581,30 @@ func (r Repository) waitUntilPullRequestIsMergeable(ctx context.Context, options
 		logrus.WithFields(logrus.Fields{
 			"repository":   r.FullName(),
 			"pull-request": pr.GetHTMLURL(),
-		}).Tracef("Waiting %s until next GitHub request...", options.PullRequest.Merge.PollInterval.String())
-		time.Sleep(options.PullRequest.Merge.PollInterval)
+		}).Tracef("Waiting %s until next GitHub request...", options.Merge.PollInterval.String())
+		time.Sleep(options.Merge.PollInterval)
 	}
 
 	return nil
 }
 
-func prHasLabels(pr *github.PullRequest, labels []string) bool {
-	matchingLabels := 0
-	for _, requiredLabel := range labels {
-		for _, label := range pr.Labels {
-			if label.GetName() == requiredLabel {
-				matchingLabels++
-				break
-			}
-		}
+func fromGitHubPullRequest(pr *github.PullRequest) *PullRequest {
+	var labels []string
+	for _, label := range pr.Labels {
+		labels = append(labels, label.GetName())
+	}
+	pullRequest := &PullRequest{
+		Number:     pr.GetNumber(),
+		URL:        pr.GetHTMLURL(),
+		Title:      pr.GetTitle(),
+		Body:       pr.GetBody(),
+		HeadBranch: pr.GetHead().GetRef(),
+		Labels:     labels,
+	}
+	if !pr.GetMergedAt().IsZero() {
+		pullRequest.MergeCommit = pr.GetMergeCommitSHA()
 	}
-	return matchingLabels == len(labels)
+	return pullRequest
 }
 
 func errIsStatusNotFound(err error) bool {
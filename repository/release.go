@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// hidden marker added to the body of the pull requests, with the tag of the release to create once the pull request is merged
+	releaseTagMarkerRegexp = regexp.MustCompile(`(?m)^<!-- octopilot-release-tag: (\S+) -->$`)
+	// the same marker, with the blank lines following it - to remove it
+	releaseTagMarkerLinesRegexp = regexp.MustCompile(`(?m)^<!-- octopilot-release-tag: \S+ -->$\n*`)
+)
+
+// Release is a provider-agnostic representation of a GitHub Release - or a GitLab Release - created once a pull request has been merged.
+type Release struct {
+	Tag    string
+	Commit string
+	Name   string
+	Body   string
+}
+
+// releaseTag returns the tag of the release recorded in the given pull request body - and false if there is none
+func releaseTag(body string) (string, bool) {
+	matches := releaseTagMarkerRegexp.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	// the last marker wins, in case the body has been appended to
+	return matches[len(matches)-1][1], true
+}
+
+// withReleaseTag returns the given pull request body, with a single marker for the given release tag at the end
+func withReleaseTag(body, tag string) string {
+	body = strings.TrimRight(releaseTagMarkerLinesRegexp.ReplaceAllString(body, ""), "\n ")
+	marker := fmt.Sprintf("<!-- octopilot-release-tag: %s -->", tag)
+	if len(body) == 0 {
+		return marker
+	}
+	return body + "\n\n" + marker
+}
+
+// setReleaseTag executes the template of the release tag, and records the tag in the body of the pull request - if the releases are enabled
+func (o *PullRequestOptions) setReleaseTag(tplExecutorFunc templateExecutor) error {
+	if !o.Release.OnMerge {
+		return nil
+	}
+	if len(o.Release.Tag) == 0 {
+		return errors.New("missing release tag: it is required to create a release on merge")
+	}
+
+	tag, err := tplExecutorFunc(o.Release.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to run template for release tag %s: %w", o.Release.Tag, err)
+	}
+	tag = strings.TrimSpace(tag)
+	if len(tag) == 0 || strings.ContainsAny(tag, " \t\n") {
+		return fmt.Errorf("invalid release tag %q: must be a non-empty string without spaces", tag)
+	}
+	o.Body = withReleaseTag(o.Body, tag)
+	return nil
+}
+
+// createReleasesOfMergedPullRequests creates a release for each recently merged pull request with a release tag - if it doesn't exist yet.
+// This is how the releases are created "on merge": when the pull requests are merged outside of octopilot, the releases are created by the next run.
+func (r Repository) createReleasesOfMergedPullRequests(ctx context.Context, provider Provider, options UpdateOptions) error {
+	if !options.GitHub.PullRequest.Release.OnMerge {
+		return nil
+	}
+
+	prs, err := provider.findMergedPullRequests(ctx, r, options.GitHub.PullRequest)
+	if err != nil {
+		return fmt.Errorf("failed to find the merged Pull Requests of repository %s: %w", r.FullName(), err)
+	}
+
+	for _, pr := range prs {
+		tag, found := releaseTag(pr.Body)
+		if !found {
+			continue
+		}
+		if len(pr.MergeCommit) == 0 {
+			logrus.WithFields(logrus.Fields{
+				"repository":   r.FullName(),
+				"pull-request": pr.URL,
+				"tag":          tag,
+			}).Warning("No merge commit found for the merged Pull Request - can't create its release!")
+			continue
+		}
+		if options.DryRun {
+			logrus.WithFields(logrus.Fields{
+				"repository":   r.FullName(),
+				"pull-request": pr.URL,
+				"tag":          tag,
+			}).Warning("Running in dry-run mode, not creating the release of the merged Pull Request")
+			continue
+		}
+
+		created, err := provider.createRelease(ctx, r, options.GitHub.PullRequest, Release{
+			Tag:    tag,
+			Commit: pr.MergeCommit,
+			Name:   tag,
+			Body:   fmt.Sprintf("%s\n\nReleased from %s", pr.Title, pr.URL),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create release %s for the merged Pull Request %s: %w", tag, pr.URL, err)
+		}
+		if !created {
+			logrus.WithFields(logrus.Fields{
+				"repository":   r.FullName(),
+				"pull-request": pr.URL,
+				"tag":          tag,
+			}).Debug("Release already exists")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateReleasesOfMergedPullRequests(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mutex    sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/my-org/app/pulls":
+			assert.Equal(t, "closed", r.URL.Query().Get("state"))
+			assert.Equal(t, "main", r.URL.Query().Get("base"))
+			_, _ = w.Write([]byte(`[
+				{"number":5,"html_url":"https://github.example.com/my-org/app/pull/5","title":"Release 1.2.0","body":"Bump version\n\n<!-- octopilot-release-tag: v1.2.0 -->","merged_at":"2023-01-02T10:00:00Z","merge_commit_sha":"abc123","labels":[{"name":"octopilot-update"}]},
+				{"number":4,"html_url":"https://github.example.com/my-org/app/pull/4","title":"Release 1.1.0","body":"<!-- octopilot-release-tag: v1.1.0 -->","merged_at":"2023-01-01T10:00:00Z","merge_commit_sha":"def456","labels":[{"name":"octopilot-update"}]},
+				{"number":3,"html_url":"https://github.example.com/my-org/app/pull/3","title":"Closed","body":"<!-- octopilot-release-tag: v1.0.1 -->","merge_commit_sha":"0a1b2c","labels":[{"name":"octopilot-update"}]},
+				{"number":2,"html_url":"https://github.example.com/my-org/app/pull/2","title":"No release","body":"Bump version","merged_at":"2022-12-01T10:00:00Z","merge_commit_sha":"123abc","labels":[{"name":"octopilot-update"}]},
+				{"number":1,"html_url":"https://github.example.com/my-org/app/pull/1","title":"Not from octopilot","body":"<!-- octopilot-release-tag: v0.1.0 -->","merged_at":"2022-11-01T10:00:00Z","merge_commit_sha":"456def","labels":[]}
+			]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/my-org/app/releases/tags/v1.2.0":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/my-org/app/releases/tags/v1.1.0":
+			_, _ = w.Write([]byte(`{"id":1,"tag_name":"v1.1.0"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/my-org/app/releases":
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "v1.2.0", payload["tag_name"])
+			assert.Equal(t, "abc123", payload["target_commitish"])
+			assert.Equal(t, "v1.2.0", payload["name"])
+			assert.Equal(t, "Release 1.2.0\n\nReleased from https://github.example.com/my-org/app/pull/5", payload["body"])
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":2,"tag_name":"v1.2.0","html_url":"https://github.example.com/my-org/app/releases/tag/v1.2.0"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	options := UpdateOptions{
+		GitHub: GitHubOptions{
+			URL:        server.URL,
+			AuthMethod: "token",
+			Token:      "gh-token",
+			PullRequest: PullRequestOptions{
+				Labels:     []string{"octopilot-update"},
+				BaseBranch: "main",
+				Release: PullRequestReleaseOptions{
+					OnMerge: true,
+					Tag:     "v{{ .version }}",
+				},
+			},
+		},
+	}
+	repo := Repository{Owner: "my-org", Name: "app"}
+	provider, err := newProvider(repo, options)
+	require.NoError(t, err)
+
+	err = repo.createReleasesOfMergedPullRequests(context.Background(), provider, options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"GET /api/v3/repos/my-org/app/pulls",
+		"GET /api/v3/repos/my-org/app/releases/tags/v1.2.0",
+		"POST /api/v3/repos/my-org/app/releases",
+		"GET /api/v3/repos/my-org/app/releases/tags/v1.1.0",
+	}, requests)
+}
+
+func TestCreateReleasesOfMergedMergeRequests(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mutex    sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.EscapedPath()))
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/api/v4/projects/group%2Fapp/merge_requests":
+			assert.Equal(t, "merged", r.URL.Query().Get("state"))
+			assert.Equal(t, "octopilot-update", r.URL.Query().Get("labels"))
+			_, _ = w.Write([]byte(`[
+				{"iid":8,"web_url":"https://gitlab.example.com/group/app/-/merge_requests/8","title":"Release 2.0.0","description":"<!-- octopilot-release-tag: 2.0.0 -->","state":"merged","sha":"head789","merge_commit_sha":null,"labels":["octopilot-update"]}
+			]`))
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/api/v4/projects/group%2Fapp/releases/2.0.0":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/api/v4/projects/group%2Fapp/releases":
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "2.0.0", payload["tag_name"])
+			assert.Equal(t, "head789", payload["ref"], "a fast-forward merge should be released from the head of the merge request")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"tag_name":"2.0.0","_links":{"self":"https://gitlab.example.com/group/app/-/releases/2.0.0"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	options := UpdateOptions{
+		GitLab: GitLabOptions{
+			URL:   server.URL,
+			Token: "gl-token",
+		},
+		GitHub: GitHubOptions{
+			PullRequest: PullRequestOptions{
+				Labels: []string{"octopilot-update"},
+				Release: PullRequestReleaseOptions{
+					OnMerge: true,
+					Tag:     "{{ .version }}",
+				},
+			},
+		},
+	}
+	repo := Repository{Owner: "group", Name: "app", Params: map[string]string{"provider": "gitlab"}}
+	provider, err := newProvider(repo, options)
+	require.NoError(t, err)
+
+	err = repo.createReleasesOfMergedPullRequests(context.Background(), provider, options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"GET /api/v4/projects/group%2Fapp/merge_requests",
+		"GET /api/v4/projects/group%2Fapp/releases/2.0.0",
+		"POST /api/v4/projects/group%2Fapp/releases",
+	}, requests)
+}
+
+func TestCreateReleasesOfMergedPullRequestsDisabled(t *testing.T) {
+	t.Parallel()
+
+	// the local provider fails to create releases: it must not be called
+	err := Repository{Owner: "owner", Name: "repo"}.createReleasesOfMergedPullRequests(context.Background(), &localProvider{}, UpdateOptions{})
+	assert.NoError(t, err)
+}
+
+func TestSetReleaseTag(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		options          PullRequestOptions
+		tplExecutorFunc  templateExecutor
+		expectedBody     string
+		expectedErrorMsg string
+	}{
+		{
+			name: "releases disabled",
+			options: PullRequestOptions{
+				Body:    "Bump version",
+				Release: PullRequestReleaseOptions{Tag: "v1.2.0"},
+			},
+			expectedBody: "Bump version",
+		},
+		{
+			name: "release tag template",
+			options: PullRequestOptions{
+				Body:    "Bump version\n",
+				Release: PullRequestReleaseOptions{OnMerge: true, Tag: "v{{ version }}"},
+			},
+			tplExecutorFunc: func(text string) (string, error) {
+				return "v1.2.0\n", nil
+			},
+			expectedBody: "Bump version\n\n<!-- octopilot-release-tag: v1.2.0 -->",
+		},
+		{
+			name: "missing release tag",
+			options: PullRequestOptions{
+				Body:    "Bump version",
+				Release: PullRequestReleaseOptions{OnMerge: true},
+			},
+			expectedErrorMsg: "missing release tag: it is required to create a release on merge",
+		},
+		{
+			name: "invalid release tag",
+			options: PullRequestOptions{
+				Release: PullRequestReleaseOptions{OnMerge: true, Tag: "{{ readFile \"VERSION\" }}"},
+			},
+			tplExecutorFunc: func(text string) (string, error) {
+				return "1.2.0 beta", nil
+			},
+			expectedErrorMsg: `invalid release tag "1.2.0 beta": must be a non-empty string without spaces`,
+		},
+		{
+			name: "failing release tag template",
+			options: PullRequestOptions{
+				Release: PullRequestReleaseOptions{OnMerge: true, Tag: "{{ readFile \"VERSION\" }}"},
+			},
+			tplExecutorFunc: func(text string) (string, error) {
+				return "", errors.New("no such file")
+			},
+			expectedErrorMsg: `failed to run template for release tag {{ readFile "VERSION" }}: no such file`,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			options := test.options
+			err := options.setReleaseTag(test.tplExecutorFunc)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedBody, options.Body)
+		})
+	}
+}
+
+func TestUpdatePullRequestReleaseTag(t *testing.T) {
+	t.Parallel()
+
+	// the body of the existing PR is kept, but its release tag is updated
+	pr := &PullRequest{Body: "Bump version to 1.1.0\n\n<!-- octopilot-release-tag: v1.1.0 -->"}
+	options := PullRequestOptions{
+		Body:                "Bump version to 1.2.0\n\n<!-- octopilot-release-tag: v1.2.0 -->",
+		BodyUpdateOperation: IgnoreUpdateOperation,
+	}
+	assert.True(t, applyPullRequestUpdateOperations(options, pr))
+	assert.Equal(t, "Bump version to 1.1.0\n\n<!-- octopilot-release-tag: v1.2.0 -->", pr.Body)
+	tag, found := releaseTag(pr.Body)
+	assert.True(t, found)
+	assert.Equal(t, "v1.2.0", tag)
+
+	// the bodies are appended, with a single release tag
+	options.BodyUpdateOperation = AppendUpdateOperation
+	assert.True(t, applyPullRequestUpdateOperations(options, pr))
+	assert.Equal(t, "Bump version to 1.1.0\n\nBump version to 1.2.0\n\n<!-- octopilot-release-tag: v1.2.0 -->", pr.Body)
+
+	// nothing to update
+	options.BodyUpdateOperation = IgnoreUpdateOperation
+	assert.False(t, applyPullRequestUpdateOperations(options, pr))
+}
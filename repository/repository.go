@@ -1,4 +1,4 @@
-// Package repository contains everything related to working with git repositories hosted on GitHub: cloning, commits, creating branches, pushing, creating/updating/merging pull requests, and so on.
+// Package repository contains everything related to working with git repositories hosted on GitHub or GitLab: cloning, commits, creating branches, pushing, creating/updating/merging pull requests, and so on.
 package repository
 
 import (
@@ -8,9 +8,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/dailymotion-oss/octopilot/internal/changes"
 	"github.com/dailymotion-oss/octopilot/internal/parameters"
 	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/go-git/go-git/v5"
 	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
 )
@@ -19,12 +23,14 @@ var (
 	// type(params)
 	repoRegexp = regexp.MustCompile(`^(?P<type>[A-Za-z0-9._\-/]+)(?:\((?P<params>.+)\))?$`)
 
-	// owner/name(params)
-	repoWithNameRegexp = regexp.MustCompile(`^(?P<owner>[A-Za-z0-9_\-]+)/(?P<name>[A-Za-z0-9._\-]+)(?:\((?P<params>.+)\))?$`)
+	// owner/name(params) - where the owner can contain GitLab subgroups: group/subgroup/name(params)
+	repoWithNameRegexp = regexp.MustCompile(`^(?P<owner>[A-Za-z0-9_\-]+(?:/[A-Za-z0-9._\-]+)*)/(?P<name>[A-Za-z0-9._\-]+)(?:\((?P<params>.+)\))?$`)
 )
 
-// Repository is a representation of a GitHub repository.
+// Repository is a representation of a GitHub - or GitLab - repository.
 type Repository struct {
+	// Host is the optional host of the git hosting service, such as gitlab.com - if empty, the provider's configured URL is used
+	Host   string
 	Owner  string
 	Name   string
 	Params map[string]string
@@ -48,12 +54,14 @@ func Parse(ctx context.Context, repos []string, githubOpts GitHubOptions) ([]Rep
 			}
 			repositories = append(repositories, discoveredRepos...)
 		default:
-			matches := repoWithNameRegexp.FindStringSubmatch(repo)
+			host, repoWithoutHost := splitRepoHost(repo)
+			matches := repoWithNameRegexp.FindStringSubmatch(repoWithoutHost)
 			if len(matches) < 4 {
 				return nil, fmt.Errorf("invalid syntax for %s: found %d matches instead of 4: %v", repo, len(matches), matches)
 			}
 
 			repositories = append(repositories, Repository{
+				Host:   host,
 				Owner:  matches[1],
 				Name:   matches[2],
 				Params: parameters.Parse(matches[3]),
@@ -63,6 +71,20 @@ func Parse(ctx context.Context, repos []string, githubOpts GitHubOptions) ([]Rep
 	return repositories, nil
 }
 
+// splitRepoHost extracts the optional host prefix of a repository, such as in gitlab.com/group/name(params).
+// The first path segment is considered as a host if it contains a dot, and is followed by at least an owner and a name.
+func splitRepoHost(repo string) (string, string) {
+	path := repo
+	if i := strings.Index(path, "("); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(path, "/")
+	if len(segments) < 3 || !strings.Contains(segments[0], ".") {
+		return "", repo
+	}
+	return segments[0], strings.TrimPrefix(repo, segments[0]+"/")
+}
+
 func discoverRepositoriesFrom(ctx context.Context, params map[string]string, githubOpts GitHubOptions) ([]Repository, error) {
 	if query, ok := params["query"]; ok {
 		return discoverRepositoriesFromQuery(ctx, query, params, githubOpts)
@@ -81,7 +103,34 @@ func discoverRepositoriesFrom(ctx context.Context, params map[string]string, git
 func (r Repository) Update(ctx context.Context, updaters []update.Updater, options UpdateOptions) (bool, error) {
 	r.adjustOptionsFromParams(&options)
 
-	repoPath := filepath.Join(options.Git.CloneDir, r.Owner, r.Name)
+	provider, err := newProvider(r, options)
+	if err != nil {
+		return false, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"repository": r.FullName(),
+		"provider":   provider.name(),
+	}).Trace("Using provider")
+
+	if prRepo := options.GitHub.PullRequest.Repository; len(prRepo) > 0 {
+		if !repoWithNameRegexp.MatchString(prRepo) || strings.Contains(prRepo, "(") {
+			return false, fmt.Errorf("invalid pull request repository %s: must be in the form owner/name", prRepo)
+		}
+		if provider.name() != GitHubProvider {
+			return false, fmt.Errorf("a different pull request repository is only supported with the %s provider - repository %s uses the %s provider", GitHubProvider, r.FullName(), provider.name())
+		}
+	}
+
+	if len(options.BaseBranches) > 0 {
+		return r.updateBaseBranches(ctx, updaters, options, provider)
+	}
+	return r.update(ctx, updaters, options, provider)
+}
+
+// update updates the repository with the given provider - against the base branch of the options
+func (r Repository) update(ctx context.Context, updaters []update.Updater, options UpdateOptions, provider Provider) (bool, error) {
+	var err error
+	repoPath := filepath.Join(options.Git.CloneDir, r.Host, r.Owner, r.Name)
 	if !options.KeepFiles {
 		defer func() {
 			logrus.WithFields(logrus.Fields{
@@ -97,6 +146,14 @@ func (r Repository) Update(ctx context.Context, updaters []update.Updater, optio
 		}()
 	}
 
+	if err = options.Plan.register(r, repoPath); err != nil {
+		return false, err
+	}
+
+	if err = r.createReleasesOfMergedPullRequests(ctx, provider, options); err != nil {
+		return false, err
+	}
+
 	var strategy Strategy
 	switch options.Strategy {
 	case "recreate":
@@ -108,6 +165,7 @@ func (r Repository) Update(ctx context.Context, updaters []update.Updater, optio
 			RepoPath:   repoPath,
 			Updaters:   updaters,
 			Options:    options,
+			Provider:   provider,
 		}
 	case "append":
 		logrus.WithFields(logrus.Fields{
@@ -118,6 +176,7 @@ func (r Repository) Update(ctx context.Context, updaters []update.Updater, optio
 			RepoPath:   repoPath,
 			Updaters:   updaters,
 			Options:    options,
+			Provider:   provider,
 		}
 	default:
 		logrus.WithFields(logrus.Fields{
@@ -128,6 +187,7 @@ func (r Repository) Update(ctx context.Context, updaters []update.Updater, optio
 			RepoPath:   repoPath,
 			Updaters:   updaters,
 			Options:    options,
+			Provider:   provider,
 		}
 	}
 
@@ -135,9 +195,15 @@ func (r Repository) Update(ctx context.Context, updaters []update.Updater, optio
 	if err != nil {
 		return false, fmt.Errorf("%w", err)
 	}
+	options.SARIFReport.publish(r, repoPath, options, pr, repoUpdated)
+	if err = options.Plan.recordDiff(repoPath); err != nil {
+		return false, fmt.Errorf("failed to record the plan of repository %s: %w", r.FullName(), err)
+	}
 	if !repoUpdated {
 		return false, nil
 	}
+	prRepo, _ := options.GitHub.PullRequest.pullRequestRepository(r)
+	options.RollbackManifest.record(r, prRepo, provider, repoPath, pr)
 
 	if !options.GitHub.PullRequest.Merge.Enabled {
 		logrus.WithFields(logrus.Fields{
@@ -152,27 +218,67 @@ func (r Repository) Update(ctx context.Context, updaters []update.Updater, optio
 		return true, nil
 	}
 
-	err = r.mergePullRequest(ctx, options.GitHub, pr)
+	err = provider.mergePullRequest(ctx, r, options.GitHub.PullRequest, pr)
 	if err != nil {
-		return true, fmt.Errorf("failed to merge Pull Request %s: %w", pr.GetHTMLURL(), err)
+		return true, fmt.Errorf("failed to merge Pull Request %s: %w", pr.URL, err)
+	}
+
+	// no need to wait for the next run to create the release of the merged Pull Request
+	if err = r.createReleasesOfMergedPullRequests(ctx, provider, options); err != nil {
+		return true, err
 	}
 
 	return true, nil
 }
 
-func (r Repository) runUpdaters(ctx context.Context, updaters []update.Updater, repoPath string) (bool, error) {
-	var repoUpdated bool
-	for _, updater := range updaters {
+func (r Repository) runUpdaters(ctx context.Context, updaters []update.Updater, repoPath string, options UpdateOptions) (bool, error) {
+	gitOpts := options.Git
+	var (
+		repoUpdated     bool
+		updatedUpdaters []update.Updater
+	)
+	options.SARIFReport.reset(repoPath)
+	// the changes of each updater are only computed if they are recorded
+	recordChanges := options.SARIFReport != nil || len(options.ExportChangesDir) > 0
+	if len(options.ExportChangesDir) > 0 {
+		ctx = changes.WithSecrets(ctx)
+	}
+	for i, updater := range updaters {
 		logrus.WithFields(logrus.Fields{
 			"repository": r.FullName(),
 			"updater":    updater.String(),
 		}).Trace("Running updater")
-		updated, err := updater.Update(ctx, repoPath)
+		var snapshot map[string]fileContent
+		if recordChanges {
+			var err error
+			if snapshot, err = snapshotChangedFiles(repoPath, gitOpts); err != nil {
+				return false, fmt.Errorf("failed to snapshot the changed files of repository %s: %w", r.FullName(), err)
+			}
+		}
+		updaterCtx := ctx
+		if len(options.ExportChangesDir) > 0 {
+			// the encrypted files changed by the updater are exported in plaintext
+			updaterCtx = changes.WithPlaintexts(ctx)
+		}
+		updated, err := updater.Update(updaterCtx, repoPath)
 		if err != nil {
 			return false, fmt.Errorf("failed to update repository %s: %w", r.FullName(), err)
 		}
 		if updated {
 			repoUpdated = true
+			updatedUpdaters = append(updatedUpdaters, updater)
+		}
+		if updated && recordChanges {
+			fileChanges, err := updaterChanges(repoPath, gitOpts, snapshot)
+			if err != nil {
+				return false, fmt.Errorf("failed to list the files changed by updater %s in repository %s: %w", updater.String(), r.FullName(), err)
+			}
+			options.SARIFReport.recordUpdater(repoPath, updater, fileChanges)
+			if len(options.ExportChangesDir) > 0 {
+				if err = r.exportChanges(updaterCtx, options.ExportChangesDir, i, fileChanges); err != nil {
+					return false, fmt.Errorf("failed to export the changes of updater %s in repository %s: %w", updater.String(), r.FullName(), err)
+				}
+			}
 		}
 		logrus.WithFields(logrus.Fields{
 			"repository": r.FullName(),
@@ -181,9 +287,50 @@ func (r Repository) runUpdaters(ctx context.Context, updaters []update.Updater,
 		}).Debug("Updater finished")
 	}
 	logrus.WithField("repository", r.FullName()).Debug("All updaters finished")
+
+	if err := options.Plan.checkChanges(r, repoPath, gitOpts); err != nil {
+		return false, err
+	}
+
+	if repoUpdated && len(gitOpts.AuditLogFile) > 0 {
+		err := appendAuditLog(repoPath, gitOpts.AuditLogFile, gitOpts.AuditLogRunID, updatedUpdaters, time.Now())
+		if err != nil {
+			return false, fmt.Errorf("failed to append to audit log %s of repository %s: %w", gitOpts.AuditLogFile, r.FullName(), err)
+		}
+	}
 	return repoUpdated, nil
 }
 
+// hasEnoughChangedFiles returns true if the updaters changed at least the minimum number of files required to create a pull request.
+// If not, the changes are reverted - if requested.
+func (r Repository) hasEnoughChangedFiles(gitRepo *git.Repository, options UpdateOptions) (bool, error) {
+	if options.MinChangedFiles <= 1 {
+		return true, nil
+	}
+
+	changedFiles, err := countChangedFiles(gitRepo, options.Git)
+	if err != nil {
+		return false, fmt.Errorf("failed to count changed files: %w", err)
+	}
+	if changedFiles >= options.MinChangedFiles {
+		return true, nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository":        r.FullName(),
+		"changed-files":     changedFiles,
+		"min-changed-files": options.MinChangedFiles,
+	}).Info("Not enough files changed, skipping Pull Request")
+
+	if options.RevertBelowMinChangedFiles {
+		err = revertChanges(gitRepo)
+		if err != nil {
+			return false, fmt.Errorf("failed to revert changes: %w", err)
+		}
+	}
+	return false, nil
+}
+
 func (r Repository) newBranchName(prefix string) string {
 	branchName := fmt.Sprintf("%s%s", prefix, xid.New().String())
 	logrus.WithFields(logrus.Fields{
@@ -204,6 +351,26 @@ func (r Repository) adjustOptionsFromParams(options *UpdateOptions) {
 			options.GitHub.PullRequest.Merge.Enabled = merge
 		}
 	}
+	if minChangedFilesStr, found := r.Params["min-changed-files"]; found {
+		if minChangedFiles, err := strconv.Atoi(minChangedFilesStr); err == nil {
+			options.MinChangedFiles = minChangedFiles
+		}
+	}
+}
+
+// withFullName returns a repository on the same host - and with the same params - for the given "owner/name" full name.
+// It returns false if the full name is invalid.
+func (r Repository) withFullName(fullName string) (Repository, bool) {
+	i := strings.LastIndex(fullName, "/")
+	if i <= 0 || i == len(fullName)-1 {
+		return r, false
+	}
+	return Repository{
+		Host:   r.Host,
+		Owner:  fullName[:i],
+		Name:   fullName[i+1:],
+		Params: r.Params,
+	}, true
 }
 
 // FullName returns the repository full name.
@@ -2,11 +2,21 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/dailymotion-oss/octopilot/internal/changes"
+	"github.com/dailymotion-oss/octopilot/update"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mozilla.org/sops/v3"
+	"go.mozilla.org/sops/v3/aes"
+	"go.mozilla.org/sops/v3/age"
+	"go.mozilla.org/sops/v3/cmd/sops/common"
+	"go.mozilla.org/sops/v3/cmd/sops/formats"
+	"go.mozilla.org/sops/v3/keys"
 )
 
 func TestParse(t *testing.T) {
@@ -116,6 +126,40 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "repository with a host and subgroups",
+			repos: []string{"gitlab.example.com/group/subgroup/my-repo(provider=gitlab,branch=release/1.0)"},
+			expected: []Repository{
+				{
+					Host:  "gitlab.example.com",
+					Owner: "group/subgroup",
+					Name:  "my-repo",
+					Params: map[string]string{
+						"provider": "gitlab",
+						"branch":   "release/1.0",
+					},
+				},
+			},
+		},
+		{
+			name:  "mixed github and gitlab repositories",
+			repos: []string{"dailymotion-oss/octopilot", "gitlab.com/some-group/MyGreatRepo(merge=true)"},
+			expected: []Repository{
+				{
+					Owner:  "dailymotion-oss",
+					Name:   "octopilot",
+					Params: map[string]string{},
+				},
+				{
+					Host:  "gitlab.com",
+					Owner: "some-group",
+					Name:  "MyGreatRepo",
+					Params: map[string]string{
+						"merge": "true",
+					},
+				},
+			},
+		},
 		{
 			name:  "discover from environment",
 			repos: []string{"discover-from(env=OCTOPILOT_TEST_DISCOVER_FROM,sep=;,merge=true)"},
@@ -159,3 +203,107 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestRunUpdatersExportChanges(t *testing.T) {
+	t.Parallel()
+
+	repoPath := initLocalRepository(t, map[string]string{
+		"app.yaml":       "name: app\nversion: v1\ntoken: none\n",
+		"unchanged.yaml": "version: v2\n",
+		"token.txt":      "s3cret",
+	})
+
+	updaters, err := update.Parse([]string{
+		"yaml(file=app.yaml,path=version)=v2",
+		"yaml(file=unchanged.yaml,path=version)=v2",
+		"yaml(file=app.yaml,path=name)=my-app",
+		"exec(cmd=sh,args=-c 'rm unchanged.yaml',stdout=output.txt)",
+		"yaml(file=app.yaml,path=token)=file(path=token.txt,encoding=base64)",
+	})
+	require.NoError(t, err)
+
+	exportDir := t.TempDir()
+	repo := Repository{Owner: "owner", Name: "repo"}
+	updated, err := repo.runUpdaters(context.Background(), updaters, repoPath, UpdateOptions{ExportChangesDir: exportDir})
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	maskedToken := changes.Mask(base64.StdEncoding.EncodeToString([]byte("s3cret")))
+	expectedFiles := map[string]string{
+		"owner/repo/updater-1/app.yaml.before":       "name: app\nversion: v1\ntoken: none\n",
+		"owner/repo/updater-1/app.yaml.after":        "name: app\nversion: v2\ntoken: none\n",
+		"owner/repo/updater-3/app.yaml.before":       "name: app\nversion: v2\ntoken: none\n",
+		"owner/repo/updater-3/app.yaml.after":        "name: my-app\nversion: v2\ntoken: none\n",
+		"owner/repo/updater-4/output.txt.after":      "",
+		"owner/repo/updater-4/unchanged.yaml.before": "version: v2\n",
+		"owner/repo/updater-5/app.yaml.before":       "name: my-app\nversion: v2\ntoken: none\n",
+		"owner/repo/updater-5/app.yaml.after":        "name: my-app\nversion: v2\ntoken: " + maskedToken + "\n",
+	}
+	var actualFiles []string
+	err = filepath.Walk(exportDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(exportDir, path)
+		if err != nil {
+			return err
+		}
+		actualFiles = append(actualFiles, filepath.ToSlash(relPath))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, actualFiles, len(expectedFiles), "only the changed files should be exported: %v", actualFiles)
+	for file, expectedContent := range expectedFiles {
+		actualContent, err := os.ReadFile(filepath.Join(exportDir, file))
+		require.NoErrorf(t, err, "can't read exported file %s", file)
+		assert.Equalf(t, expectedContent, string(actualContent), "exported file %s doesn't match", file)
+	}
+}
+
+func TestRunUpdatersExportSopsChanges(t *testing.T) {
+	// not parallel: the age key of the sops unit tests is defined in an env var
+	ageKeyFile, err := filepath.Abs(filepath.Join("..", "update", "sops", "testdata", "age.key"))
+	require.NoError(t, err)
+	os.Setenv("SOPS_AGE_KEY_FILE", ageKeyFile)
+	masterKeys, err := age.MasterKeysFromRecipients("age16fvu9n7dkhdkrrrtfwctfzf94zvh58ars22k2fv9rmhkr9rkfszsyw8zzq")
+	require.NoError(t, err)
+
+	store := common.StoreForFormat(formats.Yaml)
+	branches, err := store.LoadPlainFile([]byte("app:\n    token: old-token\n    user: admin\n"))
+	require.NoError(t, err)
+	tree := sops.Tree{
+		FilePath: "secrets.yaml",
+		Metadata: sops.Metadata{
+			KeyGroups: []sops.KeyGroup{[]keys.MasterKey{masterKeys[0]}},
+			Version:   "3.5.0",
+		},
+		Branches: branches,
+	}
+	dataKey, errs := tree.GenerateDataKey()
+	require.Empty(t, errs)
+	tree.Metadata.DataKey = dataKey
+	require.NoError(t, common.EncryptTree(common.EncryptTreeOpts{Cipher: aes.NewCipher(), DataKey: dataKey, Tree: &tree}))
+	encryptedData, err := store.EmitEncryptedFile(tree)
+	require.NoError(t, err)
+
+	repoPath := initLocalRepository(t, map[string]string{"secrets.yaml": string(encryptedData)})
+	updaters, err := update.Parse([]string{"sops(file=secrets.yaml,key=app.token)=new-token"})
+	require.NoError(t, err)
+
+	exportDir := t.TempDir()
+	repo := Repository{Owner: "owner", Name: "repo"}
+	updated, err := repo.runUpdaters(context.Background(), updaters, repoPath, UpdateOptions{ExportChangesDir: exportDir})
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	expectedFiles := map[string]string{
+		"owner/repo/updater-1/secrets.yaml.before": "app:\n    token: '" + changes.Mask("old-token") + "'\n    user: '" + changes.Mask("admin") + "'\n",
+		"owner/repo/updater-1/secrets.yaml.after":  "app:\n    token: '" + changes.Mask("new-token") + "'\n    user: '" + changes.Mask("admin") + "'\n",
+	}
+	for file, expectedContent := range expectedFiles {
+		actualContent, err := os.ReadFile(filepath.Join(exportDir, file))
+		require.NoErrorf(t, err, "can't read exported file %s", file)
+		assert.Equalf(t, expectedContent, string(actualContent), "exported file %s doesn't match", file)
+		assert.NotContains(t, string(actualContent), "ENC[", "the exported file %s should be decrypted", file)
+	}
+}
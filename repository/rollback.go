@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// RollbackManifest records the changes made on the remote repositories during a run - pull requests, branches and commits - so that they can be rolled back.
+// It is shared by all the repositories updated in the same run.
+type RollbackManifest struct {
+	RunID     string          `json:"runId"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Entries   []RollbackEntry `json:"entries"`
+
+	mutex sync.Mutex
+}
+
+// RollbackEntry is the record of the changes made on a single repository.
+type RollbackEntry struct {
+	Host              string `json:"host,omitempty"`
+	Owner             string `json:"owner"`
+	Name              string `json:"name"`
+	Provider          string `json:"provider"`
+	Branch            string `json:"branch"`
+	Commit            string `json:"commit,omitempty"`
+	PullRequestNumber int    `json:"pullRequestNumber,omitempty"`
+	PullRequestURL    string `json:"pullRequestUrl,omitempty"`
+	// PullRequestRepository is the "owner/name" of the repository of the pull request - if it's not the updated repository
+	PullRequestRepository string `json:"pullRequestRepository,omitempty"`
+	// Created is true if the pull request and its branch have been created by the run - only those are rolled back
+	Created bool `json:"created"`
+}
+
+// NewRollbackManifest returns a new empty manifest for the given run.
+func NewRollbackManifest(runID string) *RollbackManifest {
+	return &RollbackManifest{
+		RunID:     runID,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// ReadRollbackManifest reads the manifest written to the given file.
+func ReadRollbackManifest(path string) (*RollbackManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rollback manifest %s: %w", path, err)
+	}
+	manifest := &RollbackManifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse rollback manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// Write writes the manifest to the given file, as JSON. The entries are sorted by repository, so that the manifest is stable.
+func (m *RollbackManifest) Write(path string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sort.SliceStable(m.Entries, func(i, j int) bool {
+		return m.Entries[i].repository().FullName() < m.Entries[j].repository().FullName()
+	})
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode rollback manifest: %w", err)
+	}
+	if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write rollback manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// record adds the changes made on the given repository - and its pull request, created in the given pull request repository - to the manifest.
+// A nil manifest doesn't record anything.
+func (m *RollbackManifest) record(r, prRepo Repository, provider Provider, repoPath string, pr *PullRequest) {
+	if m == nil || pr == nil {
+		return
+	}
+
+	entry := RollbackEntry{
+		Host:              r.Host,
+		Owner:             r.Owner,
+		Name:              r.Name,
+		Provider:          provider.name(),
+		Branch:            pr.HeadBranch,
+		PullRequestNumber: pr.Number,
+		PullRequestURL:    pr.URL,
+		Created:           pr.Created,
+	}
+	if prRepo.FullName() != r.FullName() {
+		entry.PullRequestRepository = prRepo.FullName()
+	}
+	if gitRepo, err := git.PlainOpen(repoPath); err == nil {
+		if head, err := gitRepo.Head(); err == nil {
+			entry.Commit = head.Hash().String()
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Entries = append(m.Entries, entry)
+}
+
+// Rollback rolls back all the changes recorded in the given manifest: it closes the pull requests created by the run, and deletes their branches.
+// The pull requests which already existed before the run are left untouched.
+// It doesn't stop at the first failure, but returns an error if any entry failed to be rolled back.
+// In dry-run mode, it only logs what it would roll back.
+func Rollback(ctx context.Context, manifest *RollbackManifest, options UpdateOptions) error {
+	return manifest.rollback(ctx, func(r Repository) (Provider, error) {
+		return newProvider(r, options)
+	}, options.DryRun)
+}
+
+func (m *RollbackManifest) rollback(ctx context.Context, providerFunc func(Repository) (Provider, error), dryRun bool) error {
+	var failures int
+	for _, entry := range m.Entries {
+		r := entry.repository()
+		err := entry.rollback(ctx, r, providerFunc, dryRun)
+		if err != nil {
+			failures++
+			logrus.WithError(err).WithField("repository", r.FullName()).Error("Rollback failed")
+			continue
+		}
+		logrus.WithField("repository", r.FullName()).Info("Rollback finished")
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed to rollback %d out of %d repositories", failures, len(m.Entries))
+	}
+	return nil
+}
+
+func (e RollbackEntry) rollback(ctx context.Context, r Repository, providerFunc func(Repository) (Provider, error), dryRun bool) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"repository":   r.FullName(),
+		"branch":       e.Branch,
+		"pull-request": e.PullRequestURL,
+	})
+	if !e.Created {
+		logger.Warning("The Pull Request existed before the run, not rolling it back")
+		return nil
+	}
+	if len(e.Branch) == 0 {
+		return errors.New("no branch recorded")
+	}
+	if dryRun {
+		logger.Warning("Running in dry-run mode, not closing the Pull Request and deleting its branch")
+		return nil
+	}
+
+	provider, err := providerFunc(r)
+	if err != nil {
+		return err
+	}
+
+	if e.PullRequestNumber > 0 {
+		err = provider.closePullRequest(ctx, e.pullRequestRepository(r), &PullRequest{
+			Number:     e.PullRequestNumber,
+			URL:        e.PullRequestURL,
+			HeadBranch: e.Branch,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return provider.deleteBranch(ctx, r, e.Branch)
+}
+
+// pullRequestRepository returns the repository of the pull request: either the given updated repository, or the recorded pull request repository
+func (e RollbackEntry) pullRequestRepository(r Repository) Repository {
+	if prRepo, valid := r.withFullName(e.PullRequestRepository); valid {
+		return prRepo
+	}
+	return r
+}
+
+func (e RollbackEntry) repository() Repository {
+	return Repository{
+		Host:  e.Host,
+		Owner: e.Owner,
+		Name:  e.Name,
+		Params: map[string]string{
+			"provider": e.Provider,
+		},
+	}
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollback(t *testing.T) {
+	t.Parallel()
+
+	// fake run on 2 repositories: only the first one is updated
+	updatedProvider := &localProvider{path: initLocalRepository(t, map[string]string{"a.txt": "a1"})}
+	unchangedProvider := &localProvider{path: initLocalRepository(t, map[string]string{"a.txt": "a2"})}
+	providers := map[string]*localProvider{
+		"owner/updated":   updatedProvider,
+		"owner/unchanged": unchangedProvider,
+	}
+	manifest := NewRollbackManifest("test-run")
+	for fullName, provider := range providers {
+		repo := Repository{Owner: "owner", Name: filepath.Base(fullName), Params: map[string]string{}}
+		clonePath := t.TempDir()
+		strategy := &RecreateStrategy{
+			Repository: repo,
+			RepoPath:   clonePath,
+			Updaters: []update.Updater{
+				&writeFileUpdater{file: "a.txt", content: "a2"},
+			},
+			Provider: provider,
+			Options: UpdateOptions{
+				Git: GitOptions{
+					StageAllChanged: true,
+					AuthorName:      "test",
+					AuthorEmail:     "test@example.com",
+					CommitterName:   "test",
+					CommitterEmail:  "test@example.com",
+					CommitTitle:     "update",
+					BranchPrefix:    "octopilot-test",
+				},
+				GitHub: GitHubOptions{
+					PullRequest: PullRequestOptions{Title: "update", Body: "update"},
+				},
+			},
+		}
+		updated, pr, err := strategy.Run(context.Background())
+		require.NoError(t, err)
+		if updated {
+			manifest.record(repo, repo, provider, clonePath, pr)
+		}
+	}
+
+	manifestPath := filepath.Join(t.TempDir(), "rollback.json")
+	require.NoError(t, manifest.Write(manifestPath))
+	manifest, err := ReadRollbackManifest(manifestPath)
+	require.NoError(t, err)
+	assert.Equal(t, "test-run", manifest.RunID)
+	require.Len(t, manifest.Entries, 1)
+	entry := manifest.Entries[0]
+	assert.Equal(t, "updated", entry.Name)
+	assert.Equal(t, "local", entry.Provider)
+	assert.Equal(t, 1, entry.PullRequestNumber)
+	assert.True(t, entry.Created)
+
+	// the branch and commit recorded are the ones pushed
+	originRepo, err := git.PlainOpen(updatedProvider.path)
+	require.NoError(t, err)
+	branchRef, err := originRepo.Reference(plumbing.NewBranchReferenceName(entry.Branch), true)
+	require.NoError(t, err)
+	assert.Equal(t, branchRef.Hash().String(), entry.Commit)
+
+	providerFunc := func(r Repository) (Provider, error) {
+		return providers[r.FullName()], nil
+	}
+
+	// dry-run doesn't change anything
+	require.NoError(t, manifest.rollback(context.Background(), providerFunc, true))
+	assert.Equal(t, []string{"create"}, updatedProvider.pullRequests)
+	_, err = originRepo.Reference(plumbing.NewBranchReferenceName(entry.Branch), true)
+	require.NoError(t, err)
+
+	err = manifest.rollback(context.Background(), providerFunc, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"create", "close #1"}, updatedProvider.pullRequests)
+	assert.Empty(t, unchangedProvider.pullRequests)
+	_, err = originRepo.Reference(plumbing.NewBranchReferenceName(entry.Branch), true)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+}
+
+func TestRollbackFailure(t *testing.T) {
+	t.Parallel()
+
+	provider := &localProvider{path: initLocalRepository(t, map[string]string{"a.txt": "a1"})}
+	manifest := &RollbackManifest{
+		Entries: []RollbackEntry{
+			{Owner: "owner", Name: "missing-branch", Provider: "local", PullRequestNumber: 4, Created: true},
+			{Owner: "owner", Name: "no-pr", Provider: "local", Branch: "master", Created: true},
+		},
+	}
+
+	err := manifest.rollback(context.Background(), func(r Repository) (Provider, error) {
+		return provider, nil
+	}, false)
+	require.EqualError(t, err, "failed to rollback 1 out of 2 repositories")
+	assert.Empty(t, provider.pullRequests)
+}
+
+func TestRollbackExistingPullRequest(t *testing.T) {
+	t.Parallel()
+
+	// the pull request and its branch already existed before the run: the run only appended a commit to them
+	provider := &localProvider{
+		path:           initLocalRepository(t, map[string]string{"a.txt": "a1"}),
+		existingBranch: "octopilot-existing",
+	}
+	repo := Repository{Owner: "owner", Name: "existing", Params: map[string]string{}}
+	clonePath := t.TempDir()
+	strategy := &ResetStrategy{
+		Repository: repo,
+		RepoPath:   clonePath,
+		Updaters: []update.Updater{
+			&writeFileUpdater{file: "a.txt", content: "a2"},
+		},
+		Provider: provider,
+		Options: UpdateOptions{
+			Git: GitOptions{
+				StageAllChanged: true,
+				AuthorName:      "test",
+				AuthorEmail:     "test@example.com",
+				CommitterName:   "test",
+				CommitterEmail:  "test@example.com",
+				CommitTitle:     "update",
+			},
+			GitHub: GitHubOptions{
+				PullRequest: PullRequestOptions{Title: "update", Body: "update"},
+			},
+		},
+	}
+	updated, pr, err := strategy.Run(context.Background())
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	manifest := NewRollbackManifest("test-run")
+	manifest.record(repo, repo, provider, clonePath, pr)
+	require.Len(t, manifest.Entries, 1)
+	assert.False(t, manifest.Entries[0].Created)
+
+	err = manifest.rollback(context.Background(), func(r Repository) (Provider, error) {
+		return provider, nil
+	}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"update"}, provider.pullRequests)
+	originRepo, err := git.PlainOpen(provider.path)
+	require.NoError(t, err)
+	_, err = originRepo.Reference(plumbing.NewBranchReferenceName("octopilot-existing"), true)
+	assert.NoError(t, err)
+}
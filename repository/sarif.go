@@ -0,0 +1,391 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dailymotion-oss/octopilot/update"
+)
+
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// maxDiffCells limits the size of the table used to compute the changed lines of a file - beyond it, the changed lines are reported as a single region
+	maxDiffCells = 1 << 20
+
+	// sarifSourceRoot is the base URI of the locations of the results: the root of the repository
+	sarifSourceRoot = "SRCROOT"
+)
+
+// the type of an updater, from its string representation - such as "Sops[file=secrets.yaml,key=password]"
+var updaterTypeRegexp = regexp.MustCompile(`^([A-Za-z]+)\[`)
+
+// SARIFReport records the files changed by each updater during a run - with the changed lines - and writes them as a SARIF log,
+// so that the changes show up in the code scanning dashboards of the security tooling.
+// Each updated repository is a run of the log, and each updater type is a rule. The changed values are never recorded.
+// It is shared by all the repositories updated in the same run.
+type SARIFReport struct {
+	toolVersion string
+
+	mutex sync.Mutex
+	// the results of the updaters, by repository path - until the repository is updated
+	pending map[string][]sarifResult
+	runs    []sarifRun
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+	Properties         map[string]interface{}           `json:"properties,omitempty"`
+
+	repository string
+	baseBranch string
+}
+
+type sarifTool struct {
+	Driver sarifToolComponent `json:"driver"`
+}
+
+type sarifToolComponent struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+
+	ruleName string
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI         string        `json:"uri"`
+	URIBaseID   string        `json:"uriBaseId,omitempty"`
+	Description *sarifMessage `json:"description,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// NewSARIFReport returns a new empty report, for the given version of octopilot.
+func NewSARIFReport(toolVersion string) *SARIFReport {
+	return &SARIFReport{
+		toolVersion: toolVersion,
+		pending:     make(map[string][]sarifResult),
+	}
+}
+
+// Write writes the report to the given file, as a SARIF log. The runs are sorted by repository, so that the report is stable.
+func (s *SARIFReport) Write(path string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sort.SliceStable(s.runs, func(i, j int) bool {
+		if s.runs[i].repository != s.runs[j].repository {
+			return s.runs[i].repository < s.runs[j].repository
+		}
+		return s.runs[i].baseBranch < s.runs[j].baseBranch
+	})
+	log := sarifLog{
+		Schema:  sarifSchemaURI,
+		Version: sarifVersion,
+		Runs:    s.runs,
+	}
+	if log.Runs == nil {
+		log.Runs = []sarifRun{}
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SARIF report: %w", err)
+	}
+	if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write SARIF report %s: %w", path, err)
+	}
+	return nil
+}
+
+// reset forgets the results recorded for the repository cloned at the given path - before the updaters run again.
+// A nil report doesn't record anything.
+func (s *SARIFReport) reset(repoPath string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.pending, repoPath)
+}
+
+// recordUpdater records the given files changed by the given updater in the repository cloned at the given path.
+// A nil report doesn't record anything.
+func (s *SARIFReport) recordUpdater(repoPath string, updater update.Updater, fileChanges []fileChange) {
+	if s == nil {
+		return
+	}
+	var results []sarifResult
+	for _, change := range fileChanges {
+		results = append(results, changeResults(updater, change.file, change.before, change.after)...)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending[repoPath] = append(s.pending[repoPath], results...)
+}
+
+// publish adds the results recorded for the repository cloned at the given path as a run of the report - if the repository has been updated.
+// A nil report doesn't record anything.
+func (s *SARIFReport) publish(r Repository, repoPath string, options UpdateOptions, pr *PullRequest, repoUpdated bool) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	results := s.pending[repoPath]
+	delete(s.pending, repoPath)
+	if !repoUpdated || len(results) == 0 {
+		return
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifToolComponent{
+				Name:           "octopilot",
+				Version:        s.toolVersion,
+				InformationURI: "https://github.com/dailymotion-oss/octopilot",
+			},
+		},
+		OriginalURIBaseIDs: map[string]sarifArtifactLocation{
+			sarifSourceRoot: {
+				URI:         sourceRootURI(repoPath),
+				Description: &sarifMessage{Text: fmt.Sprintf("The root of the clone of the %s repository", r.FullName())},
+			},
+		},
+		Results: results,
+		Properties: map[string]interface{}{
+			"repository": r.FullName(),
+			"baseBranch": options.GitHub.PullRequest.BaseBranch,
+			"dryRun":     options.DryRun,
+		},
+		repository: r.FullName(),
+		baseBranch: options.GitHub.PullRequest.BaseBranch,
+	}
+	if pr != nil && len(pr.URL) > 0 {
+		run.Properties["pullRequest"] = pr.URL
+	}
+	rules := make(map[string]bool)
+	for _, result := range results {
+		if rules[result.RuleID] {
+			continue
+		}
+		rules[result.RuleID] = true
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               result.RuleID,
+			Name:             result.ruleName,
+			ShortDescription: sarifMessage{Text: fmt.Sprintf("File changed by the %s updater", result.ruleName)},
+		})
+	}
+	s.runs = append(s.runs, run)
+}
+
+// sourceRootURI returns the absolute file URI of the given repository path - with a trailing slash, as required for the base URIs of the locations
+func sourceRootURI(repoPath string) string {
+	if absPath, err := filepath.Abs(repoPath); err == nil {
+		repoPath = absPath
+	}
+	path := filepath.ToSlash(repoPath)
+	if !strings.HasPrefix(path, "/") {
+		// windows paths, such as C:/repo
+		path = "/" + path
+	}
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// changeResults returns the results for a file changed by the given updater: one per changed region - or a single one if the lines can't be computed
+func changeResults(updater update.Updater, file string, oldContent, newContent fileContent) []sarifResult {
+	ruleName := updaterType(updater)
+	var action string
+	switch {
+	case !oldContent.exists:
+		action = "created"
+	case !newContent.exists:
+		action = "deleted"
+	default:
+		action = "changed"
+	}
+	newResult := func(region *sarifRegion) sarifResult {
+		return sarifResult{
+			RuleID:  "octopilot/" + strings.ToLower(ruleName),
+			Level:   "note",
+			Message: sarifMessage{Text: fmt.Sprintf("File %s by %s", action, updater.String())},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(file), URIBaseID: sarifSourceRoot},
+					Region:           region,
+				},
+			}},
+			ruleName: ruleName,
+		}
+	}
+
+	// no lines for a deleted - or binary - file
+	if !newContent.exists || strings.ContainsRune(oldContent.data, 0) || strings.ContainsRune(newContent.data, 0) {
+		return []sarifResult{newResult(nil)}
+	}
+	regions := changedRegions(oldContent.data, newContent.data)
+	if len(regions) == 0 {
+		return []sarifResult{newResult(nil)}
+	}
+	results := make([]sarifResult, 0, len(regions))
+	for i := range regions {
+		results = append(results, newResult(&regions[i]))
+	}
+	return results
+}
+
+// changedRegions returns the regions of the new content with changed lines - a region with only deleted lines is the line following them.
+// The lines are compared with a longest common subsequence, once the common first and last lines are trimmed.
+func changedRegions(oldContent, newContent string) []sarifRegion {
+	oldLines, newLines := splitLines(oldContent), splitLines(newContent)
+	lastLine := len(newLines)
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix && oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	oldLines, newLines = oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix]
+	if len(oldLines) == 0 && len(newLines) == 0 {
+		return nil
+	}
+
+	var regions []sarifRegion
+	if (len(oldLines)+1)*(len(newLines)+1) > maxDiffCells {
+		regions = []sarifRegion{{StartLine: prefix + 1, EndLine: prefix + len(newLines)}}
+		if len(newLines) == 0 {
+			regions[0].EndLine = prefix + 1
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+		lcs := make([][]int32, len(oldLines)+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, len(newLines)+1)
+		}
+		for i := len(oldLines) - 1; i >= 0; i-- {
+			for j := len(newLines) - 1; j >= 0; j-- {
+				switch {
+				case oldLines[i] == newLines[j]:
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				case lcs[i+1][j] >= lcs[i][j+1]:
+					lcs[i][j] = lcs[i+1][j]
+				default:
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+
+		var i, j int
+		inRegion := false
+		for i < len(oldLines) || j < len(newLines) {
+			switch {
+			case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+				inRegion = false
+				i++
+				j++
+			case j < len(newLines) && (i == len(oldLines) || lcs[i][j+1] >= lcs[i+1][j]):
+				// inserted line
+				line := prefix + j + 1
+				if !inRegion {
+					regions = append(regions, sarifRegion{StartLine: line})
+					inRegion = true
+				}
+				regions[len(regions)-1].EndLine = line
+				j++
+			default:
+				// deleted line
+				if !inRegion {
+					line := prefix + j + 1
+					regions = append(regions, sarifRegion{StartLine: line, EndLine: line})
+					inRegion = true
+				}
+				i++
+			}
+		}
+	}
+
+	// the lines deleted at the end of the file are reported on its last line
+	if lastLine == 0 {
+		lastLine = 1
+	}
+	for i := range regions {
+		if regions[i].StartLine > lastLine {
+			regions[i].StartLine = lastLine
+		}
+		if regions[i].EndLine > lastLine {
+			regions[i].EndLine = lastLine
+		}
+	}
+	return regions
+}
+
+// splitLines returns the lines of the given text - with their line endings
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// updaterType returns the type of the given updater - such as "Sops" - from its string representation.
+// For the wrapped updaters - such as "YAML[...] | Format[...]" - it is the type of the inner updater.
+func updaterType(updater update.Updater) string {
+	matches := updaterTypeRegexp.FindStringSubmatch(updater.String())
+	if len(matches) < 2 {
+		return "Updater"
+	}
+	return matches[1]
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/internal/jsonschema"
+	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSARIFReport(t *testing.T) {
+	t.Parallel()
+
+	schemaData, err := os.ReadFile(filepath.Join("testdata", "sarif", "sarif-schema-2.1.0.json"))
+	require.NoError(t, err)
+	schema, err := jsonschema.Parse(schemaData)
+	require.NoError(t, err)
+
+	type result struct {
+		RuleID    string
+		URI       string
+		StartLine int
+		EndLine   int
+		Message   string
+	}
+	tests := []struct {
+		name            string
+		minChangedFiles int
+		expectedRules   []string
+		expectedResults []result
+	}{
+		{
+			name:          "changes of multiple updaters",
+			expectedRules: []string{"octopilot/writefile"},
+			expectedResults: []result{
+				{RuleID: "octopilot/writefile", URI: "config.yaml", StartLine: 2, EndLine: 2, Message: "File changed by WriteFile[file=config.yaml]"},
+				{RuleID: "octopilot/writefile", URI: "new.txt", StartLine: 1, EndLine: 2, Message: "File created by WriteFile[file=new.txt]"},
+				{RuleID: "octopilot/writefile", URI: "config.yaml", StartLine: 3, EndLine: 3, Message: "File changed by WriteFile[file=config.yaml]"},
+			},
+		},
+		{
+			name:            "repository not updated",
+			minChangedFiles: 10,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			provider := &localProvider{
+				path: initLocalRepository(t, map[string]string{"config.yaml": "name: app\nversion: 1.0.0\nreplicas: 2\n"}),
+			}
+			report := NewSARIFReport("1.2.3")
+			repo := Repository{Owner: "owner", Name: "repo", Params: map[string]string{}}
+			options := UpdateOptions{
+				Strategy:        "recreate",
+				MinChangedFiles: test.minChangedFiles,
+				SARIFReport:     report,
+				Git: GitOptions{
+					CloneDir:        t.TempDir(),
+					StageAllChanged: true,
+					StagePatterns:   []string{"**/*"},
+					AuthorName:      "test",
+					AuthorEmail:     "test@example.com",
+					CommitterName:   "test",
+					CommitterEmail:  "test@example.com",
+					CommitTitle:     "update",
+					BranchPrefix:    "octopilot-",
+				},
+				GitHub: GitHubOptions{
+					PullRequest: PullRequestOptions{Title: "update", Body: "update", BaseBranch: "master"},
+				},
+			}
+
+			_, err := repo.update(context.Background(), []update.Updater{
+				&writeFileUpdater{file: "config.yaml", content: "name: app\nversion: 1.1.0\nreplicas: 2\n"},
+				&writeFileUpdater{file: "new.txt", content: "a\nb\n"},
+				&writeFileUpdater{file: "config.yaml", content: "name: app\nversion: 1.1.0\nreplicas: 3\n"},
+			}, options, provider)
+			require.NoError(t, err)
+
+			reportPath := filepath.Join(t.TempDir(), "octopilot.sarif")
+			require.NoError(t, report.Write(reportPath))
+			data, err := os.ReadFile(reportPath)
+			require.NoError(t, err)
+			validationErrors, err := schema.Validate(data)
+			require.NoError(t, err)
+			assert.Empty(t, validationErrors)
+			assert.NotContains(t, string(data), "1.1.0", "the changed values must not be recorded")
+
+			var log sarifLog
+			require.NoError(t, json.Unmarshal(data, &log))
+			assert.Equal(t, "2.1.0", log.Version)
+			if len(test.expectedResults) == 0 {
+				assert.Empty(t, log.Runs)
+				return
+			}
+			require.Len(t, log.Runs, 1)
+			run := log.Runs[0]
+			assert.Equal(t, "octopilot", run.Tool.Driver.Name)
+			assert.Equal(t, "1.2.3", run.Tool.Driver.Version)
+			assert.Equal(t, "owner/repo", run.Properties["repository"])
+			assert.Equal(t, "master", run.Properties["baseBranch"])
+			require.Contains(t, run.OriginalURIBaseIDs, "SRCROOT")
+			assert.Regexp(t, `^file:///.+/$`, run.OriginalURIBaseIDs["SRCROOT"].URI)
+			var rules []string
+			for _, rule := range run.Tool.Driver.Rules {
+				rules = append(rules, rule.ID)
+			}
+			assert.Equal(t, test.expectedRules, rules)
+			var results []result
+			for _, r := range run.Results {
+				require.Len(t, r.Locations, 1)
+				location := r.Locations[0].PhysicalLocation
+				require.NotNil(t, location.Region)
+				assert.Equal(t, "SRCROOT", location.ArtifactLocation.URIBaseID)
+				results = append(results, result{
+					RuleID:    r.RuleID,
+					URI:       location.ArtifactLocation.URI,
+					StartLine: location.Region.StartLine,
+					EndLine:   location.Region.EndLine,
+					Message:   r.Message.Text,
+				})
+			}
+			assert.Equal(t, test.expectedResults, results)
+		})
+	}
+}
+
+func TestChangedRegions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected []sarifRegion
+	}{
+		{
+			name:     "changed lines",
+			old:      "a\nb\nc\nd\ne\n",
+			new:      "a\nB\nc\nD\nE\n",
+			expected: []sarifRegion{{StartLine: 2, EndLine: 2}, {StartLine: 4, EndLine: 5}},
+		},
+		{
+			name:     "inserted lines",
+			old:      "a\nb\n",
+			new:      "a\nx\ny\nb\n",
+			expected: []sarifRegion{{StartLine: 2, EndLine: 3}},
+		},
+		{
+			name:     "deleted lines",
+			old:      "a\nb\nc\n",
+			new:      "a\nc\n",
+			expected: []sarifRegion{{StartLine: 2, EndLine: 2}},
+		},
+		{
+			name:     "lines deleted at the end of the file",
+			old:      "a\nb\nc\n",
+			new:      "a\n",
+			expected: []sarifRegion{{StartLine: 1, EndLine: 1}},
+		},
+		{
+			name:     "new file without trailing newline",
+			old:      "",
+			new:      "a\nb",
+			expected: []sarifRegion{{StartLine: 1, EndLine: 2}},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, changedRegions(test.old, test.new))
+		})
+	}
+}
@@ -3,9 +3,10 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/dailymotion-oss/octopilot/update"
-	"github.com/google/go-github/v36/github"
+	"github.com/go-git/go-git/v5"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,23 +17,24 @@ type AppendStrategy struct {
 	RepoPath   string
 	Updaters   []update.Updater
 	Options    UpdateOptions
+	Provider   Provider
 }
 
 // Run executes the strategy, and returns true if the repo was updated, and the created/updated PR.
-func (s *AppendStrategy) Run(ctx context.Context) (bool, *github.PullRequest, error) {
-	gitRepo, err := cloneGitRepository(ctx, s.Repository, s.RepoPath, s.Options.GitHub)
+func (s *AppendStrategy) Run(ctx context.Context) (bool, *PullRequest, error) {
+	gitRepo, err := cloneGitRepository(ctx, s.Repository, s.RepoPath, s.Provider)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to clone repository %s: %w", s.Repository.FullName(), err)
 	}
 
-	existingPR, err := s.Repository.findMatchingPullRequest(ctx, s.Options.GitHub)
+	existingPR, err := s.Provider.findMatchingPullRequest(ctx, s.Repository, s.Options.GitHub.PullRequest)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to find matching pull request for repository %s: %w", s.Repository.FullName(), err)
 	}
 
 	var branchName string
 	if existingPR != nil {
-		branchName = existingPR.Head.GetRef()
+		branchName = existingPR.HeadBranch
 		err = switchBranch(ctx, gitRepo, switchBranchOptions{
 			BranchName: branchName,
 		})
@@ -47,7 +49,7 @@ func (s *AppendStrategy) Run(ctx context.Context) (bool, *github.PullRequest, er
 		return false, nil, fmt.Errorf("failed to switch to branch %s: %w", branchName, err)
 	}
 
-	repoUpdated, err := s.Repository.runUpdaters(ctx, s.Updaters, s.RepoPath)
+	repoUpdated, err := s.Repository.runUpdaters(ctx, s.Updaters, s.RepoPath, s.Options)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to update repository %s: %w", s.Repository.FullName(), err)
 	}
@@ -55,6 +57,14 @@ func (s *AppendStrategy) Run(ctx context.Context) (bool, *github.PullRequest, er
 		return false, nil, nil
 	}
 
+	enoughChangedFiles, err := s.Repository.hasEnoughChangedFiles(gitRepo, s.Options)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to check changed files of repository %s: %w", s.Repository.FullName(), err)
+	}
+	if !enoughChangedFiles {
+		return false, nil, nil
+	}
+
 	if err = s.Options.Git.setDefaultValues(s.Updaters, templateExecutorFor(s.Options, s.Repository, s.RepoPath)); err != nil {
 		return false, nil, fmt.Errorf("failed to set default git values: %w", err)
 	}
@@ -76,23 +86,86 @@ func (s *AppendStrategy) Run(ctx context.Context) (bool, *github.PullRequest, er
 		return false, nil, nil
 	}
 
-	err = pushChanges(ctx, gitRepo, pushOptions{
-		GitHubOpts: s.Options.GitHub,
-		BranchName: branchName,
-	})
-	if err != nil {
-		return false, nil, fmt.Errorf("failed to push changes to git repository %s: %w", s.Repository.FullName(), err)
+	retryInterval := s.Options.Git.PushRetryInterval
+	for retry := 0; ; retry++ {
+		err = pushChanges(ctx, gitRepo, pushOptions{
+			Provider:   s.Provider,
+			BranchName: branchName,
+		})
+		if err == nil {
+			break
+		}
+		if !isNonFastForwardError(err) || retry >= s.Options.Git.PushRetryCount {
+			return false, nil, fmt.Errorf("failed to push changes to git repository %s: %w", s.Repository.FullName(), err)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"repository": s.Repository.FullName(),
+			"branch":     branchName,
+			"retry":      retry + 1,
+			"interval":   retryInterval,
+		}).WithError(err).Warning("Push rejected because the remote branch has been updated, applying the changes again on top of it")
+		select {
+		case <-ctx.Done():
+			return false, nil, ctx.Err()
+		case <-time.After(retryInterval):
+		}
+		retryInterval *= 2
+
+		changesCommitted, err = s.reapplyChanges(ctx, gitRepo, branchName)
+		if err != nil {
+			return false, nil, err
+		}
+		if !changesCommitted {
+			logrus.WithField("repository", s.Repository.FullName()).Debug("No changes - or not enough changed files - on top of the remote branch, nothing to push")
+			return false, nil, nil
+		}
 	}
 
-	var pr *github.PullRequest
+	var pr *PullRequest
 	if existingPR != nil {
-		pr, err = s.Repository.updatePullRequest(ctx, s.Options.GitHub, existingPR)
+		pr, err = s.Provider.updatePullRequest(ctx, s.Repository, s.Options.GitHub.PullRequest, existingPR)
 	} else {
-		pr, err = s.Repository.createPullRequest(ctx, s.Options.GitHub, branchName)
+		if err = s.Options.GitHub.PullRequest.CreatePacer.wait(ctx); err != nil {
+			return false, nil, fmt.Errorf("failed to wait before creating Pull Request: %w", err)
+		}
+		pr, err = s.Provider.createPullRequest(ctx, s.Repository, s.Options.GitHub.PullRequest, branchName)
 	}
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to create or update Pull Request: %w", err)
 	}
+	pr.Created = existingPR == nil
 
 	return true, pr, nil
 }
+
+// reapplyChanges resets the local branch to the remote branch - which has been updated concurrently - and runs the updaters again to commit the changes on top of it.
+func (s *AppendStrategy) reapplyChanges(ctx context.Context, gitRepo *git.Repository, branchName string) (bool, error) {
+	err := resetToRemoteBranch(ctx, gitRepo, s.Provider, branchName)
+	if err != nil {
+		return false, fmt.Errorf("failed to reset branch %s of repository %s: %w", branchName, s.Repository.FullName(), err)
+	}
+
+	repoUpdated, err := s.Repository.runUpdaters(ctx, s.Updaters, s.RepoPath, s.Options)
+	if err != nil {
+		return false, fmt.Errorf("failed to update repository %s: %w", s.Repository.FullName(), err)
+	}
+	if !repoUpdated {
+		return false, nil
+	}
+
+	// on top of the updated remote branch, the updaters may change less files than before
+	enoughChangedFiles, err := s.Repository.hasEnoughChangedFiles(gitRepo, s.Options)
+	if err != nil {
+		return false, fmt.Errorf("failed to check changed files of repository %s: %w", s.Repository.FullName(), err)
+	}
+	if !enoughChangedFiles {
+		return false, nil
+	}
+
+	changesCommitted, err := commitChanges(ctx, gitRepo, s.Options)
+	if err != nil {
+		return false, fmt.Errorf("failed to commit changes to git repository %s: %w", s.Repository.FullName(), err)
+	}
+	return changesCommitted, nil
+}
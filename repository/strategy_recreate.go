@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/dailymotion-oss/octopilot/update"
-	"github.com/google/go-github/v36/github"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,11 +14,12 @@ type RecreateStrategy struct {
 	RepoPath   string
 	Updaters   []update.Updater
 	Options    UpdateOptions
+	Provider   Provider
 }
 
 // Run executes the strategy, and returns true if the repo was updated, and the created PR.
-func (s *RecreateStrategy) Run(ctx context.Context) (bool, *github.PullRequest, error) {
-	gitRepo, err := cloneGitRepository(ctx, s.Repository, s.RepoPath, s.Options.GitHub)
+func (s *RecreateStrategy) Run(ctx context.Context) (bool, *PullRequest, error) {
+	gitRepo, err := cloneGitRepository(ctx, s.Repository, s.RepoPath, s.Provider)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to clone repository %s: %w", s.Repository.FullName(), err)
 	}
@@ -33,7 +33,7 @@ func (s *RecreateStrategy) Run(ctx context.Context) (bool, *github.PullRequest,
 		return false, nil, fmt.Errorf("failed to switch to branch %s: %w", branchName, err)
 	}
 
-	repoUpdated, err := s.Repository.runUpdaters(ctx, s.Updaters, s.RepoPath)
+	repoUpdated, err := s.Repository.runUpdaters(ctx, s.Updaters, s.RepoPath, s.Options)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to update repository %s: %w", s.Repository.FullName(), err)
 	}
@@ -41,6 +41,14 @@ func (s *RecreateStrategy) Run(ctx context.Context) (bool, *github.PullRequest,
 		return false, nil, nil
 	}
 
+	enoughChangedFiles, err := s.Repository.hasEnoughChangedFiles(gitRepo, s.Options)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to check changed files of repository %s: %w", s.Repository.FullName(), err)
+	}
+	if !enoughChangedFiles {
+		return false, nil, nil
+	}
+
 	if err = s.Options.Git.setDefaultValues(s.Updaters, templateExecutorFor(s.Options, s.Repository, s.RepoPath)); err != nil {
 		return false, nil, fmt.Errorf("failed to set default git values: %w", err)
 	}
@@ -62,17 +70,22 @@ func (s *RecreateStrategy) Run(ctx context.Context) (bool, *github.PullRequest,
 	}
 
 	err = pushChanges(ctx, gitRepo, pushOptions{
-		GitHubOpts: s.Options.GitHub,
+		Provider:   s.Provider,
 		BranchName: branchName,
 	})
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to push changes to git repository %s: %w", s.Repository.FullName(), err)
 	}
 
-	pr, err := s.Repository.createPullRequest(ctx, s.Options.GitHub, branchName)
+	if err = s.Options.GitHub.PullRequest.CreatePacer.wait(ctx); err != nil {
+		return false, nil, fmt.Errorf("failed to wait before creating Pull Request: %w", err)
+	}
+
+	pr, err := s.Provider.createPullRequest(ctx, s.Repository, s.Options.GitHub.PullRequest, branchName)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to create Pull Request: %w", err)
 	}
+	pr.Created = true
 
 	return true, pr, nil
 }
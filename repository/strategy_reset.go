@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/dailymotion-oss/octopilot/update"
-	"github.com/google/go-github/v36/github"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,23 +15,24 @@ type ResetStrategy struct {
 	RepoPath   string
 	Updaters   []update.Updater
 	Options    UpdateOptions
+	Provider   Provider
 }
 
 // Run executes the strategy, and returns true if the repo was updated, and the created/updated PR.
-func (s *ResetStrategy) Run(ctx context.Context) (bool, *github.PullRequest, error) {
-	gitRepo, err := cloneGitRepository(ctx, s.Repository, s.RepoPath, s.Options.GitHub)
+func (s *ResetStrategy) Run(ctx context.Context) (bool, *PullRequest, error) {
+	gitRepo, err := cloneGitRepository(ctx, s.Repository, s.RepoPath, s.Provider)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to clone repository %s: %w", s.Repository.FullName(), err)
 	}
 
-	existingPR, err := s.Repository.findMatchingPullRequest(ctx, s.Options.GitHub)
+	existingPR, err := s.Provider.findMatchingPullRequest(ctx, s.Repository, s.Options.GitHub.PullRequest)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to find matching pull request for repository %s: %w", s.Repository.FullName(), err)
 	}
 
 	var branchName string
 	if existingPR != nil {
-		branchName = existingPR.Head.GetRef()
+		branchName = existingPR.HeadBranch
 	} else {
 		branchName = s.Repository.newBranchName(s.Options.Git.BranchPrefix)
 	}
@@ -45,7 +45,7 @@ func (s *ResetStrategy) Run(ctx context.Context) (bool, *github.PullRequest, err
 		return false, nil, fmt.Errorf("failed to switch to branch %s: %w", branchName, err)
 	}
 
-	repoUpdated, err := s.Repository.runUpdaters(ctx, s.Updaters, s.RepoPath)
+	repoUpdated, err := s.Repository.runUpdaters(ctx, s.Updaters, s.RepoPath, s.Options)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to update repository %s: %w", s.Repository.FullName(), err)
 	}
@@ -53,6 +53,14 @@ func (s *ResetStrategy) Run(ctx context.Context) (bool, *github.PullRequest, err
 		return false, nil, nil
 	}
 
+	enoughChangedFiles, err := s.Repository.hasEnoughChangedFiles(gitRepo, s.Options)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to check changed files of repository %s: %w", s.Repository.FullName(), err)
+	}
+	if !enoughChangedFiles {
+		return false, nil, nil
+	}
+
 	if err = s.Options.Git.setDefaultValues(s.Updaters, templateExecutorFor(s.Options, s.Repository, s.RepoPath)); err != nil {
 		return false, nil, fmt.Errorf("failed to set default git values: %w", err)
 	}
@@ -75,7 +83,7 @@ func (s *ResetStrategy) Run(ctx context.Context) (bool, *github.PullRequest, err
 	}
 
 	err = pushChanges(ctx, gitRepo, pushOptions{
-		GitHubOpts: s.Options.GitHub,
+		Provider:   s.Provider,
 		BranchName: branchName,
 		ForcePush:  true,
 	})
@@ -83,15 +91,19 @@ func (s *ResetStrategy) Run(ctx context.Context) (bool, *github.PullRequest, err
 		return false, nil, fmt.Errorf("failed to push changes to git repository %s: %w", s.Repository.FullName(), err)
 	}
 
-	var pr *github.PullRequest
+	var pr *PullRequest
 	if existingPR != nil {
-		pr, err = s.Repository.updatePullRequest(ctx, s.Options.GitHub, existingPR)
+		pr, err = s.Provider.updatePullRequest(ctx, s.Repository, s.Options.GitHub.PullRequest, existingPR)
 	} else {
-		pr, err = s.Repository.createPullRequest(ctx, s.Options.GitHub, branchName)
+		if err = s.Options.GitHub.PullRequest.CreatePacer.wait(ctx); err != nil {
+			return false, nil, fmt.Errorf("failed to wait before creating Pull Request: %w", err)
+		}
+		pr, err = s.Provider.createPullRequest(ctx, s.Repository, s.Options.GitHub.PullRequest, branchName)
 	}
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to create or update Pull Request: %w", err)
 	}
+	pr.Created = existingPR == nil
 
 	return true, pr, nil
 }
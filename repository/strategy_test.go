@@ -0,0 +1,438 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dailymotion-oss/octopilot/update"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localProvider is a test provider for a local git repository, which records the pull requests operations
+type localProvider struct {
+	path           string
+	existingBranch string
+	pullRequests   []string
+	createdAt      []time.Time
+	// createdFrom records the "base <- head" branches of the created pull requests
+	createdFrom []string
+}
+
+func (p *localProvider) name() string {
+	return "local"
+}
+
+func (p *localProvider) gitURL(_ Repository) (string, error) {
+	return p.path, nil
+}
+
+func (p *localProvider) gitAuth(_ context.Context) (*http.BasicAuth, error) {
+	return nil, nil
+}
+
+func (p *localProvider) findMatchingPullRequest(_ context.Context, _ Repository, _ PullRequestOptions) (*PullRequest, error) {
+	if len(p.existingBranch) == 0 {
+		return nil, nil
+	}
+	return &PullRequest{HeadBranch: p.existingBranch}, nil
+}
+
+func (p *localProvider) createPullRequest(_ context.Context, _ Repository, options PullRequestOptions, branchName string) (*PullRequest, error) {
+	p.pullRequests = append(p.pullRequests, "create")
+	p.createdFrom = append(p.createdFrom, fmt.Sprintf("%s <- %s", options.BaseBranch, branchName))
+	p.createdAt = append(p.createdAt, time.Now())
+	return &PullRequest{Number: len(p.pullRequests), HeadBranch: branchName}, nil
+}
+
+func (p *localProvider) updatePullRequest(_ context.Context, _ Repository, _ PullRequestOptions, pr *PullRequest) (*PullRequest, error) {
+	p.pullRequests = append(p.pullRequests, "update")
+	return pr, nil
+}
+
+func (p *localProvider) mergePullRequest(_ context.Context, _ Repository, _ PullRequestOptions, _ *PullRequest) error {
+	return errors.New("not supported")
+}
+
+func (p *localProvider) closePullRequest(_ context.Context, _ Repository, pr *PullRequest) error {
+	p.pullRequests = append(p.pullRequests, fmt.Sprintf("close #%d", pr.Number))
+	return nil
+}
+
+func (p *localProvider) findMergedPullRequests(_ context.Context, _ Repository, _ PullRequestOptions) ([]*PullRequest, error) {
+	return nil, nil
+}
+
+func (p *localProvider) createRelease(_ context.Context, _ Repository, _ PullRequestOptions, _ Release) (bool, error) {
+	return false, errors.New("not supported")
+}
+
+func (p *localProvider) deleteBranch(_ context.Context, _ Repository, branchName string) error {
+	gitRepo, err := git.PlainOpen(p.path)
+	if err != nil {
+		return err
+	}
+	return gitRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName(branchName))
+}
+
+// initLocalRepository creates a git repository with a first commit containing the given files
+func initLocalRepository(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	repoPath := t.TempDir()
+	gitRepo, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+	workTree, err := gitRepo.Worktree()
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644))
+	}
+	_, err = workTree.Add(".")
+	require.NoError(t, err)
+	_, err = workTree.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return repoPath
+}
+
+func TestStrategyMinChangedFiles(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		updaters             []update.Updater
+		minChangedFiles      int
+		revert               bool
+		expectedUpdated      bool
+		expectedPullRequests []string
+		expectedClean        bool
+	}{
+		{
+			name: "not enough changed files",
+			updaters: []update.Updater{
+				&writeFileUpdater{file: "a.txt", content: "a2"},
+			},
+			minChangedFiles: 2,
+			expectedClean:   false,
+		},
+		{
+			name: "not enough changed files with revert",
+			updaters: []update.Updater{
+				&writeFileUpdater{file: "a.txt", content: "a2"},
+				&writeFileUpdater{file: "new.txt", content: "new"},
+			},
+			minChangedFiles: 3,
+			revert:          true,
+			expectedClean:   true,
+		},
+		{
+			name: "enough changed files",
+			updaters: []update.Updater{
+				&writeFileUpdater{file: "a.txt", content: "a2"},
+				&writeFileUpdater{file: "b.txt", content: "b2"},
+			},
+			minChangedFiles:      2,
+			expectedUpdated:      true,
+			expectedPullRequests: []string{"create"},
+			expectedClean:        true,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			provider := &localProvider{
+				path: initLocalRepository(t, map[string]string{"a.txt": "a1", "b.txt": "b1"}),
+			}
+			repo := Repository{Owner: "owner", Name: "repo", Params: map[string]string{}}
+			clonePath := t.TempDir()
+			strategy := &RecreateStrategy{
+				Repository: repo,
+				RepoPath:   clonePath,
+				Updaters:   test.updaters,
+				Provider:   provider,
+				Options: UpdateOptions{
+					MinChangedFiles:            test.minChangedFiles,
+					RevertBelowMinChangedFiles: test.revert,
+					Git: GitOptions{
+						StageAllChanged: true,
+						StagePatterns:   []string{"*.txt"},
+						AuthorName:      "test",
+						AuthorEmail:     "test@example.com",
+						CommitterName:   "test",
+						CommitterEmail:  "test@example.com",
+						CommitTitle:     "update",
+						BranchPrefix:    "octopilot-test",
+					},
+					GitHub: GitHubOptions{
+						PullRequest: PullRequestOptions{Title: "update", Body: "update"},
+					},
+				},
+			}
+
+			updated, pr, err := strategy.Run(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedUpdated, updated)
+			assert.Equal(t, test.expectedPullRequests, provider.pullRequests)
+			if len(test.expectedPullRequests) == 0 {
+				assert.Nil(t, pr)
+			}
+
+			gitRepo, err := git.PlainOpen(clonePath)
+			require.NoError(t, err)
+			workTree, err := gitRepo.Worktree()
+			require.NoError(t, err)
+			status, err := workTree.Status()
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedClean, status.IsClean(), status.String())
+		})
+	}
+}
+
+// racingUpdater is a test updater that writes files - and, the first time it runs, simulates a concurrent update of the remote branch with the racing files
+type racingUpdater struct {
+	originPath  string
+	branchName  string
+	files       map[string]string
+	racingFiles map[string]string
+	runs        int
+}
+
+func (u *racingUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	u.runs++
+	if u.runs == 1 {
+		racingPath, err := os.MkdirTemp("", "octopilot-racing")
+		if err != nil {
+			return false, err
+		}
+		defer os.RemoveAll(racingPath)
+
+		racingRepo, err := git.PlainCloneContext(ctx, racingPath, false, &git.CloneOptions{
+			URL:           u.originPath,
+			ReferenceName: plumbing.NewBranchReferenceName(u.branchName),
+		})
+		if err != nil {
+			return false, err
+		}
+		workTree, err := racingRepo.Worktree()
+		if err != nil {
+			return false, err
+		}
+		for file, content := range u.racingFiles {
+			if err = os.WriteFile(filepath.Join(racingPath, file), []byte(content), 0644); err != nil {
+				return false, err
+			}
+			if _, err = workTree.Add(file); err != nil {
+				return false, err
+			}
+		}
+		_, err = workTree.Commit("racing commit", &git.CommitOptions{
+			Author: &object.Signature{Name: "someone else", Email: "someone@example.com", When: time.Now()},
+		})
+		if err != nil {
+			return false, err
+		}
+		if err = racingRepo.PushContext(ctx, &git.PushOptions{}); err != nil {
+			return false, err
+		}
+	}
+	for file, content := range u.files {
+		if err := os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (u *racingUpdater) Message() (string, string) {
+	return "Update files", ""
+}
+
+func (u *racingUpdater) String() string {
+	return "Racing"
+}
+
+func TestAppendStrategyPushRetry(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		files                map[string]string
+		racingFiles          map[string]string
+		minChangedFiles      int
+		expectedUpdated      bool
+		expectedPullRequests []string
+		expectedHeadMessage  string
+	}{
+		{
+			name:                 "changes applied again on top of the remote branch",
+			files:                map[string]string{"version.txt": "v2"},
+			racingFiles:          map[string]string{"racing.txt": "racing"},
+			expectedUpdated:      true,
+			expectedPullRequests: []string{"update"},
+			expectedHeadMessage:  "update\n\nupdate version",
+		},
+		{
+			name:                "not enough changed files on top of the remote branch",
+			files:               map[string]string{"version.txt": "v2", "other.txt": "o2"},
+			racingFiles:         map[string]string{"other.txt": "o2"},
+			minChangedFiles:     2,
+			expectedHeadMessage: "racing commit",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			const branchName = "octopilot-existing"
+			originPath := initLocalRepository(t, map[string]string{"version.txt": "v1", "other.txt": "o1"})
+			originRepo, err := git.PlainOpen(originPath)
+			require.NoError(t, err)
+			head, err := originRepo.Head()
+			require.NoError(t, err)
+			require.NoError(t, originRepo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branchName), head.Hash())))
+
+			provider := &localProvider{
+				path:           originPath,
+				existingBranch: branchName,
+			}
+			updater := &racingUpdater{
+				originPath:  originPath,
+				branchName:  branchName,
+				files:       test.files,
+				racingFiles: test.racingFiles,
+			}
+			strategy := &AppendStrategy{
+				Repository: Repository{Owner: "owner", Name: "repo", Params: map[string]string{}},
+				RepoPath:   t.TempDir(),
+				Updaters:   []update.Updater{updater},
+				Provider:   provider,
+				Options: UpdateOptions{
+					MinChangedFiles: test.minChangedFiles,
+					Git: GitOptions{
+						StageAllChanged:   true,
+						AuthorName:        "test",
+						AuthorEmail:       "test@example.com",
+						CommitterName:     "test",
+						CommitterEmail:    "test@example.com",
+						CommitTitle:       "update",
+						CommitBody:        "update version",
+						PushRetryCount:    2,
+						PushRetryInterval: time.Millisecond,
+					},
+					GitHub: GitHubOptions{
+						PullRequest: PullRequestOptions{Title: "update", Body: "update"},
+					},
+				},
+			}
+
+			updated, pr, err := strategy.Run(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedUpdated, updated)
+			assert.Equal(t, test.expectedPullRequests, provider.pullRequests)
+			assert.Equal(t, 2, updater.runs, "the updater should run again after the push rejection")
+			if test.expectedUpdated {
+				assert.Equal(t, branchName, pr.HeadBranch)
+			} else {
+				assert.Nil(t, pr)
+			}
+
+			branchRef, err := originRepo.Reference(plumbing.NewBranchReferenceName(branchName), true)
+			require.NoError(t, err)
+			commit, err := originRepo.CommitObject(branchRef.Hash())
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedHeadMessage, commit.Message)
+			if test.expectedUpdated {
+				parent, err := commit.Parent(0)
+				require.NoError(t, err)
+				assert.Equal(t, "racing commit", parent.Message)
+			}
+		})
+	}
+}
+
+func TestStrategyCreatePacer(t *testing.T) {
+	t.Parallel()
+
+	const (
+		reposCount = 4
+		delay      = 100 * time.Millisecond
+	)
+	pacer := NewCreationPacer(delay, 20*time.Millisecond)
+	providers := make([]*localProvider, reposCount)
+	var wg sync.WaitGroup
+	for i := range providers {
+		providers[i] = &localProvider{
+			path: initLocalRepository(t, map[string]string{"a.txt": "a1"}),
+		}
+		strategy := &RecreateStrategy{
+			Repository: Repository{Owner: "owner", Name: fmt.Sprintf("repo-%d", i), Params: map[string]string{}},
+			RepoPath:   t.TempDir(),
+			Updaters: []update.Updater{
+				&writeFileUpdater{file: "a.txt", content: "a2"},
+			},
+			Provider: providers[i],
+			Options: UpdateOptions{
+				Git: GitOptions{
+					StageAllChanged: true,
+					AuthorName:      "test",
+					AuthorEmail:     "test@example.com",
+					CommitterName:   "test",
+					CommitterEmail:  "test@example.com",
+					CommitTitle:     "update",
+					BranchPrefix:    "octopilot-test",
+				},
+				GitHub: GitHubOptions{
+					PullRequest: PullRequestOptions{Title: "update", Body: "update", CreatePacer: pacer},
+				},
+			},
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updated, _, err := strategy.Run(context.Background())
+			assert.NoError(t, err)
+			assert.True(t, updated)
+		}()
+	}
+	wg.Wait()
+
+	var createdAt []time.Time
+	for _, provider := range providers {
+		require.Len(t, provider.createdAt, 1)
+		createdAt = append(createdAt, provider.createdAt...)
+	}
+	sort.Slice(createdAt, func(i, j int) bool {
+		return createdAt[i].Before(createdAt[j])
+	})
+	for i := 1; i < len(createdAt); i++ {
+		assert.GreaterOrEqual(t, createdAt[i].Sub(createdAt[i-1]), delay, "pull request %d created too soon after the previous one", i)
+	}
+}
+
+func TestCreationPacerCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	pacer := NewCreationPacer(time.Hour, 0)
+	require.NoError(t, pacer.wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pacer.wait(ctx), context.DeadlineExceeded)
+
+	assert.Nil(t, NewCreationPacer(0, 0))
+	assert.NoError(t, (*CreationPacer)(nil).wait(context.Background()))
+}
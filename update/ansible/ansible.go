@@ -0,0 +1,115 @@
+// Package ansible provides an updater for Ansible vars files and YAML inventories, based on the YAML updater.
+package ansible
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/dailymotion-oss/octopilot/update/yaml"
+)
+
+// AnsibleUpdater is an updater for Ansible vars files - such as group_vars/all.yml - and YAML inventories.
+// It is a preset of the YAML updater: the path to update is built from the name of the var, and the optional group and host of the inventory.
+// Values - including Jinja templates such as "{{ base_url }}/api" - are written as opaque strings, and never expanded.
+type AnsibleUpdater struct {
+	*yaml.YamlUpdater
+	Var   string
+	Group string
+	Host  string
+}
+
+// NewUpdater builds a new Ansible updater from the given parameters and valuer
+func NewUpdater(params map[string]string, valuer value.Valuer) (*AnsibleUpdater, error) {
+	updater := &AnsibleUpdater{
+		Var:   params["var"],
+		Group: params["group"],
+		Host:  params["host"],
+	}
+
+	if len(updater.Var) == 0 {
+		return nil, errors.New("missing var parameter")
+	}
+	if _, found := params["path"]; found {
+		return nil, errors.New("the path parameter can't be used with the ansible updater - use the var parameter instead")
+	}
+	if _, found := params["image"]; found {
+		return nil, errors.New("the image parameter can't be used with the ansible updater")
+	}
+	if len(updater.Host) > 0 && len(updater.Group) == 0 {
+		return nil, errors.New("the host parameter requires the group parameter")
+	}
+
+	yamlParams := make(map[string]string, len(params)+1)
+	for key, value := range params {
+		switch key {
+		case "var", "group", "host":
+		default:
+			yamlParams[key] = value
+		}
+	}
+	yamlParams["path"] = updater.path()
+
+	var err error
+	updater.YamlUpdater, err = yaml.NewUpdater(yamlParams, valuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return updater, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *AnsibleUpdater) Message() (title, body string) {
+	title = fmt.Sprintf("Update %s %s", u.FilePath, u.Var)
+	switch {
+	case len(u.Host) > 0:
+		body = fmt.Sprintf("Updating Ansible var `%s` of host `%s` in group `%s` in file(s) `%s`", u.Var, u.Host, u.Group, u.FilePath)
+	case len(u.Group) > 0:
+		body = fmt.Sprintf("Updating Ansible var `%s` of group `%s` in file(s) `%s`", u.Var, u.Group, u.FilePath)
+	default:
+		body = fmt.Sprintf("Updating Ansible var `%s` in file(s) `%s`", u.Var, u.FilePath)
+	}
+	return title, body
+}
+
+// String returns a string representation of the updater
+func (u *AnsibleUpdater) String() string {
+	return fmt.Sprintf("Ansible[var=%s,group=%s,host=%s,file=%s]", u.Var, u.Group, u.Host, u.FilePath)
+}
+
+// path returns the yq path of the var to update:
+// - at the root of the file for a vars file
+// - in the vars of the group for an inventory - nested groups are separated by a dot, such as "prod.webservers"
+// - in the vars of the host of the group for an inventory
+func (u *AnsibleUpdater) path() string {
+	var path strings.Builder
+	if len(u.Group) > 0 {
+		path.WriteString(".all")
+		for _, group := range strings.Split(u.Group, ".") {
+			if group == "all" {
+				continue
+			}
+			path.WriteString(".children" + pathElement(group))
+		}
+		if len(u.Host) > 0 {
+			path.WriteString(".hosts" + pathElement(u.Host))
+		} else {
+			path.WriteString(".vars")
+		}
+	}
+	if path.Len() == 0 {
+		path.WriteString(".")
+	}
+	for _, key := range strings.Split(u.Var, ".") {
+		path.WriteString(pathElement(key))
+	}
+	return path.String()
+}
+
+// pathElement returns the quoted yq path element for the given key - so that keys with special characters, such as host names, are supported
+func pathElement(key string) string {
+	return "[" + strconv.Quote(key) + "]"
+}
@@ -0,0 +1,293 @@
+package ansible
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/dailymotion-oss/octopilot/update/yaml"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpdater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		params           map[string]string
+		expected         *AnsibleUpdater
+		expectedErrorMsg string
+	}{
+		{
+			name: "vars file",
+			params: map[string]string{
+				"file": "group_vars/all.yml",
+				"var":  "app_version",
+			},
+			expected: &AnsibleUpdater{
+				YamlUpdater: &yaml.YamlUpdater{
+					FilePath: "group_vars/all.yml",
+					Path:     `.["app_version"]`,
+					Indent:   2,
+				},
+				Var: "app_version",
+			},
+		},
+		{
+			name: "nested var of a nested group",
+			params: map[string]string{
+				"file":   "inventory.yml",
+				"var":    "app.version",
+				"group":  "prod.webservers",
+				"create": "true",
+			},
+			expected: &AnsibleUpdater{
+				YamlUpdater: &yaml.YamlUpdater{
+					FilePath:   "inventory.yml",
+					Path:       `.all.children["prod"].children["webservers"].vars["app"]["version"]`,
+					AutoCreate: true,
+					Indent:     2,
+				},
+				Var:   "app.version",
+				Group: "prod.webservers",
+			},
+		},
+		{
+			name: "host var",
+			params: map[string]string{
+				"file":  "inventory.yml",
+				"var":   "app_version",
+				"group": "all",
+				"host":  "web1.example.com",
+			},
+			expected: &AnsibleUpdater{
+				YamlUpdater: &yaml.YamlUpdater{
+					FilePath: "inventory.yml",
+					Path:     `.all.hosts["web1.example.com"]["app_version"]`,
+					Indent:   2,
+				},
+				Var:   "app_version",
+				Group: "all",
+				Host:  "web1.example.com",
+			},
+		},
+		{
+			name: "missing var",
+			params: map[string]string{
+				"file": "group_vars/all.yml",
+			},
+			expectedErrorMsg: "missing var parameter",
+		},
+		{
+			name: "path param",
+			params: map[string]string{
+				"file": "group_vars/all.yml",
+				"var":  "app_version",
+				"path": "app_version",
+			},
+			expectedErrorMsg: "the path parameter can't be used with the ansible updater - use the var parameter instead",
+		},
+		{
+			name: "host without group",
+			params: map[string]string{
+				"file": "inventory.yml",
+				"var":  "app_version",
+				"host": "web1",
+			},
+			expectedErrorMsg: "the host parameter requires the group parameter",
+		},
+		{
+			name: "missing file",
+			params: map[string]string{
+				"var": "app_version",
+			},
+			expectedErrorMsg: "missing file parameter",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := NewUpdater(test.params, nil)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		files         map[string]string
+		params        map[string]string
+		value         string
+		expected      bool
+		expectedFiles map[string]string
+	}{
+		{
+			name: "update a var in a vars file",
+			files: map[string]string{
+				"group_vars/all.yml": `---
+# the version of the app
+app_version: 1.0.0
+app_url: "{{ base_url }}/app"
+`,
+			},
+			params: map[string]string{
+				"file": "group_vars/all.yml",
+				"var":  "app_version",
+			},
+			value:    "1.1.0",
+			expected: true,
+			expectedFiles: map[string]string{
+				"group_vars/all.yml": `---
+# the version of the app
+app_version: 1.1.0
+app_url: "{{ base_url }}/app"
+`,
+			},
+		},
+		{
+			name: "set a jinja template without expanding it",
+			files: map[string]string{
+				"group_vars/jinja.yml": `---
+app_version: 1.0.0
+app_image: my-app:1.0.0
+`,
+			},
+			params: map[string]string{
+				"file": "group_vars/jinja.yml",
+				"var":  "app_image",
+			},
+			value:    "{{ registry }}/my-app:{{ app_version }}",
+			expected: true,
+			expectedFiles: map[string]string{
+				"group_vars/jinja.yml": `---
+app_version: 1.0.0
+app_image: '{{ registry }}/my-app:{{ app_version }}'
+`,
+			},
+		},
+		{
+			name: "update the var of a group in an inventory",
+			files: map[string]string{
+				"inventory.yml": `all:
+  children:
+    webservers:
+      hosts:
+        web1.example.com:
+          app_version: 1.0.0
+        web2.example.com:
+      vars:
+        app_version: 1.0.0
+`,
+			},
+			params: map[string]string{
+				"file":  "inventory.yml",
+				"var":   "app_version",
+				"group": "webservers",
+			},
+			value:    "1.1.0",
+			expected: true,
+			expectedFiles: map[string]string{
+				"inventory.yml": `all:
+  children:
+    webservers:
+      hosts:
+        web1.example.com:
+          app_version: 1.0.0
+        web2.example.com:
+      vars:
+        app_version: 1.1.0
+`,
+			},
+		},
+		{
+			name: "update the var of a host in an inventory",
+			files: map[string]string{
+				"host-inventory.yml": `all:
+  children:
+    webservers:
+      hosts:
+        web1.example.com:
+          app_version: 1.0.0
+        web2.example.com:
+          app_version: 1.0.0
+`,
+			},
+			params: map[string]string{
+				"file":  "host-inventory.yml",
+				"var":   "app_version",
+				"group": "webservers",
+				"host":  "web1.example.com",
+			},
+			value:    "1.1.0",
+			expected: true,
+			expectedFiles: map[string]string{
+				"host-inventory.yml": `all:
+  children:
+    webservers:
+      hosts:
+        web1.example.com:
+          app_version: 1.1.0
+        web2.example.com:
+          app_version: 1.0.0
+`,
+			},
+		},
+		{
+			name: "no changes",
+			files: map[string]string{
+				"group_vars/no-changes.yml": `---
+app_version: 1.0.0
+`,
+			},
+			params: map[string]string{
+				"file": "group_vars/no-changes.yml",
+				"var":  "app_version",
+			},
+			value:    "1.0.0",
+			expected: false,
+			expectedFiles: map[string]string{
+				"group_vars/no-changes.yml": `---
+app_version: 1.0.0
+`,
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			for filename, content := range test.files {
+				err := os.MkdirAll(filepath.Dir(filepath.Join("testdata", filename)), 0755)
+				require.NoErrorf(t, err, "can't create testdata directories for %s", filename)
+				err = os.WriteFile(filepath.Join("testdata", filename), []byte(content), 0644)
+				require.NoErrorf(t, err, "can't write testdata file %s", filename)
+			}
+
+			updater, err := NewUpdater(test.params, value.StringValuer(test.value))
+			require.NoError(t, err)
+			actual, err := updater.Update(context.Background(), "testdata")
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+
+			for filename, expectedContent := range test.expectedFiles {
+				actualContent, err := os.ReadFile(filepath.Join("testdata", filename))
+				require.NoErrorf(t, err, "can't read testdata file %s", filename)
+				assert.Equalf(t, expectedContent, string(actualContent), "testdata file %s doesn't match", filename)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package ansiblevault provides an updater for YAML files encrypted with ansible-vault.
+package ansiblevault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/dailymotion-oss/octopilot/internal/changes"
+	"github.com/dailymotion-oss/octopilot/internal/yaml"
+	"github.com/dailymotion-oss/octopilot/update/value"
+)
+
+// passwordFileEnvVar is the environment variable used by ansible to define the vault password file
+const passwordFileEnvVar = "ANSIBLE_VAULT_PASSWORD_FILE"
+
+// AnsibleVaultUpdater is an updater for YAML files encrypted with ansible-vault: it decrypts the file(s), sets the value of the key(s), and re-encrypts them with the same password.
+type AnsibleVaultUpdater struct {
+	FilePath     string
+	Keys         []string
+	PasswordFile string
+	PasswordEnv  string
+	Valuer       value.Valuer
+}
+
+// NewUpdater builds a new ansible-vault updater from the given parameters and valuer
+func NewUpdater(params map[string]string, valuer value.Valuer) (*AnsibleVaultUpdater, error) {
+	updater := &AnsibleVaultUpdater{}
+
+	updater.FilePath = params["file"]
+	if len(updater.FilePath) == 0 {
+		return nil, errors.New("missing file parameter")
+	}
+
+	for _, key := range strings.Split(params["key"], ";") {
+		if key = strings.TrimSpace(key); len(key) > 0 {
+			updater.Keys = append(updater.Keys, key)
+		}
+	}
+	if len(updater.Keys) == 0 {
+		return nil, errors.New("missing key parameter")
+	}
+
+	updater.PasswordFile = params["password-file"]
+	updater.PasswordEnv = params["password-env"]
+	if len(updater.PasswordFile) > 0 && len(updater.PasswordEnv) > 0 {
+		return nil, errors.New("the password-file and password-env parameters can't be used together")
+	}
+
+	updater.Valuer = valuer
+
+	return updater, nil
+}
+
+// Update updates the repository cloned at the given path, and returns true if changes have been made
+func (u *AnsibleVaultUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	value, err := u.Valuer.Value(ctx, repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	filePaths, err := filepath.Glob(filepath.Join(repoPath, u.FilePath))
+	if err != nil {
+		return false, fmt.Errorf("failed to expand glob pattern %s: %w", u.FilePath, err)
+	}
+	if len(filePaths) == 0 {
+		return false, nil
+	}
+
+	password, err := u.password()
+	if err != nil {
+		return false, err
+	}
+
+	var updated bool
+	for _, filePath := range filePaths {
+		relFilePath, err := filepath.Rel(repoPath, filePath)
+		if err != nil {
+			relFilePath = filePath
+		}
+
+		fileUpdated, err := u.updateFile(ctx, filePath, relFilePath, password, value)
+		if err != nil {
+			return false, fmt.Errorf("failed to update file %s: %w", relFilePath, err)
+		}
+		if fileUpdated {
+			updated = true
+		}
+	}
+
+	return updated, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *AnsibleVaultUpdater) Message() (title, body string) {
+	keys := strings.Join(u.Keys, ", ")
+	title = fmt.Sprintf("Update %s %s", u.FilePath, keys)
+	body = fmt.Sprintf("Updating ansible-vault encrypted file(s) `%s` key(s) `%s`", u.FilePath, keys)
+	return title, body
+}
+
+// String returns a string representation of the updater
+func (u *AnsibleVaultUpdater) String() string {
+	return fmt.Sprintf("AnsibleVault[key=%s,file=%s]", strings.Join(u.Keys, ";"), u.FilePath)
+}
+
+func (u *AnsibleVaultUpdater) updateFile(ctx context.Context, filePath, relFilePath, password, value string) (bool, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to access file: %w", err)
+	}
+
+	encrypted, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	plaintext, header, err := decrypt(encrypted, password)
+	if err != nil {
+		return false, err
+	}
+
+	var rootNode yamlv3.Node
+	if err = yamlv3.Unmarshal(plaintext, &rootNode); err != nil {
+		return false, fmt.Errorf("failed to parse the decrypted YAML content: %w", err)
+	}
+	if rootNode.Kind == 0 {
+		rootNode = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{{Kind: yamlv3.MappingNode, Tag: "!!map"}}}
+	}
+
+	// compare the re-encoded content before and after the update, so that a pure formatting change doesn't re-encrypt the file
+	originalData, err := encodeYAML(&rootNode)
+	if err != nil {
+		return false, err
+	}
+	var maskedOriginalData []byte
+	if changes.RecordsPlaintexts(ctx) {
+		// the changes are exported in plaintext, but with all the values masked
+		if maskedOriginalData, err = encodeYAML(maskNode(&rootNode)); err != nil {
+			return false, err
+		}
+	}
+	for _, key := range u.Keys {
+		if _, err = yaml.SetValue(rootNode.Content[0], convertKeyToPath(key), value); err != nil {
+			return false, fmt.Errorf("failed to set key %s: %w", key, err)
+		}
+	}
+	updatedData, err := encodeYAML(&rootNode)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(originalData, updatedData) {
+		return false, nil
+	}
+	var maskedUpdatedData []byte
+	if changes.RecordsPlaintexts(ctx) {
+		if maskedUpdatedData, err = encodeYAML(maskNode(&rootNode)); err != nil {
+			return false, err
+		}
+	}
+
+	reencrypted, err := encrypt(updatedData, password, header)
+	if err != nil {
+		return false, fmt.Errorf("failed to re-encrypt content: %w", err)
+	}
+	if err = writeFileAtomically(filePath, reencrypted, fileInfo.Mode()); err != nil {
+		return false, fmt.Errorf("failed to write re-encrypted content: %w", err)
+	}
+	changes.RecordPlaintext(ctx, relFilePath, maskedOriginalData, maskedUpdatedData)
+	return true, nil
+}
+
+// password returns the vault password, read from the password file or the environment variable
+func (u *AnsibleVaultUpdater) password() (string, error) {
+	if len(u.PasswordEnv) > 0 {
+		password, found := os.LookupEnv(u.PasswordEnv)
+		if !found || len(password) == 0 {
+			return "", fmt.Errorf("no vault password found in env var %s", u.PasswordEnv)
+		}
+		return password, nil
+	}
+
+	passwordFile := u.PasswordFile
+	if len(passwordFile) == 0 {
+		passwordFile = os.Getenv(passwordFileEnvVar)
+	}
+	if len(passwordFile) == 0 {
+		return "", fmt.Errorf("no vault password: use the password-file or password-env parameters, or the %s env var", passwordFileEnvVar)
+	}
+	data, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault password file %s: %w", passwordFile, err)
+	}
+	// ansible strips the leading and trailing whitespaces of the password files
+	password := strings.TrimSpace(string(data))
+	if len(password) == 0 {
+		return "", fmt.Errorf("empty vault password file %s", passwordFile)
+	}
+	return password, nil
+}
+
+// maskNode returns a copy of the given node with all the scalar values masked - but not the keys - so that it can be exported without its secrets
+func maskNode(node *yamlv3.Node) *yamlv3.Node {
+	masked := *node
+	masked.Content = make([]*yamlv3.Node, 0, len(node.Content))
+	for i, child := range node.Content {
+		if node.Kind == yamlv3.MappingNode && i%2 == 0 {
+			// keep the keys
+			masked.Content = append(masked.Content, child)
+			continue
+		}
+		masked.Content = append(masked.Content, maskNode(child))
+	}
+	if masked.Kind == yamlv3.ScalarNode {
+		masked.Tag = "!!str"
+		masked.Style = 0
+		masked.Value = changes.Mask(node.Value)
+	}
+	return &masked
+}
+
+func encodeYAML(rootNode *yamlv3.Node) ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := yamlv3.NewEncoder(&buffer)
+	enc.SetIndent(2)
+	err := enc.Encode(rootNode)
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the YAML content: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// writeFileAtomically writes the data to a temporary file in the same directory, and then renames it - so that the file is never partially written
+func writeFileAtomically(filePath string, data []byte, mode os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpFile.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filePath)
+}
+
+func convertKeyToPath(key string) []string {
+	return strings.Split(key, ".")
+}
@@ -0,0 +1,271 @@
+package ansiblevault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/internal/changes"
+	"github.com/dailymotion-oss/octopilot/update/value"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpdater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		params           map[string]string
+		expected         *AnsibleVaultUpdater
+		expectedErrorMsg string
+	}{
+		{
+			name: "valid params",
+			params: map[string]string{
+				"file":          "group_vars/all/vault.yml",
+				"key":           "app.token",
+				"password-file": "/secrets/vault-password",
+			},
+			expected: &AnsibleVaultUpdater{
+				FilePath:     "group_vars/all/vault.yml",
+				Keys:         []string{"app.token"},
+				PasswordFile: "/secrets/vault-password",
+			},
+		},
+		{
+			name: "multiple keys",
+			params: map[string]string{
+				"file":         "group_vars/*/vault.yml",
+				"key":          "app.token; other-app.token",
+				"password-env": "VAULT_PASSWORD",
+			},
+			expected: &AnsibleVaultUpdater{
+				FilePath:    "group_vars/*/vault.yml",
+				Keys:        []string{"app.token", "other-app.token"},
+				PasswordEnv: "VAULT_PASSWORD",
+			},
+		},
+		{
+			name: "both password-file and password-env",
+			params: map[string]string{
+				"file":          "vault.yml",
+				"key":           "app.token",
+				"password-file": "/secrets/vault-password",
+				"password-env":  "VAULT_PASSWORD",
+			},
+			expectedErrorMsg: "the password-file and password-env parameters can't be used together",
+		},
+		{
+			name: "missing file",
+			params: map[string]string{
+				"key": "app.token",
+			},
+			expectedErrorMsg: "missing file parameter",
+		},
+		{
+			name: "missing key",
+			params: map[string]string{
+				"file": "vault.yml",
+			},
+			expectedErrorMsg: "missing key parameter",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := NewUpdater(test.params, nil)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+	const password = "vault-password"
+	tests := []struct {
+		name     string
+		files    map[string]string
+		keys     []string
+		value    string
+		expected bool
+		// expectedFiles are the expected decrypted contents
+		expectedFiles map[string]string
+	}{
+		{
+			name: "update an existing value",
+			files: map[string]string{
+				"vault.yml": `# the secrets of the app
+app:
+  token: old-token
+  port: 8080
+`,
+			},
+			keys:     []string{"app.token"},
+			value:    "new-token",
+			expected: true,
+			expectedFiles: map[string]string{
+				"vault.yml": `# the secrets of the app
+app:
+  token: new-token
+  port: 8080
+`,
+			},
+		},
+		{
+			name: "update multiple keys in multiple files",
+			files: map[string]string{
+				"group_vars/prod/vault.yml": `app:
+  token: old-token
+`,
+				"group_vars/staging/vault.yml": `app:
+  token: old-token
+other-app:
+  token: old-token
+`,
+			},
+			keys:     []string{"app.token", "other-app.token"},
+			value:    "new-token",
+			expected: true,
+			expectedFiles: map[string]string{
+				"group_vars/prod/vault.yml": `app:
+  token: new-token
+other-app:
+  token: new-token
+`,
+				"group_vars/staging/vault.yml": `app:
+  token: new-token
+other-app:
+  token: new-token
+`,
+			},
+		},
+		{
+			name: "no changes",
+			files: map[string]string{
+				"vault.yml": `app:
+  token: good-token
+`,
+			},
+			keys:     []string{"app.token"},
+			value:    "good-token",
+			expected: false,
+			expectedFiles: map[string]string{
+				"vault.yml": `app:
+  token: good-token
+`,
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := t.TempDir()
+			passwordFile := filepath.Join(t.TempDir(), "vault-password")
+			require.NoError(t, os.WriteFile(passwordFile, []byte(password+"\n"), 0600))
+
+			originalEncryptedFiles := map[string][]byte{}
+			for filename, content := range test.files {
+				encrypted, err := encrypt([]byte(content), password, vaultHeader{Version: "1.1"})
+				require.NoError(t, err)
+				filePath := filepath.Join(repoPath, filename)
+				require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+				require.NoError(t, os.WriteFile(filePath, encrypted, 0600))
+				originalEncryptedFiles[filename] = encrypted
+			}
+
+			filePattern := "vault.yml"
+			if len(test.files) > 1 {
+				filePattern = "group_vars/*/vault.yml"
+			}
+			updater := &AnsibleVaultUpdater{
+				FilePath:     filePattern,
+				Keys:         test.keys,
+				PasswordFile: passwordFile,
+				Valuer:       value.StringValuer(test.value),
+			}
+			actual, err := updater.Update(context.Background(), repoPath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+
+			for filename, expectedContent := range test.expectedFiles {
+				encrypted, err := os.ReadFile(filepath.Join(repoPath, filename))
+				require.NoError(t, err)
+				if !test.expected {
+					assert.Equal(t, originalEncryptedFiles[filename], encrypted, "file %s should not be re-encrypted", filename)
+				}
+				decrypted, _, err := decrypt(encrypted, password)
+				require.NoError(t, err)
+				assert.Equal(t, expectedContent, string(decrypted))
+
+				fileInfo, err := os.Stat(filepath.Join(repoPath, filename))
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+			}
+
+			entries, err := os.ReadDir(repoPath)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				assert.NotContains(t, entry.Name(), ".vault.yml.", "temporary file should be removed")
+			}
+		})
+	}
+}
+
+func TestUpdateWrongPassword(t *testing.T) {
+	t.Parallel()
+	repoPath := t.TempDir()
+	encrypted, err := encrypt([]byte("app:\n  token: old-token\n"), "vault-password", vaultHeader{Version: "1.1"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "vault.yml"), encrypted, 0600))
+	passwordFile := filepath.Join(t.TempDir(), "vault-password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("wrong-password"), 0600))
+
+	updater := &AnsibleVaultUpdater{
+		FilePath:     "vault.yml",
+		Keys:         []string{"app.token"},
+		PasswordFile: passwordFile,
+		Valuer:       value.StringValuer("new-token"),
+	}
+	updated, err := updater.Update(context.Background(), repoPath)
+	require.EqualError(t, err, "failed to update file vault.yml: failed to decrypt vault: HMAC verification failed - check the vault password")
+	assert.False(t, updated)
+}
+
+func TestUpdateRecordPlaintext(t *testing.T) {
+	t.Parallel()
+	repoPath := t.TempDir()
+	encrypted, err := encrypt([]byte("app:\n  token: old-token\n  user: admin\n"), "vault-password", vaultHeader{Version: "1.1"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "vault.yml"), encrypted, 0600))
+	passwordFile := filepath.Join(t.TempDir(), "vault-password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("vault-password"), 0600))
+
+	updater := &AnsibleVaultUpdater{
+		FilePath:     "vault.yml",
+		Keys:         []string{"app.token"},
+		PasswordFile: passwordFile,
+		Valuer:       value.StringValuer("new-token"),
+	}
+	ctx := changes.WithPlaintexts(context.Background())
+	updated, err := updater.Update(ctx, repoPath)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, map[string]changes.Plaintext{
+		"vault.yml": {
+			Before: []byte("app:\n  token: '" + changes.Mask("old-token") + "'\n  user: '" + changes.Mask("admin") + "'\n"),
+			After:  []byte("app:\n  token: '" + changes.Mask("new-token") + "'\n  user: '" + changes.Mask("admin") + "'\n"),
+		},
+	}, changes.Plaintexts(ctx))
+}
@@ -0,0 +1,177 @@
+package ansiblevault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// constants of the ansible-vault AES256 format
+// see https://docs.ansible.com/ansible/latest/vault_guide/vault_using_encrypted_content.html#format-of-files-encrypted-with-ansible-vault
+const (
+	vaultHeaderPrefix = "$ANSIBLE_VAULT"
+	vaultCipher       = "AES256"
+	vaultSaltLength   = 32
+	vaultKeyLength    = 32
+	vaultIterations   = 10000
+	vaultLineLength   = 80
+)
+
+// vaultHeader is the first line of a vault-encrypted file, such as "$ANSIBLE_VAULT;1.2;AES256;prod"
+type vaultHeader struct {
+	Version string
+	Label   string
+}
+
+func (h vaultHeader) String() string {
+	if len(h.Label) > 0 {
+		return strings.Join([]string{vaultHeaderPrefix, h.Version, vaultCipher, h.Label}, ";")
+	}
+	return strings.Join([]string{vaultHeaderPrefix, h.Version, vaultCipher}, ";")
+}
+
+// decrypt decrypts the given vault-encrypted content with the given password, and returns the plaintext and the header of the vault.
+func decrypt(data []byte, password string) ([]byte, vaultHeader, error) {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n")), "\n")
+	header, err := parseHeader(lines[0])
+	if err != nil {
+		return nil, header, err
+	}
+
+	envelope, err := hex.DecodeString(strings.Join(lines[1:], ""))
+	if err != nil {
+		return nil, header, fmt.Errorf("invalid vault content: %w", err)
+	}
+	parts := bytes.Split(envelope, []byte("\n"))
+	if len(parts) != 3 {
+		return nil, header, fmt.Errorf("invalid vault content: expected 3 parts instead of %d", len(parts))
+	}
+	var salt, expectedHMAC, ciphertext []byte
+	for i, target := range []*[]byte{&salt, &expectedHMAC, &ciphertext} {
+		*target, err = hex.DecodeString(string(parts[i]))
+		if err != nil {
+			return nil, header, fmt.Errorf("invalid vault content: %w", err)
+		}
+	}
+
+	cipherKey, hmacKey, iv := deriveKeys(password, salt)
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), expectedHMAC) {
+		return nil, header, errors.New("failed to decrypt vault: HMAC verification failed - check the vault password")
+	}
+
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, header, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(plaintext, ciphertext)
+
+	plaintext, err = unpad(plaintext)
+	if err != nil {
+		return nil, header, err
+	}
+	return plaintext, header, nil
+}
+
+// encrypt encrypts the given plaintext with the given password - and a new random salt - and returns the vault-encrypted content.
+func encrypt(plaintext []byte, password string, header vaultHeader) ([]byte, error) {
+	salt := make([]byte, vaultSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	cipherKey, hmacKey, iv := deriveKeys(password, salt)
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	padded := pad(plaintext)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+
+	envelope := strings.Join([]string{
+		hex.EncodeToString(salt),
+		hex.EncodeToString(mac.Sum(nil)),
+		hex.EncodeToString(ciphertext),
+	}, "\n")
+	encoded := hex.EncodeToString([]byte(envelope))
+
+	var buffer bytes.Buffer
+	buffer.WriteString(header.String())
+	buffer.WriteString("\n")
+	for i := 0; i < len(encoded); i += vaultLineLength {
+		end := i + vaultLineLength
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		buffer.WriteString(encoded[i:end])
+		buffer.WriteString("\n")
+	}
+	return buffer.Bytes(), nil
+}
+
+func parseHeader(line string) (vaultHeader, error) {
+	fields := strings.Split(strings.TrimSpace(line), ";")
+	if len(fields) < 3 || fields[0] != vaultHeaderPrefix {
+		return vaultHeader{}, errors.New("not a vault-encrypted file: missing $ANSIBLE_VAULT header")
+	}
+	header := vaultHeader{
+		Version: fields[1],
+	}
+	if fields[2] != vaultCipher {
+		return header, fmt.Errorf("unsupported vault cipher %s: only %s is supported", fields[2], vaultCipher)
+	}
+	switch header.Version {
+	case "1.1":
+	case "1.2":
+		if len(fields) > 3 {
+			header.Label = fields[3]
+		}
+	default:
+		return header, fmt.Errorf("unsupported vault version %s: must be one of 1.1 or 1.2", header.Version)
+	}
+	return header, nil
+}
+
+// deriveKeys returns the cipher key, the HMAC key and the initialization vector derived from the password and the salt
+func deriveKeys(password string, salt []byte) (cipherKey, hmacKey, iv []byte) {
+	derived := pbkdf2.Key([]byte(password), salt, vaultIterations, 2*vaultKeyLength+aes.BlockSize, sha256.New)
+	return derived[:vaultKeyLength], derived[vaultKeyLength : 2*vaultKeyLength], derived[2*vaultKeyLength:]
+}
+
+// pad applies a PKCS#7 padding to the given data
+func pad(data []byte) []byte {
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+// unpad removes the PKCS#7 padding of the given data
+func unpad(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("failed to decrypt vault: empty content")
+	}
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, errors.New("failed to decrypt vault: invalid padding")
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("failed to decrypt vault: invalid padding")
+		}
+	}
+	return data[:len(data)-padding], nil
+}
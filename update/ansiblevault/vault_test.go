@@ -0,0 +1,96 @@
+package ansiblevault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		plaintext string
+		header    vaultHeader
+	}{
+		{
+			name:      "vault 1.1",
+			plaintext: "app:\n  token: some-token\n",
+			header:    vaultHeader{Version: "1.1"},
+		},
+		{
+			name:      "vault 1.2 with a vault id label",
+			plaintext: "app:\n  token: some-token\n",
+			header:    vaultHeader{Version: "1.2", Label: "prod"},
+		},
+		{
+			name:      "content with a size multiple of the block size",
+			plaintext: "0123456789abcdef",
+			header:    vaultHeader{Version: "1.1"},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			encrypted, err := encrypt([]byte(test.plaintext), "secret", test.header)
+			require.NoError(t, err)
+
+			lines := strings.Split(strings.TrimSuffix(string(encrypted), "\n"), "\n")
+			assert.Equal(t, test.header.String(), lines[0])
+			for _, line := range lines[1:] {
+				assert.LessOrEqual(t, len(line), vaultLineLength)
+			}
+
+			decrypted, header, err := decrypt(encrypted, "secret")
+			require.NoError(t, err)
+			assert.Equal(t, test.plaintext, string(decrypted))
+			assert.Equal(t, test.header, header)
+
+			_, _, err = decrypt(encrypted, "wrong-password")
+			require.EqualError(t, err, "failed to decrypt vault: HMAC verification failed - check the vault password")
+		})
+	}
+}
+
+func TestDecryptInvalidContent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		content          string
+		expectedErrorMsg string
+	}{
+		{
+			name:             "plain YAML file",
+			content:          "app:\n  token: some-token\n",
+			expectedErrorMsg: "not a vault-encrypted file: missing $ANSIBLE_VAULT header",
+		},
+		{
+			name:             "unsupported version",
+			content:          "$ANSIBLE_VAULT;1.0;AES256\n616263\n",
+			expectedErrorMsg: "unsupported vault version 1.0: must be one of 1.1 or 1.2",
+		},
+		{
+			name:             "unsupported cipher",
+			content:          "$ANSIBLE_VAULT;1.1;AES\n616263\n",
+			expectedErrorMsg: "unsupported vault cipher AES: only AES256 is supported",
+		},
+		{
+			name:             "invalid envelope",
+			content:          "$ANSIBLE_VAULT;1.1;AES256\n616263\n",
+			expectedErrorMsg: "invalid vault content: expected 3 parts instead of 1",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := decrypt([]byte(test.content), "secret")
+			require.EqualError(t, err, test.expectedErrorMsg)
+		})
+	}
+}
@@ -0,0 +1,419 @@
+// Package botconfig provides updaters for the configuration files of the dependency bots: Renovate and Dependabot.
+package botconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	internalyaml "github.com/dailymotion-oss/octopilot/internal/yaml"
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"gopkg.in/yaml.v3"
+)
+
+// supported dependency bots
+const (
+	Renovate   = "renovate"
+	Dependabot = "dependabot"
+)
+
+// defaultFiles are the known locations of the configuration file of each bot, in the order they are looked up
+var defaultFiles = map[string][]string{
+	Renovate: {
+		"renovate.json",
+		".github/renovate.json",
+		".gitlab/renovate.json",
+		".renovaterc.json",
+		".renovaterc",
+	},
+	Dependabot: {
+		".github/dependabot.yml",
+		".github/dependabot.yaml",
+	},
+}
+
+// BotConfigUpdater is an updater that sets a field in the configuration file of a dependency bot - such as the schedule of Renovate,
+// or the interval of a Dependabot ecosystem - or appends an entry to a list - such as a Renovate package rule.
+// The value is parsed as YAML - or JSON - so that it can be a boolean, a list or an object. The comments and the order of the keys are preserved.
+type BotConfigUpdater struct {
+	Bot string
+	// FilePath is the configuration file - if empty, the first existing file of the known locations of the bot is used
+	FilePath string
+	Path     string
+	// Ecosystem and Directory select the entries of the Dependabot "updates" list in which the path is set
+	Ecosystem string
+	Directory string
+	// Append appends the value to the list at the given path - unless the list already contains it
+	Append bool
+	// Indent is the indentation of the YAML files - the indentation of the JSON files is detected from their content
+	Indent int
+	EOL    eol.Mode
+	Valuer value.Valuer
+}
+
+// NewRenovateUpdater builds a new updater for the Renovate configuration file from the given parameters and valuer
+func NewRenovateUpdater(params map[string]string, valuer value.Valuer) (*BotConfigUpdater, error) {
+	return newUpdater(Renovate, params, valuer)
+}
+
+// NewDependabotUpdater builds a new updater for the Dependabot configuration file from the given parameters and valuer
+func NewDependabotUpdater(params map[string]string, valuer value.Valuer) (*BotConfigUpdater, error) {
+	return newUpdater(Dependabot, params, valuer)
+}
+
+func newUpdater(bot string, params map[string]string, valuer value.Valuer) (*BotConfigUpdater, error) {
+	updater := &BotConfigUpdater{
+		Bot:      bot,
+		FilePath: params["file"],
+		Path:     params["path"],
+	}
+
+	if len(updater.Path) == 0 {
+		return nil, errors.New("missing path parameter")
+	}
+	for _, key := range strings.Split(updater.Path, ".") {
+		if len(key) == 0 {
+			return nil, fmt.Errorf("invalid path parameter %s: empty key", updater.Path)
+		}
+	}
+
+	updater.Ecosystem = params["ecosystem"]
+	updater.Directory = params["directory"]
+	if bot != Dependabot && (len(updater.Ecosystem) > 0 || len(updater.Directory) > 0) {
+		return nil, fmt.Errorf("the ecosystem and directory parameters can only be used with the %s updater", Dependabot)
+	}
+	if len(updater.Directory) > 0 && len(updater.Ecosystem) == 0 {
+		return nil, errors.New("the directory parameter can't be used without the ecosystem parameter")
+	}
+
+	updater.Append, _ = strconv.ParseBool(params["append"])
+
+	updater.Indent, _ = strconv.Atoi(params["indent"])
+	if updater.Indent <= 0 {
+		updater.Indent = 2
+	}
+
+	var err error
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
+	}
+
+	updater.Valuer = valuer
+
+	return updater, nil
+}
+
+// Update updates the repository cloned at the given path, and returns true if changes have been made
+func (u *BotConfigUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	value, err := u.Valuer.Value(ctx, repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	filePaths, err := u.filePaths(repoPath)
+	if err != nil {
+		return false, err
+	}
+
+	var updated bool
+	for _, filePath := range filePaths {
+		relFilePath, err := filepath.Rel(repoPath, filePath)
+		if err != nil {
+			relFilePath = filePath
+		}
+
+		fileUpdated, err := u.updateFile(filePath, value)
+		if err != nil {
+			return false, fmt.Errorf("failed to update file %s: %w", relFilePath, err)
+		}
+		if fileUpdated {
+			updated = true
+		}
+	}
+
+	return updated, nil
+}
+
+// filePaths returns the configuration files to update: the files matching the file pattern, or the first existing file of the known locations of the bot
+func (u *BotConfigUpdater) filePaths(repoPath string) ([]string, error) {
+	if len(u.FilePath) > 0 {
+		filePaths, err := filepath.Glob(filepath.Join(repoPath, u.FilePath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand glob pattern %s: %w", u.FilePath, err)
+		}
+		return filePaths, nil
+	}
+
+	for _, file := range defaultFiles[u.Bot] {
+		filePath := filepath.Join(repoPath, file)
+		if _, err := os.Stat(filePath); err == nil {
+			return []string{filePath}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (u *BotConfigUpdater) updateFile(filePath string, value string) (bool, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to access file: %w", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var rootNode yaml.Node
+	if err = yaml.Unmarshal(data, &rootNode); err != nil {
+		return false, fmt.Errorf("failed to parse file: %w", err)
+	}
+	if rootNode.Kind != yaml.DocumentNode || len(rootNode.Content) == 0 {
+		return false, errors.New("empty configuration file")
+	}
+
+	targetNodes, err := u.targetNodes(rootNode.Content[0])
+	if err != nil {
+		return false, err
+	}
+
+	var updated bool
+	for _, targetNode := range targetNodes {
+		// each target gets its own value node, so that they can be modified independently
+		valueNode, err := parseValue(value)
+		if err != nil {
+			return false, err
+		}
+		targetUpdated, err := setPath(targetNode, strings.Split(u.Path, "."), valueNode, u.Append)
+		if err != nil {
+			return false, fmt.Errorf("failed to set %s: %w", u.Path, err)
+		}
+		if targetUpdated {
+			updated = true
+		}
+	}
+	if !updated {
+		return false, nil
+	}
+
+	var buffer bytes.Buffer
+	if internalyaml.IsJSON(filePath, data) {
+		err = internalyaml.EncodeJSON(&buffer, rootNode.Content[0], jsonIndent(data))
+		if err == nil && bytes.HasSuffix(data, []byte("\n")) {
+			buffer.WriteString("\n")
+		}
+	} else {
+		enc := yaml.NewEncoder(&buffer)
+		enc.SetIndent(u.Indent)
+		err = enc.Encode(&rootNode)
+		if err == nil {
+			err = enc.Close()
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to encode updated configuration: %w", err)
+	}
+
+	updatedData := eol.Apply(u.EOL, data, buffer.Bytes())
+	if bytes.Equal(data, updatedData) {
+		return false, nil
+	}
+
+	err = os.WriteFile(filePath, updatedData, fileInfo.Mode())
+	if err != nil {
+		return false, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return true, nil
+}
+
+// targetNodes returns the nodes in which the path is set: the root node, or the entries of the Dependabot "updates" list matching the ecosystem and directory
+func (u *BotConfigUpdater) targetNodes(rootNode *yaml.Node) ([]*yaml.Node, error) {
+	if len(u.Ecosystem) == 0 {
+		return []*yaml.Node{rootNode}, nil
+	}
+
+	updatesNode := mappingValue(rootNode, "updates")
+	if updatesNode == nil || updatesNode.Kind != yaml.SequenceNode {
+		return nil, errors.New("no updates defined in the configuration")
+	}
+	var targetNodes []*yaml.Node
+	for _, entryNode := range updatesNode.Content {
+		if ecosystemNode := mappingValue(entryNode, "package-ecosystem"); ecosystemNode == nil || ecosystemNode.Value != u.Ecosystem {
+			continue
+		}
+		if len(u.Directory) > 0 {
+			if directoryNode := mappingValue(entryNode, "directory"); directoryNode == nil || directoryNode.Value != u.Directory {
+				continue
+			}
+		}
+		targetNodes = append(targetNodes, entryNode)
+	}
+	if len(targetNodes) == 0 {
+		return nil, fmt.Errorf("no updates defined for %s", u.target())
+	}
+	return targetNodes, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *BotConfigUpdater) Message() (title, body string) {
+	title = fmt.Sprintf("Update %s configuration %s", u.botName(), u.Path)
+	action := "Setting"
+	if u.Append {
+		action = "Appending to"
+	}
+	body = fmt.Sprintf("%s `%s` in the %s configuration of %s", action, u.Path, u.botName(), u.target())
+	return title, body
+}
+
+// String returns a string representation of the updater
+func (u *BotConfigUpdater) String() string {
+	return fmt.Sprintf("BotConfig[bot=%s,file=%s,path=%s,ecosystem=%s,directory=%s,append=%v]", u.Bot, u.FilePath, u.Path, u.Ecosystem, u.Directory, u.Append)
+}
+
+func (u *BotConfigUpdater) botName() string {
+	if u.Bot == Dependabot {
+		return "Dependabot"
+	}
+	return "Renovate"
+}
+
+func (u *BotConfigUpdater) target() string {
+	target := "the repository"
+	if len(u.Ecosystem) > 0 {
+		target = fmt.Sprintf("ecosystem %s", u.Ecosystem)
+		if len(u.Directory) > 0 {
+			target += fmt.Sprintf(" in directory %s", u.Directory)
+		}
+	}
+	return target
+}
+
+// parseValue parses the value as YAML - or JSON - so that it keeps its type. An empty value is an empty string.
+// The flow style - of the JSON lists and objects - is removed, so that the value is written in the style of the YAML files.
+func parseValue(value string) (*yaml.Node, error) {
+	var documentNode yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &documentNode); err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	if len(documentNode.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	}
+	valueNode := documentNode.Content[0]
+	removeFlowStyle(valueNode)
+	return valueNode, nil
+}
+
+func removeFlowStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	for _, child := range node.Content {
+		removeFlowStyle(child)
+	}
+}
+
+// setPath sets the value node at the given path in the mapping node - or appends it to the list at the given path - creating any missing mapping on the way.
+// It returns true if the node has changed.
+func setPath(node *yaml.Node, path []string, valueNode *yaml.Node, appendValue bool) (bool, error) {
+	if node.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("can't set %s on a non-mapping value", path[0])
+	}
+
+	index := -1
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == path[0] {
+			index = i + 1
+			break
+		}
+	}
+	if index < 0 {
+		newNode := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		switch {
+		case len(path) > 1:
+		case appendValue:
+			newNode = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{valueNode}}
+		default:
+			newNode = valueNode
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}, newNode)
+		if len(path) > 1 {
+			return setPath(newNode, path[1:], valueNode, appendValue)
+		}
+		return true, nil
+	}
+
+	if len(path) > 1 {
+		return setPath(node.Content[index], path[1:], valueNode, appendValue)
+	}
+
+	currentNode := node.Content[index]
+	if appendValue {
+		if currentNode.Kind != yaml.SequenceNode {
+			return false, fmt.Errorf("%s is not a list", path[0])
+		}
+		for _, itemNode := range currentNode.Content {
+			if equal, err := equalNodes(itemNode, valueNode); err != nil || equal {
+				return false, err
+			}
+		}
+		currentNode.Content = append(currentNode.Content, valueNode)
+		return true, nil
+	}
+
+	equal, err := equalNodes(currentNode, valueNode)
+	if err != nil || equal {
+		return false, err
+	}
+	if currentNode.Kind == yaml.ScalarNode && valueNode.Kind == yaml.ScalarNode && valueNode.Tag == "!!str" && valueNode.Style == 0 {
+		// keep the quotes of the current value
+		valueNode.Style = currentNode.Style
+	}
+	valueNode.HeadComment = currentNode.HeadComment
+	valueNode.LineComment = currentNode.LineComment
+	valueNode.FootComment = currentNode.FootComment
+	node.Content[index] = valueNode
+	return true, nil
+}
+
+// equalNodes returns true if both nodes have the same data - ignoring their style and comments
+func equalNodes(a, b *yaml.Node) (bool, error) {
+	var aData, bData interface{}
+	if err := a.Decode(&aData); err != nil {
+		return false, err
+	}
+	if err := b.Decode(&bData); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(aData, bData), nil
+}
+
+// mappingValue returns the value node for the given key in a mapping node, or nil if it can't be found
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// jsonIndent returns the indentation of the given JSON content - the indentation of its first indented line - or 2 spaces by default
+func jsonIndent(data []byte) int {
+	for _, line := range bytes.Split(data, []byte("\n"))[1:] {
+		trimmedLine := bytes.TrimLeft(line, " ")
+		if len(trimmedLine) > 0 && len(trimmedLine) < len(line) {
+			return len(line) - len(trimmedLine)
+		}
+	}
+	return 2
+}
@@ -0,0 +1,425 @@
+package botconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+)
+
+func TestNewUpdater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		bot              string
+		params           map[string]string
+		expected         *BotConfigUpdater
+		expectedErrorMsg string
+	}{
+		{
+			name: "renovate params",
+			bot:  Renovate,
+			params: map[string]string{
+				"path":   "packageRules",
+				"append": "true",
+			},
+			expected: &BotConfigUpdater{
+				Bot:    Renovate,
+				Path:   "packageRules",
+				Append: true,
+				Indent: 2,
+			},
+		},
+		{
+			name: "dependabot params",
+			bot:  Dependabot,
+			params: map[string]string{
+				"file":      ".github/dependabot.yaml",
+				"path":      "schedule.interval",
+				"ecosystem": "gomod",
+				"directory": "/",
+				"indent":    "4",
+			},
+			expected: &BotConfigUpdater{
+				Bot:       Dependabot,
+				FilePath:  ".github/dependabot.yaml",
+				Path:      "schedule.interval",
+				Ecosystem: "gomod",
+				Directory: "/",
+				Indent:    4,
+			},
+		},
+		{
+			name: "ecosystem param with renovate",
+			bot:  Renovate,
+			params: map[string]string{
+				"path":      "schedule",
+				"ecosystem": "npm",
+			},
+			expectedErrorMsg: "the ecosystem and directory parameters can only be used with the dependabot updater",
+		},
+		{
+			name: "directory param without ecosystem",
+			bot:  Dependabot,
+			params: map[string]string{
+				"path":      "schedule.interval",
+				"directory": "/",
+			},
+			expectedErrorMsg: "the directory parameter can't be used without the ecosystem parameter",
+		},
+		{
+			name: "invalid path param",
+			bot:  Renovate,
+			params: map[string]string{
+				"path": "lockFileMaintenance..enabled",
+			},
+			expectedErrorMsg: "invalid path parameter lockFileMaintenance..enabled: empty key",
+		},
+		{
+			name:             "missing mandatory path param",
+			bot:              Renovate,
+			params:           map[string]string{},
+			expectedErrorMsg: "missing path parameter",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := newUpdater(test.bot, test.params, nil)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		files            map[string]string
+		updater          *BotConfigUpdater
+		expected         bool
+		expectedErrorMsg string
+		expectedFiles    map[string]string
+	}{
+		{
+			name: "set the renovate schedule",
+			files: map[string]string{
+				"renovate.json": `{
+    "extends": ["config:base"],
+    "schedule": ["at any time"],
+    "enabled": true
+}
+`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:    Renovate,
+				Path:   "schedule",
+				Valuer: value.StringValuer(`["before 6am on monday"]`),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"renovate.json": `{
+    "extends": [
+        "config:base"
+    ],
+    "schedule": [
+        "before 6am on monday"
+    ],
+    "enabled": true
+}
+`,
+			},
+		},
+		{
+			name: "append a renovate package rule",
+			files: map[string]string{
+				".github/renovate.json": `{
+  "extends": [
+    "config:base"
+  ],
+  "packageRules": [
+    {
+      "matchUpdateTypes": [
+        "patch"
+      ],
+      "automerge": true
+    }
+  ]
+}
+`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:    Renovate,
+				Path:   "packageRules",
+				Append: true,
+				Valuer: value.StringValuer(`{"matchPackageNames": ["lodash"], "enabled": false}`),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				".github/renovate.json": `{
+  "extends": [
+    "config:base"
+  ],
+  "packageRules": [
+    {
+      "matchUpdateTypes": [
+        "patch"
+      ],
+      "automerge": true
+    },
+    {
+      "matchPackageNames": [
+        "lodash"
+      ],
+      "enabled": false
+    }
+  ]
+}
+`,
+			},
+		},
+		{
+			name: "don't append an existing renovate package rule",
+			files: map[string]string{
+				"renovate.json": `{
+  "packageRules": [
+    {
+      "matchPackageNames": ["lodash"],
+      "enabled": false
+    }
+  ]
+}
+`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:    Renovate,
+				Path:   "packageRules",
+				Append: true,
+				Valuer: value.StringValuer(`{"enabled": false, "matchPackageNames": ["lodash"]}`),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"renovate.json": `{
+  "packageRules": [
+    {
+      "matchPackageNames": ["lodash"],
+      "enabled": false
+    }
+  ]
+}
+`,
+			},
+		},
+		{
+			name: "create a missing renovate list",
+			files: map[string]string{
+				"renovate.json": `{
+  "extends": ["config:base"]
+}`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:      Renovate,
+				FilePath: "renovate.json",
+				Path:     "packageRules",
+				Append:   true,
+				Valuer:   value.StringValuer(`{"matchDepTypes": ["devDependencies"], "automerge": true}`),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"renovate.json": `{
+  "extends": [
+    "config:base"
+  ],
+  "packageRules": [
+    {
+      "matchDepTypes": [
+        "devDependencies"
+      ],
+      "automerge": true
+    }
+  ]
+}`,
+			},
+		},
+		{
+			name: "set the dependabot schedule of an ecosystem",
+			files: map[string]string{
+				".github/dependabot.yml": `# Dependabot config
+version: 2
+updates:
+  - package-ecosystem: "npm" # javascript
+    directory: "/"
+    schedule:
+      interval: "weekly"
+  - package-ecosystem: gomod
+    directory: /
+    schedule:
+      interval: weekly
+`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:       Dependabot,
+				Path:      "schedule.interval",
+				Ecosystem: "npm",
+				Indent:    2,
+				Valuer:    value.StringValuer("daily"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				".github/dependabot.yml": `# Dependabot config
+version: 2
+updates:
+  - package-ecosystem: "npm" # javascript
+    directory: "/"
+    schedule:
+      interval: "daily"
+  - package-ecosystem: gomod
+    directory: /
+    schedule:
+      interval: weekly
+`,
+			},
+		},
+		{
+			name: "set a new dependabot field of all the ecosystem entries",
+			files: map[string]string{
+				".github/dependabot.yml": `version: 2
+updates:
+  - package-ecosystem: docker
+    directory: /app
+  - package-ecosystem: docker
+    directory: /worker
+`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:       Dependabot,
+				Path:      "open-pull-requests-limit",
+				Ecosystem: "docker",
+				Indent:    2,
+				Valuer:    value.StringValuer("5"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				".github/dependabot.yml": `version: 2
+updates:
+  - package-ecosystem: docker
+    directory: /app
+    open-pull-requests-limit: 5
+  - package-ecosystem: docker
+    directory: /worker
+    open-pull-requests-limit: 5
+`,
+			},
+		},
+		{
+			name: "no change to the dependabot schedule",
+			files: map[string]string{
+				".github/dependabot.yaml": `version: 2
+updates:
+  - package-ecosystem: gomod
+    directory: /
+    schedule:
+      interval: "daily"
+`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:       Dependabot,
+				Path:      "schedule.interval",
+				Ecosystem: "gomod",
+				Directory: "/",
+				Indent:    2,
+				Valuer:    value.StringValuer("daily"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				".github/dependabot.yaml": `version: 2
+updates:
+  - package-ecosystem: gomod
+    directory: /
+    schedule:
+      interval: "daily"
+`,
+			},
+		},
+		{
+			name: "unknown dependabot ecosystem",
+			files: map[string]string{
+				".github/dependabot.yml": `version: 2
+updates:
+  - package-ecosystem: gomod
+    directory: /
+`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:       Dependabot,
+				Path:      "schedule.interval",
+				Ecosystem: "pip",
+				Indent:    2,
+				Valuer:    value.StringValuer("daily"),
+			},
+			expectedErrorMsg: "failed to update file .github/dependabot.yml: no updates defined for ecosystem pip",
+		},
+		{
+			name: "append to a non-list value",
+			files: map[string]string{
+				"renovate.json": `{"schedule": "at any time"}`,
+			},
+			updater: &BotConfigUpdater{
+				Bot:    Renovate,
+				Path:   "schedule",
+				Append: true,
+				Valuer: value.StringValuer("before 6am"),
+			},
+			expectedErrorMsg: "failed to update file renovate.json: failed to set schedule: schedule is not a list",
+		},
+		{
+			name:  "no configuration file",
+			files: map[string]string{},
+			updater: &BotConfigUpdater{
+				Bot:    Renovate,
+				Path:   "enabled",
+				Valuer: value.StringValuer("false"),
+			},
+			expected: false,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := t.TempDir()
+			for filename, content := range test.files {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoPath, filename)), 0755))
+				require.NoErrorf(t, os.WriteFile(filepath.Join(repoPath, filename), []byte(content), 0644), "can't write file %s", filename)
+			}
+
+			actual, err := test.updater.Update(context.Background(), repoPath)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.False(t, actual)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+			for filename, expectedContent := range test.expectedFiles {
+				actualContent, err := os.ReadFile(filepath.Join(repoPath, filename))
+				require.NoErrorf(t, err, "can't read file %s", filename)
+				assert.Equalf(t, expectedContent, string(actualContent), "file %s doesn't match", filename)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// codeOwnersLocations are the locations of the CODEOWNERS file in a repository, in the order used by GitHub and GitLab
+var codeOwnersLocations = []string{
+	filepath.Join(".github", "CODEOWNERS"),
+	"CODEOWNERS",
+	filepath.Join("docs", "CODEOWNERS"),
+}
+
+// CodeOwnersUpdater is an updater that wraps another updater, and only keeps the changes made to the files owned by a specific owner - as defined in the CODEOWNERS file of the repository.
+// The changes made to the other files are reverted, so that an automation can't edit the files owned by other teams.
+type CodeOwnersUpdater struct {
+	Updater Updater
+	Owner   string
+}
+
+// newCodeOwnersUpdater wraps the given updater with a filter on the given owner - such as "@my-org/my-team"
+func newCodeOwnersUpdater(updater Updater, owner string) (*CodeOwnersUpdater, error) {
+	owner = strings.TrimSpace(owner)
+	if len(owner) == 0 {
+		return nil, errors.New("empty codeowner parameter")
+	}
+	return &CodeOwnersUpdater{
+		Updater: updater,
+		Owner:   owner,
+	}, nil
+}
+
+// Update runs the wrapped updater, then reverts the changes made to the files not owned by the owner, and returns true if some owned files have been changed
+func (u *CodeOwnersUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	rules, err := readCodeOwners(repoPath)
+	if err != nil {
+		return false, err
+	}
+
+	snapshot, err := snapshotWorktree(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to snapshot files in %s: %w", repoPath, err)
+	}
+
+	updated, err := u.Updater.Update(ctx, repoPath)
+	if err != nil || !updated {
+		return updated, err
+	}
+
+	files, err := snapshot.changedFiles(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to list the changed files in %s: %w", repoPath, err)
+	}
+
+	var ownedChanges int
+	for _, file := range files {
+		owners := rules.owners(filepath.ToSlash(file))
+		if hasOwner(owners, u.Owner) {
+			ownedChanges++
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"file":   file,
+			"owner":  u.Owner,
+			"owners": owners,
+		}).Warn("Skipping changes to a file not owned by the configured code owner")
+		original, err := snapshot.original(file)
+		if err != nil {
+			return false, err
+		}
+		if err = revertFile(repoPath, file, original); err != nil {
+			return false, fmt.Errorf("failed to revert the changes to file %s: %w", file, err)
+		}
+	}
+
+	if len(files) > 0 && ownedChanges == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *CodeOwnersUpdater) Message() (title, body string) {
+	return u.Updater.Message()
+}
+
+// String returns a string representation of the updater
+func (u *CodeOwnersUpdater) String() string {
+	return fmt.Sprintf("%s | CodeOwners[owner=%s]", u.Updater.String(), u.Owner)
+}
+
+// revertFile restores the original content of the given file - or deletes it if it didn't exist before the update.
+func revertFile(repoPath, file string, original *fileContent) error {
+	filePath := filepath.Join(repoPath, file)
+	if original == nil {
+		err := os.Remove(filePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	if original.mode&fs.ModeSymlink != 0 {
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return os.Symlink(string(original.data), filePath)
+	}
+	return os.WriteFile(filePath, original.data, original.mode.Perm())
+}
+
+// hasOwner returns true if the given owner is one of the owners - ignoring the case, as GitHub and GitLab do.
+func hasOwner(owners []string, owner string) bool {
+	for _, o := range owners {
+		if strings.EqualFold(o, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// codeOwnersRule is a line of a CODEOWNERS file: a pattern, and the owners of the files matching it
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeOwnersRules are the rules of a CODEOWNERS file, in the order of the file
+type codeOwnersRules []codeOwnersRule
+
+// owners returns the owners of the given file - using a slash-separated path relative to the repository.
+// The last matching rule wins, as with GitHub and GitLab.
+func (rules codeOwnersRules) owners(file string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(file) {
+			return rules[i].owners
+		}
+	}
+	return nil
+}
+
+// readCodeOwners reads and parses the CODEOWNERS file of the given repository
+func readCodeOwners(repoPath string) (codeOwnersRules, error) {
+	for _, location := range codeOwnersLocations {
+		data, err := os.ReadFile(filepath.Join(repoPath, location))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", location, err)
+		}
+		rules, err := parseCodeOwners(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", location, err)
+		}
+		return rules, nil
+	}
+	return nil, fmt.Errorf("no CODEOWNERS file found in %s", strings.Join(codeOwnersLocations, ", "))
+}
+
+// parseCodeOwners parses the content of a CODEOWNERS file.
+// GitLab sections - such as "[Documentation]" - are ignored, but their rules are kept.
+func parseCodeOwners(data []byte) (codeOwnersRules, error) {
+	var rules codeOwnersRules
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		pattern, err := codeOwnersPatternToRegexp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", fields[0], err)
+		}
+		rules = append(rules, codeOwnersRule{
+			pattern: pattern,
+			owners:  fields[1:],
+		})
+	}
+	return rules, scanner.Err()
+}
+
+// codeOwnersPatternToRegexp converts a CODEOWNERS pattern - which follows the gitignore syntax - to a regexp matching slash-separated relative paths.
+func codeOwnersPatternToRegexp(pattern string) (*regexp.Regexp, error) {
+	// a pattern with a slash - other than a trailing one - is relative to the root of the repository
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	directory := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	if directory {
+		// a directory pattern only matches the files inside the directory
+		expr.WriteString("/.*$")
+	} else {
+		// a pattern matches the files, and the content of the directories
+		expr.WriteString("(/.*)?$")
+	}
+	return regexp.Compile(expr.String())
+}
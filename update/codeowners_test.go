@@ -0,0 +1,243 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFilesUpdater is a stub updater that writes the given content to multiple files.
+type writeFilesUpdater struct {
+	files map[string]string
+}
+
+func (u *writeFilesUpdater) Update(_ context.Context, repoPath string) (bool, error) {
+	for file, content := range u.files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(repoPath, file)), 0755); err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (u *writeFilesUpdater) Message() (title, body string) {
+	return "Update files", ""
+}
+
+func (u *writeFilesUpdater) String() string {
+	return "WriteFiles"
+}
+
+func TestCodeOwnersUpdaterUpdate(t *testing.T) {
+	t.Parallel()
+
+	codeOwners := `# default owners
+*                       @my-org/platform
+
+/apps/team-a/           @my-org/team-a
+/apps/team-b/           @my-org/team-b
+/apps/shared/*.yaml     @my-org/team-a @my-org/team-b
+apps/**/secrets.yaml    @my-org/security
+`
+	files := map[string]string{
+		"apps/team-a/values.yaml":       "version: 1.0.0\n",
+		"apps/team-a/nested/config.yml": "version: 1.0.0\n",
+		"apps/team-a/secrets.yaml":      "password: secret\n",
+		"apps/team-b/values.yaml":       "version: 1.0.0\n",
+		"apps/shared/values.yaml":       "version: 1.0.0\n",
+		"README.md":                     "# readme\n",
+	}
+
+	tests := []struct {
+		name          string
+		codeOwners    string
+		location      string
+		owner         string
+		updates       map[string]string
+		expected      bool
+		expectedFiles map[string]string
+		expectedError string
+	}{
+		{
+			name:  "only the files owned by the team are updated",
+			owner: "@my-org/team-a",
+			updates: map[string]string{
+				"apps/team-a/values.yaml":       "version: 1.1.0\n",
+				"apps/team-a/nested/config.yml": "version: 1.1.0\n",
+				"apps/team-b/values.yaml":       "version: 1.1.0\n",
+				"apps/shared/values.yaml":       "version: 1.1.0\n",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"apps/team-a/values.yaml":       "version: 1.1.0\n",
+				"apps/team-a/nested/config.yml": "version: 1.1.0\n",
+				"apps/team-b/values.yaml":       "version: 1.0.0\n",
+				"apps/shared/values.yaml":       "version: 1.1.0\n",
+			},
+		},
+		{
+			name:  "the other team has its own files",
+			owner: "@My-Org/Team-B",
+			updates: map[string]string{
+				"apps/team-a/values.yaml": "version: 1.1.0\n",
+				"apps/team-b/values.yaml": "version: 1.1.0\n",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"apps/team-a/values.yaml": "version: 1.0.0\n",
+				"apps/team-b/values.yaml": "version: 1.1.0\n",
+			},
+		},
+		{
+			name:  "the last matching rule wins",
+			owner: "@my-org/team-a",
+			updates: map[string]string{
+				"apps/team-a/values.yaml":  "version: 1.1.0\n",
+				"apps/team-a/secrets.yaml": "password: changed\n",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"apps/team-a/values.yaml":  "version: 1.1.0\n",
+				"apps/team-a/secrets.yaml": "password: secret\n",
+			},
+		},
+		{
+			name:  "new files not owned are deleted",
+			owner: "@my-org/team-a",
+			updates: map[string]string{
+				"apps/team-a/new.yaml": "version: 1.1.0\n",
+				"apps/team-b/new.yaml": "version: 1.1.0\n",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"apps/team-a/new.yaml": "version: 1.1.0\n",
+			},
+		},
+		{
+			name:  "no owned files changed",
+			owner: "@my-org/team-a",
+			updates: map[string]string{
+				"apps/team-b/values.yaml": "version: 1.1.0\n",
+				"README.md":               "# new readme\n",
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"apps/team-b/values.yaml": "version: 1.0.0\n",
+				"README.md":               "# readme\n",
+			},
+		},
+		{
+			name:       "CODEOWNERS file at the root",
+			codeOwners: "/apps/ @my-org/team-a\n",
+			location:   "CODEOWNERS",
+			owner:      "@my-org/team-a",
+			updates: map[string]string{
+				"apps/team-b/values.yaml": "version: 1.1.0\n",
+				"README.md":               "# new readme\n",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"apps/team-b/values.yaml": "version: 1.1.0\n",
+				"README.md":               "# readme\n",
+			},
+		},
+		{
+			name:          "no CODEOWNERS file",
+			location:      "-",
+			owner:         "@my-org/team-a",
+			expectedError: "no CODEOWNERS file found in .github/CODEOWNERS, CODEOWNERS, docs/CODEOWNERS",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := t.TempDir()
+			for file, content := range files {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoPath, file)), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644))
+			}
+			location, content := filepath.Join(".github", "CODEOWNERS"), codeOwners
+			if len(test.location) > 0 {
+				location = test.location
+			}
+			if len(test.codeOwners) > 0 {
+				content = test.codeOwners
+			}
+			if location != "-" {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoPath, location)), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(repoPath, location), []byte(content), 0644))
+			}
+
+			commitRepository(t, repoPath)
+
+			updater := &CodeOwnersUpdater{
+				Updater: &writeFilesUpdater{files: test.updates},
+				Owner:   test.owner,
+			}
+			updated, err := updater.Update(context.Background(), repoPath)
+			if len(test.expectedError) > 0 {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, updated)
+
+			for file := range test.updates {
+				expectedContent, expected := test.expectedFiles[file]
+				actualContent, err := os.ReadFile(filepath.Join(repoPath, file))
+				if !expected {
+					assert.ErrorIs(t, err, os.ErrNotExist, file)
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, expectedContent, string(actualContent), file)
+			}
+		})
+	}
+}
+
+func TestCodeOwnersRulesOwners(t *testing.T) {
+	t.Parallel()
+	rules, err := parseCodeOwners([]byte(`# comment
+*.js          @js-owner # inline comment
+/docs/        @docs-owner
+docs/**/*.md  @markdown-owner
+build/logs/   @logs-owner
+
+[Section]
+config        @config-owner
+/no-owner.txt
+`))
+	require.NoError(t, err)
+
+	tests := map[string][]string{
+		"main.js":                    {"@js-owner"},
+		"src/app/main.js":            {"@js-owner"},
+		"docs/index.html":            {"@docs-owner"},
+		"docs/guide/intro.md":        {"@markdown-owner"},
+		"docs/intro.md":              {"@markdown-owner"},
+		"src/docs/index.html":        nil,
+		"build/logs/output.log":      {"@logs-owner"},
+		"sub/build/logs/output.log":  nil,
+		"config":                     {"@config-owner"},
+		"src/config/settings.yaml":   {"@config-owner"},
+		"no-owner.txt":               {},
+		"src/no-owner.txt":           nil,
+		"src/main.go":                nil,
+		"src/main.jsx":               nil,
+		"build/logs":                 nil,
+		"apps/main.js/something.txt": {"@js-owner"},
+	}
+	for file, expected := range tests {
+		assert.Equal(t, expected, rules.owners(file), file)
+	}
+}
@@ -0,0 +1,165 @@
+// Package flux provides an updater for Flux custom resources - such as HelmRelease or OCIRepository - based on the YAML updater.
+package flux
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/dailymotion-oss/octopilot/update/yaml"
+)
+
+// resource is a kind of Flux custom resource, with the API group of the kind and the path of the field to update
+type resource struct {
+	Group string
+	Path  string
+	// ImagePath is used instead of Path when an image name is given - to update the tag of this image
+	ImagePath string
+}
+
+// resources are the supported Flux custom resources, indexed by their kind
+var resources = map[string]resource{
+	"HelmRelease": {
+		Group: "helm.toolkit.fluxcd.io",
+		Path:  ".spec.chart.spec.version",
+	},
+	"HelmChart": {
+		Group: "source.toolkit.fluxcd.io",
+		Path:  ".spec.version",
+	},
+	"OCIRepository": {
+		Group: "source.toolkit.fluxcd.io",
+		Path:  ".spec.ref.tag",
+	},
+	"GitRepository": {
+		Group: "source.toolkit.fluxcd.io",
+		Path:  ".spec.ref.tag",
+	},
+	"ImagePolicy": {
+		Group: "image.toolkit.fluxcd.io",
+		Path:  ".spec.policy.semver.range",
+	},
+	"Kustomization": {
+		Group:     "kustomize.toolkit.fluxcd.io",
+		ImagePath: ".spec.images[] | select(.name == %q) | .newTag",
+	},
+}
+
+// FluxUpdater is an updater for Flux custom resources: the chart version of a HelmRelease, the tag of an OCIRepository, the image tag of a Kustomization, ...
+// It is a preset of the YAML updater: the path to update is built from the kind of the resource, and the resource is selected by its name - in multi-documents files.
+type FluxUpdater struct {
+	*yaml.YamlUpdater
+	Kind      string
+	Name      string
+	Namespace string
+	ImageName string
+}
+
+// NewUpdater builds a new Flux updater from the given parameters and valuer
+func NewUpdater(params map[string]string, valuer value.Valuer) (*FluxUpdater, error) {
+	updater := &FluxUpdater{
+		Kind:      params["kind"],
+		Name:      params["name"],
+		Namespace: params["namespace"],
+		ImageName: params["image"],
+	}
+
+	if len(updater.Kind) == 0 {
+		return nil, errors.New("missing kind parameter")
+	}
+	kind, res, found := kindResource(updater.Kind)
+	if !found {
+		return nil, fmt.Errorf("unsupported kind %s: must be one of %s", updater.Kind, strings.Join(supportedKinds(), ", "))
+	}
+	updater.Kind = kind
+	if _, found := params["path"]; found {
+		return nil, errors.New("the path parameter can't be used with the flux updater - use the kind parameter instead")
+	}
+	switch {
+	case len(res.ImagePath) > 0 && len(updater.ImageName) == 0:
+		return nil, fmt.Errorf("missing image parameter for kind %s", updater.Kind)
+	case len(res.ImagePath) == 0 && len(updater.ImageName) > 0:
+		return nil, fmt.Errorf("the image parameter can't be used with kind %s", updater.Kind)
+	}
+
+	yamlParams := make(map[string]string, len(params)+1)
+	for key, value := range params {
+		switch key {
+		case "kind", "name", "namespace", "image":
+		default:
+			yamlParams[key] = value
+		}
+	}
+	yamlParams["path"] = updater.path(res)
+
+	var err error
+	updater.YamlUpdater, err = yaml.NewUpdater(yamlParams, valuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return updater, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *FluxUpdater) Message() (title, body string) {
+	title = fmt.Sprintf("Update %s %s", u.FilePath, u.resourceDescription())
+	body = fmt.Sprintf("Updating Flux %s in file(s) `%s`", u.resourceDescription(), u.FilePath)
+	if len(u.ImageName) > 0 {
+		body = fmt.Sprintf("Updating the tag of image `%s` of Flux %s in file(s) `%s`", u.ImageName, u.resourceDescription(), u.FilePath)
+	}
+	return title, body
+}
+
+// String returns a string representation of the updater
+func (u *FluxUpdater) String() string {
+	return fmt.Sprintf("Flux[kind=%s,name=%s,namespace=%s,image=%s,file=%s]", u.Kind, u.Name, u.Namespace, u.ImageName, u.FilePath)
+}
+
+func (u *FluxUpdater) resourceDescription() string {
+	if len(u.Name) > 0 {
+		return u.Kind + " " + u.Name
+	}
+	return u.Kind
+}
+
+// path returns the yq expression of the field to update, only in the documents of the given kind - and name, and namespace - so that the other documents are left untouched
+func (u *FluxUpdater) path(res resource) string {
+	conditions := []string{
+		fmt.Sprintf(".kind == %q", u.Kind),
+		fmt.Sprintf(`(.apiVersion | test("^%s/"))`, strings.ReplaceAll(res.Group, ".", "[.]")),
+	}
+	if len(u.Name) > 0 {
+		conditions = append(conditions, fmt.Sprintf(".metadata.name == %q", u.Name))
+	}
+	if len(u.Namespace) > 0 {
+		conditions = append(conditions, fmt.Sprintf(".metadata.namespace == %q", u.Namespace))
+	}
+
+	path := res.Path
+	if len(u.ImageName) > 0 {
+		path = fmt.Sprintf(res.ImagePath, u.ImageName)
+	}
+	return fmt.Sprintf("(select(%s) | %s)", strings.Join(conditions, " and "), path)
+}
+
+// kindResource returns the normalized kind and the resource of the given kind - ignoring the case, so that "helmrelease" is supported.
+func kindResource(kind string) (string, resource, bool) {
+	for k, res := range resources {
+		if strings.EqualFold(k, kind) {
+			return k, res, true
+		}
+	}
+	return "", resource{}, false
+}
+
+func supportedKinds() []string {
+	kinds := make([]string, 0, len(resources))
+	for kind := range resources {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
@@ -0,0 +1,318 @@
+package flux
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/dailymotion-oss/octopilot/update/yaml"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpdater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		params           map[string]string
+		expected         *FluxUpdater
+		expectedErrorMsg string
+	}{
+		{
+			name: "helm release",
+			params: map[string]string{
+				"file": "apps/podinfo.yaml",
+				"kind": "helmrelease",
+				"name": "podinfo",
+			},
+			expected: &FluxUpdater{
+				YamlUpdater: &yaml.YamlUpdater{
+					FilePath: "apps/podinfo.yaml",
+					Path:     `(select(.kind == "HelmRelease" and (.apiVersion | test("^helm[.]toolkit[.]fluxcd[.]io/")) and .metadata.name == "podinfo") | .spec.chart.spec.version)`,
+					Indent:   2,
+				},
+				Kind: "HelmRelease",
+				Name: "podinfo",
+			},
+		},
+		{
+			name: "kustomization image in a namespace",
+			params: map[string]string{
+				"file":      "clusters/prod/apps.yaml",
+				"kind":      "Kustomization",
+				"name":      "apps",
+				"namespace": "flux-system",
+				"image":     "ghcr.io/stefanprodan/podinfo",
+				"monotonic": "semver",
+			},
+			expected: &FluxUpdater{
+				YamlUpdater: &yaml.YamlUpdater{
+					FilePath:  "clusters/prod/apps.yaml",
+					Path:      `(select(.kind == "Kustomization" and (.apiVersion | test("^kustomize[.]toolkit[.]fluxcd[.]io/")) and .metadata.name == "apps" and .metadata.namespace == "flux-system") | .spec.images[] | select(.name == "ghcr.io/stefanprodan/podinfo") | .newTag)`,
+					Indent:    2,
+					Monotonic: "semver",
+				},
+				Kind:      "Kustomization",
+				Name:      "apps",
+				Namespace: "flux-system",
+				ImageName: "ghcr.io/stefanprodan/podinfo",
+			},
+		},
+		{
+			name: "missing kind",
+			params: map[string]string{
+				"file": "apps/podinfo.yaml",
+			},
+			expectedErrorMsg: "missing kind parameter",
+		},
+		{
+			name: "unsupported kind",
+			params: map[string]string{
+				"file": "apps/podinfo.yaml",
+				"kind": "Deployment",
+			},
+			expectedErrorMsg: "unsupported kind Deployment: must be one of GitRepository, HelmChart, HelmRelease, ImagePolicy, Kustomization, OCIRepository",
+		},
+		{
+			name: "kustomization without image",
+			params: map[string]string{
+				"file": "clusters/prod/apps.yaml",
+				"kind": "Kustomization",
+			},
+			expectedErrorMsg: "missing image parameter for kind Kustomization",
+		},
+		{
+			name: "helm release with image",
+			params: map[string]string{
+				"file":  "apps/podinfo.yaml",
+				"kind":  "HelmRelease",
+				"image": "ghcr.io/stefanprodan/podinfo",
+			},
+			expectedErrorMsg: "the image parameter can't be used with kind HelmRelease",
+		},
+		{
+			name: "path param",
+			params: map[string]string{
+				"file": "apps/podinfo.yaml",
+				"kind": "HelmRelease",
+				"path": ".spec.values.image.tag",
+			},
+			expectedErrorMsg: "the path parameter can't be used with the flux updater - use the kind parameter instead",
+		},
+		{
+			name: "missing file",
+			params: map[string]string{
+				"kind": "HelmRelease",
+			},
+			expectedErrorMsg: "missing file parameter",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := NewUpdater(test.params, nil)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	const helmReleases = `# flux apps
+apiVersion: source.toolkit.fluxcd.io/v1beta2
+kind: HelmRepository
+metadata:
+  name: podinfo
+spec:
+  url: https://stefanprodan.github.io/podinfo
+---
+apiVersion: helm.toolkit.fluxcd.io/v2beta1
+kind: HelmRelease
+metadata:
+  name: podinfo
+spec:
+  interval: 5m
+  chart:
+    spec:
+      chart: podinfo
+      version: "6.2.0" # the chart version
+      sourceRef:
+        kind: HelmRepository
+        name: podinfo
+---
+apiVersion: helm.toolkit.fluxcd.io/v2beta1
+kind: HelmRelease
+metadata:
+  name: redis
+spec:
+  chart:
+    spec:
+      chart: redis
+      version: "17.0.0"
+`
+
+	tests := []struct {
+		name          string
+		files         map[string]string
+		params        map[string]string
+		value         string
+		expected      bool
+		expectedFiles map[string]string
+	}{
+		{
+			name: "bump the version of a helm release",
+			files: map[string]string{
+				"helm-release-bump.yaml": helmReleases,
+			},
+			params: map[string]string{
+				"file": "helm-release-bump.yaml",
+				"kind": "HelmRelease",
+				"name": "podinfo",
+			},
+			value:    "6.3.0",
+			expected: true,
+			expectedFiles: map[string]string{
+				"helm-release-bump.yaml": `# flux apps
+apiVersion: source.toolkit.fluxcd.io/v1beta2
+kind: HelmRepository
+metadata:
+  name: podinfo
+spec:
+  url: https://stefanprodan.github.io/podinfo
+---
+apiVersion: helm.toolkit.fluxcd.io/v2beta1
+kind: HelmRelease
+metadata:
+  name: podinfo
+spec:
+  interval: 5m
+  chart:
+    spec:
+      chart: podinfo
+      version: "6.3.0" # the chart version
+      sourceRef:
+        kind: HelmRepository
+        name: podinfo
+---
+apiVersion: helm.toolkit.fluxcd.io/v2beta1
+kind: HelmRelease
+metadata:
+  name: redis
+spec:
+  chart:
+    spec:
+      chart: redis
+      version: "17.0.0"
+`,
+			},
+		},
+		{
+			name: "helm release already up to date",
+			files: map[string]string{
+				"helm-release-no-changes.yaml": helmReleases,
+			},
+			params: map[string]string{
+				"file": "helm-release-no-changes.yaml",
+				"kind": "HelmRelease",
+				"name": "podinfo",
+			},
+			value:    "6.2.0",
+			expected: false,
+			expectedFiles: map[string]string{
+				"helm-release-no-changes.yaml": helmReleases,
+			},
+		},
+		{
+			name: "unknown helm release",
+			files: map[string]string{
+				"helm-release-unknown.yaml": helmReleases,
+			},
+			params: map[string]string{
+				"file": "helm-release-unknown.yaml",
+				"kind": "HelmRelease",
+				"name": "unknown",
+			},
+			value:    "1.0.0",
+			expected: false,
+			expectedFiles: map[string]string{
+				"helm-release-unknown.yaml": helmReleases,
+			},
+		},
+		{
+			name: "update the image tag of a kustomization",
+			files: map[string]string{
+				"kustomization.yaml": `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: apps
+  namespace: flux-system
+spec:
+  path: ./apps
+  images:
+    - name: ghcr.io/stefanprodan/podinfo
+      newTag: 6.2.0
+    - name: redis
+      newTag: "7.0"
+`,
+			},
+			params: map[string]string{
+				"file":  "kustomization.yaml",
+				"kind":  "Kustomization",
+				"name":  "apps",
+				"image": "ghcr.io/stefanprodan/podinfo",
+			},
+			value:    "6.3.0",
+			expected: true,
+			expectedFiles: map[string]string{
+				"kustomization.yaml": `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: apps
+  namespace: flux-system
+spec:
+  path: ./apps
+  images:
+    - name: ghcr.io/stefanprodan/podinfo
+      newTag: 6.3.0
+    - name: redis
+      newTag: "7.0"
+`,
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			for filename, content := range test.files {
+				err := os.WriteFile(filepath.Join("testdata", filename), []byte(content), 0644)
+				require.NoErrorf(t, err, "can't write testdata file %s", filename)
+			}
+
+			updater, err := NewUpdater(test.params, value.StringValuer(test.value))
+			require.NoError(t, err)
+			actual, err := updater.Update(context.Background(), "testdata")
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+
+			for filename, expectedContent := range test.expectedFiles {
+				actualContent, err := os.ReadFile(filepath.Join("testdata", filename))
+				require.NoErrorf(t, err, "can't read testdata file %s", filename)
+				assert.Equalf(t, expectedContent, string(actualContent), "testdata file %s doesn't match", filename)
+			}
+		})
+	}
+}
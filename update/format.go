@@ -0,0 +1,110 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/cosiner/argv"
+)
+
+// FormatUpdater is an updater that wraps another updater, and runs an external formatter on the files changed by the wrapped updater.
+// The formatter is run with the path of each changed file - relative to the repository - as its last argument, from the root of the repository.
+type FormatUpdater struct {
+	Updater Updater
+	Command string
+	Args    []string
+}
+
+// newFormatUpdater wraps the given updater with a formatter defined by the given command line - such as "prettier --write".
+func newFormatUpdater(updater Updater, commandLine string) (*FormatUpdater, error) {
+	if len(strings.TrimSpace(commandLine)) == 0 {
+		return nil, errors.New("empty format-command parameter")
+	}
+
+	args, err := argv.Argv(commandLine, func(backquoted string) (string, error) {
+		return backquoted, nil
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert format-command '%s' with argv: %w", commandLine, err)
+	}
+	if len(args) != 1 || len(args[0]) == 0 {
+		return nil, fmt.Errorf("invalid format-command '%s': must be a single command", commandLine)
+	}
+
+	return &FormatUpdater{
+		Updater: updater,
+		Command: args[0][0],
+		Args:    args[0][1:],
+	}, nil
+}
+
+// Update runs the wrapped updater, then the formatter on the changed files, and returns true if the formatted files differ from the original ones
+func (u *FormatUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	snapshot, err := snapshotWorktree(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to snapshot files in %s: %w", repoPath, err)
+	}
+
+	updated, err := u.Updater.Update(ctx, repoPath)
+	if err != nil || !updated {
+		return updated, err
+	}
+
+	files, err := snapshot.changedFiles(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to list the changed files in %s: %w", repoPath, err)
+	}
+
+	for _, file := range files {
+		regular, err := isRegularFile(repoPath, file)
+		if err != nil {
+			return false, err
+		}
+		if !regular {
+			continue
+		}
+		if err = u.format(ctx, repoPath, file); err != nil {
+			return false, err
+		}
+	}
+
+	// the formatter might have reverted the changes made by the updater
+	formatted, err := snapshot.changedFiles(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to list the changed files in %s: %w", repoPath, err)
+	}
+	if len(files) > 0 && len(formatted) == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *FormatUpdater) Message() (title, body string) {
+	return u.Updater.Message()
+}
+
+// String returns a string representation of the updater
+func (u *FormatUpdater) String() string {
+	return fmt.Sprintf("%s | Format[cmd=%s,args=%v]", u.Updater.String(), u.Command, u.Args)
+}
+
+func (u *FormatUpdater) format(ctx context.Context, repoPath, file string) error {
+	var output bytes.Buffer
+	args := append(append([]string{}, u.Args...), file)
+	cmd := exec.CommandContext(ctx, u.Command, args...) //nolint: gosec // the format command is provided by the user
+	cmd.Dir = repoPath
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("failed to format %s with cmd '%s' and args %v - got output [%s]: %w", file, u.Command, u.Args, strings.TrimSpace(output.String()), err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFileUpdater is a stub updater that writes the given content to a file.
+type writeFileUpdater struct {
+	file    string
+	content string
+}
+
+func (u *writeFileUpdater) Update(_ context.Context, repoPath string) (bool, error) {
+	return true, os.WriteFile(filepath.Join(repoPath, u.file), []byte(u.content), 0644)
+}
+
+func (u *writeFileUpdater) Message() (title, body string) {
+	return "Update " + u.file, ""
+}
+
+func (u *writeFileUpdater) String() string {
+	return "WriteFile[file=" + u.file + "]"
+}
+
+// commitRepository initializes a git repository at the given path, and commits all its files - as the wrapping updaters only see the changes of a git worktree.
+func commitRepository(t *testing.T, repoPath string) {
+	t.Helper()
+	gitRepo, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+	workTree, err := gitRepo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, workTree.AddWithOptions(&git.AddOptions{All: true}))
+	_, err = workTree.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+}
+
+func TestFormatUpdaterUpdate(t *testing.T) {
+	t.Parallel()
+
+	// stub formatter normalizing the spaces around the '=' sign
+	formatter := filepath.Join(t.TempDir(), "format.sh")
+	require.NoError(t, os.WriteFile(formatter, []byte("#!/bin/sh\nsed -i 's/ *= */ = /' \"$1\"\n"), 0755))
+
+	tests := []struct {
+		name             string
+		files            map[string]string
+		updater          *FormatUpdater
+		expected         bool
+		expectedFiles    map[string]string
+		expectedErrorMsg string
+	}{
+		{
+			name: "changed file is formatted",
+			files: map[string]string{
+				"config.properties": "version = 1.0.0\n",
+				"other.properties":  "key=value\n",
+			},
+			updater: &FormatUpdater{
+				Updater: &writeFileUpdater{file: "config.properties", content: "version=1.1.0\n"},
+				Command: formatter,
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"config.properties": "version = 1.1.0\n",
+				"other.properties":  "key=value\n",
+			},
+		},
+		{
+			name: "formatting reverts the changes",
+			files: map[string]string{
+				"config.properties": "version = 1.0.0\n",
+			},
+			updater: &FormatUpdater{
+				Updater: &writeFileUpdater{file: "config.properties", content: "version=1.0.0\n"},
+				Command: formatter,
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"config.properties": "version = 1.0.0\n",
+			},
+		},
+		{
+			name: "formatter failure",
+			files: map[string]string{
+				"config.properties": "version = 1.0.0\n",
+			},
+			updater: &FormatUpdater{
+				Updater: &writeFileUpdater{file: "config.properties", content: "version=1.1.0\n"},
+				Command: "sh",
+				Args:    []string{"-c", "echo invalid syntax; exit 1", "sh"},
+			},
+			expectedErrorMsg: "failed to format config.properties with cmd 'sh' and args [-c echo invalid syntax; exit 1 sh] - got output [invalid syntax]: exit status 1",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := t.TempDir()
+			for file, content := range test.files {
+				require.NoError(t, os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644))
+			}
+
+			commitRepository(t, repoPath)
+
+			updated, err := test.updater.Update(context.Background(), repoPath)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, updated)
+			for file, expectedContent := range test.expectedFiles {
+				content, err := os.ReadFile(filepath.Join(repoPath, file))
+				require.NoError(t, err)
+				assert.Equal(t, expectedContent, string(content), file)
+			}
+		})
+	}
+}
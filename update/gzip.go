@@ -0,0 +1,177 @@
+package update
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+)
+
+// gzipContentFile is the name of the file - with the extension of the decompressed file - used by the inner updater
+const gzipContentFile = "content"
+
+// GzipUpdater is an updater that transparently decompresses gzip-compressed files, applies an inner updater to their content, and compresses them again.
+// The files are compressed deterministically - without modification time - so that the same content always produces the same bytes.
+type GzipUpdater struct {
+	FilePath string
+	Level    int
+	Updater  Updater
+	// contentFile is the name of the decompressed file given to the inner updater
+	contentFile string
+}
+
+// newGzipUpdater builds a new gzip updater from the given parameters and valuer: the parameters which are not specific to the gzip updater are given to the inner updater.
+func newGzipUpdater(params map[string]string, valuer value.Valuer) (*GzipUpdater, error) {
+	u := &GzipUpdater{
+		FilePath: params["file"],
+		Level:    gzip.DefaultCompression,
+	}
+	if len(u.FilePath) == 0 {
+		return nil, errors.New("missing file parameter")
+	}
+
+	if level := params["level"]; len(level) > 0 {
+		var err error
+		u.Level, err = strconv.Atoi(level)
+		if err != nil || u.Level < gzip.HuffmanOnly || u.Level > gzip.BestCompression {
+			return nil, fmt.Errorf("invalid level %s: must be a compression level between %d and %d", level, gzip.HuffmanOnly, gzip.BestCompression)
+		}
+	}
+
+	innerUpdaterName := params["updater"]
+	if len(innerUpdaterName) == 0 {
+		innerUpdaterName = "yaml"
+	}
+	if innerUpdaterName == "gzip" || innerUpdaterName == "exec" {
+		return nil, fmt.Errorf("invalid updater %s: can't be used inside a gzip updater", innerUpdaterName)
+	}
+
+	u.contentFile = gzipContentFile + filepath.Ext(strings.TrimSuffix(u.FilePath, ".gz"))
+	innerParams := make(map[string]string, len(params))
+	for key, value := range params {
+		switch key {
+		case "file", "level", "updater":
+		default:
+			innerParams[key] = value
+		}
+	}
+	innerParams["file"] = u.contentFile
+
+	var err error
+	u.Updater, err = newUpdater(innerUpdaterName, innerParams, valuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the inner updater %s: %w", innerUpdaterName, err)
+	}
+
+	return u, nil
+}
+
+// Update updates the repository cloned at the given path, and returns true if changes have been made
+func (u *GzipUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	filePaths, err := filepath.Glob(filepath.Join(repoPath, u.FilePath))
+	if err != nil {
+		return false, fmt.Errorf("failed to expand glob pattern %s: %w", u.FilePath, err)
+	}
+
+	var updated bool
+	for _, filePath := range filePaths {
+		relFilePath, err := filepath.Rel(repoPath, filePath)
+		if err != nil {
+			relFilePath = filePath
+		}
+
+		fileUpdated, err := u.updateFile(ctx, filePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to update file %s: %w", relFilePath, err)
+		}
+		if fileUpdated {
+			updated = true
+		}
+	}
+
+	return updated, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *GzipUpdater) Message() (title, body string) {
+	title = fmt.Sprintf("Update %s", u.FilePath)
+	_, innerBody := u.Updater.Message()
+	body = fmt.Sprintf("Updating gzip-compressed file(s) `%s`: %s", u.FilePath, innerBody)
+	return title, body
+}
+
+// String returns a string representation of the updater
+func (u *GzipUpdater) String() string {
+	return fmt.Sprintf("Gzip[file=%s,level=%d,updater=%s]", u.FilePath, u.Level, u.Updater.String())
+}
+
+func (u *GzipUpdater) updateFile(ctx context.Context, filePath string) (bool, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to access file: %w", err)
+	}
+
+	compressed, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return false, fmt.Errorf("failed to decompress file: %w", err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return false, fmt.Errorf("failed to decompress file: %w", err)
+	}
+	header := reader.Header
+
+	tmpDir, err := os.MkdirTemp("", "octopilot-gzip")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	contentPath := filepath.Join(tmpDir, u.contentFile)
+	if err = os.WriteFile(contentPath, content, 0600); err != nil {
+		return false, fmt.Errorf("failed to write decompressed content: %w", err)
+	}
+	if _, err = u.Updater.Update(ctx, tmpDir); err != nil {
+		return false, err
+	}
+	updatedContent, err := os.ReadFile(contentPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read updated content: %w", err)
+	}
+
+	if bytes.Equal(content, updatedContent) {
+		return false, nil
+	}
+
+	var buffer bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buffer, u.Level)
+	if err != nil {
+		return false, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	// keep the original file name and comment, but not the modification time - for deterministic output
+	writer.Name = header.Name
+	writer.Comment = header.Comment
+	if _, err = writer.Write(updatedContent); err != nil {
+		return false, fmt.Errorf("failed to compress updated content: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return false, fmt.Errorf("failed to compress updated content: %w", err)
+	}
+
+	if err = os.WriteFile(filePath, buffer.Bytes(), fileInfo.Mode()); err != nil {
+		return false, fmt.Errorf("failed to write updated content: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,179 @@
+package update
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipContent(t *testing.T, content string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Name = "config.json"
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func gunzipFile(t *testing.T, filePath string) (string, *gzip.Header) {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(content), &reader.Header
+}
+
+func TestGzipUpdaterUpdate(t *testing.T) {
+	t.Parallel()
+
+	const original = `{
+  "app": {
+    "name": "my-app",
+    "version": "1.0.0"
+  }
+}
+`
+	tests := []struct {
+		name            string
+		params          map[string]string
+		valuer          value.Valuer
+		expected        bool
+		expectedContent string
+	}{
+		{
+			name: "update a JSON field with the yq updater",
+			params: map[string]string{
+				"file":       "config.json.gz",
+				"updater":    "yq",
+				"expression": `.app.version = "1.1.0"`,
+				"json":       "true",
+			},
+			expected: true,
+			expectedContent: `{
+  "app": {
+    "name": "my-app",
+    "version": "1.1.0"
+  }
+}
+`,
+		},
+		{
+			name: "update a JSON field with the regex updater",
+			params: map[string]string{
+				"file":    "config.json.gz",
+				"updater": "regex",
+				"pattern": `"version": "(.*)"`,
+			},
+			valuer:   value.StringValuer("2.0.0"),
+			expected: true,
+			expectedContent: `{
+  "app": {
+    "name": "my-app",
+    "version": "2.0.0"
+  }
+}
+`,
+		},
+		{
+			name: "no changes",
+			params: map[string]string{
+				"file":    "config.json.gz",
+				"updater": "regex",
+				"pattern": `"version": "(.*)"`,
+			},
+			valuer:          value.StringValuer("1.0.0"),
+			expected:        false,
+			expectedContent: original,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := t.TempDir()
+			filePath := filepath.Join(repoPath, "config.json.gz")
+			originalData := gzipContent(t, original)
+			require.NoError(t, os.WriteFile(filePath, originalData, 0644))
+
+			updater, err := newGzipUpdater(test.params, test.valuer)
+			require.NoError(t, err)
+			updated, err := updater.Update(context.Background(), repoPath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, updated)
+
+			content, header := gunzipFile(t, filePath)
+			assert.Equal(t, test.expectedContent, content)
+			assert.Equal(t, "config.json", header.Name)
+			if !test.expected {
+				data, err := os.ReadFile(filePath)
+				require.NoError(t, err)
+				assert.Equal(t, originalData, data, "unchanged file must keep the same bytes")
+				return
+			}
+			assert.True(t, header.ModTime.IsZero() || header.ModTime.Unix() == 0)
+
+			// compressing the same content again produces the same bytes
+			firstData, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filePath, originalData, 0644))
+			_, err = updater.Update(context.Background(), repoPath)
+			require.NoError(t, err)
+			secondData, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			assert.Equal(t, firstData, secondData)
+		})
+	}
+}
+
+func TestNewGzipUpdater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		params           map[string]string
+		expectedErrorMsg string
+	}{
+		{
+			name:             "missing file",
+			params:           map[string]string{"path": "version"},
+			expectedErrorMsg: "missing file parameter",
+		},
+		{
+			name:             "invalid level",
+			params:           map[string]string{"file": "config.yaml.gz", "path": "version", "level": "12"},
+			expectedErrorMsg: "invalid level 12: must be a compression level between -2 and 9",
+		},
+		{
+			name:             "invalid inner updater",
+			params:           map[string]string{"file": "config.yaml.gz", "updater": "gzip"},
+			expectedErrorMsg: "invalid updater gzip: can't be used inside a gzip updater",
+		},
+		{
+			name:             "invalid inner updater params",
+			params:           map[string]string{"file": "config.yaml.gz"},
+			expectedErrorMsg: "failed to create the inner updater yaml: missing path parameter",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := newGzipUpdater(test.params, value.StringValuer("1.0.0"))
+			require.EqualError(t, err, test.expectedErrorMsg)
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package helm
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"strconv"
 
+	"github.com/dailymotion-oss/octopilot/internal/eol"
 	"github.com/dailymotion-oss/octopilot/update/value"
 	"gopkg.in/yaml.v3"
 )
@@ -17,6 +19,7 @@ import (
 type HelmUpdater struct {
 	Dependency string
 	Indent     int
+	EOL        eol.Mode
 	Valuer     value.Valuer
 }
 
@@ -34,6 +37,12 @@ func NewUpdater(params map[string]string, valuer value.Valuer) (*HelmUpdater, er
 		updater.Indent = 2
 	}
 
+	var err error
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
+	}
+
 	updater.Valuer = valuer
 
 	return updater, nil
@@ -80,7 +89,7 @@ func (u *HelmUpdater) updateChartDependenciesFile(filePath string, version strin
 	}
 
 	var rootNode yaml.Node
-	err = yaml.Unmarshal(data, &rootNode)
+	err = yaml.Unmarshal(eol.Convert(data, eol.LF), &rootNode)
 	if err != nil {
 		return false, fmt.Errorf("failed to unmarshal YAML file %s: %w", filePath, err)
 	}
@@ -90,13 +99,8 @@ func (u *HelmUpdater) updateChartDependenciesFile(filePath string, version strin
 		return false, nil
 	}
 
-	f, err := os.Create(filePath)
-	if err != nil {
-		return false, fmt.Errorf("failed to create file %s: %w", filePath, err)
-	}
-	defer f.Close()
-
-	enc := yaml.NewEncoder(f)
+	var buffer bytes.Buffer
+	enc := yaml.NewEncoder(&buffer)
 	enc.SetIndent(u.Indent)
 	err = enc.Encode(&rootNode)
 	if err != nil {
@@ -107,6 +111,11 @@ func (u *HelmUpdater) updateChartDependenciesFile(filePath string, version strin
 		return false, fmt.Errorf("failed to close the YAML encoder for %s: %w", filePath, err)
 	}
 
+	err = os.WriteFile(filePath, eol.Apply(u.EOL, data, buffer.Bytes()), 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to write file %s: %w", filePath, err)
+	}
+
 	return updated, nil
 }
 
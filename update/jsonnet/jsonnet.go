@@ -0,0 +1,149 @@
+// Package jsonnet provides an updater that updates the values defined in jsonnet source files.
+package jsonnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	"github.com/dailymotion-oss/octopilot/update/value"
+)
+
+// JsonnetUpdater is an updater that sets the value of a local binding - or the default value of a top-level argument - in jsonnet source files.
+// The values are replaced in place, so the formatting and comments are preserved.
+type JsonnetUpdater struct {
+	FilePath string
+	Local    string
+	TLA      string
+	EOL      eol.Mode
+	Valuer   value.Valuer
+}
+
+// NewUpdater builds a new jsonnet updater from the given parameters and valuer
+func NewUpdater(params map[string]string, valuer value.Valuer) (*JsonnetUpdater, error) {
+	updater := &JsonnetUpdater{}
+
+	updater.FilePath = params["file"]
+	if len(updater.FilePath) == 0 {
+		return nil, errors.New("missing file parameter")
+	}
+
+	updater.Local = params["local"]
+	updater.TLA = params["tla"]
+	if len(updater.Local) == 0 && len(updater.TLA) == 0 {
+		return nil, errors.New("missing local or tla parameter")
+	}
+	if len(updater.Local) > 0 && len(updater.TLA) > 0 {
+		return nil, errors.New("the local and tla parameters can't be used together")
+	}
+
+	var err error
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
+	}
+
+	updater.Valuer = valuer
+
+	return updater, nil
+}
+
+// Update updates the repository cloned at the given path, and returns true if changes have been made
+func (u *JsonnetUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	value, err := u.Valuer.Value(ctx, repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	filePaths, err := filepath.Glob(filepath.Join(repoPath, u.FilePath))
+	if err != nil {
+		return false, fmt.Errorf("failed to expand glob pattern %s: %w", u.FilePath, err)
+	}
+
+	var updated bool
+	for _, filePath := range filePaths {
+		relFilePath, err := filepath.Rel(repoPath, filePath)
+		if err != nil {
+			relFilePath = filePath
+		}
+
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to access file %s: %w", relFilePath, err)
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to read file %s: %w", relFilePath, err)
+		}
+
+		literals, err := u.findLiterals(content)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse file %s: %w", relFilePath, err)
+		}
+		// replace the values from the end, so that the positions of the previous ones are still valid
+		sort.Slice(literals, func(i, j int) bool {
+			return literals[i].start > literals[j].start
+		})
+
+		updatedContent := content
+		for _, lit := range literals {
+			newValue, err := formatLiteral(lit, value)
+			if err != nil {
+				return false, fmt.Errorf("failed to format value for file %s: %w", relFilePath, err)
+			}
+			updatedContent = append(updatedContent[:lit.start:lit.start], append([]byte(newValue), updatedContent[lit.end:]...)...)
+		}
+		updatedContent = eol.Apply(u.EOL, content, updatedContent)
+
+		if bytes.Equal(content, updatedContent) {
+			continue
+		}
+
+		if err = os.WriteFile(filePath, updatedContent, fileInfo.Mode()); err != nil {
+			return false, fmt.Errorf("failed to write updated content to file %s: %w", relFilePath, err)
+		}
+
+		updated = true
+	}
+
+	return updated, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *JsonnetUpdater) Message() (title, body string) {
+	title = fmt.Sprintf("Update %s", u.FilePath)
+	if len(u.TLA) > 0 {
+		body = fmt.Sprintf("Updating top-level argument `%s` in file(s) `%s`", u.TLA, u.FilePath)
+	} else {
+		body = fmt.Sprintf("Updating local binding `%s` in file(s) `%s`", u.Local, u.FilePath)
+	}
+	return title, body
+}
+
+// String returns a string representation of the updater
+func (u *JsonnetUpdater) String() string {
+	return fmt.Sprintf("Jsonnet[local=%s,tla=%s,file=%s]", u.Local, u.TLA, u.FilePath)
+}
+
+func (u *JsonnetUpdater) findLiterals(content []byte) ([]*literal, error) {
+	tokens, err := tokenize(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(u.TLA) > 0 {
+		lit, err := findTopLevelArgument(tokens, u.TLA)
+		if err != nil || lit == nil {
+			return nil, err
+		}
+		return []*literal{lit}, nil
+	}
+
+	return findLocalBindings(tokens, u.Local)
+}
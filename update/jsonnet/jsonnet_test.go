@@ -0,0 +1,327 @@
+package jsonnet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	"github.com/dailymotion-oss/octopilot/update/value"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpdater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		params           map[string]string
+		expected         *JsonnetUpdater
+		expectedErrorMsg string
+	}{
+		{
+			name: "valid params with a local binding",
+			params: map[string]string{
+				"file":  "main.jsonnet",
+				"local": "version",
+				"eol":   "lf",
+			},
+			expected: &JsonnetUpdater{
+				FilePath: "main.jsonnet",
+				Local:    "version",
+				EOL:      eol.LF,
+			},
+		},
+		{
+			name: "valid params with a top-level argument",
+			params: map[string]string{
+				"file": "main.jsonnet",
+				"tla":  "version",
+			},
+			expected: &JsonnetUpdater{
+				FilePath: "main.jsonnet",
+				TLA:      "version",
+			},
+		},
+		{
+			name:             "nil params",
+			expectedErrorMsg: "missing file parameter",
+		},
+		{
+			name: "missing local and tla params",
+			params: map[string]string{
+				"file": "main.jsonnet",
+			},
+			expectedErrorMsg: "missing local or tla parameter",
+		},
+		{
+			name: "both local and tla params",
+			params: map[string]string{
+				"file":  "main.jsonnet",
+				"local": "version",
+				"tla":   "version",
+			},
+			expectedErrorMsg: "the local and tla parameters can't be used together",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := NewUpdater(test.params, nil)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		files            map[string]string
+		updater          *JsonnetUpdater
+		expected         bool
+		expectedErrorMsg string
+		expectedFiles    map[string]string
+	}{
+		{
+			name: "update a local binding",
+			files: map[string]string{
+				"local.jsonnet": `// the version of the app
+local version = '1.0.0';  # released yesterday
+local image = 'my-app:' + version;
+
+{
+  image: image,
+  replicas: 2,
+}
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "local.jsonnet",
+				Local:    "version",
+				Valuer:   value.StringValuer("1.1.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"local.jsonnet": `// the version of the app
+local version = '1.1.0';  # released yesterday
+local image = 'my-app:' + version;
+
+{
+  image: image,
+  replicas: 2,
+}
+`,
+			},
+		},
+		{
+			name: "update a local binding declared with other bindings",
+			files: map[string]string{
+				"multiple.libsonnet": `local name = "my-app", replicas = 2, labels = { app: name };
+{
+  local port = 8080,
+  name: name,
+  replicas: replicas,
+  port: port,
+}
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "*.libsonnet",
+				Local:    "replicas",
+				Valuer:   value.StringValuer("3\n"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"multiple.libsonnet": `local name = "my-app", replicas = 3, labels = { app: name };
+{
+  local port = 8080,
+  name: name,
+  replicas: replicas,
+  port: port,
+}
+`,
+			},
+		},
+		{
+			name: "update a local binding inside an object",
+			files: map[string]string{
+				"object.jsonnet": `{
+  local port = 8080,
+  server: { port: port },
+}
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "object.jsonnet",
+				Local:    "port",
+				Valuer:   value.StringValuer("8443"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"object.jsonnet": `{
+  local port = 8443,
+  server: { port: port },
+}
+`,
+			},
+		},
+		{
+			name: "update a string with characters to escape",
+			files: map[string]string{
+				"escape.jsonnet": `local message = 'hello';
+local path = @'C:\app';
+{ message: message, path: path }
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "escape.jsonnet",
+				Local:    "message",
+				Valuer:   value.StringValuer(`it's a "quote"`),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"escape.jsonnet": `local message = 'it\'s a "quote"';
+local path = @'C:\app';
+{ message: message, path: path }
+`,
+			},
+		},
+		{
+			name: "update the default value of a top-level argument",
+			files: map[string]string{
+				"tla.jsonnet": `local defaults = import 'defaults.libsonnet';
+
+function(env='dev', version='1.0.0', replicas=1)
+  defaults {
+    version: version,
+  }
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "tla.jsonnet",
+				TLA:      "version",
+				Valuer:   value.StringValuer("2.0.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"tla.jsonnet": `local defaults = import 'defaults.libsonnet';
+
+function(env='dev', version='2.0.0', replicas=1)
+  defaults {
+    version: version,
+  }
+`,
+			},
+		},
+		{
+			name: "no changes",
+			files: map[string]string{
+				"no-changes.jsonnet": `local version = "1.0.0";
+{ version: version }
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "no-changes.jsonnet",
+				Local:    "version",
+				Valuer:   value.StringValuer("1.0.0"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"no-changes.jsonnet": `local version = "1.0.0";
+{ version: version }
+`,
+			},
+		},
+		{
+			name: "missing local binding",
+			files: map[string]string{
+				"missing-local.jsonnet": `local name = "my-app";
+{ name: name }
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "missing-local.jsonnet",
+				Local:    "version",
+				Valuer:   value.StringValuer("1.1.0"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"missing-local.jsonnet": `local name = "my-app";
+{ name: name }
+`,
+			},
+		},
+		{
+			name: "local binding with an expression",
+			files: map[string]string{
+				"expression.jsonnet": `local version = std.extVar('version');
+{ version: version }
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "expression.jsonnet",
+				Local:    "version",
+				Valuer:   value.StringValuer("1.1.0"),
+			},
+			expectedErrorMsg: "failed to parse file expression.jsonnet: invalid local binding version: value std is not a literal",
+		},
+		{
+			name: "top-level argument without default value",
+			files: map[string]string{
+				"tla-no-default.jsonnet": `function(version) { version: version }
+`,
+			},
+			updater: &JsonnetUpdater{
+				FilePath: "tla-no-default.jsonnet",
+				TLA:      "version",
+				Valuer:   value.StringValuer("1.1.0"),
+			},
+			expectedErrorMsg: "failed to parse file tla-no-default.jsonnet: top-level argument version has no default value",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			{
+				for filename, content := range test.files {
+					err := os.MkdirAll(filepath.Dir(filepath.Join("testdata", filename)), 0755)
+					require.NoErrorf(t, err, "can't create testdata directories for %s", filename)
+					err = os.WriteFile(filepath.Join("testdata", filename), []byte(content), 0644)
+					require.NoErrorf(t, err, "can't write testdata file %s", filename)
+				}
+			}
+
+			actual, err := test.updater.Update(context.Background(), "testdata")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.False(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+				actualFilePaths, err := filepath.Glob(filepath.Join("testdata", test.updater.FilePath))
+				require.NoError(t, err, "can't expand glob pattern for actual testdata file")
+				for _, actualFilePath := range actualFilePaths {
+					actualRelFilePath, err := filepath.Rel("testdata", actualFilePath)
+					require.NoErrorf(t, err, "can't get relative path for actual testdata file %s", actualFilePath)
+					actualFileContent, err := os.ReadFile(actualFilePath)
+					require.NoErrorf(t, err, "can't read actual testdata file %s", actualFilePath)
+					expectedFileContent := test.expectedFiles[actualRelFilePath]
+					assert.Equalf(t, expectedFileContent, string(actualFileContent), "testdata file %s doesn't match", actualFilePath)
+				}
+			}
+		})
+	}
+}
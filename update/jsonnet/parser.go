@@ -0,0 +1,363 @@
+package jsonnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	identifierToken tokenKind = iota
+	stringToken
+	numberToken
+	symbolToken
+)
+
+// token is a lexical token of a jsonnet source, with its position in the source.
+// Comments and whitespaces are not tokens.
+type token struct {
+	kind  tokenKind
+	value string
+	start int
+	end   int
+	// quote is the quote character of a string token, or 0 for a text block
+	quote byte
+	// verbatim is true for a verbatim string token, such as @'C:\path'
+	verbatim bool
+}
+
+func (t token) isSymbol(symbol string) bool {
+	return t.kind == symbolToken && t.value == symbol
+}
+
+func (t token) isIdentifier(name string) bool {
+	return t.kind == identifierToken && t.value == name
+}
+
+// literal is the position of a literal value - a string, number, boolean or null - in a jsonnet source.
+type literal struct {
+	start int
+	end   int
+	// str is the string token of the literal, or nil if the literal is not a string
+	str *token
+}
+
+// tokenize splits the given jsonnet source into tokens.
+func tokenize(src []byte) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(src); {
+		c := src[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '#' || (c == '/' && pos+1 < len(src) && src[pos+1] == '/'):
+			end := bytes.IndexByte(src[pos:], '\n')
+			if end < 0 {
+				pos = len(src)
+			} else {
+				pos += end + 1
+			}
+		case c == '/' && pos+1 < len(src) && src[pos+1] == '*':
+			end := bytes.Index(src[pos+2:], []byte("*/"))
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", pos)
+			}
+			pos += 2 + end + 2
+		case c == '"' || c == '\'':
+			end, err := scanString(src, pos+1, c)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: stringToken, value: string(src[pos:end]), start: pos, end: end, quote: c})
+			pos = end
+		case c == '@' && pos+1 < len(src) && (src[pos+1] == '"' || src[pos+1] == '\''):
+			end, err := scanVerbatimString(src, pos+2, src[pos+1])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: stringToken, value: string(src[pos:end]), start: pos, end: end, quote: src[pos+1], verbatim: true})
+			pos = end
+		case bytes.HasPrefix(src[pos:], []byte("|||")):
+			end, err := scanTextBlock(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: stringToken, value: string(src[pos:end]), start: pos, end: end})
+			pos = end
+		case isDigit(c):
+			end := scanNumber(src, pos)
+			tokens = append(tokens, token{kind: numberToken, value: string(src[pos:end]), start: pos, end: end})
+			pos = end
+		case isIdentifierStart(c):
+			end := pos + 1
+			for end < len(src) && (isIdentifierStart(src[end]) || isDigit(src[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: identifierToken, value: string(src[pos:end]), start: pos, end: end})
+			pos = end
+		default:
+			tokens = append(tokens, token{kind: symbolToken, value: string(c), start: pos, end: pos + 1})
+			pos++
+		}
+	}
+	return tokens, nil
+}
+
+func scanString(src []byte, pos int, quote byte) (int, error) {
+	start := pos - 1
+	for pos < len(src) {
+		switch src[pos] {
+		case '\\':
+			pos += 2
+		case quote:
+			return pos + 1, nil
+		default:
+			pos++
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+func scanVerbatimString(src []byte, pos int, quote byte) (int, error) {
+	start := pos - 2
+	for pos < len(src) {
+		if src[pos] == quote {
+			if pos+1 < len(src) && src[pos+1] == quote {
+				pos += 2
+				continue
+			}
+			return pos + 1, nil
+		}
+		pos++
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+// scanTextBlock returns the end of the text block starting at the given position:
+// the text block ends with a line starting with ||| - ignoring the leading whitespaces.
+func scanTextBlock(src []byte, pos int) (int, error) {
+	start := pos
+	lineEnd := bytes.IndexByte(src[pos:], '\n')
+	if lineEnd < 0 {
+		return 0, fmt.Errorf("unterminated text block at offset %d", start)
+	}
+	pos += lineEnd + 1
+	for pos < len(src) {
+		line := src[pos:]
+		if lineEnd = bytes.IndexByte(line, '\n'); lineEnd >= 0 {
+			line = line[:lineEnd]
+		}
+		trimmed := bytes.TrimLeft(line, " \t")
+		if bytes.HasPrefix(trimmed, []byte("|||")) {
+			return pos + (len(line) - len(trimmed)) + 3, nil
+		}
+		if lineEnd < 0 {
+			break
+		}
+		pos += lineEnd + 1
+	}
+	return 0, fmt.Errorf("unterminated text block at offset %d", start)
+}
+
+func scanNumber(src []byte, pos int) int {
+	for pos < len(src) && isDigit(src[pos]) {
+		pos++
+	}
+	if pos+1 < len(src) && src[pos] == '.' && isDigit(src[pos+1]) {
+		pos++
+		for pos < len(src) && isDigit(src[pos]) {
+			pos++
+		}
+	}
+	if pos < len(src) && (src[pos] == 'e' || src[pos] == 'E') {
+		exp := pos + 1
+		if exp < len(src) && (src[exp] == '+' || src[exp] == '-') {
+			exp++
+		}
+		if exp < len(src) && isDigit(src[exp]) {
+			pos = exp
+			for pos < len(src) && isDigit(src[pos]) {
+				pos++
+			}
+		}
+	}
+	return pos
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// parseLiteral parses the literal value starting at the given token index,
+// and returns it with the index of the token following the literal.
+// It fails if the value is not a literal, but an expression.
+func parseLiteral(tokens []token, i int) (*literal, int, error) {
+	if i >= len(tokens) {
+		return nil, i, errors.New("missing value")
+	}
+
+	var lit *literal
+	tok := tokens[i]
+	switch {
+	case tok.kind == stringToken:
+		if tok.quote == 0 {
+			return nil, i, errors.New("text blocks are not supported")
+		}
+		lit = &literal{start: tok.start, end: tok.end, str: &tokens[i]}
+	case tok.kind == numberToken, tok.isIdentifier("true"), tok.isIdentifier("false"), tok.isIdentifier("null"):
+		lit = &literal{start: tok.start, end: tok.end}
+	case tok.isSymbol("-") && i+1 < len(tokens) && tokens[i+1].kind == numberToken:
+		i++
+		lit = &literal{start: tok.start, end: tokens[i].end}
+	default:
+		return nil, i, fmt.Errorf("value %s is not a literal", tok.value)
+	}
+	i++
+
+	// the literal must be the whole value, not the start of an expression, such as 'v' + version
+	if i < len(tokens) && !tokens[i].isSymbol(";") && !tokens[i].isSymbol(",") && !tokens[i].isSymbol("}") && !tokens[i].isSymbol(")") {
+		return nil, i, errors.New("value is an expression, not a literal")
+	}
+	return lit, i, nil
+}
+
+// findLocalBindings returns the literal values of all the local bindings with the given name - such as "local name = 'value';".
+func findLocalBindings(tokens []token, name string) ([]*literal, error) {
+	var literals []*literal
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].isIdentifier("local") {
+			continue
+		}
+		// multiple bindings can be declared at once: local a = 1, b = 2;
+		for j := i + 1; j < len(tokens); {
+			if j+1 >= len(tokens) || tokens[j].kind != identifierToken {
+				break
+			}
+			if !tokens[j+1].isSymbol("=") {
+				// function binding, such as local f(x) = x;
+				break
+			}
+			if tokens[j].value != name {
+				j = skipExpression(tokens, j+2)
+			} else {
+				lit, next, err := parseLiteral(tokens, j+2)
+				if err != nil {
+					return nil, fmt.Errorf("invalid local binding %s: %w", name, err)
+				}
+				literals = append(literals, lit)
+				j = next
+			}
+			if j >= len(tokens) || !tokens[j].isSymbol(",") {
+				break
+			}
+			j++
+		}
+	}
+	return literals, nil
+}
+
+// findTopLevelArgument returns the literal default value of the given top-level argument,
+// when the jsonnet source is a function - such as "function(name='value') {...}".
+// It returns nil if the source is not a function, or if the function doesn't have such argument.
+func findTopLevelArgument(tokens []token, name string) (*literal, error) {
+	i := 0
+	// skip the top-level local bindings
+	for i < len(tokens) && tokens[i].isIdentifier("local") {
+		for i < len(tokens) && !tokens[i].isSymbol(";") {
+			i = skipExpression(tokens, i+1)
+		}
+		i++
+	}
+	if i+1 >= len(tokens) || !tokens[i].isIdentifier("function") || !tokens[i+1].isSymbol("(") {
+		return nil, nil
+	}
+
+	for i += 2; i < len(tokens) && !tokens[i].isSymbol(")"); {
+		if tokens[i].kind != identifierToken {
+			return nil, fmt.Errorf("invalid top-level argument %s", tokens[i].value)
+		}
+		argName := tokens[i].value
+		i++
+		hasDefault := i < len(tokens) && tokens[i].isSymbol("=")
+		if argName == name {
+			if !hasDefault {
+				return nil, fmt.Errorf("top-level argument %s has no default value", name)
+			}
+			lit, _, err := parseLiteral(tokens, i+1)
+			if err != nil {
+				return nil, fmt.Errorf("invalid top-level argument %s: %w", name, err)
+			}
+			return lit, nil
+		}
+		if hasDefault {
+			i = skipExpression(tokens, i+1)
+		}
+		if i < len(tokens) && tokens[i].isSymbol(",") {
+			i++
+		}
+	}
+	return nil, nil
+}
+
+// skipExpression returns the index of the first token after the expression starting at the given index:
+// the first comma, semicolon or closing bracket that is not nested in the expression.
+func skipExpression(tokens []token, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind != symbolToken {
+			continue
+		}
+		switch tok.value {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case ",", ";":
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// formatLiteral formats the given value to replace the given literal:
+// strings are quoted with the same style as the current value, other values are written as-is.
+func formatLiteral(lit *literal, value string) (string, error) {
+	if lit.str == nil {
+		return strings.TrimSpace(value), nil
+	}
+
+	quote := string(lit.str.quote)
+	if lit.str.verbatim {
+		return "@" + quote + strings.ReplaceAll(value, quote, quote+quote) + quote, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	if quote == `"` {
+		return string(data), nil
+	}
+	// convert the JSON double-quoted string into a single-quoted string
+	inner := string(data[1 : len(data)-1])
+	inner = strings.ReplaceAll(inner, `\"`, `"`)
+	inner = strings.ReplaceAll(inner, `'`, `\'`)
+	return "'" + inner + "'", nil
+}
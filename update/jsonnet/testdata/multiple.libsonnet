@@ -0,0 +1,7 @@
+local name = "my-app", replicas = 3, labels = { app: name };
+{
+  local port = 8080,
+  name: name,
+  replicas: replicas,
+  port: port,
+}
@@ -0,0 +1,126 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+const (
+	managedVersionMarker        = "octopilot-managed-version"
+	defaultManagedVersionMarker = "#"
+)
+
+// managedVersionRegexp matches the managed version marker, such as "# octopilot-managed-version: 3"
+var managedVersionRegexp = regexp.MustCompile(managedVersionMarker + `:[ \t]*([0-9]+)`)
+
+// ManagedVersionUpdater is an updater that wraps another updater, and increments the managed version marker - such as "# octopilot-managed-version: 3" - of the files changed by the wrapped updater.
+// It makes it possible to detect the edits made to the files outside of Octopilot.
+type ManagedVersionUpdater struct {
+	Updater      Updater
+	CreateMarker bool
+	Comment      string
+}
+
+// newManagedVersionUpdater wraps the given updater with the managed version marker parameters
+func newManagedVersionUpdater(updater Updater, params map[string]string) (*ManagedVersionUpdater, error) {
+	u := &ManagedVersionUpdater{
+		Updater: updater,
+		Comment: params["marker-comment"],
+	}
+	if len(u.Comment) == 0 {
+		u.Comment = defaultManagedVersionMarker
+	}
+
+	if createMarker := params["create-marker"]; len(createMarker) > 0 {
+		var err error
+		u.CreateMarker, err = strconv.ParseBool(createMarker)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse create-marker parameter %s: %w", createMarker, err)
+		}
+	}
+
+	return u, nil
+}
+
+// Update runs the wrapped updater, then increments the managed version marker of the changed files
+func (u *ManagedVersionUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	snapshot, err := snapshotWorktree(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to snapshot files in %s: %w", repoPath, err)
+	}
+
+	updated, err := u.Updater.Update(ctx, repoPath)
+	if err != nil || !updated {
+		return updated, err
+	}
+
+	files, err := snapshot.changedFiles(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to list the changed files in %s: %w", repoPath, err)
+	}
+
+	for _, file := range files {
+		regular, err := isRegularFile(repoPath, file)
+		if err != nil {
+			return false, err
+		}
+		if !regular {
+			continue
+		}
+		if err = u.bumpVersion(filepath.Join(repoPath, file)); err != nil {
+			return false, fmt.Errorf("failed to increment the managed version of file %s: %w", file, err)
+		}
+	}
+
+	return true, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *ManagedVersionUpdater) Message() (title, body string) {
+	return u.Updater.Message()
+}
+
+// String returns a string representation of the updater
+func (u *ManagedVersionUpdater) String() string {
+	return fmt.Sprintf("%s | ManagedVersion[create-marker=%v]", u.Updater.String(), u.CreateMarker)
+}
+
+func (u *ManagedVersionUpdater) bumpVersion(filePath string) error {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	var updatedContent []byte
+	if loc := managedVersionRegexp.FindSubmatchIndex(content); loc != nil {
+		version, err := strconv.Atoi(string(content[loc[2]:loc[3]]))
+		if err != nil {
+			return fmt.Errorf("invalid managed version %s: %w", content[loc[2]:loc[3]], err)
+		}
+		updatedContent = append(updatedContent, content[:loc[2]]...)
+		updatedContent = append(updatedContent, strconv.Itoa(version+1)...)
+		updatedContent = append(updatedContent, content[loc[3]:]...)
+	} else {
+		if !u.CreateMarker {
+			return nil
+		}
+		lineEnding := []byte("\n")
+		if bytes.Contains(content, []byte("\r\n")) {
+			lineEnding = []byte("\r\n")
+		}
+		updatedContent = append(updatedContent, fmt.Sprintf("%s %s: 1", u.Comment, managedVersionMarker)...)
+		updatedContent = append(updatedContent, lineEnding...)
+		updatedContent = append(updatedContent, content...)
+	}
+
+	return os.WriteFile(filePath, updatedContent, fileInfo.Mode())
+}
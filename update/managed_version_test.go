@@ -0,0 +1,104 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagedVersionUpdaterUpdate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		files         map[string]string
+		updater       *ManagedVersionUpdater
+		expected      bool
+		expectedFiles map[string]string
+	}{
+		{
+			name: "increment the marker on change",
+			files: map[string]string{
+				"config.yaml": "# octopilot-managed-version: 41\nversion: 1.0.0\n",
+				"other.yaml":  "# octopilot-managed-version: 7\nkey: value\n",
+			},
+			updater: &ManagedVersionUpdater{
+				Updater: &writeFileUpdater{file: "config.yaml", content: "# octopilot-managed-version: 41\nversion: 1.1.0\n"},
+				Comment: "#",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"config.yaml": "# octopilot-managed-version: 42\nversion: 1.1.0\n",
+				"other.yaml":  "# octopilot-managed-version: 7\nkey: value\n",
+			},
+		},
+		{
+			name: "no increment without change",
+			files: map[string]string{
+				"config.yaml": "# octopilot-managed-version: 41\nversion: 1.0.0\n",
+			},
+			updater: &ManagedVersionUpdater{
+				Updater: &writeFileUpdater{file: "config.yaml", content: "# octopilot-managed-version: 41\nversion: 1.0.0\n"},
+				Comment: "#",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"config.yaml": "# octopilot-managed-version: 41\nversion: 1.0.0\n",
+			},
+		},
+		{
+			name: "missing marker is not created by default",
+			files: map[string]string{
+				"config.yaml": "version: 1.0.0\n",
+			},
+			updater: &ManagedVersionUpdater{
+				Updater: &writeFileUpdater{file: "config.yaml", content: "version: 1.1.0\n"},
+				Comment: "#",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"config.yaml": "version: 1.1.0\n",
+			},
+		},
+		{
+			name: "create the missing marker",
+			files: map[string]string{
+				"main.tf": "version = \"1.0.0\"\r\n",
+			},
+			updater: &ManagedVersionUpdater{
+				Updater:      &writeFileUpdater{file: "main.tf", content: "version = \"1.1.0\"\r\n"},
+				CreateMarker: true,
+				Comment:      "//",
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"main.tf": "// octopilot-managed-version: 1\r\nversion = \"1.1.0\"\r\n",
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := t.TempDir()
+			for file, content := range test.files {
+				require.NoError(t, os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644))
+			}
+
+			commitRepository(t, repoPath)
+
+			updated, err := test.updater.Update(context.Background(), repoPath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, updated)
+			for file, expectedContent := range test.expectedFiles {
+				content, err := os.ReadFile(filepath.Join(repoPath, file))
+				require.NoError(t, err)
+				assert.Equal(t, expectedContent, string(content), file)
+			}
+		})
+	}
+}
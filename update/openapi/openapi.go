@@ -0,0 +1,260 @@
+// Package openapi provides an updater that updates OpenAPI specification files, using the OpenAPI semantics to address values.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	internalyaml "github.com/dailymotion-oss/octopilot/internal/yaml"
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIUpdater is an updater that updates OpenAPI specification files (YAML or JSON).
+// The value to update is addressed either by server index, by path (and optional operation method), or by component.
+type OpenAPIUpdater struct {
+	FilePath  string
+	Server    int
+	Path      string
+	Method    string
+	Component string
+	Field     string
+	Indent    int
+	EOL       eol.Mode
+	Valuer    value.Valuer
+}
+
+// NewUpdater builds a new OpenAPI updater from the given parameters and valuer
+func NewUpdater(params map[string]string, valuer value.Valuer) (*OpenAPIUpdater, error) {
+	updater := &OpenAPIUpdater{
+		Server: -1,
+	}
+
+	updater.FilePath = params["file"]
+	if len(updater.FilePath) == 0 {
+		return nil, errors.New("missing file parameter")
+	}
+
+	var targets int
+	if server, found := params["server"]; found {
+		index, err := strconv.Atoi(server)
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("invalid server parameter %s: must be a positive index", server)
+		}
+		updater.Server = index
+		targets++
+	}
+	if path, found := params["path"]; found {
+		updater.Path = path
+		updater.Method = strings.ToLower(params["method"])
+		targets++
+	}
+	if component, found := params["component"]; found {
+		if elems := strings.Split(component, "/"); len(elems) != 2 || len(elems[0]) == 0 || len(elems[1]) == 0 {
+			return nil, fmt.Errorf("invalid component parameter %s: must be in the type/name format, such as schemas/Pet", component)
+		}
+		updater.Component = component
+		targets++
+	}
+	if targets != 1 {
+		return nil, errors.New("exactly one of the server, path or component parameters must be defined")
+	}
+
+	updater.Field = params["field"]
+	if len(updater.Field) == 0 {
+		if updater.Server < 0 {
+			return nil, errors.New("missing field parameter")
+		}
+		updater.Field = "url"
+	}
+
+	updater.Indent, _ = strconv.Atoi(params["indent"])
+	if updater.Indent <= 0 {
+		updater.Indent = 2
+	}
+
+	var err error
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
+	}
+
+	updater.Valuer = valuer
+
+	return updater, nil
+}
+
+// Update updates the repository cloned at the given path, and returns true if changes have been made
+func (u *OpenAPIUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	value, err := u.Valuer.Value(ctx, repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	filePaths, err := filepath.Glob(filepath.Join(repoPath, u.FilePath))
+	if err != nil {
+		return false, fmt.Errorf("failed to expand glob pattern %s: %w", u.FilePath, err)
+	}
+
+	var updated bool
+	for _, filePath := range filePaths {
+		relFilePath, err := filepath.Rel(repoPath, filePath)
+		if err != nil {
+			relFilePath = filePath
+		}
+
+		fileUpdated, err := u.updateFile(filePath, value)
+		if err != nil {
+			return false, fmt.Errorf("failed to update file %s: %w", relFilePath, err)
+		}
+		if fileUpdated {
+			updated = true
+		}
+	}
+
+	return updated, nil
+}
+
+func (u *OpenAPIUpdater) updateFile(filePath string, value string) (bool, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to access file: %w", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var rootNode yaml.Node
+	err = yaml.Unmarshal(eol.Convert(data, eol.LF), &rootNode)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal spec: %w", err)
+	}
+	if rootNode.Kind != yaml.DocumentNode || len(rootNode.Content) == 0 || rootNode.Content[0].Kind != yaml.MappingNode {
+		return false, errors.New("the spec is not an object")
+	}
+
+	targetNode, err := u.targetNode(rootNode.Content[0])
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := internalyaml.SetValue(targetNode, strings.Split(u.Field, "."), value)
+	if err != nil {
+		return false, fmt.Errorf("failed to set field %s: %w", u.Field, err)
+	}
+	if !updated {
+		return false, nil
+	}
+
+	var buffer bytes.Buffer
+	if internalyaml.IsJSON(filePath, data) {
+		err = internalyaml.EncodeJSON(&buffer, rootNode.Content[0], u.Indent)
+		if err == nil && bytes.HasSuffix(data, []byte("\n")) {
+			buffer.WriteString("\n")
+		}
+	} else {
+		enc := yaml.NewEncoder(&buffer)
+		enc.SetIndent(u.Indent)
+		err = enc.Encode(&rootNode)
+		if err == nil {
+			err = enc.Close()
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to encode updated spec: %w", err)
+	}
+
+	updatedData := eol.Apply(u.EOL, data, buffer.Bytes())
+	if bytes.Equal(data, updatedData) {
+		return false, nil
+	}
+
+	err = os.WriteFile(filePath, updatedData, fileInfo.Mode())
+	if err != nil {
+		return false, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return true, nil
+}
+
+// targetNode returns the node addressed by the server, path/method or component parameters
+func (u *OpenAPIUpdater) targetNode(specNode *yaml.Node) (*yaml.Node, error) {
+	switch {
+	case u.Server >= 0:
+		serversNode := mappingValue(specNode, "servers")
+		if serversNode == nil || serversNode.Kind != yaml.SequenceNode {
+			return nil, errors.New("no servers defined in the spec")
+		}
+		if u.Server >= len(serversNode.Content) {
+			return nil, fmt.Errorf("server index %d out of range: the spec has %d servers", u.Server, len(serversNode.Content))
+		}
+		return serversNode.Content[u.Server], nil
+	case len(u.Path) > 0:
+		pathNode := mappingValue(mappingValue(specNode, "paths"), u.Path)
+		if pathNode == nil {
+			return nil, fmt.Errorf("path %s not found in the spec", u.Path)
+		}
+		if len(u.Method) == 0 {
+			return pathNode, nil
+		}
+		operationNode := mappingValue(pathNode, u.Method)
+		if operationNode == nil {
+			return nil, fmt.Errorf("operation %s not found for path %s in the spec", u.Method, u.Path)
+		}
+		return operationNode, nil
+	default:
+		elems := strings.SplitN(u.Component, "/", 2)
+		componentNode := mappingValue(mappingValue(mappingValue(specNode, "components"), elems[0]), elems[1])
+		if componentNode == nil {
+			return nil, fmt.Errorf("component %s not found in the spec", u.Component)
+		}
+		return componentNode, nil
+	}
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *OpenAPIUpdater) Message() (title, body string) {
+	title = fmt.Sprintf("Update %s", u.FilePath)
+	body = fmt.Sprintf("Updating field `%s` of %s in OpenAPI spec(s) `%s`", u.Field, u.target(), u.FilePath)
+	return title, body
+}
+
+// String returns a string representation of the updater
+func (u *OpenAPIUpdater) String() string {
+	return fmt.Sprintf("OpenAPI[file=%s,target=%s,field=%s,indent=%v]", u.FilePath, u.target(), u.Field, u.Indent)
+}
+
+func (u *OpenAPIUpdater) target() string {
+	switch {
+	case u.Server >= 0:
+		return fmt.Sprintf("server %d", u.Server)
+	case len(u.Path) > 0 && len(u.Method) > 0:
+		return fmt.Sprintf("operation %s %s", strings.ToUpper(u.Method), u.Path)
+	case len(u.Path) > 0:
+		return fmt.Sprintf("path %s", u.Path)
+	default:
+		return fmt.Sprintf("component %s", u.Component)
+	}
+}
+
+// mappingValue returns the value node for the given key in a mapping node, or nil if it can't be found
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
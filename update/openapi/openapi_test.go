@@ -0,0 +1,414 @@
+package openapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpdater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		params           map[string]string
+		expected         *OpenAPIUpdater
+		expectedErrorMsg string
+	}{
+		{
+			name: "server with default field",
+			params: map[string]string{
+				"file":   "openapi.yaml",
+				"server": "1",
+			},
+			expected: &OpenAPIUpdater{
+				FilePath: "openapi.yaml",
+				Server:   1,
+				Field:    "url",
+				Indent:   2,
+			},
+		},
+		{
+			name: "operation with custom indent",
+			params: map[string]string{
+				"file":   "openapi.json",
+				"path":   "/pets/{id}",
+				"method": "GET",
+				"field":  "x-backend",
+				"indent": "4",
+			},
+			expected: &OpenAPIUpdater{
+				FilePath: "openapi.json",
+				Server:   -1,
+				Path:     "/pets/{id}",
+				Method:   "get",
+				Field:    "x-backend",
+				Indent:   4,
+			},
+		},
+		{
+			name: "component",
+			params: map[string]string{
+				"file":      "openapi.yaml",
+				"component": "schemas/Pet",
+				"field":     "description",
+			},
+			expected: &OpenAPIUpdater{
+				FilePath:  "openapi.yaml",
+				Server:    -1,
+				Component: "schemas/Pet",
+				Field:     "description",
+				Indent:    2,
+			},
+		},
+		{
+			name:             "nil params",
+			expectedErrorMsg: "missing file parameter",
+		},
+		{
+			name: "missing target",
+			params: map[string]string{
+				"file": "openapi.yaml",
+			},
+			expectedErrorMsg: "exactly one of the server, path or component parameters must be defined",
+		},
+		{
+			name: "multiple targets",
+			params: map[string]string{
+				"file":   "openapi.yaml",
+				"server": "0",
+				"path":   "/pets",
+			},
+			expectedErrorMsg: "exactly one of the server, path or component parameters must be defined",
+		},
+		{
+			name: "invalid server index",
+			params: map[string]string{
+				"file":   "openapi.yaml",
+				"server": "first",
+			},
+			expectedErrorMsg: "invalid server parameter first: must be a positive index",
+		},
+		{
+			name: "invalid component",
+			params: map[string]string{
+				"file":      "openapi.yaml",
+				"component": "Pet",
+				"field":     "description",
+			},
+			expectedErrorMsg: "invalid component parameter Pet: must be in the type/name format, such as schemas/Pet",
+		},
+		{
+			name: "missing field for operation",
+			params: map[string]string{
+				"file":   "openapi.yaml",
+				"path":   "/pets",
+				"method": "get",
+			},
+			expectedErrorMsg: "missing field parameter",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := NewUpdater(test.params, nil)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		files            map[string]string
+		updater          *OpenAPIUpdater
+		expected         bool
+		expectedErrorMsg string
+		expectedFiles    map[string]string
+	}{
+		{
+			name: "update server url in a YAML spec",
+			files: map[string]string{
+				"server-url.yaml": `openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+# the servers
+servers:
+  - url: https://staging.example.com/v1
+    description: staging
+  - url: https://old.example.com/v1 # production
+    description: production
+paths: {}
+`,
+			},
+			updater: &OpenAPIUpdater{
+				FilePath: "server-url.yaml",
+				Server:   1,
+				Field:    "url",
+				Indent:   2,
+				Valuer:   value.StringValuer("https://api.example.com/v1"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"server-url.yaml": `openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+# the servers
+servers:
+  - url: https://staging.example.com/v1
+    description: staging
+  - url: https://api.example.com/v1 # production
+    description: production
+paths: {}
+`,
+			},
+		},
+		{
+			name: "update operation extension with dotted and slashed keys in a JSON spec",
+			files: map[string]string{
+				"operation-extension.json": `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/pets/{id}": {
+      "get": {
+        "operationId": "getPet",
+        "x-backend": "http://pets.v1.svc",
+        "responses": {
+          "200": {
+            "description": "a pet"
+          }
+        }
+      },
+      "delete": {
+        "x-backend": "http://pets.v1.svc",
+        "deprecated": true
+      }
+    }
+  }
+}
+`,
+			},
+			updater: &OpenAPIUpdater{
+				FilePath: "operation-extension.json",
+				Server:   -1,
+				Path:     "/pets/{id}",
+				Method:   "get",
+				Field:    "x-backend",
+				Indent:   2,
+				Valuer:   value.StringValuer("http://pets.v2.svc"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"operation-extension.json": `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/pets/{id}": {
+      "get": {
+        "operationId": "getPet",
+        "x-backend": "http://pets.v2.svc",
+        "responses": {
+          "200": {
+            "description": "a pet"
+          }
+        }
+      },
+      "delete": {
+        "x-backend": "http://pets.v1.svc",
+        "deprecated": true
+      }
+    }
+  }
+}
+`,
+			},
+		},
+		{
+			name: "add missing extension to a component",
+			files: map[string]string{
+				"component-extension.yaml": `openapi: 3.0.3
+components:
+  schemas:
+    Pet:
+      type: object
+`,
+			},
+			updater: &OpenAPIUpdater{
+				FilePath:  "component-extension.yaml",
+				Server:    -1,
+				Component: "schemas/Pet",
+				Field:     "x-owner.team",
+				Indent:    2,
+				Valuer:    value.StringValuer("pets"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"component-extension.yaml": `openapi: 3.0.3
+components:
+  schemas:
+    Pet:
+      type: object
+      x-owner:
+        team: pets
+`,
+			},
+		},
+		{
+			name: "update typed extensions of an operation",
+			files: map[string]string{
+				"typed-extensions.yaml": `openapi: 3.0.3
+paths:
+  /pets:
+    get:
+      x-timeout: 30
+      x-cache: true
+      x-backend: "http://pets.v1.svc"
+`,
+			},
+			updater: &OpenAPIUpdater{
+				FilePath: "typed-extensions.yaml",
+				Server:   -1,
+				Path:     "/pets",
+				Method:   "get",
+				Field:    "x-timeout",
+				Indent:   2,
+				Valuer:   value.StringValuer("60"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"typed-extensions.yaml": `openapi: 3.0.3
+paths:
+  /pets:
+    get:
+      x-timeout: 60
+      x-cache: true
+      x-backend: "http://pets.v1.svc"
+`,
+			},
+		},
+		{
+			name: "update boolean extension of an operation in a JSON spec",
+			files: map[string]string{
+				"boolean-extension.json": `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/pets": {
+      "get": {
+        "x-cache": true
+      }
+    }
+  }
+}
+`,
+			},
+			updater: &OpenAPIUpdater{
+				FilePath: "boolean-extension.json",
+				Server:   -1,
+				Path:     "/pets",
+				Method:   "get",
+				Field:    "x-cache",
+				Indent:   2,
+				Valuer:   value.StringValuer("false"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"boolean-extension.json": `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/pets": {
+      "get": {
+        "x-cache": false
+      }
+    }
+  }
+}
+`,
+			},
+		},
+		{
+			name: "no changes",
+			files: map[string]string{
+				"no-changes.yaml": `openapi: 3.0.3
+servers:
+    - url: "https://api.example.com"
+`,
+			},
+			updater: &OpenAPIUpdater{
+				FilePath: "no-changes.yaml",
+				Server:   0,
+				Field:    "url",
+				Indent:   2,
+				Valuer:   value.StringValuer("https://api.example.com"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"no-changes.yaml": `openapi: 3.0.3
+servers:
+    - url: "https://api.example.com"
+`,
+			},
+		},
+		{
+			name: "unknown operation",
+			files: map[string]string{
+				"unknown-operation.yaml": `openapi: 3.0.3
+paths:
+  /pets:
+    get: {}
+`,
+			},
+			updater: &OpenAPIUpdater{
+				FilePath: "unknown-operation.yaml",
+				Server:   -1,
+				Path:     "/pets",
+				Method:   "post",
+				Field:    "x-backend",
+				Indent:   2,
+				Valuer:   value.StringValuer("http://pets.svc"),
+			},
+			expectedErrorMsg: "failed to update file unknown-operation.yaml: operation post not found for path /pets in the spec",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			{
+				for filename, content := range test.files {
+					err := os.WriteFile(filepath.Join("testdata", filename), []byte(content), 0644)
+					require.NoErrorf(t, err, "can't write testdata file %s", filename)
+				}
+			}
+
+			actual, err := test.updater.Update(context.Background(), "testdata")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.False(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+				for filename, expectedContent := range test.expectedFiles {
+					actualContent, err := os.ReadFile(filepath.Join("testdata", filename))
+					require.NoErrorf(t, err, "can't read actual testdata file %s", filename)
+					assert.Equalf(t, expectedContent, string(actualContent), "testdata file %s doesn't match", filename)
+				}
+			}
+		})
+	}
+}
@@ -9,16 +9,30 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 
+	"github.com/dailymotion-oss/octopilot/internal/eol"
 	"github.com/dailymotion-oss/octopilot/update/value"
 )
 
+var (
+	regionNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+	endMarkerRegexp  = regexp.MustCompile(`octopilot:end(?:[^A-Za-z0-9_.\-]|$)`)
+)
+
 // RegexUpdater is an updater that uses a regex to update files.
+// If a region is defined, only the content between the region markers is updated - or replaced entirely if there is no pattern.
 type RegexUpdater struct {
-	FilePath string
-	Pattern  string
-	Regexp   *regexp.Regexp
-	Valuer   value.Valuer
+	FilePath     string
+	Pattern      string
+	Regexp       *regexp.Regexp
+	Region       string
+	CreateRegion bool
+	Comment      string
+	CommentEnd   string
+	EOL          eol.Mode
+	Valuer       value.Valuer
 }
 
 // NewUpdater builds a new regex updater from the given parameters and valuer
@@ -30,18 +44,46 @@ func NewUpdater(params map[string]string, valuer value.Valuer) (*RegexUpdater, e
 		return nil, errors.New("missing file parameter")
 	}
 
+	updater.Region = params["region"]
 	updater.Pattern = params["pattern"]
-	if len(updater.Pattern) == 0 {
+	if len(updater.Pattern) == 0 && len(updater.Region) == 0 {
 		return nil, errors.New("missing pattern parameter")
 	}
 
 	var err error
-	updater.Regexp, err = regexp.Compile(updater.Pattern)
-	if err != nil {
-		return nil, fmt.Errorf("invalid pattern %s: %w", updater.Pattern, err)
+	if len(updater.Pattern) > 0 {
+		updater.Regexp, err = regexp.Compile(updater.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", updater.Pattern, err)
+		}
+		if subexp := updater.Regexp.NumSubexp(); subexp != 1 {
+			return nil, fmt.Errorf("invalid pattern %s: it must have a single parenthesized subexpression, but it has %d", updater.Pattern, subexp)
+		}
 	}
-	if subexp := updater.Regexp.NumSubexp(); subexp != 1 {
-		return nil, fmt.Errorf("invalid pattern %s: it must have a single parenthesized subexpression, but it has %d", updater.Pattern, subexp)
+
+	if len(updater.Region) > 0 {
+		if !regionNameRegexp.MatchString(updater.Region) {
+			return nil, fmt.Errorf("invalid region name %s: it must only contain letters, digits, dots, dashes or underscores", updater.Region)
+		}
+		if createRegionStr, found := params["create-region"]; found {
+			updater.CreateRegion, err = strconv.ParseBool(createRegionStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid create-region parameter %s: %w", createRegionStr, err)
+			}
+		}
+		if updater.CreateRegion && updater.Regexp != nil {
+			return nil, errors.New("the create-region parameter can't be used with the pattern parameter")
+		}
+		updater.Comment = params["comment"]
+		if len(updater.Comment) == 0 {
+			updater.Comment = "#"
+		}
+		updater.CommentEnd = params["comment-end"]
+	}
+
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
 	}
 
 	updater.Valuer = valuer
@@ -78,33 +120,26 @@ func (u RegexUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 			return false, fmt.Errorf("failed to read file %s: %w", relFilePath, err)
 		}
 
-		if !u.Regexp.Match(content) {
-			continue
-		}
-
-		var (
-			updatedContent  bytes.Buffer
-			currentPosition int
-		)
-		allIndexes := u.Regexp.FindAllSubmatchIndex(content, -1)
-		for _, indexes := range allIndexes {
-			if len(indexes) == 4 {
-				valueStartPosition := indexes[2]
-				valueEndPosition := indexes[3]
-				if _, err = updatedContent.Write(content[currentPosition:valueStartPosition]); err != nil {
-					return false, fmt.Errorf("failed to copy existing content to the buffer: %w", err)
-				}
-				if _, err = updatedContent.WriteString(value); err != nil {
-					return false, fmt.Errorf("failed to write new value to the buffer: %w", err)
-				}
-				currentPosition = valueEndPosition
+		var updatedContent []byte
+		if len(u.Region) > 0 {
+			updatedContent, err = u.updateRegions(content, value)
+			if err != nil {
+				return false, fmt.Errorf("failed to update region %s in file %s: %w", u.Region, relFilePath, err)
+			}
+			if bytes.Equal(content, eol.Apply(u.EOL, content, updatedContent)) {
+				continue
+			}
+		} else {
+			if !u.Regexp.Match(content) {
+				continue
+			}
+			updatedContent, err = u.replaceMatches(content, value)
+			if err != nil {
+				return false, err
 			}
-		}
-		if _, err = updatedContent.Write(content[currentPosition:]); err != nil {
-			return false, fmt.Errorf("failed to copy existing content to the buffer: %w", err)
 		}
 
-		if err = os.WriteFile(filePath, updatedContent.Bytes(), fileInfo.Mode()); err != nil {
+		if err = os.WriteFile(filePath, eol.Apply(u.EOL, content, updatedContent), fileInfo.Mode()); err != nil {
 			return false, fmt.Errorf("failed to write updated content to file %s: %w", relFilePath, err)
 		}
 
@@ -117,11 +152,119 @@ func (u RegexUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 // Message returns the default title and body that should be used in the commits / pull requests
 func (u RegexUpdater) Message() (title, body string) {
 	title = fmt.Sprintf("Update %s", u.FilePath)
-	body = fmt.Sprintf("Updating file(s) `%s` using pattern `%s`", u.FilePath, u.Pattern)
+	switch {
+	case len(u.Region) > 0 && len(u.Pattern) > 0:
+		body = fmt.Sprintf("Updating region `%s` of file(s) `%s` using pattern `%s`", u.Region, u.FilePath, u.Pattern)
+	case len(u.Region) > 0:
+		body = fmt.Sprintf("Updating region `%s` of file(s) `%s`", u.Region, u.FilePath)
+	default:
+		body = fmt.Sprintf("Updating file(s) `%s` using pattern `%s`", u.FilePath, u.Pattern)
+	}
 	return title, body
 }
 
 // String returns a string representation of the updater
 func (u RegexUpdater) String() string {
+	if len(u.Region) > 0 {
+		return fmt.Sprintf("Regex[pattern=%s,region=%s,file=%s]", u.Pattern, u.Region, u.FilePath)
+	}
 	return fmt.Sprintf("Regex[pattern=%s,file=%s]", u.Pattern, u.FilePath)
 }
+
+// replaceMatches replaces the capturing group of all the matches of the pattern in the given content with the given value
+func (u RegexUpdater) replaceMatches(content []byte, value string) ([]byte, error) {
+	var (
+		updatedContent  bytes.Buffer
+		currentPosition int
+	)
+	allIndexes := u.Regexp.FindAllSubmatchIndex(content, -1)
+	for _, indexes := range allIndexes {
+		if len(indexes) == 4 {
+			valueStartPosition := indexes[2]
+			valueEndPosition := indexes[3]
+			if _, err := updatedContent.Write(content[currentPosition:valueStartPosition]); err != nil {
+				return nil, fmt.Errorf("failed to copy existing content to the buffer: %w", err)
+			}
+			if _, err := updatedContent.WriteString(value); err != nil {
+				return nil, fmt.Errorf("failed to write new value to the buffer: %w", err)
+			}
+			currentPosition = valueEndPosition
+		}
+	}
+	if _, err := updatedContent.Write(content[currentPosition:]); err != nil {
+		return nil, fmt.Errorf("failed to copy existing content to the buffer: %w", err)
+	}
+	return updatedContent.Bytes(), nil
+}
+
+// updateRegions updates the content of all the regions - between the start and end markers - with the given value.
+// If there is a pattern, it is applied to the content of the regions only. Otherwise the content of the regions is replaced by the value.
+// If there is no region in the content and the create-region option is enabled, a new region is appended at the end of the content.
+func (u RegexUpdater) updateRegions(content []byte, value string) ([]byte, error) {
+	var (
+		startMarkerRegexp = regexp.MustCompile(`octopilot:start\s+name=` + regexp.QuoteMeta(u.Region) + `(?:[^A-Za-z0-9_.\-]|$)`)
+		updatedContent    bytes.Buffer
+		regionContent     bytes.Buffer
+		inRegion          bool
+		regionFound       bool
+	)
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		switch {
+		case !inRegion:
+			updatedContent.Write(line)
+			if startMarkerRegexp.Match(line) {
+				inRegion = true
+				regionFound = true
+				regionContent.Reset()
+			}
+		case endMarkerRegexp.Match(line):
+			newRegionContent, err := u.regionContent(regionContent.Bytes(), value)
+			if err != nil {
+				return nil, err
+			}
+			updatedContent.Write(newRegionContent)
+			updatedContent.Write(line)
+			inRegion = false
+		default:
+			regionContent.Write(line)
+		}
+	}
+	if inRegion {
+		return nil, errors.New("missing end marker `octopilot:end`")
+	}
+
+	if !regionFound && u.CreateRegion {
+		if updatedContent.Len() > 0 && !bytes.HasSuffix(updatedContent.Bytes(), []byte("\n")) {
+			updatedContent.WriteString("\n")
+		}
+		updatedContent.WriteString(u.marker("octopilot:start name=" + u.Region))
+		newRegionContent, err := u.regionContent(nil, value)
+		if err != nil {
+			return nil, err
+		}
+		updatedContent.Write(newRegionContent)
+		updatedContent.WriteString(u.marker("octopilot:end"))
+	}
+
+	return updatedContent.Bytes(), nil
+}
+
+// regionContent returns the new content of a region
+func (u RegexUpdater) regionContent(currentContent []byte, value string) ([]byte, error) {
+	if u.Regexp != nil {
+		return u.replaceMatches(currentContent, value)
+	}
+	if len(value) > 0 && !strings.HasSuffix(value, "\n") {
+		value += "\n"
+	}
+	return []byte(value), nil
+}
+
+// marker returns a marker line, using the comment syntax
+func (u RegexUpdater) marker(marker string) string {
+	line := u.Comment + " " + marker
+	if len(u.CommentEnd) > 0 {
+		line += " " + u.CommentEnd
+	}
+	return line + "\n"
+}
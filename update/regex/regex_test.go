@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/dailymotion-oss/octopilot/internal/eol"
 	"github.com/dailymotion-oss/octopilot/update/value"
 
 	"github.com/stretchr/testify/assert"
@@ -45,6 +46,56 @@ func TestNewUpdater(t *testing.T) {
 				Regexp:   regexp.MustCompile(`\s+version: \"(.*)\"`),
 			},
 		},
+		{
+			name: "valid params with a region",
+			params: map[string]string{
+				"file":          "README.md",
+				"region":        "versions",
+				"create-region": "true",
+				"comment":       "<!--",
+				"comment-end":   "-->",
+			},
+			expected: &RegexUpdater{
+				FilePath:     "README.md",
+				Region:       "versions",
+				CreateRegion: true,
+				Comment:      "<!--",
+				CommentEnd:   "-->",
+			},
+		},
+		{
+			name: "valid params with a region and a pattern",
+			params: map[string]string{
+				"file":    "Makefile",
+				"region":  "tools",
+				"pattern": `VERSION=(.*)`,
+			},
+			expected: &RegexUpdater{
+				FilePath: "Makefile",
+				Region:   "tools",
+				Pattern:  `VERSION=(.*)`,
+				Regexp:   regexp.MustCompile(`VERSION=(.*)`),
+				Comment:  "#",
+			},
+		},
+		{
+			name: "invalid region name",
+			params: map[string]string{
+				"file":   "README.md",
+				"region": "my region",
+			},
+			expectedErrorMsg: "invalid region name my region: it must only contain letters, digits, dots, dashes or underscores",
+		},
+		{
+			name: "create region with a pattern",
+			params: map[string]string{
+				"file":          "Makefile",
+				"region":        "tools",
+				"pattern":       `VERSION=(.*)`,
+				"create-region": "true",
+			},
+			expectedErrorMsg: "the create-region parameter can't be used with the pattern parameter",
+		},
 		{
 			name:             "nil params",
 			expectedErrorMsg: "missing file parameter",
@@ -187,6 +238,23 @@ releases:
 `,
 			},
 		},
+		{
+			name: "convert to crlf line endings",
+			files: map[string]string{
+				"lf-to-crlf.txt": "first line\nversion: 1.0.0\nlast line\n",
+			},
+			updater: &RegexUpdater{
+				FilePath: "lf-to-crlf.txt",
+				Pattern:  `version: (.*)`,
+				Regexp:   regexp.MustCompile(`version: (.*)`),
+				EOL:      eol.CRLF,
+				Valuer:   value.StringValuer("2.0.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"lf-to-crlf.txt": "first line\r\nversion: 2.0.0\r\nlast line\r\n",
+			},
+		},
 		{
 			name: "update in multi-line mode in a single file",
 			files: map[string]string{
@@ -205,6 +273,132 @@ whatever content
 				"readme.txt": `new content`,
 			},
 		},
+		{
+			name: "replace the content of a region",
+			files: map[string]string{
+				"region-replace.sh": `#!/bin/sh
+# hand-written content
+echo "hello"
+# octopilot:start name=versions
+APP_VERSION=1.0.0
+OLD_VERSION=0.9.0
+# octopilot:end
+# more hand-written content
+APP_VERSION=keep-me
+`,
+			},
+			updater: &RegexUpdater{
+				FilePath: "region-replace.sh",
+				Region:   "versions",
+				Comment:  "#",
+				Valuer:   value.StringValuer("APP_VERSION=2.0.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"region-replace.sh": `#!/bin/sh
+# hand-written content
+echo "hello"
+# octopilot:start name=versions
+APP_VERSION=2.0.0
+# octopilot:end
+# more hand-written content
+APP_VERSION=keep-me
+`,
+			},
+		},
+		{
+			name: "update a region using a pattern",
+			files: map[string]string{
+				"region-pattern.mk": `VERSION=manual
+# octopilot:start name=tools
+LINT_VERSION=1.0.0
+VERSION=1.0.0
+# octopilot:end
+# octopilot:start name=others
+VERSION=1.0.0
+# octopilot:end
+`,
+			},
+			updater: &RegexUpdater{
+				FilePath: "region-pattern.mk",
+				Region:   "tools",
+				Pattern:  `(?m)^VERSION=(.*)$`,
+				Regexp:   regexp.MustCompile(`(?m)^VERSION=(.*)$`),
+				Comment:  "#",
+				Valuer:   value.StringValuer("2.0.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"region-pattern.mk": `VERSION=manual
+# octopilot:start name=tools
+LINT_VERSION=1.0.0
+VERSION=2.0.0
+# octopilot:end
+# octopilot:start name=others
+VERSION=1.0.0
+# octopilot:end
+`,
+			},
+		},
+		{
+			name: "create a missing region",
+			files: map[string]string{
+				"region-create.md": `# My project
+
+Some hand-written documentation.`,
+			},
+			updater: &RegexUpdater{
+				FilePath:     "region-create.md",
+				Region:       "versions",
+				CreateRegion: true,
+				Comment:      "<!--",
+				CommentEnd:   "-->",
+				Valuer:       value.StringValuer("Current version: 1.0.0\n"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"region-create.md": `# My project
+
+Some hand-written documentation.
+<!-- octopilot:start name=versions -->
+Current version: 1.0.0
+<!-- octopilot:end -->
+`,
+			},
+		},
+		{
+			name: "missing region without creation",
+			files: map[string]string{
+				"region-missing.txt": `VERSION=1.0.0
+`,
+			},
+			updater: &RegexUpdater{
+				FilePath: "region-missing.txt",
+				Region:   "versions",
+				Comment:  "#",
+				Valuer:   value.StringValuer("VERSION=2.0.0"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"region-missing.txt": `VERSION=1.0.0
+`,
+			},
+		},
+		{
+			name: "region without end marker",
+			files: map[string]string{
+				"region-no-end.txt": `# octopilot:start name=versions
+VERSION=1.0.0
+`,
+			},
+			updater: &RegexUpdater{
+				FilePath: "region-no-end.txt",
+				Region:   "versions",
+				Comment:  "#",
+				Valuer:   value.StringValuer("VERSION=2.0.0"),
+			},
+			expectedErrorMsg: "failed to update region versions in file region-no-end.txt: missing end marker `octopilot:end`",
+		},
 		{
 			name: "no update in a single file",
 			files: map[string]string{
@@ -0,0 +1,109 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/dailymotion-oss/octopilot/update/yaml"
+
+	"github.com/sirupsen/logrus"
+)
+
+// restartedAtAnnotation is the pod template annotation set by "kubectl rollout restart" to trigger a new rollout
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// rolloutKinds are the kinds of Kubernetes workloads which can be restarted with the restartedAt annotation
+var rolloutKinds = []string{"Deployment", "StatefulSet", "DaemonSet"}
+
+// RolloutRestartUpdater is an updater that wraps another updater - which changes a ConfigMap or a Secret for example - and sets the restartedAt annotation on the pod template of a Kubernetes workload,
+// so that the workload is restarted when the change is deployed. The annotation is only set if the wrapped updater made some changes.
+type RolloutRestartUpdater struct {
+	Updater  Updater
+	FilePath string
+	Name     string
+	// now returns the timestamp of the restart - defaults to the current time
+	now func() time.Time
+}
+
+// newRolloutRestartUpdater wraps the given updater with a rollout restart of the workload(s) defined in the given manifest file(s)
+func newRolloutRestartUpdater(updater Updater, params map[string]string) (*RolloutRestartUpdater, error) {
+	u := &RolloutRestartUpdater{
+		Updater:  updater,
+		FilePath: strings.TrimSpace(params["rollout-restart"]),
+		Name:     strings.TrimSpace(params["rollout-restart-name"]),
+	}
+	if len(u.FilePath) == 0 {
+		return nil, errors.New("empty rollout-restart parameter")
+	}
+	return u, nil
+}
+
+// Update runs the wrapped updater, then sets the restartedAt annotation on the workload(s) if the wrapped updater made some changes
+func (u *RolloutRestartUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	updated, err := u.Updater.Update(ctx, repoPath)
+	if err != nil || !updated {
+		return updated, err
+	}
+
+	now := time.Now
+	if u.now != nil {
+		now = u.now
+	}
+	annotationUpdater := &yaml.YamlUpdater{
+		FilePath:   u.FilePath,
+		Path:       u.annotationPath(),
+		AutoCreate: true,
+		Style:      "double",
+		Indent:     2,
+		Valuer:     value.StringValuer(now().UTC().Format(time.RFC3339)),
+	}
+	restarted, err := annotationUpdater.Update(ctx, repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to set the %s annotation in %s: %w", restartedAtAnnotation, u.FilePath, err)
+	}
+	if !restarted {
+		logrus.WithFields(logrus.Fields{
+			"file": u.FilePath,
+			"name": u.Name,
+		}).Warn("No workload restarted: the restartedAt annotation has not been changed")
+	}
+
+	return true, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *RolloutRestartUpdater) Message() (title, body string) {
+	title, body = u.Updater.Message()
+	body = fmt.Sprintf("%s\n\nRestarting the %s defined in `%s`", body, u.workloadDescription(), u.FilePath)
+	return title, strings.TrimSpace(body)
+}
+
+// String returns a string representation of the updater
+func (u *RolloutRestartUpdater) String() string {
+	return fmt.Sprintf("%s | RolloutRestart[file=%s,name=%s]", u.Updater.String(), u.FilePath, u.Name)
+}
+
+// annotationPath returns the yq expression of the restartedAt annotation of the workload(s) - the other documents of the file are left untouched
+func (u *RolloutRestartUpdater) annotationPath() string {
+	kinds := make([]string, 0, len(rolloutKinds))
+	for _, kind := range rolloutKinds {
+		kinds = append(kinds, fmt.Sprintf(".kind == %q", kind))
+	}
+	selector := "(" + strings.Join(kinds, " or ") + ")"
+	if len(u.Name) > 0 {
+		selector = fmt.Sprintf("%s and .metadata.name == %q", selector, u.Name)
+	}
+	return fmt.Sprintf("(select(%s) | .spec.template.metadata.annotations[%s])", selector, strconv.Quote(restartedAtAnnotation))
+}
+
+func (u *RolloutRestartUpdater) workloadDescription() string {
+	if len(u.Name) > 0 {
+		return fmt.Sprintf("workload `%s`", u.Name)
+	}
+	return "workload(s)"
+}
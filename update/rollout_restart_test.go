@@ -0,0 +1,186 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+	"github.com/dailymotion-oss/octopilot/update/yaml"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRolloutRestartUpdaterUpdate(t *testing.T) {
+	t.Parallel()
+
+	const (
+		configMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app
+data:
+  LOG_LEVEL: info
+`
+		manifests = `apiVersion: v1
+kind: Service
+metadata:
+  name: my-app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app
+spec:
+  template:
+    metadata:
+      labels:
+        app: my-app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-worker
+spec:
+  template:
+    metadata:
+      annotations:
+        kubectl.kubernetes.io/restartedAt: "2023-01-01T00:00:00Z"
+`
+	)
+	now := func() time.Time {
+		return time.Date(2023, time.June, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	}
+
+	tests := []struct {
+		name              string
+		logLevel          string
+		workload          string
+		expected          bool
+		expectedConfigMap string
+		expectedManifests string
+	}{
+		{
+			name:     "config changed",
+			logLevel: "debug",
+			workload: "my-app",
+			expected: true,
+			expectedConfigMap: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app
+data:
+  LOG_LEVEL: debug
+`,
+			expectedManifests: `apiVersion: v1
+kind: Service
+metadata:
+  name: my-app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app
+spec:
+  template:
+    metadata:
+      labels:
+        app: my-app
+      annotations:
+        kubectl.kubernetes.io/restartedAt: "2023-06-01T10:30:00Z"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-worker
+spec:
+  template:
+    metadata:
+      annotations:
+        kubectl.kubernetes.io/restartedAt: "2023-01-01T00:00:00Z"
+`,
+		},
+		{
+			name:     "config changed without a workload name",
+			logLevel: "debug",
+			expected: true,
+			expectedConfigMap: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app
+data:
+  LOG_LEVEL: debug
+`,
+			expectedManifests: `apiVersion: v1
+kind: Service
+metadata:
+  name: my-app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app
+spec:
+  template:
+    metadata:
+      labels:
+        app: my-app
+      annotations:
+        kubectl.kubernetes.io/restartedAt: "2023-06-01T10:30:00Z"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-worker
+spec:
+  template:
+    metadata:
+      annotations:
+        kubectl.kubernetes.io/restartedAt: "2023-06-01T10:30:00Z"
+`,
+		},
+		{
+			name:              "config unchanged",
+			logLevel:          "info",
+			workload:          "my-app",
+			expected:          false,
+			expectedConfigMap: configMap,
+			expectedManifests: manifests,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(repoPath, "configmap.yaml"), []byte(configMap), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(repoPath, "deployment.yaml"), []byte(manifests), 0644))
+
+			updater := &RolloutRestartUpdater{
+				Updater: &yaml.YamlUpdater{
+					FilePath: "configmap.yaml",
+					Path:     "data.LOG_LEVEL",
+					Indent:   2,
+					Valuer:   value.StringValuer(test.logLevel),
+				},
+				FilePath: "deployment.yaml",
+				Name:     test.workload,
+				now:      now,
+			}
+			updated, err := updater.Update(context.Background(), repoPath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, updated)
+
+			actualConfigMap, err := os.ReadFile(filepath.Join(repoPath, "configmap.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedConfigMap, string(actualConfigMap))
+			actualManifests, err := os.ReadFile(filepath.Join(repoPath, "deployment.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedManifests, string(actualManifests))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package sops
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/dailymotion-oss/octopilot/internal/yaml"
+)
+
+// supported formats of the base64-encoded embedded configs
+const (
+	embeddedFormatYAML = "yaml"
+	embeddedFormatJSON = "json"
+)
+
+// updateEmbeddedValue decodes the given base64-encoded config, sets the value of its inner (dotted) path, and returns the re-encoded config.
+// It returns the original encoded config if the inner value didn't change - so that the file is not needlessly re-encrypted.
+func updateEmbeddedValue(encoded, format, innerPath, value string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", fmt.Errorf("failed to base64-decode the embedded config: %w", err)
+	}
+
+	switch format {
+	case embeddedFormatYAML:
+		var rootNode yamlv3.Node
+		err = yamlv3.Unmarshal(data, &rootNode)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse the embedded YAML config: %w", err)
+		}
+		if rootNode.Kind == 0 {
+			rootNode = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{{Kind: yamlv3.MappingNode, Tag: "!!map"}}}
+		}
+		updated, err := yaml.SetValue(rootNode.Content[0], strings.Split(innerPath, "."), value)
+		if err != nil {
+			return "", fmt.Errorf("failed to set %s in the embedded YAML config: %w", innerPath, err)
+		}
+		if !updated {
+			return encoded, nil
+		}
+		var buffer bytes.Buffer
+		enc := yamlv3.NewEncoder(&buffer)
+		enc.SetIndent(2)
+		err = enc.Encode(&rootNode)
+		if err == nil {
+			err = enc.Close()
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to encode the embedded YAML config: %w", err)
+		}
+		data = buffer.Bytes()
+	case embeddedFormatJSON:
+		var config interface{}
+		err = json.Unmarshal(data, &config)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse the embedded JSON config: %w", err)
+		}
+		config, updated, err := setJSONValue(config, strings.Split(innerPath, "."), value)
+		if err != nil {
+			return "", fmt.Errorf("failed to set %s in the embedded JSON config: %w", innerPath, err)
+		}
+		if !updated {
+			return encoded, nil
+		}
+		data, err = json.Marshal(config)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode the embedded JSON config: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("unsupported embedded format %s", format)
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// setJSONValue sets the value of the (nested) field in the given JSON object, creating any missing object on the way.
+// It returns the updated object, and true if the value has changed.
+func setJSONValue(config interface{}, path []string, value string) (interface{}, bool, error) {
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	object, ok := config.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("can't set %s on a non-object value", path[0])
+	}
+
+	if len(path) > 1 {
+		child, updated, err := setJSONValue(object[path[0]], path[1:], value)
+		if err != nil {
+			return nil, false, err
+		}
+		object[path[0]] = child
+		return object, updated, nil
+	}
+
+	var newValue interface{} = value
+	switch current := object[path[0]].(type) {
+	case map[string]interface{}, []interface{}:
+		return nil, false, errors.New(path[0] + " is not a scalar value")
+	case string:
+		if current == value {
+			return object, false, nil
+		}
+	case float64:
+		// keep the type of the existing value
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			if number == current {
+				return object, false, nil
+			}
+			newValue = json.Number(value)
+		}
+	case bool:
+		if boolean, err := strconv.ParseBool(value); err == nil {
+			if boolean == current {
+				return object, false, nil
+			}
+			newValue = boolean
+		}
+	}
+	object[path[0]] = newValue
+	return object, true, nil
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"go.mozilla.org/sops/v3"
@@ -16,14 +17,50 @@ import (
 	"go.mozilla.org/sops/v3/cmd/sops/formats"
 	"go.mozilla.org/sops/v3/keyservice"
 
+	"github.com/dailymotion-oss/octopilot/internal/changes"
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	"github.com/dailymotion-oss/octopilot/internal/missingkey"
+	"github.com/dailymotion-oss/octopilot/internal/monotonic"
+	"github.com/dailymotion-oss/octopilot/internal/semantic"
 	"github.com/dailymotion-oss/octopilot/update/value"
 )
 
+// the separator of the key segments, and the character used to escape it in a segment
+const (
+	keySeparator = '.'
+	keyEscape    = '\\'
+)
+
+// defaultWildcard is the key segment matching all the keys at its level - unless another wildcard is configured
+const defaultWildcard = "*"
+
+// supported output formats of the sops-encrypted files
+const (
+	outputFormatYAML = "yaml"
+	outputFormatJSON = "json"
+)
+
 // SopsUpdater is an updater that uses the sops lib to update sops-encrypted files.
 type SopsUpdater struct {
-	FilePath string
-	Key      string
-	Valuer   value.Valuer
+	FilePath  string
+	Key       string
+	EOL       eol.Mode
+	Monotonic monotonic.Mode
+	Strict    bool
+	// Embedded is the format (yaml or json) of the base64-encoded config stored in the key - if any
+	Embedded     string
+	EmbeddedPath string
+	// IgnoreKeys are the keys ignored when checking if the file has changed - such as timestamps
+	IgnoreKeys []string
+	// MissingKey is the strategy used for the files which don't contain the key - by default, the key is created
+	MissingKey missingkey.Strategy
+	// OutputFormat is the format (yaml or json) used to write the files - if different from the format of their extension
+	OutputFormat string
+	// Wildcard is the key segment matching all the keys at its level - such as services.*.apiKey - by default "*"
+	Wildcard string
+	// SemanticDiff compares the data of the original and updated trees - instead of their YAML or JSON representation - so that formatting-only changes are ignored
+	SemanticDiff bool
+	Valuer       value.Valuer
 }
 
 // NewUpdater builds a new SOPS updater from the given parameters and valuer
@@ -39,6 +76,66 @@ func NewUpdater(params map[string]string, valuer value.Valuer) (*SopsUpdater, er
 	if len(updater.Key) == 0 {
 		return nil, errors.New("missing key parameter")
 	}
+	if _, err := convertKeyToPath(updater.Key); err != nil {
+		return nil, fmt.Errorf("invalid key parameter %s: %w", updater.Key, err)
+	}
+
+	var err error
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
+	}
+
+	updater.Monotonic, err = monotonic.ParseMode(params["monotonic"])
+	if err != nil {
+		return nil, err
+	}
+	updater.Strict, _ = strconv.ParseBool(params["strict"])
+
+	updater.Embedded = strings.ToLower(params["embedded"])
+	switch updater.Embedded {
+	case "":
+	case embeddedFormatYAML, embeddedFormatJSON:
+		updater.EmbeddedPath = params["embedded-path"]
+		if len(updater.EmbeddedPath) == 0 {
+			return nil, errors.New("missing embedded-path parameter")
+		}
+		if updater.Monotonic != monotonic.None {
+			return nil, errors.New("the monotonic parameter can't be used with an embedded config")
+		}
+	default:
+		return nil, fmt.Errorf("invalid embedded parameter %s: must be one of yaml or json", updater.Embedded)
+	}
+
+	if ignoreKeys := params["ignore-keys"]; len(ignoreKeys) > 0 {
+		for _, key := range strings.Split(ignoreKeys, ";") {
+			if key = strings.TrimSpace(key); len(key) > 0 {
+				if _, err := convertKeyToPath(key); err != nil {
+					return nil, fmt.Errorf("invalid ignore-keys parameter %s: %w", key, err)
+				}
+				updater.IgnoreKeys = append(updater.IgnoreKeys, key)
+			}
+		}
+	}
+
+	updater.MissingKey, err = missingkey.ParseStrategy(params["missing-key-strategy"])
+	if err != nil {
+		return nil, err
+	}
+
+	updater.OutputFormat = strings.ToLower(params["output-format"])
+	switch updater.OutputFormat {
+	case "", outputFormatYAML, outputFormatJSON:
+	default:
+		return nil, fmt.Errorf("invalid output-format parameter %s: must be one of yaml or json", updater.OutputFormat)
+	}
+
+	updater.Wildcard = params["wildcard"]
+	if strings.Contains(updater.Wildcard, ".") {
+		return nil, fmt.Errorf("invalid wildcard parameter %s: must not contain a dot", updater.Wildcard)
+	}
+
+	updater.SemanticDiff, _ = strconv.ParseBool(params["semantic-diff"])
 
 	updater.Valuer = valuer
 
@@ -74,20 +171,21 @@ func (u SopsUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 			return false, fmt.Errorf("failed to access file %s: %w", relFilePath, err)
 		}
 
-		var (
-			format = formats.FormatForPath(filePath)
-			store  = common.StoreForFormat(format)
-		)
+		fileData, err := os.ReadFile(filePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to read file %s: %w", relFilePath, err)
+		}
 
-		tree, err := common.LoadEncryptedFileWithBugFixes(common.GenericDecryptOpts{
-			Cipher:      cipher,
-			InputStore:  store,
-			InputPath:   filePath,
-			KeyServices: svcs,
-		})
+		inputFormat, tree, err := u.loadEncryptedFile(cipher, svcs, filePath)
 		if err != nil {
 			return false, fmt.Errorf("failed to load encrypted file %s: %w", filePath, err)
 		}
+		var (
+			outputFormat = u.outputFormat(filePath)
+			convert      = outputFormat != inputFormat
+			store        = common.StoreForFormat(inputFormat)
+			outputStore  = common.StoreForFormat(outputFormat)
+		)
 
 		dataKey, err := common.DecryptTree(common.DecryptTreeOpts{
 			Cipher:      cipher,
@@ -98,36 +196,100 @@ func (u SopsUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 			return false, fmt.Errorf("failed to decrypt tree for %s: %w", filePath, err)
 		}
 
-		originalData, err := store.EmitPlainFile(tree.Branches)
+		originalData, err := store.EmitPlainFile(u.maskIgnoredKeys(tree.Branches))
 		if err != nil {
 			return false, fmt.Errorf("failed to emit original tree for %s: %w", filePath, err)
 		}
+		var maskedOriginalData []byte
+		if changes.RecordsPlaintexts(ctx) {
+			// the changes are exported in plaintext, but with all the values masked
+			maskedOriginalData, err = store.EmitPlainFile(maskValues(tree.Branches))
+			if err != nil {
+				return false, fmt.Errorf("failed to emit masked original tree for %s: %w", filePath, err)
+			}
+		}
+
+		path, err := convertKeyToPath(u.Key)
+		if err != nil {
+			return false, fmt.Errorf("invalid key %s: %w", u.Key, err)
+		}
+		if u.Monotonic != monotonic.None {
+			increases, err := u.valueIncreases(tree.Branches, path, value)
+			if err != nil {
+				return false, fmt.Errorf("failed to compare the current value of key %s in file %s: %w", u.Key, relFilePath, err)
+			}
+			if !increases {
+				continue
+			}
+		}
 
-		path := convertKeyToPath(u.Key)
 		for i := range tree.Branches {
-			newTree := tree.Branches[i].Set(path, value)
-			// fix for https://github.com/mozilla/sops/issues/407
-			// to be removed once https://github.com/mozilla/sops/pull/899 gets merged & released
-			if previousTreeHasBeenErased(tree.Branches[i], newTree) {
-				// if the path top-level element doesn't exist, it will return a new tree with only our path
-				// the workaround is to add a single-level item first, and then the whole new branch
-				rootEntry := []interface{}{
-					path[0],
+			paths := u.expandPath(tree.Branches[i], path)
+			if len(paths) == 0 && u.MissingKey == missingkey.Error {
+				return false, fmt.Errorf("key %s not found in file %s (missing-key-strategy=%s)", u.Key, relFilePath, u.MissingKey)
+			}
+
+			for _, path := range paths {
+				if !hasKey(tree.Branches[i], path) {
+					switch u.MissingKey {
+					case missingkey.Skip:
+						continue
+					case missingkey.Error:
+						return false, fmt.Errorf("key %s not found in file %s (missing-key-strategy=%s)", u.Key, relFilePath, u.MissingKey)
+					}
+				}
+
+				value := value
+				if len(u.Embedded) > 0 {
+					encoded, found := lookupValue(tree.Branches[i], path)
+					if !found {
+						return false, fmt.Errorf("failed to update embedded config in file %s: key %s not found", relFilePath, u.Key)
+					}
+					value, err = updateEmbeddedValue(encoded, u.Embedded, u.EmbeddedPath, value)
+					if err != nil {
+						return false, fmt.Errorf("failed to update embedded config of key %s in file %s: %w", u.Key, relFilePath, err)
+					}
+				}
+
+				newTree := tree.Branches[i].Set(path, value)
+				// fix for https://github.com/mozilla/sops/issues/407
+				// to be removed once https://github.com/mozilla/sops/pull/899 gets merged & released
+				if previousTreeHasBeenErased(tree.Branches[i], newTree) {
+					// if the path top-level element doesn't exist, it will return a new tree with only our path
+					// the workaround is to add a single-level item first, and then the whole new branch
+					rootEntry := []interface{}{
+						path[0],
+					}
+					newTree = tree.Branches[i].Set(rootEntry, value)
+					newTree = newTree.Set(path, value)
 				}
-				newTree = tree.Branches[i].Set(rootEntry, value)
-				newTree = newTree.Set(path, value)
+				tree.Branches[i] = newTree
 			}
-			tree.Branches[i] = newTree
 		}
 
-		// check if we updated something or not, before re-encrypting...
-		updatedData, err := store.EmitPlainFile(tree.Branches)
+		// check if we updated something or not - ignoring the ignored keys - before re-encrypting...
+		updatedData, err := store.EmitPlainFile(u.maskIgnoredKeys(tree.Branches))
 		if err != nil {
 			return false, fmt.Errorf("failed to emit updated tree for %s: %w", filePath, err)
 		}
-		if string(updatedData) == string(originalData) {
+		// ...unless the file needs to be written in another format
+		unchanged := string(updatedData) == string(originalData)
+		if !unchanged && u.SemanticDiff && (inputFormat == formats.Yaml || inputFormat == formats.Json) {
+			unchanged, err = semantic.Equal(originalData, updatedData)
+			if err != nil {
+				return false, fmt.Errorf("failed to compare the data of file %s: %w", relFilePath, err)
+			}
+		}
+		if unchanged && !convert {
 			continue
 		}
+		var maskedUpdatedData []byte
+		if changes.RecordsPlaintexts(ctx) {
+			maskedUpdatedData, err = outputStore.EmitPlainFile(maskValues(tree.Branches))
+			if err != nil {
+				return false, fmt.Errorf("failed to emit masked updated tree for %s: %w", filePath, err)
+			}
+		}
 
 		err = common.EncryptTree(common.EncryptTreeOpts{
 			DataKey: dataKey,
@@ -138,15 +300,17 @@ func (u SopsUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 			return false, fmt.Errorf("failed to encrypt tree for %s: %w", filePath, err)
 		}
 
-		encryptedFile, err := store.EmitEncryptedFile(*tree)
+		encryptedFile, err := outputStore.EmitEncryptedFile(*tree)
 		if err != nil {
 			return false, fmt.Errorf("failed to generate re-encrypted file %s: %w", filePath, err)
 		}
 
+		encryptedFile = eol.Apply(u.EOL, fileData, encryptedFile)
 		err = os.WriteFile(filePath, encryptedFile, fileInfo.Mode())
 		if err != nil {
 			return false, fmt.Errorf("failed to write re-encrypted data to file %s: %w", filePath, err)
 		}
+		changes.RecordPlaintext(ctx, relFilePath, maskedOriginalData, maskedUpdatedData)
 
 		updated = true
 	}
@@ -158,20 +322,300 @@ func (u SopsUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 func (u SopsUpdater) Message() (title, body string) {
 	title = fmt.Sprintf("Update %s %s", u.FilePath, u.Key)
 	body = fmt.Sprintf("Updating sops-encrypted file `%s` key `%s`", u.FilePath, u.Key)
+	if len(u.Embedded) > 0 {
+		body = fmt.Sprintf("Updating path `%s` of the embedded %s config in sops-encrypted file `%s` key `%s`", u.EmbeddedPath, u.Embedded, u.FilePath, u.Key)
+	}
 	return title, body
 }
 
 // String returns a string representation of the updater
 func (u SopsUpdater) String() string {
-	return fmt.Sprintf("Sops[key=%s,file=%s]", u.Key, u.FilePath)
+	var output string
+	if len(u.OutputFormat) > 0 {
+		output = ",output-format=" + u.OutputFormat
+	}
+	if len(u.Embedded) > 0 {
+		return fmt.Sprintf("Sops[key=%s,file=%s,embedded=%s,embedded-path=%s%s]", u.Key, u.FilePath, u.Embedded, u.EmbeddedPath, output)
+	}
+	return fmt.Sprintf("Sops[key=%s,file=%s%s]", u.Key, u.FilePath, output)
+}
+
+// loadEncryptedFile loads the given encrypted file, and returns the format it has been read with.
+// The format is the one of the file's extension, but if an output format is set and the file can't be read with the format of its extension,
+// the output format is used: the file has already been converted by a previous update - such as a .json file written as YAML.
+func (u SopsUpdater) loadEncryptedFile(cipher sops.Cipher, svcs []keyservice.KeyServiceClient, filePath string) (formats.Format, *sops.Tree, error) {
+	format := formats.FormatForPath(filePath)
+	tree, err := common.LoadEncryptedFileWithBugFixes(common.GenericDecryptOpts{
+		Cipher:      cipher,
+		InputStore:  common.StoreForFormat(format),
+		InputPath:   filePath,
+		KeyServices: svcs,
+	})
+	if err == nil {
+		return format, tree, nil
+	}
+
+	outputFormat := u.outputFormat(filePath)
+	if outputFormat == format {
+		return format, nil, err
+	}
+	tree, outputErr := common.LoadEncryptedFileWithBugFixes(common.GenericDecryptOpts{
+		Cipher:      cipher,
+		InputStore:  common.StoreForFormat(outputFormat),
+		InputPath:   filePath,
+		KeyServices: svcs,
+	})
+	if outputErr != nil {
+		// return the error for the format of the extension, which is the format expected by default
+		return format, nil, err
+	}
+	return outputFormat, tree, nil
+}
+
+// outputFormat returns the format used to write the given file: the output format if set, or the format of the file's extension
+func (u SopsUpdater) outputFormat(filePath string) formats.Format {
+	if len(u.OutputFormat) == 0 {
+		return formats.FormatForPath(filePath)
+	}
+	return formats.FormatFromString(u.OutputFormat)
+}
+
+// valueIncreases returns true if the new value is greater than the current value of the key in all the branches - using the monotonic mode.
+// If the key doesn't exist yet, there is nothing to compare, so the value can be written.
+// If the value doesn't increase and the strict mode is enabled, an error is returned.
+func (u SopsUpdater) valueIncreases(branches sops.TreeBranches, path []interface{}, value string) (bool, error) {
+	for _, branch := range branches {
+		for _, path := range u.expandPath(branch, path) {
+			current, found := lookupValue(branch, path)
+			if !found {
+				continue
+			}
+			increases, err := monotonic.IsGreater(u.Monotonic, current, value)
+			if err != nil {
+				return false, err
+			}
+			if !increases {
+				if u.Strict {
+					return false, fmt.Errorf("new value %q is not greater than current value %q (monotonic=%s)", strings.TrimSpace(value), current, u.Monotonic)
+				}
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// expandPath returns the concrete paths matching the given path in the tree branch: a wildcard segment matches all the keys at its level.
+// A wildcard in the middle of the path only matches the maps, and a wildcard at the end of the path only matches the scalar leaves.
+// A path without wildcard is returned as-is - even if it doesn't exist yet in the tree branch, so that it can be created.
+func (u SopsUpdater) expandPath(branch sops.TreeBranch, path []interface{}) [][]interface{} {
+	wildcard := u.Wildcard
+	if len(wildcard) == 0 {
+		wildcard = defaultWildcard
+	}
+
+	index := -1
+	for i, segment := range path {
+		if segment == wildcard {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return [][]interface{}{path}
+	}
+
+	parent := branch
+	for _, segment := range path[:index] {
+		child, found := lookupBranch(parent, segment)
+		if !found {
+			return nil
+		}
+		parent = child
+	}
+
+	var paths [][]interface{}
+	for _, item := range parent {
+		if _, isComment := item.Key.(sops.Comment); isComment {
+			continue
+		}
+		prefix := append(append([]interface{}{}, path[:index]...), item.Key)
+		switch child := item.Value.(type) {
+		case sops.TreeBranch:
+			if index == len(path)-1 {
+				continue
+			}
+			for _, childPath := range u.expandPath(child, path[index+1:]) {
+				paths = append(paths, append(append([]interface{}{}, prefix...), childPath...))
+			}
+		case []interface{}:
+		default:
+			if index == len(path)-1 {
+				paths = append(paths, prefix)
+			}
+		}
+	}
+	return paths
+}
+
+// lookupBranch returns the child tree branch with the given key
+func lookupBranch(branch sops.TreeBranch, key interface{}) (sops.TreeBranch, bool) {
+	for _, item := range branch {
+		if item.Key == key {
+			child, ok := item.Value.(sops.TreeBranch)
+			return child, ok
+		}
+	}
+	return nil, false
+}
+
+// maskIgnoredKeys returns a copy of the given branches without the ignored keys, so that they are not taken into account when comparing the trees.
+func (u SopsUpdater) maskIgnoredKeys(branches sops.TreeBranches) sops.TreeBranches {
+	if len(u.IgnoreKeys) == 0 {
+		return branches
+	}
+	masked := make(sops.TreeBranches, len(branches))
+	for i := range branches {
+		masked[i] = branches[i]
+		for _, key := range u.IgnoreKeys {
+			// the ignored keys have been validated when creating the updater
+			if path, err := convertKeyToPath(key); err == nil {
+				masked[i] = removeKey(masked[i], path)
+			}
+		}
+	}
+	return masked
+}
+
+// maskValues returns a copy of the given branches with all the values masked - except the comments - so that they can be exported without their secrets
+func maskValues(branches sops.TreeBranches) sops.TreeBranches {
+	masked := make(sops.TreeBranches, len(branches))
+	for i := range branches {
+		masked[i] = maskBranch(branches[i])
+	}
+	return masked
+}
+
+func maskBranch(branch sops.TreeBranch) sops.TreeBranch {
+	masked := make(sops.TreeBranch, 0, len(branch))
+	for _, item := range branch {
+		if _, isComment := item.Key.(sops.Comment); !isComment {
+			item.Value = maskValue(item.Value)
+		}
+		masked = append(masked, item)
+	}
+	return masked
 }
 
-func convertKeyToPath(key string) []interface{} {
-	path := make([]interface{}, 0)
-	for _, entry := range strings.Split(key, ".") {
-		path = append(path, entry)
+func maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case sops.TreeBranch:
+		return maskBranch(v)
+	case []interface{}:
+		masked := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			masked = append(masked, maskValue(item))
+		}
+		return masked
+	case sops.Comment, nil:
+		return v
+	default:
+		return changes.Mask(fmt.Sprint(v))
+	}
+}
+
+// removeKey returns a copy of the tree branch without the value at the given path - the original tree branch is not modified
+func removeKey(branch sops.TreeBranch, path []interface{}) sops.TreeBranch {
+	result := make(sops.TreeBranch, 0, len(branch))
+	for _, item := range branch {
+		if item.Key != path[0] {
+			result = append(result, item)
+			continue
+		}
+		if len(path) == 1 {
+			continue
+		}
+		if child, ok := item.Value.(sops.TreeBranch); ok {
+			item.Value = removeKey(child, path[1:])
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// hasKey returns true if the tree branch has a value - of any type - at the given path
+func hasKey(branch sops.TreeBranch, path []interface{}) bool {
+	for _, item := range branch {
+		if item.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return true
+		}
+		if child, ok := item.Value.(sops.TreeBranch); ok {
+			return hasKey(child, path[1:])
+		}
+		return false
+	}
+	return false
+}
+
+// lookupValue returns the string representation of the (scalar) value at the given path in the tree branch
+func lookupValue(branch sops.TreeBranch, path []interface{}) (string, bool) {
+	for _, item := range branch {
+		if item.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			switch item.Value.(type) {
+			case sops.TreeBranch, []interface{}, nil:
+				return "", false
+			default:
+				return fmt.Sprint(item.Value), true
+			}
+		}
+		if child, ok := item.Value.(sops.TreeBranch); ok {
+			return lookupValue(child, path[1:])
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// convertKeyToPath splits the given key on the separator, and returns its path. A backslash escapes the separator - or itself -
+// so that a key segment can contain a dot: a\.b.c is the ["a.b", "c"] path, and a\\.b is the ["a\", "b"] path. Any other backslash is kept as-is.
+// The path can't have empty segments: a key with a leading, a trailing, or consecutive separators is invalid.
+func convertKeyToPath(key string) ([]interface{}, error) {
+	if len(key) == 0 {
+		return nil, errors.New("empty key")
+	}
+
+	var (
+		path    []interface{}
+		segment strings.Builder
+	)
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; {
+		case c == keyEscape && i+1 < len(key) && (key[i+1] == keySeparator || key[i+1] == keyEscape):
+			i++
+			segment.WriteByte(key[i])
+		case c == keySeparator:
+			if segment.Len() == 0 {
+				if i == 0 {
+					return nil, errors.New("leading separator")
+				}
+				return nil, fmt.Errorf("empty segment at position %d", i)
+			}
+			path = append(path, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteByte(c)
+		}
+	}
+	if segment.Len() == 0 {
+		return nil, errors.New("trailing separator")
 	}
-	return path
+	return append(path, segment.String()), nil
 }
 
 func previousTreeHasBeenErased(previous, next sops.TreeBranch) bool {
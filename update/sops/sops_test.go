@@ -1,6 +1,7 @@
 package sops
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
@@ -16,6 +17,8 @@ import (
 	"go.mozilla.org/sops/v3/decrypt"
 	"go.mozilla.org/sops/v3/keys"
 
+	"github.com/dailymotion-oss/octopilot/internal/missingkey"
+	"github.com/dailymotion-oss/octopilot/internal/monotonic"
 	"github.com/dailymotion-oss/octopilot/update/value"
 )
 
@@ -38,10 +41,190 @@ func TestNewUpdater(t *testing.T) {
 				Key:      "path.to.key",
 			},
 		},
+		{
+			name: "monotonic params",
+			params: map[string]string{
+				"file":      "secrets.yaml",
+				"key":       "app.version",
+				"monotonic": "semver",
+				"strict":    "true",
+			},
+			expected: &SopsUpdater{
+				FilePath:  "secrets.yaml",
+				Key:       "app.version",
+				Monotonic: monotonic.SemVer,
+				Strict:    true,
+			},
+		},
+		{
+			name: "invalid monotonic param",
+			params: map[string]string{
+				"file":      "secrets.yaml",
+				"key":       "app.version",
+				"monotonic": "date",
+			},
+			expectedErrorMsg: "invalid monotonic mode date: must be one of semver, numeric or lexical",
+		},
+		{
+			name: "embedded config params",
+			params: map[string]string{
+				"file":          "secrets.yaml",
+				"key":           "app.config",
+				"embedded":      "YAML",
+				"embedded-path": "server.port",
+			},
+			expected: &SopsUpdater{
+				FilePath:     "secrets.yaml",
+				Key:          "app.config",
+				Embedded:     "yaml",
+				EmbeddedPath: "server.port",
+			},
+		},
+		{
+			name: "embedded config without inner path",
+			params: map[string]string{
+				"file":     "secrets.yaml",
+				"key":      "app.config",
+				"embedded": "json",
+			},
+			expectedErrorMsg: "missing embedded-path parameter",
+		},
+		{
+			name: "invalid embedded format",
+			params: map[string]string{
+				"file":          "secrets.yaml",
+				"key":           "app.config",
+				"embedded":      "toml",
+				"embedded-path": "server.port",
+			},
+			expectedErrorMsg: "invalid embedded parameter toml: must be one of yaml or json",
+		},
+		{
+			name: "ignore keys params",
+			params: map[string]string{
+				"file":        "secrets.yaml",
+				"key":         "app.token",
+				"ignore-keys": "app.lastUpdated; metadata.generatedAt",
+			},
+			expected: &SopsUpdater{
+				FilePath:   "secrets.yaml",
+				Key:        "app.token",
+				IgnoreKeys: []string{"app.lastUpdated", "metadata.generatedAt"},
+			},
+		},
+		{
+			name: "missing-key-strategy param",
+			params: map[string]string{
+				"file":                 "secrets.yaml",
+				"key":                  "app.token",
+				"missing-key-strategy": "skip",
+			},
+			expected: &SopsUpdater{
+				FilePath:   "secrets.yaml",
+				Key:        "app.token",
+				MissingKey: missingkey.Skip,
+			},
+		},
+		{
+			name: "invalid missing-key-strategy param",
+			params: map[string]string{
+				"file":                 "secrets.yaml",
+				"key":                  "app.token",
+				"missing-key-strategy": "ignore",
+			},
+			expectedErrorMsg: "invalid missing-key-strategy ignore: must be one of skip, create or error",
+		},
 		{
 			name:             "nil params",
 			expectedErrorMsg: "missing file parameter",
 		},
+		{
+			name: "output format",
+			params: map[string]string{
+				"file":          "secrets.json",
+				"key":           "path.to.key",
+				"output-format": "YAML",
+			},
+			expected: &SopsUpdater{
+				FilePath:     "secrets.json",
+				Key:          "path.to.key",
+				OutputFormat: "yaml",
+			},
+		},
+		{
+			name: "invalid output format",
+			params: map[string]string{
+				"file":          "secrets.json",
+				"key":           "path.to.key",
+				"output-format": "ini",
+			},
+			expectedErrorMsg: "invalid output-format parameter ini: must be one of yaml or json",
+		},
+		{
+			name: "wildcard",
+			params: map[string]string{
+				"file":     "secrets.yaml",
+				"key":      "services.%.apiKey",
+				"wildcard": "%",
+			},
+			expected: &SopsUpdater{
+				FilePath: "secrets.yaml",
+				Key:      "services.%.apiKey",
+				Wildcard: "%",
+			},
+		},
+		{
+			name: "semantic diff",
+			params: map[string]string{
+				"file":          "secrets.yaml",
+				"key":           "path.to.key",
+				"semantic-diff": "true",
+			},
+			expected: &SopsUpdater{
+				FilePath:     "secrets.yaml",
+				Key:          "path.to.key",
+				SemanticDiff: true,
+			},
+		},
+		{
+			name: "escaped separator in the key",
+			params: map[string]string{
+				"file":        "secrets.yaml",
+				"key":         `hosts.api\.example\.com.token`,
+				"ignore-keys": `metadata.last\.update`,
+			},
+			expected: &SopsUpdater{
+				FilePath:   "secrets.yaml",
+				Key:        `hosts.api\.example\.com.token`,
+				IgnoreKeys: []string{`metadata.last\.update`},
+			},
+		},
+		{
+			name: "key with a trailing separator",
+			params: map[string]string{
+				"file": "secrets.yaml",
+				"key":  "path.to.",
+			},
+			expectedErrorMsg: "invalid key parameter path.to.: trailing separator",
+		},
+		{
+			name: "ignored key with empty segment",
+			params: map[string]string{
+				"file":        "secrets.yaml",
+				"key":         "path.to.key",
+				"ignore-keys": "metadata..timestamp",
+			},
+			expectedErrorMsg: "invalid ignore-keys parameter metadata..timestamp: empty segment at position 9",
+		},
+		{
+			name: "invalid wildcard",
+			params: map[string]string{
+				"file":     "secrets.yaml",
+				"key":      "services.*.apiKey",
+				"wildcard": "a.b",
+			},
+			expectedErrorMsg: "invalid wildcard parameter a.b: must not contain a dot",
+		},
 		{
 			name: "missing mandatory file param",
 			params: map[string]string{
@@ -83,6 +266,7 @@ func TestUpdate(t *testing.T) {
 		expected         bool
 		expectedErrorMsg string
 		expectedFiles    map[string]string
+		crlf             bool
 	}{
 		{
 			name: "update an existing secret value",
@@ -184,6 +368,387 @@ second-app:
 				"new-secrets-root.yaml": `first-app:
     token: some-token
 newtoken: new-token-value
+`,
+			},
+		},
+		{
+			name: "monotonic upgrade",
+			files: map[string]string{
+				"monotonic-upgrade-secrets.yaml": `app:
+    version: 1.9.0
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath:  "monotonic-upgrade-secrets.yaml",
+				Key:       "app.version",
+				Monotonic: monotonic.SemVer,
+				Valuer:    value.StringValuer("1.10.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"monotonic-upgrade-secrets.yaml": `app:
+    version: 1.10.0
+`,
+			},
+		},
+		{
+			name: "monotonic same value",
+			files: map[string]string{
+				"monotonic-same-secrets.yaml": `app:
+    build: 42
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath:  "monotonic-same-secrets.yaml",
+				Key:       "app.build",
+				Monotonic: monotonic.Numeric,
+				Strict:    true,
+				Valuer:    value.StringValuer("42"),
+			},
+			expectedErrorMsg: `failed to compare the current value of key app.build in file monotonic-same-secrets.yaml: new value "42" is not greater than current value "42" (monotonic=numeric)`,
+		},
+		{
+			name: "monotonic downgrade skipped",
+			files: map[string]string{
+				"monotonic-downgrade-secrets.yaml": `app:
+    version: v2.0.0
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath:  "monotonic-downgrade-secrets.yaml",
+				Key:       "app.version",
+				Monotonic: monotonic.SemVer,
+				Valuer:    value.StringValuer("v1.0.0"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"monotonic-downgrade-secrets.yaml": `app:
+    version: v2.0.0
+`,
+			},
+		},
+		{
+			name: "update a field of a base64-embedded YAML config",
+			files: map[string]string{
+				"embedded-yaml-secrets.yaml": `app:
+    config: c2VydmVyOgogIGhvc3Q6IGxvY2FsaG9zdAogIHBvcnQ6IDgwODAKbmFtZTogYXBwCg==
+    token: some-token
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath:     "embedded-yaml-secrets.yaml",
+				Key:          "app.config",
+				Embedded:     "yaml",
+				EmbeddedPath: "server.port",
+				Valuer:       value.StringValuer("9090"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				// server:\n  host: localhost\n  port: 9090\nname: app\n
+				"embedded-yaml-secrets.yaml": `app:
+    config: c2VydmVyOgogIGhvc3Q6IGxvY2FsaG9zdAogIHBvcnQ6IDkwOTAKbmFtZTogYXBwCg==
+    token: some-token
+`,
+			},
+		},
+		{
+			name: "update a field of a base64-embedded JSON config",
+			files: map[string]string{
+				"embedded-json-secrets.yaml": `config: eyJuYW1lIjoiYXBwIiwic2VydmVyIjp7InBvcnQiOjgwODB9fQ==
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath:     "embedded-json-secrets.yaml",
+				Key:          "config",
+				Embedded:     "json",
+				EmbeddedPath: "server.port",
+				Valuer:       value.StringValuer("9090"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				// {"name":"app","server":{"port":9090}}
+				"embedded-json-secrets.yaml": `config: eyJuYW1lIjoiYXBwIiwic2VydmVyIjp7InBvcnQiOjkwOTB9fQ==
+`,
+			},
+		},
+		{
+			name: "no change to a field of a base64-embedded YAML config",
+			files: map[string]string{
+				"embedded-no-changes-secrets.yaml": `config: c2VydmVyOgogIGhvc3Q6IGxvY2FsaG9zdAogIHBvcnQ6IDgwODAKbmFtZTogYXBwCg==
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath:     "embedded-no-changes-secrets.yaml",
+				Key:          "config",
+				Embedded:     "yaml",
+				EmbeddedPath: "server.port",
+				Valuer:       value.StringValuer("8080"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"embedded-no-changes-secrets.yaml": `config: c2VydmVyOgogIGhvc3Q6IGxvY2FsaG9zdAogIHBvcnQ6IDgwODAKbmFtZTogYXBwCg==
+`,
+			},
+		},
+		{
+			name: "missing key for a base64-embedded config",
+			files: map[string]string{
+				"embedded-missing-secrets.yaml": `token: some-token
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath:     "embedded-missing-secrets.yaml",
+				Key:          "config",
+				Embedded:     "yaml",
+				EmbeddedPath: "server.port",
+				Valuer:       value.StringValuer("9090"),
+			},
+			expectedErrorMsg: "failed to update embedded config in file embedded-missing-secrets.yaml: key config not found",
+		},
+		{
+			name: "only an ignored key changed",
+			files: map[string]string{
+				"ignored-keys-secrets.yaml": `app:
+    token: good-token
+    lastUpdated: "2023-01-01T00:00:00Z"
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath:   "ignored-keys-secrets.yaml",
+				Key:        "app.lastUpdated",
+				IgnoreKeys: []string{"app.lastUpdated"},
+				Valuer:     value.StringValuer("2023-06-01T12:00:00Z"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"ignored-keys-secrets.yaml": `app:
+    token: good-token
+    lastUpdated: "2023-01-01T00:00:00Z"
+`,
+			},
+		},
+		{
+			name: "skip the files without the key with the skip missing-key-strategy",
+			files: map[string]string{
+				"missing-key-skip-with.yaml":    "app:\n    token: old-token\n",
+				"missing-key-skip-without.yaml": "other: value\n",
+			},
+			updater: &SopsUpdater{
+				FilePath:   "missing-key-skip-*.yaml",
+				Key:        "app.token",
+				MissingKey: missingkey.Skip,
+				Valuer:     value.StringValuer("new-token"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"missing-key-skip-with.yaml":    "app:\n    token: new-token\n",
+				"missing-key-skip-without.yaml": "other: value\n",
+			},
+		},
+		{
+			name: "add the key to all the files with the create missing-key-strategy",
+			files: map[string]string{
+				"missing-key-create-with.yaml":    "app:\n    token: old-token\n",
+				"missing-key-create-without.yaml": "other: value\n",
+			},
+			updater: &SopsUpdater{
+				FilePath:   "missing-key-create-*.yaml",
+				Key:        "app.token",
+				MissingKey: missingkey.Create,
+				Valuer:     value.StringValuer("new-token"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"missing-key-create-with.yaml":    "app:\n    token: new-token\n",
+				"missing-key-create-without.yaml": "other: value\napp:\n    token: new-token\n",
+			},
+		},
+		{
+			name: "fail if a file doesn't have the key with the error missing-key-strategy",
+			files: map[string]string{
+				"missing-key-error-with.yaml":    "app:\n    token: old-token\n",
+				"missing-key-error-without.yaml": "app:\n    other: value\n",
+			},
+			updater: &SopsUpdater{
+				FilePath:   "missing-key-error-*.yaml",
+				Key:        "app.token",
+				MissingKey: missingkey.Error,
+				Valuer:     value.StringValuer("new-token"),
+			},
+			expectedErrorMsg: "key app.token not found in file missing-key-error-without.yaml (missing-key-strategy=error)",
+		},
+		{
+			name: "write a json file as yaml",
+			files: map[string]string{
+				"output-format-secrets.json": `{"app": {"token": "old-token", "other": "value"}}`,
+			},
+			updater: &SopsUpdater{
+				FilePath:     "output-format-secrets.json",
+				Key:          "app.token",
+				OutputFormat: "yaml",
+				Valuer:       value.StringValuer("new-token"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"output-format-secrets.json": `app:
+    token: new-token
+    other: value
+`,
+			},
+		},
+		{
+			name: "write a json file as yaml without changing the value",
+			files: map[string]string{
+				"output-format-no-changes-secrets.json": `{"app": {"token": "old-token"}}`,
+			},
+			updater: &SopsUpdater{
+				FilePath:     "output-format-no-changes-secrets.json",
+				Key:          "app.token",
+				OutputFormat: "yaml",
+				Valuer:       value.StringValuer("old-token"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"output-format-no-changes-secrets.json": `app:
+    token: old-token
+`,
+			},
+		},
+		{
+			name: "update a key containing the separator",
+			files: map[string]string{
+				"escaped-key-secrets.yaml": `hosts:
+    api.example.com:
+        token: old-token
+    api:
+        example:
+            com:
+                token: other-token
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath: "escaped-key-secrets.yaml",
+				Key:      `hosts.api\.example\.com.token`,
+				Valuer:   value.StringValuer("new-token"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"escaped-key-secrets.yaml": `hosts:
+    api.example.com:
+        token: new-token
+    api:
+        example:
+            com:
+                token: other-token
+`,
+			},
+		},
+		{
+			name: "invalid key with a leading separator",
+			files: map[string]string{
+				"leading-separator-secrets.yaml": `app:
+    token: old-token
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath: "leading-separator-secrets.yaml",
+				Key:      ".app.token",
+				Valuer:   value.StringValuer("new-token"),
+			},
+			expectedErrorMsg: "invalid key .app.token: leading separator",
+		},
+		{
+			name: "set all the keys matching a wildcard",
+			files: map[string]string{
+				"wildcard-secrets.yaml": `services:
+    api:
+        apiKey: old-key
+        url: http://api
+    web:
+        apiKey: old-key
+    worker:
+        replicas: 2
+    name: services
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath: "wildcard-secrets.yaml",
+				Key:      "services.*.apiKey",
+				Valuer:   value.StringValuer("new-key"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"wildcard-secrets.yaml": `services:
+    api:
+        apiKey: new-key
+        url: http://api
+    web:
+        apiKey: new-key
+    worker:
+        replicas: 2
+        apiKey: new-key
+    name: services
+`,
+			},
+		},
+		{
+			name: "no change to the keys matching a custom wildcard",
+			files: map[string]string{
+				"custom-wildcard-secrets.yaml": `services:
+    api:
+        apiKey: good-key
+    web:
+        apiKey: good-key
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath: "custom-wildcard-secrets.yaml",
+				Key:      "services.%.apiKey",
+				Wildcard: "%",
+				Valuer:   value.StringValuer("good-key"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"custom-wildcard-secrets.yaml": `services:
+    api:
+        apiKey: good-key
+    web:
+        apiKey: good-key
+`,
+			},
+		},
+		{
+			name: "fail if no key matches a wildcard with the error missing-key-strategy",
+			files: map[string]string{
+				"missing-wildcard-secrets.yaml": "other: value\n",
+			},
+			updater: &SopsUpdater{
+				FilePath:   "missing-wildcard-secrets.yaml",
+				Key:        "services.*.apiKey",
+				MissingKey: missingkey.Error,
+				Valuer:     value.StringValuer("new-key"),
+			},
+			expectedErrorMsg: "key services.*.apiKey not found in file missing-wildcard-secrets.yaml (missing-key-strategy=error)",
+		},
+		{
+			name: "preserve crlf line endings",
+			files: map[string]string{
+				"crlf-secrets.yaml": `app:
+    token: old-token
+    other: value
+`,
+			},
+			updater: &SopsUpdater{
+				FilePath: "crlf-secrets.yaml",
+				Key:      "app.token",
+				Valuer:   value.StringValuer("new-token"),
+			},
+			crlf:     true,
+			expected: true,
+			expectedFiles: map[string]string{
+				"crlf-secrets.yaml": `app:
+    token: new-token
+    other: value
 `,
 			},
 		},
@@ -233,6 +798,9 @@ newtoken: new-token-value
 					require.NoErrorf(t, err, "failed to encrypt file %s", filename)
 					encryptedData, err := store.EmitEncryptedFile(tree)
 					require.NoErrorf(t, err, "failed to generate encrypted file %s", filename)
+					if test.crlf {
+						encryptedData = bytes.ReplaceAll(encryptedData, []byte("\n"), []byte("\r\n"))
+					}
 					err = os.WriteFile(filepath.Join("testdata", filename), encryptedData, 0644)
 					require.NoErrorf(t, err, "failed to write encrypted data to file %s", filename)
 				}
@@ -246,12 +814,118 @@ newtoken: new-token-value
 				require.NoError(t, err)
 				assert.Equal(t, test.expected, actual)
 
-				actualEncryptedData, err := os.ReadFile(filepath.Join("testdata", test.updater.FilePath))
-				require.NoError(t, err, "can't read actual encrypted file")
-				actualCleartextData, err := decrypt.DataWithFormat(actualEncryptedData, formats.FormatForPath(test.updater.FilePath))
-				require.NoError(t, err, "can't decrypt actual encrypted content")
-				expectedFileContent := test.expectedFiles[test.updater.FilePath]
-				assert.Equal(t, expectedFileContent, string(actualCleartextData))
+				for filename, expectedFileContent := range test.expectedFiles {
+					actualEncryptedData, err := os.ReadFile(filepath.Join("testdata", filename))
+					require.NoErrorf(t, err, "can't read actual encrypted file %s", filename)
+					if test.crlf {
+						assert.Equal(t, bytes.Count(actualEncryptedData, []byte("\n")), bytes.Count(actualEncryptedData, []byte("\r\n")), "all line endings should be CRLF")
+					}
+					format := formats.FormatForPath(filename)
+					if len(test.updater.OutputFormat) > 0 {
+						format = formats.FormatFromString(test.updater.OutputFormat)
+					}
+					actualCleartextData, err := decrypt.DataWithFormat(actualEncryptedData, format)
+					require.NoErrorf(t, err, "can't decrypt actual encrypted content of file %s", filename)
+					assert.Equalf(t, expectedFileContent, string(actualCleartextData), "file %s doesn't match", filename)
+				}
+
+				if len(test.updater.OutputFormat) > 0 {
+					// the files have been converted: they must now be read with the output format, and not changed anymore
+					actual, err = test.updater.Update(context.Background(), "testdata")
+					require.NoError(t, err)
+					assert.False(t, actual, "converted files should not be updated again")
+				}
+			}
+		})
+	}
+}
+
+func TestConvertKeyToPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		key              string
+		expected         []interface{}
+		expectedErrorMsg string
+	}{
+		{
+			name:     "simple key",
+			key:      "app.token",
+			expected: []interface{}{"app", "token"},
+		},
+		{
+			name:     "single segment",
+			key:      "token",
+			expected: []interface{}{"token"},
+		},
+		{
+			name:     "escaped separator",
+			key:      `a\.b.c`,
+			expected: []interface{}{"a.b", "c"},
+		},
+		{
+			name:     "escaped separators in the last segment",
+			key:      `hosts.api\.example\.com`,
+			expected: []interface{}{"hosts", "api.example.com"},
+		},
+		{
+			name:     "escaped backslash before a separator",
+			key:      `a\\.b`,
+			expected: []interface{}{`a\`, "b"},
+		},
+		{
+			name:     "other backslashes are kept",
+			key:      `paths.C:\temp\`,
+			expected: []interface{}{"paths", `C:\temp\`},
+		},
+		{
+			name:     "escaped separator at the start of a segment",
+			key:      `\.hidden.file`,
+			expected: []interface{}{".hidden", "file"},
+		},
+		{
+			name:             "empty key",
+			key:              "",
+			expectedErrorMsg: "empty key",
+		},
+		{
+			name:             "leading separator",
+			key:              ".app.token",
+			expectedErrorMsg: "leading separator",
+		},
+		{
+			name:             "trailing separator",
+			key:              "app.token.",
+			expectedErrorMsg: "trailing separator",
+		},
+		{
+			name:             "trailing separator after an escaped backslash",
+			key:              `app\\.`,
+			expectedErrorMsg: "trailing separator",
+		},
+		{
+			name:             "consecutive separators",
+			key:              "app..token",
+			expectedErrorMsg: "empty segment at position 4",
+		},
+		{
+			name:             "single separator",
+			key:              ".",
+			expectedErrorMsg: "leading separator",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := convertKeyToPath(test.key)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
 			}
 		})
 	}
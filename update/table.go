@@ -0,0 +1,181 @@
+package update
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/update/value"
+
+	"github.com/sirupsen/logrus"
+)
+
+// columns of an updates table
+const (
+	tableColumnRepo    = "repo"
+	tableColumnFile    = "file"
+	tableColumnKey     = "key"
+	tableColumnValue   = "value"
+	tableColumnUpdater = "updater"
+)
+
+// tableKeyParams are the updaters supported in an updates table, with the name of the parameter used for the key column
+var tableKeyParams = map[string]string{
+	"yaml":         "path",
+	"sops":         "key",
+	"ansible":      "var",
+	"ansiblevault": "key",
+}
+
+// defaultTableUpdater is the updater used when the table has no updater column - or an empty value
+const defaultTableUpdater = "yaml"
+
+// TableOptions defines how an updates table is read
+type TableOptions struct {
+	// Comma is the field delimiter - such as ',' for CSV or '\t' for TSV
+	Comma rune
+	// SkipInvalidRows skips - and logs - the invalid rows, instead of failing
+	SkipInvalidRows bool
+}
+
+// RepositoryUpdates are the updaters to run on a single repository
+type RepositoryUpdates struct {
+	Repository string
+	Updaters   []Updater
+}
+
+// ParseTable parses an updates table - such as a spreadsheet exported as CSV - with one update per row, and returns the updaters grouped by repository,
+// in the order of the first row of each repository.
+// The table must have a header with the repo, file, key and value columns - and an optional updater column.
+func ParseTable(r io.Reader, opts TableOptions) ([]RepositoryUpdates, error) {
+	reader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty updates table: missing header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header of the updates table: %w", err)
+	}
+	columns, err := parseTableHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		updates []RepositoryUpdates
+		indexes = make(map[string]int)
+	)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		var (
+			repo    string
+			updater Updater
+		)
+		if err == nil {
+			repo, updater, err = parseTableRow(record, columns)
+		}
+		if err != nil {
+			if !opts.SkipInvalidRows {
+				return nil, fmt.Errorf("invalid row at line %d of the updates table: %w", line, err)
+			}
+			logrus.WithError(err).WithField("line", line).Warn("Skipping invalid row of the updates table")
+			continue
+		}
+
+		index, found := indexes[repo]
+		if !found {
+			index = len(updates)
+			indexes[repo] = index
+			updates = append(updates, RepositoryUpdates{Repository: repo})
+		}
+		updates[index].Updaters = append(updates[index].Updaters, updater)
+	}
+
+	return updates, nil
+}
+
+// parseTableHeader returns the index of each column, and validates that all the mandatory columns are defined - and only once
+func parseTableHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case tableColumnRepo, tableColumnFile, tableColumnKey, tableColumnValue, tableColumnUpdater:
+		default:
+			return nil, fmt.Errorf("invalid header of the updates table: unknown column %q - supported columns are: %s", column, strings.Join([]string{tableColumnRepo, tableColumnFile, tableColumnKey, tableColumnValue, tableColumnUpdater}, ", "))
+		}
+		if _, found := columns[column]; found {
+			return nil, fmt.Errorf("invalid header of the updates table: duplicate column %q", column)
+		}
+		columns[column] = i
+	}
+	for _, column := range []string{tableColumnRepo, tableColumnFile, tableColumnKey, tableColumnValue} {
+		if _, found := columns[column]; !found {
+			return nil, fmt.Errorf("invalid header of the updates table: missing column %q", column)
+		}
+	}
+	return columns, nil
+}
+
+// parseTableRow returns the repository and the updater defined by the given row
+func parseTableRow(record []string, columns map[string]int) (string, Updater, error) {
+	field := func(column string) string {
+		index, found := columns[column]
+		if !found || index >= len(record) {
+			return ""
+		}
+		return record[index]
+	}
+
+	var (
+		repo        = strings.TrimSpace(field(tableColumnRepo))
+		file        = strings.TrimSpace(field(tableColumnFile))
+		key         = strings.TrimSpace(field(tableColumnKey))
+		updaterName = strings.ToLower(strings.TrimSpace(field(tableColumnUpdater)))
+	)
+	for _, column := range []struct{ name, value string }{{tableColumnRepo, repo}, {tableColumnFile, file}, {tableColumnKey, key}} {
+		if len(column.value) == 0 {
+			return "", nil, fmt.Errorf("empty %s column", column.name)
+		}
+	}
+	if len(updaterName) == 0 {
+		updaterName = defaultTableUpdater
+	}
+	keyParam, found := tableKeyParams[updaterName]
+	if !found {
+		return "", nil, fmt.Errorf("unsupported updater %s: must be one of %s", updaterName, strings.Join(supportedTableUpdaters(), ", "))
+	}
+
+	params := map[string]string{
+		"file":   file,
+		keyParam: key,
+	}
+	// the value is used as-is - without being parsed as a valuer - so that it can contain any character
+	updater, err := newUpdater(updaterName, params, value.StringValuer(field(tableColumnValue)))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create an updater instance for %s: %w", updaterName, err)
+	}
+	return repo, updater, nil
+}
+
+func supportedTableUpdaters() []string {
+	names := make([]string, 0, len(tableKeyParams))
+	for name := range tableKeyParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
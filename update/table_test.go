@@ -0,0 +1,192 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTable(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		table            string
+		opts             TableOptions
+		expected         map[string][]string
+		expectedRepos    []string
+		expectedErrorMsg string
+	}{
+		{
+			name: "wrong number of fields",
+			table: `repo,file,key,value
+org/app,values.yaml,image.tag,1.2.3
+org/worker,config.yaml,version,"v2, final"
+# a comment
+org/app,secrets.yaml,db.password,s3cret,
+`,
+			expectedErrorMsg: "invalid row at line 5 of the updates table: record on line 5: wrong number of fields",
+		},
+		{
+			name: "updates grouped by repo",
+			table: `repo,updater,file,key,value
+org/app,,values.yaml,image.tag,1.2.3
+org/worker,,config.yaml,version,"v2, final"
+# a comment
+org/app,sops,secrets.yaml,db.password,s3cret
+`,
+			expectedRepos: []string{"org/app", "org/worker"},
+			expected: map[string][]string{
+				"org/app": {
+					"YAML[path=image.tag,file=values.yaml,style=,create=false,trim=false,indent=2]",
+					"Sops[key=db.password,file=secrets.yaml]",
+				},
+				"org/worker": {
+					"YAML[path=version,file=config.yaml,style=,create=false,trim=false,indent=2]",
+				},
+			},
+		},
+		{
+			name: "tsv",
+			table: "Repo\tFile\tKey\tValue\n" +
+				"org/app\tvalues.yaml\timage.tag\t1.2.3\n",
+			opts:          TableOptions{Comma: '\t'},
+			expectedRepos: []string{"org/app"},
+			expected: map[string][]string{
+				"org/app": {
+					"YAML[path=image.tag,file=values.yaml,style=,create=false,trim=false,indent=2]",
+				},
+			},
+		},
+		{
+			name: "skip invalid rows",
+			table: `repo,updater,file,key,value
+org/app,,values.yaml,image.tag,1.2.3
+org/app,,,image.tag,1.2.3
+org/app,regex,values.yaml,tag,1.2.3
+org/worker,,config.yaml,version
+`,
+			opts:          TableOptions{SkipInvalidRows: true},
+			expectedRepos: []string{"org/app"},
+			expected: map[string][]string{
+				"org/app": {
+					"YAML[path=image.tag,file=values.yaml,style=,create=false,trim=false,indent=2]",
+				},
+			},
+		},
+		{
+			name: "empty column",
+			table: `repo,file,key,value
+org/app,values.yaml,,1.2.3
+`,
+			expectedErrorMsg: "invalid row at line 2 of the updates table: empty key column",
+		},
+		{
+			name: "unsupported updater",
+			table: `repo,updater,file,key,value
+org/app,regex,values.yaml,tag,1.2.3
+`,
+			expectedErrorMsg: "invalid row at line 2 of the updates table: unsupported updater regex: must be one of ansible, ansiblevault, sops, yaml",
+		},
+		{
+			name: "missing column",
+			table: `repo,file,value
+org/app,values.yaml,1.2.3
+`,
+			expectedErrorMsg: `invalid header of the updates table: missing column "key"`,
+		},
+		{
+			name: "unknown column",
+			table: `repo,file,path,value
+org/app,values.yaml,image.tag,1.2.3
+`,
+			expectedErrorMsg: `invalid header of the updates table: unknown column "path" - supported columns are: repo, file, key, value, updater`,
+		},
+		{
+			name: "duplicate column",
+			table: `repo,file,key,value,Repo
+org/app,values.yaml,image.tag,1.2.3,org/app
+`,
+			expectedErrorMsg: `invalid header of the updates table: duplicate column "repo"`,
+		},
+		{
+			name:             "empty table",
+			table:            "",
+			expectedErrorMsg: "empty updates table: missing header",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := ParseTable(strings.NewReader(test.table), test.opts)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+				return
+			}
+			require.NoError(t, err)
+
+			var repos []string
+			updaters := make(map[string][]string)
+			for _, repoUpdates := range actual {
+				repos = append(repos, repoUpdates.Repository)
+				for _, updater := range repoUpdates.Updaters {
+					updaters[repoUpdates.Repository] = append(updaters[repoUpdates.Repository], updater.String())
+				}
+			}
+			assert.Equal(t, test.expectedRepos, repos)
+			assert.Equal(t, test.expected, updaters)
+		})
+	}
+}
+
+func TestParseTableUpdate(t *testing.T) {
+	t.Parallel()
+
+	const table = `repo,file,key,value
+org/app,values.yaml,image.tag,1.2.3
+org/worker,config.yaml,version,"v2, final"
+org/app,values.yaml,image.repository,ghcr.io/org/app
+`
+	actual, err := ParseTable(strings.NewReader(table), TableOptions{})
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+
+	files := map[string]struct {
+		filename string
+		content  string
+		expected string
+	}{
+		"org/app": {
+			filename: "values.yaml",
+			content:  "image:\n  repository: docker.io/org/app\n  tag: 1.0.0\n",
+			expected: "image:\n  repository: ghcr.io/org/app\n  tag: 1.2.3\n",
+		},
+		"org/worker": {
+			filename: "config.yaml",
+			content:  "version: v1\n",
+			expected: "version: v2, final\n",
+		},
+	}
+	for _, repoUpdates := range actual {
+		file, found := files[repoUpdates.Repository]
+		require.Truef(t, found, "unexpected repository %s", repoUpdates.Repository)
+
+		repoPath := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, file.filename), []byte(file.content), 0644))
+		for _, updater := range repoUpdates.Updaters {
+			updated, err := updater.Update(context.Background(), repoPath)
+			require.NoError(t, err)
+			assert.Truef(t, updated, "updater %s didn't update repository %s", updater, repoUpdates.Repository)
+		}
+		actualContent, err := os.ReadFile(filepath.Join(repoPath, file.filename))
+		require.NoError(t, err)
+		assert.Equalf(t, file.expected, string(actualContent), "file %s of repository %s doesn't match", file.filename, repoUpdates.Repository)
+	}
+}
@@ -0,0 +1,340 @@
+package textproto
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// field is an occurrence of a field in a text-format protobuf message.
+// For a scalar field, it references the position of the value in the content, so that it can be replaced in place - keeping the formatting and comments.
+// Each element of a repeated field - or of a list of values - is a distinct field.
+type field struct {
+	name     string
+	message  bool
+	children []*field
+	start    int
+	end      int
+	quoted   bool
+}
+
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	literalToken
+	stringToken
+	symbolToken
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+}
+
+// parser is a minimal - schema-less - parser for the protobuf text format.
+type parser struct {
+	content []byte
+	pos     int
+	next    *token
+}
+
+func parse(content []byte) ([]*field, error) {
+	p := &parser{content: content}
+	return p.parseMessage("")
+}
+
+func (p *parser) parseMessage(closing string) ([]*field, error) {
+	var fields []*field
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tok.kind == eofToken && closing == "":
+			return fields, nil
+		case tok.kind == eofToken:
+			return nil, fmt.Errorf("unexpected end of file: missing %q", closing)
+		case tok.kind == symbolToken && tok.text == closing:
+			p.consume()
+			return fields, nil
+		}
+
+		name, err := p.parseFieldName()
+		if err != nil {
+			return nil, err
+		}
+		if tok, err = p.peek(); err != nil {
+			return nil, err
+		}
+		if tok.kind == symbolToken && tok.text == ":" {
+			p.consume()
+		}
+
+		if tok, err = p.peek(); err != nil {
+			return nil, err
+		}
+		if tok.kind == symbolToken && tok.text == "[" {
+			p.consume()
+			elements, err := p.parseList(name)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, elements...)
+		} else {
+			value, err := p.parseValue(name)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, value)
+		}
+
+		if tok, err = p.peek(); err != nil {
+			return nil, err
+		}
+		if tok.kind == symbolToken && (tok.text == ";" || tok.text == ",") {
+			p.consume()
+		}
+	}
+}
+
+func (p *parser) parseFieldName() (string, error) {
+	tok, err := p.consume()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case tok.kind == literalToken:
+		return tok.text, nil
+	case tok.kind == symbolToken && tok.text == "[":
+		// extension or Any type URL, such as [type.googleapis.com/my.Message]
+		name := new(strings.Builder)
+		name.WriteString("[")
+		for {
+			tok, err = p.consume()
+			if err != nil {
+				return "", err
+			}
+			if tok.kind == eofToken {
+				return "", errors.New("unexpected end of file: missing \"]\"")
+			}
+			name.WriteString(tok.text)
+			if tok.kind == symbolToken && tok.text == "]" {
+				return name.String(), nil
+			}
+		}
+	default:
+		return "", fmt.Errorf("unexpected %s at offset %d: expected a field name", tok.describe(), tok.start)
+	}
+}
+
+func (p *parser) parseList(name string) ([]*field, error) {
+	var fields []*field
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind == symbolToken && tok.text == "]" {
+			p.consume()
+			return fields, nil
+		}
+
+		value, err := p.parseValue(name)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, value)
+
+		if tok, err = p.peek(); err != nil {
+			return nil, err
+		}
+		if tok.kind == symbolToken && tok.text == "," {
+			p.consume()
+		}
+	}
+}
+
+func (p *parser) parseValue(name string) (*field, error) {
+	tok, err := p.consume()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tok.kind == symbolToken && (tok.text == "{" || tok.text == "<"):
+		closing := "}"
+		if tok.text == "<" {
+			closing = ">"
+		}
+		children, err := p.parseMessage(closing)
+		if err != nil {
+			return nil, err
+		}
+		return &field{name: name, message: true, children: children}, nil
+	case tok.kind == literalToken:
+		return &field{name: name, start: tok.start, end: tok.end}, nil
+	case tok.kind == stringToken:
+		value := &field{name: name, start: tok.start, end: tok.end, quoted: true}
+		// adjacent strings are concatenated
+		for {
+			next, err := p.peek()
+			if err != nil {
+				return nil, err
+			}
+			if next.kind != stringToken {
+				return value, nil
+			}
+			p.consume()
+			value.end = next.end
+		}
+	default:
+		return nil, fmt.Errorf("unexpected %s at offset %d: expected a value for field %s", tok.describe(), tok.start, name)
+	}
+}
+
+func (p *parser) peek() (*token, error) {
+	if p.next == nil {
+		tok, err := p.scan()
+		if err != nil {
+			return nil, err
+		}
+		p.next = tok
+	}
+	return p.next, nil
+}
+
+func (p *parser) consume() (*token, error) {
+	tok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	p.next = nil
+	return tok, nil
+}
+
+func (p *parser) scan() (*token, error) {
+	// skip whitespaces and comments
+	for p.pos < len(p.content) {
+		c := p.content[p.pos]
+		if c == '#' {
+			for p.pos < len(p.content) && p.content[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\v' {
+			break
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.content) {
+		return &token{kind: eofToken, start: p.pos, end: p.pos}, nil
+	}
+
+	start := p.pos
+	c := p.content[p.pos]
+	switch {
+	case c == '"' || c == '\'':
+		p.pos++
+		for p.pos < len(p.content) && p.content[p.pos] != c {
+			if p.content[p.pos] == '\\' {
+				p.pos++
+			}
+			if p.pos < len(p.content) && p.content[p.pos] == '\n' {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.content) {
+			return nil, fmt.Errorf("unterminated string at offset %d", start)
+		}
+		p.pos++
+		return &token{kind: stringToken, text: string(p.content[start:p.pos]), start: start, end: p.pos}, nil
+	case isLiteralChar(c):
+		for p.pos < len(p.content) && isLiteralChar(p.content[p.pos]) {
+			p.pos++
+		}
+		return &token{kind: literalToken, text: string(p.content[start:p.pos]), start: start, end: p.pos}, nil
+	case strings.IndexByte("{}<>[]:;,/", c) >= 0:
+		p.pos++
+		return &token{kind: symbolToken, text: string(c), start: start, end: p.pos}, nil
+	default:
+		return nil, fmt.Errorf("unexpected character %q at offset %d", c, start)
+	}
+}
+
+func isLiteralChar(c byte) bool {
+	return c == '_' || c == '-' || c == '+' || c == '.' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (t *token) describe() string {
+	if t.kind == eofToken {
+		return "end of file"
+	}
+	return strconv.Quote(t.text)
+}
+
+// pathSegment is a segment of a field path: a field name, with an optional index to select a single occurrence of a repeated field
+type pathSegment struct {
+	name  string
+	index int
+}
+
+// name or name[index]
+var pathSegmentRegexp = regexp.MustCompile(`^(?P<name>[A-Za-z_][A-Za-z0-9_]*)(?:\[(?P<index>[0-9]+)\])?$`)
+
+// parsePath parses a field path, such as "server.listeners[1].port"
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for _, segmentStr := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		matches := pathSegmentRegexp.FindStringSubmatch(segmentStr)
+		if matches == nil {
+			return nil, fmt.Errorf("invalid path segment %q: expected a field name, with an optional [index]", segmentStr)
+		}
+		segment := pathSegment{name: matches[1], index: -1}
+		if len(matches[2]) > 0 {
+			segment.index, _ = strconv.Atoi(matches[2])
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// lookup returns all the fields matching the given path.
+// Without an index, a segment matches all the occurrences of a repeated field.
+func lookup(fields []*field, segments []pathSegment) []*field {
+	if len(segments) == 0 {
+		return fields
+	}
+	var (
+		segment = segments[0]
+		matches []*field
+		index   int
+	)
+	for _, f := range fields {
+		if f.name != segment.name {
+			continue
+		}
+		if segment.index < 0 || segment.index == index {
+			matches = append(matches, f)
+		}
+		index++
+	}
+	if len(segments) == 1 {
+		return matches
+	}
+
+	var results []*field
+	for _, match := range matches {
+		if match.message {
+			results = append(results, lookup(match.children, segments[1:])...)
+		}
+	}
+	return results
+}
@@ -0,0 +1,140 @@
+// Package textproto provides an updater that updates protobuf text format files - such as .textproto files.
+package textproto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	"github.com/dailymotion-oss/octopilot/update/value"
+)
+
+// TextprotoUpdater is an updater that sets the value of a field in protobuf text format files.
+// It doesn't need the protobuf schema: the values are replaced in place, so the formatting and comments are preserved.
+type TextprotoUpdater struct {
+	FilePath string
+	Path     string
+	EOL      eol.Mode
+	Valuer   value.Valuer
+}
+
+// NewUpdater builds a new textproto updater from the given parameters and valuer
+func NewUpdater(params map[string]string, valuer value.Valuer) (*TextprotoUpdater, error) {
+	updater := &TextprotoUpdater{}
+
+	updater.FilePath = params["file"]
+	if len(updater.FilePath) == 0 {
+		return nil, errors.New("missing file parameter")
+	}
+
+	updater.Path = params["path"]
+	if len(updater.Path) == 0 {
+		return nil, errors.New("missing path parameter")
+	}
+	if _, err := parsePath(updater.Path); err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", updater.Path, err)
+	}
+
+	var err error
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
+	}
+
+	updater.Valuer = valuer
+
+	return updater, nil
+}
+
+// Update updates the repository cloned at the given path, and returns true if changes have been made
+func (u *TextprotoUpdater) Update(ctx context.Context, repoPath string) (bool, error) {
+	value, err := u.Valuer.Value(ctx, repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	segments, err := parsePath(u.Path)
+	if err != nil {
+		return false, fmt.Errorf("invalid path %s: %w", u.Path, err)
+	}
+
+	filePaths, err := filepath.Glob(filepath.Join(repoPath, u.FilePath))
+	if err != nil {
+		return false, fmt.Errorf("failed to expand glob pattern %s: %w", u.FilePath, err)
+	}
+
+	var updated bool
+	for _, filePath := range filePaths {
+		relFilePath, err := filepath.Rel(repoPath, filePath)
+		if err != nil {
+			relFilePath = filePath
+		}
+
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to access file %s: %w", relFilePath, err)
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to read file %s: %w", relFilePath, err)
+		}
+
+		fields, err := parse(content)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse file %s: %w", relFilePath, err)
+		}
+
+		matches := lookup(fields, segments)
+		for _, match := range matches {
+			if match.message {
+				return false, fmt.Errorf("failed to update file %s: field %s is a message, not a scalar value", relFilePath, u.Path)
+			}
+		}
+		// replace the values from the end, so that the positions of the previous ones are still valid
+		sort.Slice(matches, func(i, j int) bool {
+			return matches[i].start > matches[j].start
+		})
+
+		updatedContent := content
+		for _, match := range matches {
+			newValue := strings.TrimSpace(value)
+			if match.quoted {
+				newValue = strconv.Quote(value)
+			}
+			updatedContent = append(updatedContent[:match.start:match.start], append([]byte(newValue), updatedContent[match.end:]...)...)
+		}
+		updatedContent = eol.Apply(u.EOL, content, updatedContent)
+
+		if bytes.Equal(content, updatedContent) {
+			continue
+		}
+
+		if err = os.WriteFile(filePath, updatedContent, fileInfo.Mode()); err != nil {
+			return false, fmt.Errorf("failed to write updated content to file %s: %w", relFilePath, err)
+		}
+
+		updated = true
+	}
+
+	return updated, nil
+}
+
+// Message returns the default title and body that should be used in the commits / pull requests
+func (u *TextprotoUpdater) Message() (title, body string) {
+	title = fmt.Sprintf("Update %s", u.FilePath)
+	body = fmt.Sprintf("Updating field `%s` in file(s) `%s`", u.Path, u.FilePath)
+	return title, body
+}
+
+// String returns a string representation of the updater
+func (u *TextprotoUpdater) String() string {
+	return fmt.Sprintf("Textproto[path=%s,file=%s]", u.Path, u.FilePath)
+}
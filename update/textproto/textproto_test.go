@@ -0,0 +1,310 @@
+package textproto
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	"github.com/dailymotion-oss/octopilot/update/value"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpdater(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		params           map[string]string
+		expected         *TextprotoUpdater
+		expectedErrorMsg string
+	}{
+		{
+			name: "valid params",
+			params: map[string]string{
+				"file": "config.textproto",
+				"path": "server.listeners[1].port",
+				"eol":  "lf",
+			},
+			expected: &TextprotoUpdater{
+				FilePath: "config.textproto",
+				Path:     "server.listeners[1].port",
+				EOL:      eol.LF,
+			},
+		},
+		{
+			name:             "nil params",
+			expectedErrorMsg: "missing file parameter",
+		},
+		{
+			name: "missing mandatory path param",
+			params: map[string]string{
+				"file": "config.textproto",
+			},
+			expectedErrorMsg: "missing path parameter",
+		},
+		{
+			name: "invalid path",
+			params: map[string]string{
+				"file": "config.textproto",
+				"path": "server.listeners[first].port",
+			},
+			expectedErrorMsg: `invalid path server.listeners[first].port: invalid path segment "listeners[first]": expected a field name, with an optional [index]`,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := NewUpdater(test.params, nil)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		files            map[string]string
+		updater          *TextprotoUpdater
+		expected         bool
+		expectedErrorMsg string
+		expectedFiles    map[string]string
+	}{
+		{
+			name: "update a scalar field",
+			files: map[string]string{
+				"scalar.textproto": `# proto-file: config.proto
+# proto-message: Config
+
+name: "my-service"  # the service name
+version: "1.0.0"
+replicas: 2
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "scalar.textproto",
+				Path:     "version",
+				Valuer:   value.StringValuer("1.1.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"scalar.textproto": `# proto-file: config.proto
+# proto-message: Config
+
+name: "my-service"  # the service name
+version: "1.1.0"
+replicas: 2
+`,
+			},
+		},
+		{
+			name: "update an unquoted scalar field with a trailing new line",
+			files: map[string]string{
+				"unquoted.textproto": `replicas: 2
+mode: BLUE
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "unquoted.textproto",
+				Path:     "mode",
+				Valuer:   value.StringValuer("GREEN\n"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"unquoted.textproto": `replicas: 2
+mode: GREEN
+`,
+			},
+		},
+		{
+			name: "update a nested message field",
+			files: map[string]string{
+				"nested.textproto": `server {
+  # the public listener
+  listener {
+    address: "0.0.0.0"
+    port: 8080
+  }
+  tls < cert_file: "/etc/tls/cert.pem" >
+}
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "nested.textproto",
+				Path:     "server.tls.cert_file",
+				Valuer:   value.StringValuer("/etc/tls/new-cert.pem"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"nested.textproto": `server {
+  # the public listener
+  listener {
+    address: "0.0.0.0"
+    port: 8080
+  }
+  tls < cert_file: "/etc/tls/new-cert.pem" >
+}
+`,
+			},
+		},
+		{
+			name: "update all occurrences of a repeated field",
+			files: map[string]string{
+				"repeated.textproto": `backends {
+  name: "primary"
+  image: "app:v1"
+}
+backends {
+  name: "secondary"
+  image: "app:v1"
+}
+tags: ["a", "b"]
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "repeated.textproto",
+				Path:     "backends.image",
+				Valuer:   value.StringValuer("app:v2"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"repeated.textproto": `backends {
+  name: "primary"
+  image: "app:v2"
+}
+backends {
+  name: "secondary"
+  image: "app:v2"
+}
+tags: ["a", "b"]
+`,
+			},
+		},
+		{
+			name: "update a single element of a repeated field",
+			files: map[string]string{
+				"repeated-index.textproto": `backends { name: "primary" weight: 80 }
+backends: { name: "secondary" weight: 20 };
+ports: [8080, 8443]
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "repeated-index.textproto",
+				Path:     "ports[1]",
+				Valuer:   value.StringValuer("9443"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"repeated-index.textproto": `backends { name: "primary" weight: 80 }
+backends: { name: "secondary" weight: 20 };
+ports: [8080, 9443]
+`,
+			},
+		},
+		{
+			name: "no changes",
+			files: map[string]string{
+				"no-changes.textproto": `version: "1.0.0"
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "no-changes.textproto",
+				Path:     "version",
+				Valuer:   value.StringValuer("1.0.0"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"no-changes.textproto": `version: "1.0.0"
+`,
+			},
+		},
+		{
+			name: "missing field",
+			files: map[string]string{
+				"missing-field.textproto": `version: "1.0.0"
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "missing-field.textproto",
+				Path:     "server.port",
+				Valuer:   value.StringValuer("8080"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"missing-field.textproto": `version: "1.0.0"
+`,
+			},
+		},
+		{
+			name: "message field",
+			files: map[string]string{
+				"message-field.textproto": `server { port: 8080 }
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "message-field.textproto",
+				Path:     "server",
+				Valuer:   value.StringValuer("8080"),
+			},
+			expectedErrorMsg: "failed to update file message-field.textproto: field server is a message, not a scalar value",
+		},
+		{
+			name: "invalid file",
+			files: map[string]string{
+				"invalid.textproto": `server { port: 8080
+`,
+			},
+			updater: &TextprotoUpdater{
+				FilePath: "invalid.textproto",
+				Path:     "server.port",
+				Valuer:   value.StringValuer("8081"),
+			},
+			expectedErrorMsg: `failed to parse file invalid.textproto: unexpected end of file: missing "}"`,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			{
+				for filename, content := range test.files {
+					err := os.MkdirAll(filepath.Dir(filepath.Join("testdata", filename)), 0755)
+					require.NoErrorf(t, err, "can't create testdata directories for %s", filename)
+					err = os.WriteFile(filepath.Join("testdata", filename), []byte(content), 0644)
+					require.NoErrorf(t, err, "can't write testdata file %s", filename)
+				}
+			}
+
+			actual, err := test.updater.Update(context.Background(), "testdata")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.False(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+				actualFilePaths, err := filepath.Glob(filepath.Join("testdata", test.updater.FilePath))
+				require.NoError(t, err, "can't expand glob pattern for actual testdata file")
+				for _, actualFilePath := range actualFilePaths {
+					actualRelFilePath, err := filepath.Rel("testdata", actualFilePath)
+					require.NoErrorf(t, err, "can't get relative path for actual testdata file %s", actualFilePath)
+					actualFileContent, err := os.ReadFile(actualFilePath)
+					require.NoErrorf(t, err, "can't read actual testdata file %s", actualFilePath)
+					expectedFileContent := test.expectedFiles[actualRelFilePath]
+					assert.Equalf(t, expectedFileContent, string(actualFileContent), "testdata file %s doesn't match", actualFilePath)
+				}
+			}
+		})
+	}
+}
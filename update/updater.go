@@ -2,15 +2,25 @@ package update
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/dailymotion-oss/octopilot/internal/changes"
 	"github.com/dailymotion-oss/octopilot/internal/parameters"
+	"github.com/dailymotion-oss/octopilot/update/ansible"
+	"github.com/dailymotion-oss/octopilot/update/ansiblevault"
+	"github.com/dailymotion-oss/octopilot/update/botconfig"
 	"github.com/dailymotion-oss/octopilot/update/exec"
+	"github.com/dailymotion-oss/octopilot/update/flux"
 	"github.com/dailymotion-oss/octopilot/update/helm"
+	"github.com/dailymotion-oss/octopilot/update/jsonnet"
+	"github.com/dailymotion-oss/octopilot/update/openapi"
 	"github.com/dailymotion-oss/octopilot/update/regex"
 	"github.com/dailymotion-oss/octopilot/update/sops"
+	"github.com/dailymotion-oss/octopilot/update/textproto"
 	"github.com/dailymotion-oss/octopilot/update/value"
 	"github.com/dailymotion-oss/octopilot/update/yaml"
 	"github.com/dailymotion-oss/octopilot/update/yq"
@@ -22,6 +32,8 @@ var (
 
 	// name(params)=value
 	updaterWithValueRegexp = regexp.MustCompile(`^(?P<name>[a-z]+)\((?P<params>.+)\)=(?P<value>.*)$`)
+
+	errUnknownUpdater = errors.New("unknown updater")
 )
 
 // Updater updates a git repository
@@ -34,12 +46,41 @@ type Updater interface {
 	String() string
 }
 
+// ValuerWrapper wraps the valuer of the update at the given index - in the slice of updates given to ParseWithValuerWrapper - defined by the given value string.
+type ValuerWrapper func(index int, valueStr string, valuer value.Valuer) value.Valuer
+
+// secretValuer records the secret values returned by its valuer in the context - so that they are masked in the exported changes
+type secretValuer struct {
+	valuer value.Valuer
+}
+
+// Value returns the value of the wrapped valuer.
+func (v secretValuer) Value(ctx context.Context, repoPath string) (string, error) {
+	val, err := v.valuer.Value(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+	changes.RecordSecret(ctx, val)
+	return val, nil
+}
+
+// Sensitive returns true: the wrapped valuer returns a secret
+func (v secretValuer) Sensitive() bool {
+	return true
+}
+
 // Parse parses a set of updates defined as string - from the CLI for example - and returns properly formatted Updaters.
 // expected syntax is documented in the user documentation: docs/current-version/content/updaters/
 func Parse(updates []string) ([]Updater, error) {
+	return ParseWithValuerWrapper(updates, nil)
+}
+
+// ParseWithValuerWrapper parses a set of updates, as Parse does, but wraps the valuer of each update with the given wrapper - if not nil.
+// It is used to record - or replay - the values used by the updaters.
+func ParseWithValuerWrapper(updates []string, wrapper ValuerWrapper) ([]Updater, error) {
 	var updaters []Updater
 
-	for _, update := range updates {
+	for i, update := range updates {
 		if len(strings.TrimSpace(update)) == 0 {
 			continue
 		}
@@ -57,6 +98,14 @@ func Parse(updates []string) ([]Updater, error) {
 				return nil, fmt.Errorf("invalid syntax for %s: found %d matches instead of 3: %v", update, len(matches), matches)
 			}
 			paramsStr = matches[2]
+		case "gzip":
+			// the value is optional, depending on the inner updater
+			if withValueMatches := updaterWithValueRegexp.FindStringSubmatch(update); len(withValueMatches) == 4 {
+				paramsStr = withValueMatches[2]
+				valueStr = withValueMatches[3]
+			} else {
+				paramsStr = matches[2]
+			}
 		default:
 			matches = updaterWithValueRegexp.FindStringSubmatch(update)
 			if len(matches) < 4 {
@@ -73,34 +122,117 @@ func Parse(updates []string) ([]Updater, error) {
 		}
 
 		params := parameters.Parse(paramsStr)
+		formatCommand, hasFormatCommand := params["format-command"]
+		delete(params, "format-command")
+		codeOwner, hasCodeOwner := params["codeowner"]
+		delete(params, "codeowner")
+		rolloutRestartParams := make(map[string]string)
+		for _, param := range []string{"rollout-restart", "rollout-restart-name"} {
+			if v, ok := params[param]; ok {
+				rolloutRestartParams[param] = v
+				delete(params, param)
+			}
+		}
+		managedVersionParams := make(map[string]string)
+		for _, param := range []string{"managed-version", "create-marker", "marker-comment"} {
+			if v, ok := params[param]; ok {
+				managedVersionParams[param] = v
+				delete(params, param)
+			}
+		}
 		valuer, err := value.ParseValuer(valueStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse value %s for %s: %w", valueStr, updaterName, err)
 		}
+		if file := params["file"]; len(file) > 0 {
+			// the updated files can't be part of a checksum of the files used as the value
+			valuer = value.ExcludeFiles(valuer, file)
+		}
+		if wrapper != nil {
+			valuer = wrapper(i, valueStr, valuer)
+		}
+		if value.IsSecret(valuer) {
+			valuer = secretValuer{valuer: valuer}
+		}
 
-		var updater Updater
-		switch updaterName {
-		case "regex":
-			updater, err = regex.NewUpdater(params, valuer)
-		case "sops":
-			updater, err = sops.NewUpdater(params, valuer)
-		case "helm":
-			updater, err = helm.NewUpdater(params, valuer)
-		case "yaml":
-			updater, err = yaml.NewUpdater(params, valuer)
-		case "yq":
-			updater, err = yq.NewUpdater(params)
-		case "exec":
-			updater, err = exec.NewUpdater(params)
-		default:
-			return nil, fmt.Errorf("unknown updater %s", updaterName)
+		updater, err := newUpdater(updaterName, params, valuer)
+		if errors.Is(err, errUnknownUpdater) {
+			return nil, err
 		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to create an updater instance for %s: %w", updaterName, err)
 		}
+		if hasCodeOwner {
+			updater, err = newCodeOwnersUpdater(updater, codeOwner)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create a code owners filter for %s: %w", updaterName, err)
+			}
+		}
+		if hasFormatCommand {
+			updater, err = newFormatUpdater(updater, formatCommand)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create a formatter for %s: %w", updaterName, err)
+			}
+		}
+		if managedVersion, _ := strconv.ParseBool(managedVersionParams["managed-version"]); managedVersion {
+			updater, err = newManagedVersionUpdater(updater, managedVersionParams)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create a managed version marker for %s: %w", updaterName, err)
+			}
+		}
+
+		if _, hasRolloutRestart := rolloutRestartParams["rollout-restart"]; hasRolloutRestart {
+			updater, err = newRolloutRestartUpdater(updater, rolloutRestartParams)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create a rollout restart for %s: %w", updaterName, err)
+			}
+		}
 
 		updaters = append(updaters, updater)
 	}
 
 	return updaters, nil
 }
+
+// newUpdater returns a new instance of the updater with the given name, built from the given parameters and valuer.
+func newUpdater(updaterName string, params map[string]string, valuer value.Valuer) (Updater, error) {
+	var (
+		updater Updater
+		err     error
+	)
+	switch updaterName {
+	case "regex":
+		updater, err = regex.NewUpdater(params, valuer)
+	case "sops":
+		updater, err = sops.NewUpdater(params, valuer)
+	case "helm":
+		updater, err = helm.NewUpdater(params, valuer)
+	case "yaml":
+		updater, err = yaml.NewUpdater(params, valuer)
+	case "ansible":
+		updater, err = ansible.NewUpdater(params, valuer)
+	case "flux":
+		updater, err = flux.NewUpdater(params, valuer)
+	case "ansiblevault":
+		updater, err = ansiblevault.NewUpdater(params, valuer)
+	case "openapi":
+		updater, err = openapi.NewUpdater(params, valuer)
+	case "textproto":
+		updater, err = textproto.NewUpdater(params, valuer)
+	case "jsonnet":
+		updater, err = jsonnet.NewUpdater(params, valuer)
+	case "renovate":
+		updater, err = botconfig.NewRenovateUpdater(params, valuer)
+	case "dependabot":
+		updater, err = botconfig.NewDependabotUpdater(params, valuer)
+	case "yq":
+		updater, err = yq.NewUpdater(params)
+	case "exec":
+		updater, err = exec.NewUpdater(params)
+	case "gzip":
+		updater, err = newGzipUpdater(params, valuer)
+	default:
+		return nil, fmt.Errorf("%w %s", errUnknownUpdater, updaterName)
+	}
+	return updater, err
+}
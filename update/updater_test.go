@@ -4,10 +4,17 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/dailymotion-oss/octopilot/update/ansible"
+	"github.com/dailymotion-oss/octopilot/update/ansiblevault"
+	"github.com/dailymotion-oss/octopilot/update/botconfig"
 	"github.com/dailymotion-oss/octopilot/update/exec"
+	"github.com/dailymotion-oss/octopilot/update/flux"
 	"github.com/dailymotion-oss/octopilot/update/helm"
+	"github.com/dailymotion-oss/octopilot/update/jsonnet"
+	"github.com/dailymotion-oss/octopilot/update/openapi"
 	"github.com/dailymotion-oss/octopilot/update/regex"
 	"github.com/dailymotion-oss/octopilot/update/sops"
+	"github.com/dailymotion-oss/octopilot/update/textproto"
 	"github.com/dailymotion-oss/octopilot/update/value"
 	"github.com/dailymotion-oss/octopilot/update/yaml"
 	"github.com/dailymotion-oss/octopilot/update/yq"
@@ -140,6 +147,251 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "yaml updater with a checksum of the yaml files",
+			updates: []string{`yaml(file=deployment.yaml,path=spec.template.metadata.annotations.checksum)=checksum(files=*.yaml)`},
+			expected: []Updater{
+				&yaml.YamlUpdater{
+					FilePath: "deployment.yaml",
+					Path:     "spec.template.metadata.annotations.checksum",
+					Indent:   2,
+					Valuer: &value.ChecksumValuer{
+						Files:     []string{"*.yaml"},
+						Algorithm: value.ChecksumAlgorithmSHA256,
+						Exclude:   []string{"deployment.yaml"},
+					},
+				},
+			},
+		},
+		{
+			name:    "single openapi updater",
+			updates: []string{"openapi(file=openapi.yaml,path=/pets/{id},method=get,field=x-backend)=http://pets.svc"},
+			expected: []Updater{
+				&openapi.OpenAPIUpdater{
+					FilePath: "openapi.yaml",
+					Server:   -1,
+					Path:     "/pets/{id}",
+					Method:   "get",
+					Field:    "x-backend",
+					Indent:   2,
+					Valuer:   value.StringValuer("http://pets.svc"),
+				},
+			},
+		},
+		{
+			name:    "single textproto updater",
+			updates: []string{"textproto(file=config.textproto,path=server.listeners[0].port)=8443"},
+			expected: []Updater{
+				&textproto.TextprotoUpdater{
+					FilePath: "config.textproto",
+					Path:     "server.listeners[0].port",
+					Valuer:   value.StringValuer("8443"),
+				},
+			},
+		},
+		{
+			name:    "single ansible updater",
+			updates: []string{"ansible(file=inventory.yml,group=webservers,var=app_version)=1.2.3"},
+			expected: []Updater{
+				&ansible.AnsibleUpdater{
+					YamlUpdater: &yaml.YamlUpdater{
+						FilePath: "inventory.yml",
+						Path:     `.all.children["webservers"].vars["app_version"]`,
+						Indent:   2,
+						Valuer:   value.StringValuer("1.2.3"),
+					},
+					Var:   "app_version",
+					Group: "webservers",
+				},
+			},
+		},
+		{
+			name:    "single flux updater",
+			updates: []string{"flux(file=apps/podinfo.yaml,kind=HelmRelease,name=podinfo)=6.3.0"},
+			expected: []Updater{
+				&flux.FluxUpdater{
+					YamlUpdater: &yaml.YamlUpdater{
+						FilePath: "apps/podinfo.yaml",
+						Path:     `(select(.kind == "HelmRelease" and (.apiVersion | test("^helm[.]toolkit[.]fluxcd[.]io/")) and .metadata.name == "podinfo") | .spec.chart.spec.version)`,
+						Indent:   2,
+						Valuer:   value.StringValuer("6.3.0"),
+					},
+					Kind: "HelmRelease",
+					Name: "podinfo",
+				},
+			},
+		},
+		{
+			name:    "single renovate updater",
+			updates: []string{"renovate(path=packageRules,append=true)={\"matchPackageNames\": [\"lodash\"], \"enabled\": false}"},
+			expected: []Updater{
+				&botconfig.BotConfigUpdater{
+					Bot:    botconfig.Renovate,
+					Path:   "packageRules",
+					Append: true,
+					Indent: 2,
+					Valuer: value.StringValuer(`{"matchPackageNames": ["lodash"], "enabled": false}`),
+				},
+			},
+		},
+		{
+			name:    "single dependabot updater",
+			updates: []string{"dependabot(path=schedule.interval,ecosystem=gomod)=daily"},
+			expected: []Updater{
+				&botconfig.BotConfigUpdater{
+					Bot:       botconfig.Dependabot,
+					Path:      "schedule.interval",
+					Ecosystem: "gomod",
+					Indent:    2,
+					Valuer:    value.StringValuer("daily"),
+				},
+			},
+		},
+		{
+			name:    "single ansiblevault updater",
+			updates: []string{"ansiblevault(file=group_vars/all/vault.yml,key=app.token,password-env=VAULT_PASSWORD)=new-token"},
+			expected: []Updater{
+				&ansiblevault.AnsibleVaultUpdater{
+					FilePath:    "group_vars/all/vault.yml",
+					Keys:        []string{"app.token"},
+					PasswordEnv: "VAULT_PASSWORD",
+					Valuer:      value.StringValuer("new-token"),
+				},
+			},
+		},
+		{
+			name:    "single jsonnet updater",
+			updates: []string{"jsonnet(file=main.jsonnet,local=version)=1.2.3"},
+			expected: []Updater{
+				&jsonnet.JsonnetUpdater{
+					FilePath: "main.jsonnet",
+					Local:    "version",
+					Valuer:   value.StringValuer("1.2.3"),
+				},
+			},
+		},
+		{
+			name:    "gzip updater with an inner yaml updater",
+			updates: []string{"gzip(file=config.yaml.gz,path=version)=1.2.3"},
+			expected: []Updater{
+				&GzipUpdater{
+					FilePath: "config.yaml.gz",
+					Level:    -1,
+					Updater: &yaml.YamlUpdater{
+						FilePath: "content.yaml",
+						Path:     "version",
+						Indent:   2,
+						Valuer:   value.StringValuer("1.2.3"),
+					},
+					contentFile: "content.yaml",
+				},
+			},
+		},
+		{
+			name:    "gzip updater with an inner yq updater",
+			updates: []string{"gzip(file=config.json.gz,updater=yq,expression='.version = \"1.2.3\"',json=true)"},
+			expected: []Updater{
+				&GzipUpdater{
+					FilePath: "config.json.gz",
+					Level:    -1,
+					Updater: &yq.YQUpdater{
+						FilePath:     "content.json",
+						Expression:   `.version = "1.2.3"`,
+						OutputFormat: yqlib.JSONOutputFormat,
+						UnwrapScalar: true,
+						Indent:       2,
+					},
+					contentFile: "content.json",
+				},
+			},
+		},
+		{
+			name:    "yaml updater with a format command",
+			updates: []string{"yaml(file=config.yaml,path=version,format-command='prettier --write')=1.2.3"},
+			expected: []Updater{
+				&FormatUpdater{
+					Updater: &yaml.YamlUpdater{
+						FilePath: "config.yaml",
+						Path:     "version",
+						Indent:   2,
+						Valuer:   value.StringValuer("1.2.3"),
+					},
+					Command: "prettier",
+					Args:    []string{"--write"},
+				},
+			},
+		},
+		{
+			name:    "yaml updater with a managed version marker",
+			updates: []string{"yaml(file=config.yaml,path=version,managed-version=true,create-marker=true)=1.2.3"},
+			expected: []Updater{
+				&ManagedVersionUpdater{
+					Updater: &yaml.YamlUpdater{
+						FilePath: "config.yaml",
+						Path:     "version",
+						Indent:   2,
+						Valuer:   value.StringValuer("1.2.3"),
+					},
+					CreateMarker: true,
+					Comment:      "#",
+				},
+			},
+		},
+		{
+			name:             "invalid create-marker parameter",
+			updates:          []string{"yaml(file=config.yaml,path=version,managed-version=true,create-marker=maybe)=1.2.3"},
+			expectedErrorMsg: `failed to create a managed version marker for yaml: failed to parse create-marker parameter maybe: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+		{
+			name:    "yaml updater with a code owner and a format command",
+			updates: []string{"yaml(file=config.yaml,path=version,codeowner=@my-org/team-a,format-command='prettier --write')=1.2.3"},
+			expected: []Updater{
+				&FormatUpdater{
+					Updater: &CodeOwnersUpdater{
+						Updater: &yaml.YamlUpdater{
+							FilePath: "config.yaml",
+							Path:     "version",
+							Indent:   2,
+							Valuer:   value.StringValuer("1.2.3"),
+						},
+						Owner: "@my-org/team-a",
+					},
+					Command: "prettier",
+					Args:    []string{"--write"},
+				},
+			},
+		},
+		{
+			name:    "yaml updater with a rollout restart",
+			updates: []string{"yaml(file=configmap.yaml,path=data.LOG_LEVEL,rollout-restart=deployment.yaml,rollout-restart-name=my-app)=debug"},
+			expected: []Updater{
+				&RolloutRestartUpdater{
+					Updater: &yaml.YamlUpdater{
+						FilePath: "configmap.yaml",
+						Path:     "data.LOG_LEVEL",
+						Indent:   2,
+						Valuer:   value.StringValuer("debug"),
+					},
+					FilePath: "deployment.yaml",
+					Name:     "my-app",
+				},
+			},
+		},
+		{
+			name:             "rollout restart without manifest",
+			updates:          []string{"yaml(file=configmap.yaml,path=data.LOG_LEVEL,rollout-restart-name=my-app,rollout-restart=)=debug"},
+			expectedErrorMsg: "failed to create a rollout restart for yaml: empty rollout-restart parameter",
+		},
+		{
+			name:             "empty code owner",
+			updates:          []string{"yaml(file=config.yaml,path=version,codeowner=)=1.2.3"},
+			expectedErrorMsg: "failed to create a code owners filter for yaml: empty codeowner parameter",
+		},
+		{
+			name:             "empty format command",
+			updates:          []string{"yaml(file=config.yaml,path=version,format-command=)=1.2.3"},
+			expectedErrorMsg: "failed to create a formatter for yaml: empty format-command parameter",
+		},
 		{
 			name: "regex and sops updaters",
 			updates: []string{
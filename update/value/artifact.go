@@ -0,0 +1,274 @@
+package value
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/dailymotion-oss/octopilot/internal/transport"
+)
+
+// supported repository managers
+const (
+	ArtifactoryRepositoryManager = "artifactory"
+	NexusRepositoryManager       = "nexus"
+)
+
+// ArtifactValuer is a valuer that returns the most recent version of an artifact stored in a repository manager - Artifactory or Nexus.
+// The versions can be filtered with a semver constraint and/or a regex pattern.
+type ArtifactValuer struct {
+	URL        string
+	Type       string
+	Repository string
+	Group      string
+	Name       string
+	Constraint *semver.Constraints
+	Pattern    *regexp.Regexp
+	Username   string
+	Password   string
+	Token      string
+}
+
+func newArtifactValuer(params map[string]string) (*ArtifactValuer, error) {
+	valuer := &ArtifactValuer{}
+
+	valuer.URL = strings.TrimSuffix(params["url"], "/")
+	if len(valuer.URL) == 0 {
+		return nil, errors.New("missing url parameter")
+	}
+	if _, err := url.Parse(valuer.URL); err != nil {
+		return nil, fmt.Errorf("invalid url parameter: %w", err)
+	}
+
+	valuer.Type = strings.ToLower(params["type"])
+	switch valuer.Type {
+	case "":
+		valuer.Type = ArtifactoryRepositoryManager
+	case ArtifactoryRepositoryManager, NexusRepositoryManager:
+	default:
+		return nil, fmt.Errorf("invalid type %s: must be one of %s or %s", valuer.Type, ArtifactoryRepositoryManager, NexusRepositoryManager)
+	}
+
+	valuer.Repository = params["repository"]
+	if len(valuer.Repository) == 0 && valuer.Type == NexusRepositoryManager {
+		return nil, errors.New("missing repository parameter")
+	}
+
+	valuer.Group = params["group"]
+	valuer.Name = params["name"]
+	if len(valuer.Name) == 0 {
+		return nil, errors.New("missing name parameter")
+	}
+
+	if constraintStr := params["constraint"]; len(constraintStr) > 0 {
+		constraint, err := semver.NewConstraint(constraintStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %s: %w", constraintStr, err)
+		}
+		valuer.Constraint = constraint
+	}
+
+	if patternStr := params["pattern"]; len(patternStr) > 0 {
+		pattern, err := regexp.Compile(patternStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", patternStr, err)
+		}
+		valuer.Pattern = pattern
+	}
+
+	valuer.Username = params["username"]
+	valuer.Password = params["password"]
+	valuer.Token = params["token"]
+
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (v ArtifactValuer) Value(ctx context.Context, _ string) (string, error) {
+	var (
+		versions []string
+		err      error
+	)
+	switch v.Type {
+	case NexusRepositoryManager:
+		versions, err = v.nexusVersions(ctx)
+	default:
+		versions, err = v.artifactoryVersions(ctx)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to list the versions of artifact %s: %w", v.artifactName(), err)
+	}
+
+	version, found := v.latestVersion(versions)
+	if !found {
+		return "", fmt.Errorf("no version of artifact %s matching the constraints in %d versions", v.artifactName(), len(versions))
+	}
+	return version, nil
+}
+
+// artifactoryVersions returns all the versions of the artifact, using the Artifactory "artifact versions search" API
+func (v ArtifactValuer) artifactoryVersions(ctx context.Context) ([]string, error) {
+	query := url.Values{}
+	query.Set("a", v.Name)
+	if len(v.Group) > 0 {
+		query.Set("g", v.Group)
+	}
+	if len(v.Repository) > 0 {
+		query.Set("repos", v.Repository)
+	}
+
+	var response struct {
+		Results []struct {
+			Version string `json:"version"`
+		} `json:"results"`
+	}
+	err := v.get(ctx, "/api/search/versions", query, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(response.Results))
+	for _, result := range response.Results {
+		versions = append(versions, result.Version)
+	}
+	return versions, nil
+}
+
+// nexusVersions returns all the versions of the artifact, using the Nexus "search" API - following the pagination
+func (v ArtifactValuer) nexusVersions(ctx context.Context) ([]string, error) {
+	var (
+		versions          []string
+		continuationToken string
+	)
+	for {
+		query := url.Values{}
+		query.Set("repository", v.Repository)
+		query.Set("name", v.Name)
+		if len(v.Group) > 0 {
+			query.Set("group", v.Group)
+		}
+		if len(continuationToken) > 0 {
+			query.Set("continuationToken", continuationToken)
+		}
+
+		var response struct {
+			Items []struct {
+				Version string `json:"version"`
+			} `json:"items"`
+			ContinuationToken string `json:"continuationToken"`
+		}
+		err := v.get(ctx, "/service/rest/v1/search", query, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range response.Items {
+			versions = append(versions, item.Version)
+		}
+		if len(response.ContinuationToken) == 0 {
+			return versions, nil
+		}
+		continuationToken = response.ContinuationToken
+	}
+}
+
+// get sends an authenticated GET request to the API of the repository manager, and decodes the JSON response.
+// The errors never contain the credentials.
+func (v ArtifactValuer) get(ctx context.Context, path string, query url.Values, response interface{}) error {
+	endpoint := fmt.Sprintf("%s%s?%s", v.URL, path, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case len(v.Token) > 0:
+		req.Header.Set("Authorization", "Bearer "+v.Token)
+	case len(v.Username) > 0:
+		req.SetBasicAuth(v.Username, v.Password)
+	}
+
+	resp, err := transport.DefaultClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to %s%s: %w", v.URL, path, v.redact(errors.Unwrap(err)))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("unexpected response from %s%s: %s", v.URL, path, resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(response)
+	if err != nil {
+		return fmt.Errorf("failed to decode response from %s%s: %w", v.URL, path, err)
+	}
+	return nil
+}
+
+// latestVersion returns the highest version matching the constraint and the pattern.
+// Versions are compared as semantic versions if possible, as strings otherwise.
+func (v ArtifactValuer) latestVersion(versions []string) (string, bool) {
+	var candidates []string
+	for _, version := range versions {
+		if v.Pattern != nil && !v.Pattern.MatchString(version) {
+			continue
+		}
+		if v.Constraint != nil {
+			semVersion, err := semver.NewVersion(version)
+			if err != nil || !v.Constraint.Check(semVersion) {
+				continue
+			}
+		}
+		candidates = append(candidates, version)
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		versionI, errI := semver.NewVersion(candidates[i])
+		versionJ, errJ := semver.NewVersion(candidates[j])
+		switch {
+		case errI == nil && errJ == nil:
+			return versionI.LessThan(versionJ)
+		case errI == nil:
+			// non-semver versions are considered older
+			return false
+		case errJ == nil:
+			return true
+		default:
+			return candidates[i] < candidates[j]
+		}
+	})
+	return candidates[len(candidates)-1], true
+}
+
+func (v ArtifactValuer) artifactName() string {
+	if len(v.Group) > 0 {
+		return v.Group + ":" + v.Name
+	}
+	return v.Name
+}
+
+// redact removes the credentials from the given error
+func (v ArtifactValuer) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	for _, secret := range []string{v.Token, v.Password} {
+		if len(secret) > 0 {
+			msg = strings.ReplaceAll(msg, secret, "[REDACTED]")
+		}
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,164 @@
+package value
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactValuerValue(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/artifactory/api/search/versions":
+			if r.Header.Get("Authorization") != "Bearer secret-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "com.example", r.URL.Query().Get("g"))
+			assert.Equal(t, "my-lib", r.URL.Query().Get("a"))
+			assert.Equal(t, "libs-release", r.URL.Query().Get("repos"))
+			_, _ = w.Write([]byte(`{"results":[{"version":"1.2.0"},{"version":"1.10.0"},{"version":"2.0.0-rc.1"},{"version":"1.9.3"},{"version":"latest"}]}`))
+		case "/nexus/service/rest/v1/search":
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "octopilot" || password != "secret-password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "maven-releases", r.URL.Query().Get("repository"))
+			if r.URL.Query().Get("continuationToken") == "" {
+				_, _ = w.Write([]byte(`{"items":[{"version":"3.1.0"},{"version":"3.2.0-build.7"}],"continuationToken":"page2"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"items":[{"version":"3.2.0-build.9"},{"version":"3.0.5"}],"continuationToken":null}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name             string
+		valuer           ArtifactValuer
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "artifactory latest version",
+			valuer: ArtifactValuer{
+				URL:        server.URL + "/artifactory",
+				Type:       ArtifactoryRepositoryManager,
+				Repository: "libs-release",
+				Group:      "com.example",
+				Name:       "my-lib",
+				Token:      "secret-token",
+			},
+			expected: "2.0.0-rc.1",
+		},
+		{
+			name: "artifactory version matching a semver constraint",
+			valuer: ArtifactValuer{
+				URL:        server.URL + "/artifactory",
+				Type:       ArtifactoryRepositoryManager,
+				Repository: "libs-release",
+				Group:      "com.example",
+				Name:       "my-lib",
+				Constraint: mustSemverConstraint(t, "~1"),
+				Token:      "secret-token",
+			},
+			expected: "1.10.0",
+		},
+		{
+			name: "nexus version matching a pattern - with pagination",
+			valuer: ArtifactValuer{
+				URL:        server.URL + "/nexus",
+				Type:       NexusRepositoryManager,
+				Repository: "maven-releases",
+				Group:      "com.example",
+				Name:       "my-lib",
+				Pattern:    regexp.MustCompile(`-build\.[0-9]+$`),
+				Username:   "octopilot",
+				Password:   "secret-password",
+			},
+			expected: "3.2.0-build.9",
+		},
+		{
+			name: "no matching version",
+			valuer: ArtifactValuer{
+				URL:        server.URL + "/artifactory",
+				Type:       ArtifactoryRepositoryManager,
+				Repository: "libs-release",
+				Group:      "com.example",
+				Name:       "my-lib",
+				Constraint: mustSemverConstraint(t, ">= 3"),
+				Token:      "secret-token",
+			},
+			expectedErrorMsg: "no version of artifact com.example:my-lib matching the constraints in 5 versions",
+		},
+		{
+			name: "invalid credentials",
+			valuer: ArtifactValuer{
+				URL:      server.URL + "/nexus",
+				Type:     NexusRepositoryManager,
+				Name:     "my-lib",
+				Username: "octopilot",
+				Password: "wrong-password",
+			},
+			expectedErrorMsg: "failed to list the versions of artifact my-lib: unexpected response from " + server.URL + "/nexus/service/rest/v1/search: 401 Unauthorized",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := test.valuer.Value(context.Background(), "")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.NotContains(t, err.Error(), "secret-token")
+				assert.NotContains(t, err.Error(), "password")
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestArtifactValuerCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	valuer := ArtifactValuer{
+		URL:   server.URL,
+		Type:  ArtifactoryRepositoryManager,
+		Name:  "my-lib",
+		Token: "secret-token",
+	}
+	_, err := valuer.Value(ctx, "")
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "context canceled")
+	assert.NotContains(t, err.Error(), "secret-token")
+}
+
+func mustSemverConstraint(t *testing.T, constraint string) *semver.Constraints {
+	t.Helper()
+	c, err := semver.NewConstraint(constraint)
+	require.NoError(t, err)
+	return c
+}
@@ -0,0 +1,121 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/2016-10-01/keyvault"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/dailymotion-oss/octopilot/internal/transport"
+)
+
+const azureKeyVaultResource = "https://vault.azure.net"
+
+// AzureKeyVaultValuer is a valuer that returns the value of a secret stored in Azure Key Vault.
+// It authenticates with the default Azure credential chain: environment, managed identity, and then Azure CLI.
+type AzureKeyVaultValuer struct {
+	VaultURL string
+	Secret   string
+	// Version is the version of the secret - if empty, the current version is used
+	Version string
+
+	// client is used to get the secrets - if nil, a client for the Key Vault API is created
+	client keyVaultClient
+}
+
+// keyVaultClient is the part of the Key Vault API used by the valuer
+type keyVaultClient interface {
+	// GetSecret returns the value of the given version of the secret - or of its current version if the version is empty
+	GetSecret(ctx context.Context, vaultURL, name, version string) (string, error)
+}
+
+func newAzureKeyVaultValuer(params map[string]string) (*AzureKeyVaultValuer, error) {
+	valuer := &AzureKeyVaultValuer{
+		VaultURL: strings.TrimSuffix(params["vault-url"], "/"),
+		Secret:   params["secret"],
+		Version:  params["version"],
+	}
+
+	if len(valuer.VaultURL) == 0 {
+		return nil, errors.New("missing vault-url parameter")
+	}
+	if vaultURL, err := url.Parse(valuer.VaultURL); err != nil || vaultURL.Scheme != "https" || len(vaultURL.Host) == 0 {
+		return nil, fmt.Errorf("invalid vault-url %s: must be an https URL, such as https://my-vault.vault.azure.net", valuer.VaultURL)
+	}
+	if len(valuer.Secret) == 0 {
+		return nil, errors.New("missing secret parameter")
+	}
+
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+// The value of the secret is never logged nor included in the errors.
+func (v AzureKeyVaultValuer) Value(ctx context.Context, _ string) (string, error) {
+	client := v.client
+	if client == nil {
+		var err error
+		client, err = newAzureKeyVaultClient()
+		if err != nil {
+			return "", fmt.Errorf("failed to create a Key Vault client: %w", err)
+		}
+	}
+
+	value, err := client.GetSecret(ctx, v.VaultURL, v.Secret, v.Version)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", v.secretName(), err)
+	}
+	return value, nil
+}
+
+// Sensitive returns true: the value of a Key Vault secret is a secret
+func (v AzureKeyVaultValuer) Sensitive() bool {
+	return true
+}
+
+func (v AzureKeyVaultValuer) secretName() string {
+	name := fmt.Sprintf("%s/secrets/%s", v.VaultURL, v.Secret)
+	if len(v.Version) > 0 {
+		name += "/" + v.Version
+	}
+	return name
+}
+
+// azureKeyVaultClient is a client for the Key Vault API, based on the Azure SDK
+type azureKeyVaultClient struct {
+	keyvault.BaseClient
+}
+
+// newAzureKeyVaultClient returns a client authenticated with the default Azure credential chain:
+// the client credentials, client certificate, or username/password from the environment - or the managed identity - and then the Azure CLI.
+// The HTTP calls use the default transport, so they go through the configured proxy.
+func newAzureKeyVaultClient() (*azureKeyVaultClient, error) {
+	authorizer, err := auth.NewAuthorizerFromEnvironmentWithResource(azureKeyVaultResource)
+	if err != nil {
+		var cliErr error
+		authorizer, cliErr = auth.NewAuthorizerFromCLIWithResource(azureKeyVaultResource)
+		if cliErr != nil {
+			return nil, fmt.Errorf("no Azure credentials found in the environment (%v) nor in the Azure CLI (%v)", err, cliErr)
+		}
+	}
+
+	client := keyvault.New()
+	client.Authorizer = authorizer
+	client.Sender = transport.DefaultClient()
+	return &azureKeyVaultClient{BaseClient: client}, nil
+}
+
+// GetSecret returns the value of the given version of the secret
+func (c azureKeyVaultClient) GetSecret(ctx context.Context, vaultURL, name, version string) (string, error) {
+	bundle, err := c.BaseClient.GetSecret(ctx, vaultURL, name, version)
+	if err != nil {
+		return "", err
+	}
+	if bundle.Value == nil {
+		return "", errors.New("the secret has no value")
+	}
+	return *bundle.Value, nil
+}
@@ -0,0 +1,168 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/2016-10-01/keyvault"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubKeyVaultClient is a Key Vault client returning the values of a fixed set of secrets, indexed by "name/version"
+type stubKeyVaultClient map[string]string
+
+func (c stubKeyVaultClient) GetSecret(ctx context.Context, _, name, version string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	value, found := c[name+"/"+version]
+	if !found {
+		return "", errors.New("secret not found")
+	}
+	return value, nil
+}
+
+func TestAzureKeyVaultValuerValue(t *testing.T) {
+	t.Parallel()
+
+	client := stubKeyVaultClient{
+		"db-password/":                 "s3cret-current",
+		"db-password/0123456789abcdef": "s3cret-old",
+	}
+
+	tests := []struct {
+		name             string
+		valuer           AzureKeyVaultValuer
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "current version",
+			valuer: AzureKeyVaultValuer{
+				VaultURL: "https://my-vault.vault.azure.net",
+				Secret:   "db-password",
+				client:   client,
+			},
+			expected: "s3cret-current",
+		},
+		{
+			name: "specific version",
+			valuer: AzureKeyVaultValuer{
+				VaultURL: "https://my-vault.vault.azure.net",
+				Secret:   "db-password",
+				Version:  "0123456789abcdef",
+				client:   client,
+			},
+			expected: "s3cret-old",
+		},
+		{
+			name: "unknown secret",
+			valuer: AzureKeyVaultValuer{
+				VaultURL: "https://my-vault.vault.azure.net",
+				Secret:   "unknown",
+				Version:  "0123456789abcdef",
+				client:   client,
+			},
+			expectedErrorMsg: "failed to get secret https://my-vault.vault.azure.net/secrets/unknown/0123456789abcdef: secret not found",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := test.valuer.Value(context.Background(), "")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAzureKeyVaultValuerCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	valuer := AzureKeyVaultValuer{
+		VaultURL: "https://my-vault.vault.azure.net",
+		Secret:   "db-password",
+		client:   stubKeyVaultClient{"db-password/": "s3cret"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := valuer.Value(ctx, "")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAzureKeyVaultClientGetSecret(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/secrets/db-password/", "/secrets/db-password":
+			_, _ = w.Write([]byte(`{"value":"s3cret-current","id":"https://my-vault.vault.azure.net/secrets/db-password/fedcba9876543210"}`))
+		case "/secrets/db-password/0123456789abcdef":
+			_, _ = w.Write([]byte(`{"value":"s3cret-old","id":"https://my-vault.vault.azure.net/secrets/db-password/0123456789abcdef"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"SecretNotFound","message":"A secret with (name/id) unknown was not found in this key vault."}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	baseClient := keyvault.New()
+	baseClient.Authorizer = autorest.NullAuthorizer{}
+	baseClient.Sender = server.Client()
+	client := azureKeyVaultClient{BaseClient: baseClient}
+
+	tests := []struct {
+		name             string
+		secret           string
+		version          string
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name:     "current version",
+			secret:   "db-password",
+			expected: "s3cret-current",
+		},
+		{
+			name:     "specific version",
+			secret:   "db-password",
+			version:  "0123456789abcdef",
+			expected: "s3cret-old",
+		},
+		{
+			name:             "unknown secret",
+			secret:           "unknown",
+			expectedErrorMsg: "SecretNotFound",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := client.GetSecret(context.Background(), server.URL, test.secret, test.version)
+			if len(test.expectedErrorMsg) > 0 {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package value
+
+import (
+	"context"
+	"crypto/sha1" //nolint: gosec // only used to compute a checksum of the files, not for security
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// supported algorithms of the checksum valuer
+const (
+	ChecksumAlgorithmSHA256 = "sha256"
+	ChecksumAlgorithmSHA512 = "sha512"
+	ChecksumAlgorithmSHA1   = "sha1"
+)
+
+// ChecksumValuer is a valuer that returns a checksum of one or more files of the repository - such as the ConfigMap mounted by a Deployment,
+// to set it in a "checksum/config" annotation of the Deployment, so that it is rolled out when the configuration changes.
+// The checksum only depends on the paths and content of the files: it changes only when the files are changed.
+type ChecksumValuer struct {
+	// Files are the paths - or glob patterns - of the files, relative to the root of the repository
+	Files     []string
+	Algorithm string
+	// Exclude are the glob patterns of the files which are never part of the checksum - such as the files updated with it - see ExcludeFiles
+	Exclude []string
+}
+
+// fileExcluder is implemented by the valuers - and the transforms of valuers - which read files of the repository
+type fileExcluder interface {
+	excludeFiles(patterns []string) Valuer
+}
+
+// ExcludeFiles returns the given valuer, without the files matching the given glob patterns - relative to the root of the repository -
+// in the files it reads. It is used to exclude the files updated with the value of a checksum valuer from the checksum:
+// otherwise the checksum changes each time it is written, and the update is never idempotent.
+func ExcludeFiles(valuer Valuer, patterns ...string) Valuer {
+	if excluder, ok := valuer.(fileExcluder); ok && len(patterns) > 0 {
+		return excluder.excludeFiles(patterns)
+	}
+	return valuer
+}
+
+func newChecksumValuer(params map[string]string) (*ChecksumValuer, error) {
+	valuer := &ChecksumValuer{}
+
+	for _, file := range strings.Split(params["files"], ";") {
+		if file = strings.TrimSpace(file); len(file) > 0 {
+			valuer.Files = append(valuer.Files, file)
+		}
+	}
+	if len(valuer.Files) == 0 {
+		return nil, errors.New("missing files parameter")
+	}
+	for _, file := range valuer.Files {
+		if filepath.IsAbs(file) || strings.HasPrefix(filepath.ToSlash(filepath.Clean(file)), "../") {
+			return nil, fmt.Errorf("invalid file %s: must be relative to the root of the repository", file)
+		}
+		if _, err := filepath.Match(file, ""); err != nil {
+			return nil, fmt.Errorf("invalid file pattern %s: %w", file, err)
+		}
+	}
+
+	valuer.Algorithm = strings.ToLower(params["algorithm"])
+	switch valuer.Algorithm {
+	case "":
+		valuer.Algorithm = ChecksumAlgorithmSHA256
+	case ChecksumAlgorithmSHA256, ChecksumAlgorithmSHA512, ChecksumAlgorithmSHA1:
+	default:
+		return nil, fmt.Errorf("invalid algorithm %s: must be one of %s, %s or %s", valuer.Algorithm, ChecksumAlgorithmSHA256, ChecksumAlgorithmSHA512, ChecksumAlgorithmSHA1)
+	}
+
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+// With a single file, it is the hex checksum of its content - just like the sha256sum command.
+// With multiple files, it is the checksum of the sorted list of the checksums and paths of the files - just like "sha256sum <files> | sha256sum".
+func (v ChecksumValuer) Value(_ context.Context, repoPath string) (string, error) {
+	files, err := v.matchingFiles(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	sums := make([]string, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(repoPath, file))
+		if err != nil {
+			return "", fmt.Errorf("failed to read file %s: %w", file, err)
+		}
+		sums = append(sums, v.checksum(content))
+	}
+	if len(files) == 1 {
+		return sums[0], nil
+	}
+
+	var manifest strings.Builder
+	for i, file := range files {
+		fmt.Fprintf(&manifest, "%s  %s\n", sums[i], file)
+	}
+	return v.checksum([]byte(manifest.String())), nil
+}
+
+func (v ChecksumValuer) excludeFiles(patterns []string) Valuer {
+	v.Exclude = append(append([]string(nil), v.Exclude...), patterns...)
+	return &v
+}
+
+// matchingFiles returns the sorted paths - relative to the root of the repository, with slashes - of the regular files matching the patterns, but not the excluded ones.
+// The files are resolved - following the symlinks - and must be inside the repository.
+func (v ChecksumValuer) matchingFiles(repoPath string) ([]string, error) {
+	rootPath, err := filepath.EvalSymlinks(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the path of the repository: %w", err)
+	}
+	found := make(map[string]bool)
+	for _, pattern := range v.Files {
+		filePaths, err := filepath.Glob(filepath.Join(repoPath, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand glob pattern %s: %w", pattern, err)
+		}
+		var matched bool
+		for _, filePath := range filePaths {
+			relPath, err := filepath.Rel(repoPath, filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the relative path of file %s: %w", filePath, err)
+			}
+			relPath = filepath.ToSlash(relPath)
+			if v.excluded(relPath) {
+				continue
+			}
+			resolvedPath, err := filepath.EvalSymlinks(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve file %s: %w", relPath, err)
+			}
+			if resolvedRelPath, err := filepath.Rel(rootPath, resolvedPath); err != nil || strings.HasPrefix(filepath.ToSlash(resolvedRelPath), "../") {
+				return nil, fmt.Errorf("invalid file %s: links to %s, outside of the repository", relPath, resolvedPath)
+			}
+			info, err := os.Stat(resolvedPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat file %s: %w", relPath, err)
+			}
+			if !info.Mode().IsRegular() {
+				continue
+			}
+			found[relPath] = true
+			matched = true
+		}
+		if !matched {
+			return nil, fmt.Errorf("no file matching %s", pattern)
+		}
+	}
+
+	files := make([]string, 0, len(found))
+	for file := range found {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// excluded returns true if the given file - relative to the root of the repository, with slashes - matches one of the excluded patterns
+func (v ChecksumValuer) excluded(file string) bool {
+	for _, pattern := range v.Exclude {
+		if matched, _ := filepath.Match(filepath.ToSlash(filepath.Clean(pattern)), file); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (v ChecksumValuer) checksum(content []byte) string {
+	var h hash.Hash
+	switch v.Algorithm {
+	case ChecksumAlgorithmSHA512:
+		h = sha512.New()
+	case ChecksumAlgorithmSHA1:
+		h = sha1.New() //nolint: gosec // only used to compute a checksum of the files, not for security
+	default:
+		h = sha256.New()
+	}
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
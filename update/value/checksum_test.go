@@ -0,0 +1,148 @@
+package value
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumValuerValue(t *testing.T) {
+	t.Parallel()
+
+	repoPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "config", "nested"), 0755))
+	for name, content := range map[string]string{
+		"config/app.properties":  "log.level=info\n",
+		"config/db.properties":   "db.pool=10\n",
+		"config/nested/ignored":  "ignored\n",
+		"k8s/configmap.yaml":     "data:\n  LOG_LEVEL: info\n",
+		"k8s/unrelated-file.txt": "unrelated\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoPath, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644))
+	}
+	sha256sum := func(content string) string {
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+	}
+
+	tests := []struct {
+		name             string
+		valuer           ChecksumValuer
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name:     "single file",
+			valuer:   ChecksumValuer{Files: []string{"k8s/configmap.yaml"}, Algorithm: ChecksumAlgorithmSHA256},
+			expected: sha256sum("data:\n  LOG_LEVEL: info\n"),
+		},
+		{
+			name:     "single file with sha512",
+			valuer:   ChecksumValuer{Files: []string{"k8s/configmap.yaml"}, Algorithm: ChecksumAlgorithmSHA512},
+			expected: fmt.Sprintf("%x", sha512.Sum512([]byte("data:\n  LOG_LEVEL: info\n"))),
+		},
+		{
+			name:   "multiple files - sorted, without duplicates and directories",
+			valuer: ChecksumValuer{Files: []string{"k8s/configmap.yaml", "config/*", "config/db.properties"}, Algorithm: ChecksumAlgorithmSHA256},
+			expected: sha256sum(sha256sum("log.level=info\n") + "  config/app.properties\n" +
+				sha256sum("db.pool=10\n") + "  config/db.properties\n" +
+				sha256sum("data:\n  LOG_LEVEL: info\n") + "  k8s/configmap.yaml\n"),
+		},
+		{
+			name:             "no matching file",
+			valuer:           ChecksumValuer{Files: []string{"k8s/configmap.yaml", "k8s/*.json"}, Algorithm: ChecksumAlgorithmSHA256},
+			expectedErrorMsg: "no file matching k8s/*.json",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := test.valuer.Value(context.Background(), repoPath)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestChecksumValuerSymlinks(t *testing.T) {
+	t.Parallel()
+
+	outsidePath := filepath.Join(t.TempDir(), "passwd")
+	require.NoError(t, os.WriteFile(outsidePath, []byte("root:x:0:0\n"), 0644))
+	repoPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "config.yaml"), []byte("level: info\n"), 0644))
+	require.NoError(t, os.Symlink("config.yaml", filepath.Join(repoPath, "link.yaml")))
+	require.NoError(t, os.Symlink(outsidePath, filepath.Join(repoPath, "outside.yaml")))
+	require.NoError(t, os.Symlink(filepath.Dir(outsidePath), filepath.Join(repoPath, "outside-dir")))
+
+	// a link to a file of the repository is read
+	actual, err := ChecksumValuer{Files: []string{"link.yaml"}, Algorithm: ChecksumAlgorithmSHA256}.Value(context.Background(), repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte("level: info\n"))), actual)
+
+	// but not a link to a file outside of the repository
+	for _, file := range []string{"*.yaml", "outside-dir/passwd"} {
+		actual, err = ChecksumValuer{Files: []string{file}, Algorithm: ChecksumAlgorithmSHA256}.Value(context.Background(), repoPath)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "outside of the repository")
+		assert.Empty(t, actual)
+	}
+}
+
+func TestChecksumValuerExcludedFiles(t *testing.T) {
+	t.Parallel()
+
+	repoPath := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644))
+	}
+	writeFile("deployment.yaml", "annotations:\n  checksum/config: \"\"\n")
+	writeFile("configmap.yaml", "data:\n  LOG_LEVEL: info\n")
+	writeFile("README.md", "# my-app\n")
+
+	// the checksum is written to the deployment, which matches the pattern of the checksum
+	valuer, err := ParseValuer("checksum(files=*.yaml)")
+	require.NoError(t, err)
+	valuer = ExcludeFiles(valuer, "deployment.yaml")
+	update := func() string {
+		t.Helper()
+		checksum, err := valuer.Value(context.Background(), repoPath)
+		require.NoError(t, err)
+		writeFile("deployment.yaml", fmt.Sprintf("annotations:\n  checksum/config: %q\n", checksum))
+		return checksum
+	}
+
+	initialChecksum := update()
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte("data:\n  LOG_LEVEL: info\n"))), initialChecksum)
+
+	// writing the checksum doesn't change it
+	assert.Equal(t, initialChecksum, update())
+
+	// an unrelated file changed
+	writeFile("README.md", "# my-app\n\nSome documentation.\n")
+	assert.Equal(t, initialChecksum, update())
+
+	// the config file changed
+	writeFile("configmap.yaml", "data:\n  LOG_LEVEL: debug\n")
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte("data:\n  LOG_LEVEL: debug\n"))), update())
+
+	// only excluded files
+	valuer = ExcludeFiles(valuer, "configmap.yaml")
+	_, err = valuer.Value(context.Background(), repoPath)
+	assert.EqualError(t, err, "no file matching *.yaml")
+}
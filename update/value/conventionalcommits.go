@@ -0,0 +1,205 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// version bumps, ordered by precedence
+const (
+	noBump = iota
+	patchBump
+	minorBump
+	majorBump
+)
+
+var (
+	// type(scope)!: description
+	conventionalCommitRegexp = regexp.MustCompile(`^(?P<type>[a-zA-Z]+)(?:\([^)]*\))?(?P<breaking>!)?: `)
+
+	// BREAKING CHANGE: description - in the commit body / footers
+	breakingChangeFooterRegexp = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+)
+
+// ConventionalCommitsValuer is a valuer that returns the next version of the repository, computed from the conventional commits since the latest version tag:
+// a breaking change bumps the major version, a feature bumps the minor version, and a fix bumps the patch version.
+// See https://www.conventionalcommits.org/
+type ConventionalCommitsValuer struct {
+	Base   *semver.Version
+	Prefix string
+}
+
+func newConventionalCommitsValuer(params map[string]string) (*ConventionalCommitsValuer, error) {
+	valuer := &ConventionalCommitsValuer{
+		Prefix: params["prefix"],
+	}
+
+	baseStr := params["base"]
+	if len(baseStr) == 0 {
+		baseStr = "1.0.0"
+	}
+	base, err := semver.NewVersion(baseStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base %s: %w", baseStr, err)
+	}
+	valuer.Base = base
+
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (v ConventionalCommitsValuer) Value(ctx context.Context, repoPath string) (string, error) {
+	gitRepo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open git repository at %s: %w", repoPath, err)
+	}
+
+	versionTags, err := v.versionTags(gitRepo)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := gitRepo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve the HEAD of the git repository: %w", err)
+	}
+	headCommit, err := gitRepo.CommitObject(head.Hash())
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve the HEAD commit %s: %w", head.Hash(), err)
+	}
+
+	latestVersion, bump, err := walkCommitsSinceVersion(ctx, headCommit, versionTags)
+	if err != nil {
+		return "", err
+	}
+
+	if latestVersion == nil {
+		return v.Base.String(), nil
+	}
+
+	var nextVersion semver.Version
+	switch bump {
+	case majorBump:
+		nextVersion = latestVersion.IncMajor()
+	case minorBump:
+		nextVersion = latestVersion.IncMinor()
+	case patchBump:
+		nextVersion = latestVersion.IncPatch()
+	default:
+		nextVersion = *latestVersion
+	}
+	return nextVersion.String(), nil
+}
+
+// versionTags returns the versions of the repository, indexed by the hash of the tagged commit.
+// Only the tags starting with the prefix, and whose remaining part is a stable semver version, are considered.
+func (v ConventionalCommitsValuer) versionTags(gitRepo *git.Repository) (map[plumbing.Hash]*semver.Version, error) {
+	tagRefs, err := gitRepo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the git tags: %w", err)
+	}
+
+	versionTags := make(map[plumbing.Hash]*semver.Version)
+	err = tagRefs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if !strings.HasPrefix(name, v.Prefix) {
+			return nil
+		}
+		version, err := semver.NewVersion(strings.TrimPrefix(name, v.Prefix))
+		if err != nil || len(version.Prerelease()) > 0 {
+			return nil
+		}
+
+		commitHash := ref.Hash()
+		tag, err := gitRepo.TagObject(ref.Hash())
+		switch {
+		case err == nil:
+			commit, err := tag.Commit()
+			if err != nil {
+				// annotated tag which is not pointing to a commit
+				return nil
+			}
+			commitHash = commit.Hash
+		case !errors.Is(err, plumbing.ErrObjectNotFound):
+			return fmt.Errorf("failed to retrieve the git tag %s: %w", name, err)
+		}
+
+		if existing, found := versionTags[commitHash]; !found || version.GreaterThan(existing) {
+			versionTags[commitHash] = version
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versionTags, nil
+}
+
+// walkCommitsSinceVersion walks the history from the given commit, without going further than the tagged commits.
+// It returns the highest version found in the tags, and the highest bump of the commits walked before reaching a tagged commit.
+func walkCommitsSinceVersion(ctx context.Context, from *object.Commit, versionTags map[plumbing.Hash]*semver.Version) (*semver.Version, int, error) {
+	var (
+		latestVersion *semver.Version
+		bump          = noBump
+		visited       = map[plumbing.Hash]bool{from.Hash: true}
+		queue         = []*object.Commit{from}
+	)
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, noBump, err
+		}
+
+		commit := queue[0]
+		queue = queue[1:]
+
+		if version, found := versionTags[commit.Hash]; found {
+			if latestVersion == nil || version.GreaterThan(latestVersion) {
+				latestVersion = version
+			}
+			continue
+		}
+
+		if commitBump := conventionalCommitBump(commit.Message); commitBump > bump {
+			bump = commitBump
+		}
+
+		err := commit.Parents().ForEach(func(parent *object.Commit) error {
+			if !visited[parent.Hash] {
+				visited[parent.Hash] = true
+				queue = append(queue, parent)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, noBump, fmt.Errorf("failed to retrieve the parents of commit %s: %w", commit.Hash, err)
+		}
+	}
+	return latestVersion, bump, nil
+}
+
+// conventionalCommitBump returns the version bump required by the given commit message.
+func conventionalCommitBump(message string) int {
+	matches := conventionalCommitRegexp.FindStringSubmatch(message)
+	if len(matches) < 3 {
+		return noBump
+	}
+	if len(matches[2]) > 0 || breakingChangeFooterRegexp.MatchString(message) {
+		return majorBump
+	}
+	switch strings.ToLower(matches[1]) {
+	case "feat":
+		return minorBump
+	case "fix", "perf":
+		return patchBump
+	default:
+		return noBump
+	}
+}
@@ -0,0 +1,187 @@
+package value
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyStep is either a commit - with the given message - or a tag of the latest commit
+type historyStep struct {
+	commit       string
+	tag          string
+	annotatedTag bool
+}
+
+// initHistory creates a new git repository in a temporary directory, with the given history
+func initHistory(t *testing.T, history []historyStep) string {
+	t.Helper()
+	repoPath := t.TempDir()
+	gitRepo, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+	workTree, err := gitRepo.Worktree()
+	require.NoError(t, err)
+
+	signature := &object.Signature{
+		Name:  "octopilot",
+		Email: "octopilot@example.com",
+		When:  time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for i, step := range history {
+		if len(step.tag) > 0 {
+			head, err := gitRepo.Head()
+			require.NoError(t, err)
+			var opts *git.CreateTagOptions
+			if step.annotatedTag {
+				opts = &git.CreateTagOptions{Tagger: signature, Message: step.tag}
+			}
+			_, err = gitRepo.CreateTag(step.tag, head.Hash(), opts)
+			require.NoError(t, err)
+			continue
+		}
+
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "file.txt"), []byte(fmt.Sprintf("step %d", i)), 0644))
+		_, err = workTree.Add("file.txt")
+		require.NoError(t, err)
+		signature.When = signature.When.Add(time.Minute)
+		_, err = workTree.Commit(step.commit, &git.CommitOptions{Author: signature})
+		require.NoError(t, err)
+	}
+	return repoPath
+}
+
+func TestConventionalCommitsValuerValue(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		history  []historyStep
+		prefix   string
+		expected string
+	}{
+		{
+			name: "no prior tag",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{commit: "fix: some bug"},
+			},
+			expected: "0.1.0",
+		},
+		{
+			name: "fix since latest tag",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{tag: "1.2.3"},
+				{commit: "fix(parser): some bug"},
+				{commit: "docs: update readme"},
+			},
+			expected: "1.2.4",
+		},
+		{
+			name: "feature since latest tag",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{tag: "1.2.3"},
+				{commit: "fix: some bug"},
+				{commit: "feat(api): new endpoint"},
+				{commit: "chore: cleanup"},
+			},
+			expected: "1.3.0",
+		},
+		{
+			name: "breaking change with an exclamation mark",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{tag: "1.2.3"},
+				{commit: "feat!: remove the old endpoint"},
+				{commit: "fix: some bug"},
+			},
+			expected: "2.0.0",
+		},
+		{
+			name: "breaking change in the footer",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{tag: "1.2.3"},
+				{commit: "refactor: new config format\n\nBREAKING CHANGE: the old format is not supported anymore"},
+			},
+			expected: "2.0.0",
+		},
+		{
+			name: "no relevant commits since latest tag",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{tag: "1.2.3"},
+				{commit: "docs: update readme"},
+				{commit: "not a conventional commit"},
+			},
+			expected: "1.2.3",
+		},
+		{
+			name: "commits before the latest tag are ignored",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{tag: "1.0.0"},
+				{commit: "feat!: breaking change"},
+				{tag: "2.0.0", annotatedTag: true},
+				{commit: "fix: some bug"},
+			},
+			expected: "2.0.1",
+		},
+		{
+			name: "prerelease and non-version tags are ignored",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{tag: "1.2.3"},
+				{commit: "feat: new feature"},
+				{tag: "1.3.0-rc.1"},
+				{tag: "latest"},
+				{commit: "fix: some bug"},
+			},
+			expected: "1.3.0",
+		},
+		{
+			name: "tags with a prefix",
+			history: []historyStep{
+				{commit: "feat: initial commit"},
+				{tag: "v1.2.3"},
+				{tag: "chart-5.0.0"},
+				{commit: "feat: new feature"},
+			},
+			prefix:   "v",
+			expected: "1.3.0",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := initHistory(t, test.history)
+			valuer := ConventionalCommitsValuer{
+				Base:   semver.MustParse("0.1.0"),
+				Prefix: test.prefix,
+			}
+			actual, err := valuer.Value(context.Background(), repoPath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	t.Run("not a git repository", func(t *testing.T) {
+		t.Parallel()
+		valuer := ConventionalCommitsValuer{
+			Base: semver.MustParse("0.1.0"),
+		}
+		_, err := valuer.Value(context.Background(), t.TempDir())
+		require.ErrorContains(t, err, "failed to open git repository")
+	})
+}
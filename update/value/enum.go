@@ -0,0 +1,76 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EnumTransform is a transform that validates that the value returned by its child valuer is one of the allowed values.
+// The value is compared without its leading and trailing whitespaces, and the matching allowed value is returned.
+type EnumTransform struct {
+	Valuer          Valuer
+	Values          []string
+	CaseInsensitive bool
+}
+
+func newEnumTransform(child Valuer, params map[string]string) (Valuer, error) {
+	transform := &EnumTransform{
+		Valuer: child,
+	}
+
+	separator := params["sep"]
+	if len(separator) == 0 {
+		separator = ";"
+	}
+	for _, value := range strings.Split(params["values"], separator) {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			transform.Values = append(transform.Values, value)
+		}
+	}
+	if len(transform.Values) == 0 {
+		return nil, errors.New("missing values parameter")
+	}
+
+	if caseInsensitiveStr, found := params["case-insensitive"]; found {
+		caseInsensitive, err := strconv.ParseBool(caseInsensitiveStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid case-insensitive parameter %s: %w", caseInsensitiveStr, err)
+		}
+		transform.CaseInsensitive = caseInsensitive
+	}
+
+	return transform, nil
+}
+
+// Sensitive returns true if the transformed value is a secret
+func (t EnumTransform) Sensitive() bool {
+	return IsSecret(t.Valuer)
+}
+
+func (t EnumTransform) excludeFiles(patterns []string) Valuer {
+	t.Valuer = ExcludeFiles(t.Valuer, patterns...)
+	return &t
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (t EnumTransform) Value(ctx context.Context, repoPath string) (string, error) {
+	value, err := t.Valuer.Value(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	trimmedValue := strings.TrimSpace(value)
+	for _, allowedValue := range t.Values {
+		if allowedValue == trimmedValue || (t.CaseInsensitive && strings.EqualFold(allowedValue, trimmedValue)) {
+			return allowedValue, nil
+		}
+	}
+
+	if t.Sensitive() {
+		return "", fmt.Errorf("secret value is not allowed - allowed values are: %s", strings.Join(t.Values, ", "))
+	}
+	return "", fmt.Errorf("value %q is not allowed - allowed values are: %s", trimmedValue, strings.Join(t.Values, ", "))
+}
@@ -0,0 +1,93 @@
+package value
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnumTransformValue(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		transform        EnumTransform
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "allowed value",
+			transform: EnumTransform{
+				Valuer: StringValuer("staging"),
+				Values: []string{"dev", "staging", "prod"},
+			},
+			expected: "staging",
+		},
+		{
+			name: "allowed value with trailing new line",
+			transform: EnumTransform{
+				Valuer: StringValuer("prod\n"),
+				Values: []string{"dev", "staging", "prod"},
+			},
+			expected: "prod",
+		},
+		{
+			name: "allowed value with a different case",
+			transform: EnumTransform{
+				Valuer:          StringValuer("PROD"),
+				Values:          []string{"dev", "staging", "prod"},
+				CaseInsensitive: true,
+			},
+			expected: "prod",
+		},
+		{
+			name: "disallowed value with a different case",
+			transform: EnumTransform{
+				Valuer: StringValuer("PROD"),
+				Values: []string{"dev", "staging", "prod"},
+			},
+			expectedErrorMsg: `value "PROD" is not allowed - allowed values are: dev, staging, prod`,
+		},
+		{
+			name: "disallowed value",
+			transform: EnumTransform{
+				Valuer:          StringValuer("production"),
+				Values:          []string{"dev", "staging", "prod"},
+				CaseInsensitive: true,
+			},
+			expectedErrorMsg: `value "production" is not allowed - allowed values are: dev, staging, prod`,
+		},
+		{
+			name: "disallowed secret value",
+			transform: EnumTransform{
+				Valuer: secretStringValuer("s3cret"),
+				Values: []string{"dev", "staging", "prod"},
+			},
+			expectedErrorMsg: `secret value is not allowed - allowed values are: dev, staging, prod`,
+		},
+		{
+			name: "child valuer error",
+			transform: EnumTransform{
+				Valuer: FileValuer{Path: "does-not-exists"},
+				Values: []string{"dev"},
+			},
+			expectedErrorMsg: "failed to read file does-not-exists: open testdata/does-not-exists: no such file or directory",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := test.transform.Value(context.Background(), "testdata")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
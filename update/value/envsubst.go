@@ -0,0 +1,67 @@
+package value
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvsubstTransform is a transform that expands the `$VAR` and `${VAR}` references in the value returned by its child valuer,
+// using the environment variables of the current process.
+type EnvsubstTransform struct {
+	Valuer Valuer
+	Strict bool
+}
+
+func newEnvsubstTransform(child Valuer, params map[string]string) (Valuer, error) {
+	transform := &EnvsubstTransform{
+		Valuer: child,
+	}
+
+	if strictStr, found := params["strict"]; found {
+		strict, err := strconv.ParseBool(strictStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid strict parameter %s: %w", strictStr, err)
+		}
+		transform.Strict = strict
+	}
+
+	return transform, nil
+}
+
+// Sensitive returns true if the transformed value is a secret
+func (t EnvsubstTransform) Sensitive() bool {
+	return IsSecret(t.Valuer)
+}
+
+func (t EnvsubstTransform) excludeFiles(patterns []string) Valuer {
+	t.Valuer = ExcludeFiles(t.Valuer, patterns...)
+	return &t
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (t EnvsubstTransform) Value(ctx context.Context, repoPath string) (string, error) {
+	value, err := t.Valuer.Value(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	var undefinedVars []string
+	expandedValue := os.Expand(value, func(name string) string {
+		envValue, found := os.LookupEnv(name)
+		if !found {
+			undefinedVars = append(undefinedVars, name)
+		}
+		return envValue
+	})
+
+	if t.Strict && len(undefinedVars) > 0 {
+		if t.Sensitive() {
+			return "", fmt.Errorf("undefined environment variables in secret value: %s", strings.Join(undefinedVars, ", "))
+		}
+		return "", fmt.Errorf("undefined environment variables in value %q: %s", value, strings.Join(undefinedVars, ", "))
+	}
+	return expandedValue, nil
+}
@@ -0,0 +1,93 @@
+package value
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvsubstTransformValue(t *testing.T) {
+	tests := []struct {
+		name             string
+		env              map[string]string
+		transform        EnvsubstTransform
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "defined variables",
+			env: map[string]string{
+				"OCTOPILOT_TEST_REGION": "eu-west-1",
+				"OCTOPILOT_TEST_ENV":    "prod",
+			},
+			transform: EnvsubstTransform{
+				Valuer: StringValuer("${OCTOPILOT_TEST_REGION}-bucket-$OCTOPILOT_TEST_ENV"),
+			},
+			expected: "eu-west-1-bucket-prod",
+		},
+		{
+			name: "defined empty variable in strict mode",
+			env: map[string]string{
+				"OCTOPILOT_TEST_SUFFIX": "",
+			},
+			transform: EnvsubstTransform{
+				Valuer: StringValuer("bucket${OCTOPILOT_TEST_SUFFIX}"),
+				Strict: true,
+			},
+			expected: "bucket",
+		},
+		{
+			name: "undefined variable",
+			transform: EnvsubstTransform{
+				Valuer: StringValuer("${OCTOPILOT_TEST_UNDEFINED}-bucket"),
+			},
+			expected: "-bucket",
+		},
+		{
+			name: "undefined variables in strict mode",
+			env: map[string]string{
+				"OCTOPILOT_TEST_REGION": "eu-west-1",
+			},
+			transform: EnvsubstTransform{
+				Valuer: StringValuer("${OCTOPILOT_TEST_REGION}-${OCTOPILOT_TEST_UNDEFINED}-$OCTOPILOT_TEST_MISSING"),
+				Strict: true,
+			},
+			expectedErrorMsg: `undefined environment variables in value "${OCTOPILOT_TEST_REGION}-${OCTOPILOT_TEST_UNDEFINED}-$OCTOPILOT_TEST_MISSING": OCTOPILOT_TEST_UNDEFINED, OCTOPILOT_TEST_MISSING`,
+		},
+		{
+			name: "undefined variables in a secret in strict mode",
+			transform: EnvsubstTransform{
+				Valuer: secretStringValuer("postgres://admin:s3cret@${OCTOPILOT_TEST_UNDEFINED}/db"),
+				Strict: true,
+			},
+			expectedErrorMsg: `undefined environment variables in secret value: OCTOPILOT_TEST_UNDEFINED`,
+		},
+		{
+			name: "child valuer error",
+			transform: EnvsubstTransform{
+				Valuer: FileValuer{Path: "does-not-exists"},
+			},
+			expectedErrorMsg: "failed to read file does-not-exists: open testdata/does-not-exists: no such file or directory",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			actual, err := test.transform.Value(context.Background(), "testdata")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
@@ -2,15 +2,26 @@ package value
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
-// FileValuer is a valuer that returns the content of a specific file.
+// supported encodings of the file content
+const (
+	FileEncodingBase64 = "base64"
+)
+
+// FileValuer is a valuer that returns the content of a specific file - optionally base64-encoded, for Kubernetes secrets for example.
 type FileValuer struct {
-	Path string
+	Path     string
+	Encoding string
+	// Wrap is the column at which the base64-encoded content is wrapped - 0 to disable wrapping
+	Wrap int
 }
 
 func newFileValuer(params map[string]string) (*FileValuer, error) {
@@ -21,6 +32,24 @@ func newFileValuer(params map[string]string) (*FileValuer, error) {
 		return nil, errors.New("missing path parameter")
 	}
 
+	valuer.Encoding = strings.ToLower(params["encoding"])
+	switch valuer.Encoding {
+	case "", FileEncodingBase64:
+	default:
+		return nil, fmt.Errorf("invalid encoding %s: must be %s", valuer.Encoding, FileEncodingBase64)
+	}
+
+	if wrapStr := params["wrap"]; len(wrapStr) > 0 {
+		if valuer.Encoding != FileEncodingBase64 {
+			return nil, errors.New("the wrap parameter can only be used with the base64 encoding")
+		}
+		wrap, err := strconv.Atoi(wrapStr)
+		if err != nil || wrap < 0 {
+			return nil, fmt.Errorf("invalid wrap %s: must be a positive number of columns", wrapStr)
+		}
+		valuer.Wrap = wrap
+	}
+
 	return valuer, nil
 }
 
@@ -34,5 +63,32 @@ func (v FileValuer) Value(_ context.Context, repoPath string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to read file %s: %w", v.Path, err)
 	}
+	if v.Encoding == FileEncodingBase64 {
+		return wrapLines(base64.StdEncoding.EncodeToString(content), v.Wrap), nil
+	}
 	return string(content), nil
 }
+
+// Sensitive returns true if the content is base64-encoded: it is used to write binary files - such as keystores - in secrets
+func (v FileValuer) Sensitive() bool {
+	return v.Encoding == FileEncodingBase64
+}
+
+// wrapLines splits the given content in lines of the given width - or returns it as-is if the width is 0
+func wrapLines(content string, width int) string {
+	if width <= 0 || len(content) <= width {
+		return content
+	}
+	var builder strings.Builder
+	for i := 0; i < len(content); i += width {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		end := i + width
+		if end > len(content) {
+			end = len(content)
+		}
+		builder.WriteString(content[i:end])
+	}
+	return builder.String()
+}
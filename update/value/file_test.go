@@ -2,7 +2,10 @@ package value
 
 import (
 	"context"
+	"encoding/base64"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,3 +50,42 @@ func TestFileValuerValue(t *testing.T) {
 		})
 	}
 }
+
+func TestFileValuerValueBase64(t *testing.T) {
+	t.Parallel()
+	expected, err := os.ReadFile(filepath.Join("testdata", "binary.bin"))
+	require.NoError(t, err)
+
+	t.Run("without wrapping", func(t *testing.T) {
+		t.Parallel()
+		valuer := FileValuer{
+			Path:     "binary.bin",
+			Encoding: FileEncodingBase64,
+		}
+		actual, err := valuer.Value(context.Background(), "testdata")
+		require.NoError(t, err)
+		assert.NotContains(t, actual, "\n")
+		decoded, err := base64.StdEncoding.DecodeString(actual)
+		require.NoError(t, err)
+		assert.Equal(t, expected, decoded)
+	})
+
+	t.Run("wrapped at 76 columns", func(t *testing.T) {
+		t.Parallel()
+		valuer := FileValuer{
+			Path:     "binary.bin",
+			Encoding: FileEncodingBase64,
+			Wrap:     76,
+		}
+		actual, err := valuer.Value(context.Background(), "testdata")
+		require.NoError(t, err)
+		lines := strings.Split(actual, "\n")
+		require.Greater(t, len(lines), 1)
+		for _, line := range lines[:len(lines)-1] {
+			assert.Len(t, line, 76)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(lines, ""))
+		require.NoError(t, err)
+		assert.Equal(t, expected, decoded)
+	})
+}
@@ -0,0 +1,188 @@
+package value
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/internal/transport"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcpSecretManagerEndpoint = "https://secretmanager.googleapis.com"
+	gcpSecretManagerScope    = "https://www.googleapis.com/auth/cloud-platform"
+	gcpSecretLatestVersion   = "latest"
+)
+
+// GCPSecretManagerValuer is a valuer that returns the payload of a secret version stored in Google Secret Manager.
+// It authenticates with the Application Default Credentials, or with a service account key file.
+type GCPSecretManagerValuer struct {
+	Project         string
+	Secret          string
+	Version         string
+	CredentialsFile string
+
+	// client is used to access the secret versions - if nil, a client for the Secret Manager API is created
+	client secretManagerClient
+}
+
+// secretManagerClient is the part of the Secret Manager API used by the valuer
+type secretManagerClient interface {
+	// AccessSecretVersion returns the payload of the secret version with the given resource name
+	AccessSecretVersion(ctx context.Context, name string) ([]byte, error)
+}
+
+func newGCPSecretManagerValuer(params map[string]string) (*GCPSecretManagerValuer, error) {
+	valuer := &GCPSecretManagerValuer{
+		Project:         params["project"],
+		Secret:          params["secret"],
+		Version:         params["version"],
+		CredentialsFile: params["credentials-file"],
+	}
+
+	if len(valuer.Project) == 0 {
+		return nil, errors.New("missing project parameter")
+	}
+	if len(valuer.Secret) == 0 {
+		return nil, errors.New("missing secret parameter")
+	}
+	if len(valuer.Version) == 0 {
+		valuer.Version = gcpSecretLatestVersion
+	}
+	if valuer.Version != gcpSecretLatestVersion {
+		if version, err := strconv.Atoi(valuer.Version); err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid version %s: must be %s or a positive number", valuer.Version, gcpSecretLatestVersion)
+		}
+	}
+
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+// The payload of the secret is never logged nor included in the errors.
+func (v GCPSecretManagerValuer) Value(ctx context.Context, _ string) (string, error) {
+	client := v.client
+	if client == nil {
+		var err error
+		client, err = newRESTSecretManagerClient(ctx, v.CredentialsFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to create a Secret Manager client: %w", err)
+		}
+	}
+
+	payload, err := client.AccessSecretVersion(ctx, v.resourceName())
+	if err != nil {
+		return "", fmt.Errorf("failed to access secret version %s: %w", v.resourceName(), err)
+	}
+	return string(payload), nil
+}
+
+// Sensitive returns true: the payload of a secret version is a secret
+func (v GCPSecretManagerValuer) Sensitive() bool {
+	return true
+}
+
+func (v GCPSecretManagerValuer) resourceName() string {
+	return fmt.Sprintf("projects/%s/secrets/%s/versions/%s", v.Project, v.Secret, v.Version)
+}
+
+// restSecretManagerClient is a client for the REST API of Secret Manager
+type restSecretManagerClient struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// newRESTSecretManagerClient returns a client authenticated with the given service account key file - or with the Application Default Credentials.
+// The HTTP calls - including the ones to retrieve the OAuth2 tokens - use the default transport, so they go through the configured proxy.
+func newRESTSecretManagerClient(ctx context.Context, credentialsFile string) (*restSecretManagerClient, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, transport.DefaultClient())
+
+	var (
+		creds *google.Credentials
+		err   error
+	)
+	if len(credentialsFile) > 0 {
+		data, err := os.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials file %s: %w", credentialsFile, err)
+		}
+		creds, err = google.CredentialsFromJSON(ctx, data, gcpSecretManagerScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credentials from file %s: %w", credentialsFile, err)
+		}
+	} else {
+		creds, err = google.FindDefaultCredentials(ctx, gcpSecretManagerScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the default credentials: %w", err)
+		}
+	}
+
+	return &restSecretManagerClient{
+		endpoint:   gcpSecretManagerEndpoint,
+		httpClient: oauth2.NewClient(ctx, creds.TokenSource),
+	}, nil
+}
+
+// AccessSecretVersion returns the payload of the secret version, using the "access" method of the API - and verifies its checksum
+func (c restSecretManagerClient) AccessSecretVersion(ctx context.Context, name string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/v1/%s:access", strings.TrimSuffix(c.endpoint, "/"), name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&apiError); err == nil && len(apiError.Error.Message) > 0 {
+			return nil, fmt.Errorf("unexpected response: %s: %s", resp.Status, apiError.Error.Message)
+		}
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	var response struct {
+		Payload struct {
+			Data       string `json:"data"`
+			DataCrc32c string `json:"dataCrc32c"`
+		} `json:"payload"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(response.Payload.Data)
+	if err != nil {
+		return nil, errors.New("failed to decode payload: invalid base64 data")
+	}
+	if len(response.Payload.DataCrc32c) > 0 {
+		expected, err := strconv.ParseUint(response.Payload.DataCrc32c, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload checksum %s", response.Payload.DataCrc32c)
+		}
+		if actual := crc32.Checksum(payload, crc32.MakeTable(crc32.Castagnoli)); uint64(actual) != expected {
+			return nil, errors.New("payload checksum mismatch: the payload is corrupted")
+		}
+	}
+	return payload, nil
+}
@@ -0,0 +1,171 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSecretManagerClient is a Secret Manager client returning the payloads of a fixed set of secret versions
+type stubSecretManagerClient map[string]string
+
+func (c stubSecretManagerClient) AccessSecretVersion(ctx context.Context, name string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	payload, found := c[name]
+	if !found {
+		return nil, errors.New("secret version not found")
+	}
+	return []byte(payload), nil
+}
+
+func TestGCPSecretManagerValuerValue(t *testing.T) {
+	t.Parallel()
+
+	client := stubSecretManagerClient{
+		"projects/my-project/secrets/db-password/versions/latest": "s3cret-v2",
+		"projects/my-project/secrets/db-password/versions/1":      "s3cret-v1",
+	}
+
+	tests := []struct {
+		name             string
+		valuer           GCPSecretManagerValuer
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "latest version",
+			valuer: GCPSecretManagerValuer{
+				Project: "my-project",
+				Secret:  "db-password",
+				Version: "latest",
+				client:  client,
+			},
+			expected: "s3cret-v2",
+		},
+		{
+			name: "specific version",
+			valuer: GCPSecretManagerValuer{
+				Project: "my-project",
+				Secret:  "db-password",
+				Version: "1",
+				client:  client,
+			},
+			expected: "s3cret-v1",
+		},
+		{
+			name: "unknown secret",
+			valuer: GCPSecretManagerValuer{
+				Project: "my-project",
+				Secret:  "unknown",
+				Version: "latest",
+				client:  client,
+			},
+			expectedErrorMsg: "failed to access secret version projects/my-project/secrets/unknown/versions/latest: secret version not found",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := test.valuer.Value(context.Background(), "")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGCPSecretManagerValuerCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	valuer := GCPSecretManagerValuer{
+		Project: "my-project",
+		Secret:  "db-password",
+		Version: "latest",
+		client:  stubSecretManagerClient{"projects/my-project/secrets/db-password/versions/latest": "s3cret"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := valuer.Value(ctx, "")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRESTSecretManagerClientAccessSecretVersion(t *testing.T) {
+	t.Parallel()
+
+	checksum := func(payload string) string {
+		return strconv.FormatUint(uint64(crc32.Checksum([]byte(payload), crc32.MakeTable(crc32.Castagnoli))), 10)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/projects/my-project/secrets/db-password/versions/latest:access":
+			// "czNjcmV0" is "s3cret" base64-encoded
+			_, _ = w.Write([]byte(`{"name":"projects/123/secrets/db-password/versions/2","payload":{"data":"czNjcmV0","dataCrc32c":"` + checksum("s3cret") + `"}}`))
+		case "/v1/projects/my-project/secrets/corrupted/versions/latest:access":
+			_, _ = w.Write([]byte(`{"payload":{"data":"czNjcmV0","dataCrc32c":"` + checksum("other") + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"Secret [projects/123/secrets/unknown] not found or has no versions.","status":"NOT_FOUND"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := restSecretManagerClient{
+		endpoint:   server.URL,
+		httpClient: server.Client(),
+	}
+
+	tests := []struct {
+		name             string
+		secret           string
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name:     "valid payload",
+			secret:   "projects/my-project/secrets/db-password/versions/latest",
+			expected: "s3cret",
+		},
+		{
+			name:             "corrupted payload",
+			secret:           "projects/my-project/secrets/corrupted/versions/latest",
+			expectedErrorMsg: "payload checksum mismatch: the payload is corrupted",
+		},
+		{
+			name:             "unknown secret",
+			secret:           "projects/my-project/secrets/unknown/versions/latest",
+			expectedErrorMsg: "unexpected response: 404 Not Found: Secret [projects/123/secrets/unknown] not found or has no versions.",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := client.AccessSecretVersion(context.Background(), test.secret)
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, string(actual))
+			}
+		})
+	}
+}
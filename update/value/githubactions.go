@@ -0,0 +1,91 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// githubActionsFields are the supported fields of the GitHub Actions context, and their matching environment variable
+// see https://docs.github.com/en/actions/learn-github-actions/variables#default-environment-variables
+var githubActionsFields = map[string]string{
+	"action":           "GITHUB_ACTION",
+	"actor":            "GITHUB_ACTOR",
+	"actor_id":         "GITHUB_ACTOR_ID",
+	"base_ref":         "GITHUB_BASE_REF",
+	"event_name":       "GITHUB_EVENT_NAME",
+	"head_ref":         "GITHUB_HEAD_REF",
+	"job":              "GITHUB_JOB",
+	"ref":              "GITHUB_REF",
+	"ref_name":         "GITHUB_REF_NAME",
+	"ref_type":         "GITHUB_REF_TYPE",
+	"repository":       "GITHUB_REPOSITORY",
+	"repository_owner": "GITHUB_REPOSITORY_OWNER",
+	"run_attempt":      "GITHUB_RUN_ATTEMPT",
+	"run_id":           "GITHUB_RUN_ID",
+	"run_number":       "GITHUB_RUN_NUMBER",
+	"server_url":       "GITHUB_SERVER_URL",
+	"sha":              "GITHUB_SHA",
+	"triggering_actor": "GITHUB_TRIGGERING_ACTOR",
+	"workflow":         "GITHUB_WORKFLOW",
+	"workflow_ref":     "GITHUB_WORKFLOW_REF",
+}
+
+// githubActionsRunURLField is a "virtual" field, built from the server URL, the repository and the run ID
+const githubActionsRunURLField = "run_url"
+
+// GitHubActionsValuer is a valuer that returns a field of the GitHub Actions context - from the GITHUB_* env vars.
+type GitHubActionsValuer struct {
+	Field      string
+	Default    string
+	HasDefault bool
+}
+
+func newGitHubActionsValuer(params map[string]string) (*GitHubActionsValuer, error) {
+	valuer := &GitHubActionsValuer{}
+
+	valuer.Field = strings.ToLower(params["field"])
+	if len(valuer.Field) == 0 {
+		return nil, errors.New("missing field parameter")
+	}
+	if _, found := githubActionsFields[valuer.Field]; !found && valuer.Field != githubActionsRunURLField {
+		return nil, fmt.Errorf("unknown field %s - supported fields are: %s", valuer.Field, strings.Join(githubActionsFieldNames(), ", "))
+	}
+
+	valuer.Default, valuer.HasDefault = params["default"]
+
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (v GitHubActionsValuer) Value(_ context.Context, _ string) (string, error) {
+	var value string
+	if v.Field == githubActionsRunURLField {
+		serverURL, repository, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+		if len(serverURL) > 0 && len(repository) > 0 && len(runID) > 0 {
+			value = fmt.Sprintf("%s/%s/actions/runs/%s", serverURL, repository, runID)
+		}
+	} else {
+		value = os.Getenv(githubActionsFields[v.Field])
+	}
+
+	if len(value) > 0 {
+		return value, nil
+	}
+	if v.HasDefault {
+		return v.Default, nil
+	}
+	return "", fmt.Errorf("GitHub Actions field %s is not available - are you running inside a GitHub Actions workflow?", v.Field)
+}
+
+func githubActionsFieldNames() []string {
+	names := []string{githubActionsRunURLField}
+	for name := range githubActionsFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
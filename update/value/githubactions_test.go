@@ -0,0 +1,91 @@
+package value
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubActionsValuerValue(t *testing.T) {
+	tests := []struct {
+		name             string
+		env              map[string]string
+		valuer           GitHubActionsValuer
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "actor",
+			env: map[string]string{
+				"GITHUB_ACTOR": "octocat",
+			},
+			valuer: GitHubActionsValuer{
+				Field: "actor",
+			},
+			expected: "octocat",
+		},
+		{
+			name: "event name",
+			env: map[string]string{
+				"GITHUB_EVENT_NAME": "push",
+			},
+			valuer: GitHubActionsValuer{
+				Field: "event_name",
+			},
+			expected: "push",
+		},
+		{
+			name: "run url",
+			env: map[string]string{
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_REPOSITORY": "dailymotion-oss/octopilot",
+				"GITHUB_RUN_ID":     "1658821493",
+			},
+			valuer: GitHubActionsValuer{
+				Field: "run_url",
+			},
+			expected: "https://github.com/dailymotion-oss/octopilot/actions/runs/1658821493",
+		},
+		{
+			name: "missing field with default",
+			env: map[string]string{
+				"GITHUB_REF": "",
+			},
+			valuer: GitHubActionsValuer{
+				Field:      "ref",
+				Default:    "refs/heads/master",
+				HasDefault: true,
+			},
+			expected: "refs/heads/master",
+		},
+		{
+			name: "missing field without default",
+			env: map[string]string{
+				"GITHUB_RUN_ID": "",
+			},
+			valuer: GitHubActionsValuer{
+				Field: "run_id",
+			},
+			expectedErrorMsg: "GitHub Actions field run_id is not available - are you running inside a GitHub Actions workflow?",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			actual, err := test.valuer.Value(context.Background(), ".")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
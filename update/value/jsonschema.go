@@ -0,0 +1,77 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/internal/jsonschema"
+)
+
+// JSONSchemaTransform is a transform that validates the value returned by its child valuer - parsed as a JSON document - against a JSON Schema.
+// The value is returned as-is if it's valid.
+type JSONSchemaTransform struct {
+	Valuer Valuer
+	// SchemaPath is the path to the JSON Schema file - relative to the repository, or absolute
+	SchemaPath string
+}
+
+func newJSONSchemaTransform(child Valuer, params map[string]string) (Valuer, error) {
+	transform := &JSONSchemaTransform{
+		Valuer:     child,
+		SchemaPath: params["schema"],
+	}
+	if len(transform.SchemaPath) == 0 {
+		return nil, errors.New("missing schema parameter")
+	}
+	return transform, nil
+}
+
+// Sensitive returns true if the transformed value is a secret
+func (t JSONSchemaTransform) Sensitive() bool {
+	return IsSecret(t.Valuer)
+}
+
+func (t JSONSchemaTransform) excludeFiles(patterns []string) Valuer {
+	t.Valuer = ExcludeFiles(t.Valuer, patterns...)
+	return &t
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (t JSONSchemaTransform) Value(ctx context.Context, repoPath string) (string, error) {
+	value, err := t.Valuer.Value(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	schemaPath := t.SchemaPath
+	if !filepath.IsAbs(schemaPath) {
+		schemaPath = filepath.Join(repoPath, t.SchemaPath)
+	}
+	schemaData, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read JSON schema %s: %w", t.SchemaPath, err)
+	}
+	schema, err := jsonschema.Parse(schemaData)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse JSON schema %s: %w", t.SchemaPath, err)
+	}
+
+	validate := schema.Validate
+	if t.Sensitive() {
+		// don't leak the secret value in the errors
+		validate = schema.ValidateSecret
+	}
+	validationErrors, err := validate([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	if len(validationErrors) > 0 {
+		return "", fmt.Errorf("value doesn't match the JSON schema %s: %s", t.SchemaPath, strings.Join(validationErrors, "; "))
+	}
+
+	return value, nil
+}
@@ -0,0 +1,91 @@
+package value
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSchemaTransformValue(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		transform        JSONSchemaTransform
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "valid document",
+			transform: JSONSchemaTransform{
+				Valuer:     StringValuer(`{"image": {"repository": "my-app", "tag": "v1.2.3"}, "replicas": 3, "env": [{"name": "DEBUG", "value": null}]}`),
+				SchemaPath: "schema.json",
+			},
+			expected: `{"image": {"repository": "my-app", "tag": "v1.2.3"}, "replicas": 3, "env": [{"name": "DEBUG", "value": null}]}`,
+		},
+		{
+			name: "invalid document",
+			transform: JSONSchemaTransform{
+				Valuer:     StringValuer(`{"image": {"repository": "", "tag": "latest"}, "replicas": 1.5, "env": [{"value": 42}], "debug": true}`),
+				SchemaPath: "schema.json",
+			},
+			expectedErrorMsg: `value doesn't match the JSON schema schema.json: /: additional property "debug" is not allowed; /env/0: missing required property "name"; /env/0/value: expected string or null, got number; /image/repository: must be at least 1 characters long; /image/tag: "latest" doesn't match pattern ^v?[0-9]+\.[0-9]+\.[0-9]+$; /replicas: expected integer, got number`,
+		},
+		{
+			name: "missing required properties",
+			transform: JSONSchemaTransform{
+				Valuer:     StringValuer(`{"replicas": 20}`),
+				SchemaPath: "schema.json",
+			},
+			expectedErrorMsg: `value doesn't match the JSON schema schema.json: /: missing required property "image"; /replicas: must be <= 10`,
+		},
+		{
+			name: "not a JSON document",
+			transform: JSONSchemaTransform{
+				Valuer:     StringValuer(`image: my-app`),
+				SchemaPath: "schema.json",
+			},
+			expectedErrorMsg: "invalid JSON document: invalid character 'i' looking for beginning of value",
+		},
+		{
+			name: "invalid secret document",
+			transform: JSONSchemaTransform{
+				Valuer:     secretStringValuer(`{"image": {"repository": "my-app", "tag": "s3cret"}, "replicas": 3}`),
+				SchemaPath: "schema.json",
+			},
+			expectedErrorMsg: `value doesn't match the JSON schema schema.json: /image/tag: secret value doesn't match pattern ^v?[0-9]+\.[0-9]+\.[0-9]+$`,
+		},
+		{
+			name: "secret not a JSON document",
+			transform: JSONSchemaTransform{
+				Valuer:     secretStringValuer(`s3cret`),
+				SchemaPath: "schema.json",
+			},
+			expectedErrorMsg: "invalid JSON document",
+		},
+		{
+			name: "missing schema file",
+			transform: JSONSchemaTransform{
+				Valuer:     StringValuer(`{}`),
+				SchemaPath: "does-not-exists.json",
+			},
+			expectedErrorMsg: "failed to read JSON schema does-not-exists.json: open testdata/does-not-exists.json: no such file or directory",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := test.transform.Value(context.Background(), "testdata")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,465 @@
+package value
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/internal/transport"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	inClusterTokenPath     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterCAPath        = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	inClusterNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// kubernetesResource is the API definition of a kind of Kubernetes resource
+type kubernetesResource struct {
+	APIVersion string
+	Resource   string
+	Namespaced bool
+}
+
+// kubernetesKinds are the built-in kinds of Kubernetes resources, indexed by their lowercase name
+var kubernetesKinds = map[string]kubernetesResource{
+	"configmap":                {APIVersion: "v1", Resource: "configmaps", Namespaced: true},
+	"secret":                   {APIVersion: "v1", Resource: "secrets", Namespaced: true},
+	"service":                  {APIVersion: "v1", Resource: "services", Namespaced: true},
+	"serviceaccount":           {APIVersion: "v1", Resource: "serviceaccounts", Namespaced: true},
+	"pod":                      {APIVersion: "v1", Resource: "pods", Namespaced: true},
+	"namespace":                {APIVersion: "v1", Resource: "namespaces"},
+	"node":                     {APIVersion: "v1", Resource: "nodes"},
+	"deployment":               {APIVersion: "apps/v1", Resource: "deployments", Namespaced: true},
+	"statefulset":              {APIVersion: "apps/v1", Resource: "statefulsets", Namespaced: true},
+	"daemonset":                {APIVersion: "apps/v1", Resource: "daemonsets", Namespaced: true},
+	"replicaset":               {APIVersion: "apps/v1", Resource: "replicasets", Namespaced: true},
+	"job":                      {APIVersion: "batch/v1", Resource: "jobs", Namespaced: true},
+	"cronjob":                  {APIVersion: "batch/v1", Resource: "cronjobs", Namespaced: true},
+	"ingress":                  {APIVersion: "networking.k8s.io/v1", Resource: "ingresses", Namespaced: true},
+	"customresourcedefinition": {APIVersion: "apiextensions.k8s.io/v1", Resource: "customresourcedefinitions"},
+}
+
+// KubernetesValuer is a valuer that returns a label, an annotation, or a field of a live Kubernetes resource.
+type KubernetesValuer struct {
+	Kind       string
+	APIVersion string
+	Resource   string
+	Namespaced bool
+	Namespace  string
+	Name       string
+	Label      string
+	Annotation string
+	Path       string
+	Kubeconfig string
+	Context    string
+}
+
+func newKubernetesValuer(params map[string]string) (*KubernetesValuer, error) {
+	valuer := &KubernetesValuer{
+		Kind:       params["kind"],
+		APIVersion: params["api-version"],
+		Resource:   params["resource"],
+		Namespace:  params["namespace"],
+		Name:       params["name"],
+		Label:      params["label"],
+		Annotation: params["annotation"],
+		Path:       strings.TrimPrefix(params["path"], "."),
+		Kubeconfig: params["kubeconfig"],
+		Context:    params["context"],
+	}
+
+	switch {
+	case len(valuer.Resource) > 0:
+		if len(valuer.APIVersion) == 0 {
+			return nil, errors.New("missing api-version parameter")
+		}
+		valuer.Namespaced = len(valuer.Namespace) > 0
+	case len(valuer.Kind) > 0:
+		kind, found := kubernetesKinds[strings.ToLower(valuer.Kind)]
+		if !found {
+			return nil, fmt.Errorf("unknown kind %s - use the resource and api-version parameters for custom resources", valuer.Kind)
+		}
+		valuer.APIVersion = kind.APIVersion
+		valuer.Resource = kind.Resource
+		valuer.Namespaced = kind.Namespaced
+	default:
+		return nil, errors.New("missing kind or resource parameter")
+	}
+
+	if len(valuer.Name) == 0 {
+		return nil, errors.New("missing name parameter")
+	}
+
+	var fields int
+	for _, field := range []string{valuer.Label, valuer.Annotation, valuer.Path} {
+		if len(field) > 0 {
+			fields++
+		}
+	}
+	if fields != 1 {
+		return nil, errors.New("exactly one of the label, annotation or path parameters is required")
+	}
+
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (v KubernetesValuer) Value(ctx context.Context, _ string) (string, error) {
+	cluster, err := loadKubernetesCluster(ctx, v.Kubeconfig, v.Context)
+	if err != nil {
+		return "", fmt.Errorf("failed to load the kubernetes cluster configuration: %w", err)
+	}
+
+	namespace := v.Namespace
+	if len(namespace) == 0 {
+		namespace = cluster.namespace
+	}
+	resourceName := fmt.Sprintf("%s %s", v.Resource, v.Name)
+	if v.Namespaced {
+		resourceName = fmt.Sprintf("%s %s/%s", v.Resource, namespace, v.Name)
+	}
+
+	object, err := cluster.get(ctx, v.resourcePath(namespace))
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", resourceName, err)
+	}
+
+	switch {
+	case len(v.Label) > 0:
+		return v.metadataValue(object, "labels", v.Label, resourceName)
+	case len(v.Annotation) > 0:
+		return v.metadataValue(object, "annotations", v.Annotation, resourceName)
+	default:
+		value, err := valueAtPath(object, v.Path)
+		if err != nil {
+			return "", fmt.Errorf("path %s not found in %s: %w", v.Path, resourceName, err)
+		}
+		return value, nil
+	}
+}
+
+// resourcePath returns the API path of the resource, such as /apis/apps/v1/namespaces/default/deployments/my-app
+func (v KubernetesValuer) resourcePath(namespace string) string {
+	prefix := "/apis/" + v.APIVersion
+	if !strings.Contains(v.APIVersion, "/") {
+		// core API group
+		prefix = "/api/" + v.APIVersion
+	}
+	if v.Namespaced {
+		return fmt.Sprintf("%s/namespaces/%s/%s/%s", prefix, url.PathEscape(namespace), v.Resource, url.PathEscape(v.Name))
+	}
+	return fmt.Sprintf("%s/%s/%s", prefix, v.Resource, url.PathEscape(v.Name))
+}
+
+func (v KubernetesValuer) metadataValue(object interface{}, field, key, resourceName string) (string, error) {
+	if obj, ok := object.(map[string]interface{}); ok {
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			if values, ok := metadata[field].(map[string]interface{}); ok {
+				if value, found := values[key]; found {
+					return fmt.Sprint(value), nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("%s %s not found in %s", strings.TrimSuffix(field, "s"), key, resourceName)
+}
+
+// kubernetesCluster is the connection to a kubernetes API server
+type kubernetesCluster struct {
+	server    string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// get returns the decoded JSON object at the given API path
+func (c *kubernetesCluster) get(ctx context.Context, path string) (interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to %s: %w", c.server, errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// the API server returns a Status object with a human-readable message
+		var status struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &status)
+		switch resp.StatusCode {
+		case http.StatusUnauthorized:
+			return nil, fmt.Errorf("unauthorized - check the kubernetes credentials: %s", status.Message)
+		case http.StatusForbidden:
+			return nil, fmt.Errorf("forbidden - check the RBAC permissions of the kubernetes user: %s", status.Message)
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("not found: %s", status.Message)
+		default:
+			return nil, fmt.Errorf("unexpected response: %s: %s", resp.Status, status.Message)
+		}
+	}
+
+	object, err := decodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return object, nil
+}
+
+// kubeconfig is the subset of the kubeconfig file format used to connect to a cluster
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Exec                  *struct {
+				Command string   `yaml:"command"`
+				Args    []string `yaml:"args"`
+				Env     []struct {
+					Name  string `yaml:"name"`
+					Value string `yaml:"value"`
+				} `yaml:"env"`
+			} `yaml:"exec"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// loadKubernetesCluster returns the connection to the kubernetes cluster, defined by:
+// - the given kubeconfig file, if set
+// - or the in-cluster service account, when running inside a pod
+// - or the kubeconfig file defined by the KUBECONFIG env var, or ~/.kube/config by default
+func loadKubernetesCluster(ctx context.Context, kubeconfigPath, contextName string) (*kubernetesCluster, error) {
+	if len(kubeconfigPath) == 0 {
+		if host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"); len(host) > 0 && len(port) > 0 {
+			return loadInClusterKubernetesCluster(host, port)
+		}
+		kubeconfigPath = os.Getenv("KUBECONFIG")
+		if i := strings.Index(kubeconfigPath, string(os.PathListSeparator)); i >= 0 {
+			kubeconfigPath = kubeconfigPath[:i]
+		}
+	}
+	if len(kubeconfigPath) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the home directory: %w", err)
+		}
+		kubeconfigPath = filepath.Join(home, ".kube", "config")
+	}
+
+	data, err := os.ReadFile(kubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig file %s: %w", kubeconfigPath, err)
+	}
+	var config kubeconfig
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig file %s: %w", kubeconfigPath, err)
+	}
+
+	if len(contextName) == 0 {
+		contextName = config.CurrentContext
+	}
+	cluster := &kubernetesCluster{namespace: "default"}
+	var clusterName, userName string
+	for _, c := range config.Contexts {
+		if c.Name == contextName {
+			clusterName, userName = c.Context.Cluster, c.Context.User
+			if len(c.Context.Namespace) > 0 {
+				cluster.namespace = c.Context.Namespace
+			}
+		}
+	}
+	if len(clusterName) == 0 {
+		return nil, fmt.Errorf("context %s not found in kubeconfig file %s", contextName, kubeconfigPath)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	var clusterFound bool
+	for _, c := range config.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		clusterFound = true
+		cluster.server = strings.TrimSuffix(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify //nolint: gosec // explicitly configured in the kubeconfig file
+		caData, err := fileOrData(c.Cluster.CertificateAuthority, c.Cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate authority for cluster %s: %w", clusterName, err)
+		}
+		if len(caData) > 0 {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(caData) {
+				return nil, fmt.Errorf("invalid certificate authority for cluster %s: no PEM-encoded certificate found", clusterName)
+			}
+		}
+	}
+	if !clusterFound {
+		return nil, fmt.Errorf("cluster %s not found in kubeconfig file %s", clusterName, kubeconfigPath)
+	}
+
+	for _, u := range config.Users {
+		if u.Name != userName {
+			continue
+		}
+		cluster.token = u.User.Token
+		if len(u.User.TokenFile) > 0 {
+			token, err := os.ReadFile(u.User.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read token file for user %s: %w", userName, err)
+			}
+			cluster.token = strings.TrimSpace(string(token))
+		}
+		if u.User.Exec != nil {
+			env := os.Environ()
+			for _, e := range u.User.Exec.Env {
+				env = append(env, e.Name+"="+e.Value)
+			}
+			cluster.token, err = execCredentialToken(ctx, u.User.Exec.Command, u.User.Exec.Args, env)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get credentials for user %s: %w", userName, err)
+			}
+		}
+		certData, err := fileOrData(u.User.ClientCertificate, u.User.ClientCertificateData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate for user %s: %w", userName, err)
+		}
+		keyData, err := fileOrData(u.User.ClientKey, u.User.ClientKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client key for user %s: %w", userName, err)
+		}
+		if len(certData) > 0 {
+			cert, err := tls.X509KeyPair(certData, keyData)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate for user %s: %w", userName, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	cluster.client = newKubernetesHTTPClient(tlsConfig)
+	return cluster, nil
+}
+
+func loadInClusterKubernetesCluster(host, port string) (*kubernetesCluster, error) {
+	token, err := os.ReadFile(inClusterTokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account token: %w", err)
+	}
+	caData, err := os.ReadFile(inClusterCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account CA: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    x509.NewCertPool(),
+	}
+	tlsConfig.RootCAs.AppendCertsFromPEM(caData)
+
+	namespace := "default"
+	if data, err := os.ReadFile(inClusterNamespacePath); err == nil {
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	return &kubernetesCluster{
+		server:    "https://" + strings.Trim(host, "[]") + ":" + port,
+		token:     strings.TrimSpace(string(token)),
+		namespace: namespace,
+		client:    newKubernetesHTTPClient(tlsConfig),
+	}, nil
+}
+
+// newKubernetesHTTPClient returns an HTTP client using the default transport - for the proxy configuration - with the given TLS configuration.
+func newKubernetesHTTPClient(tlsConfig *tls.Config) *http.Client {
+	httpTransport, ok := transport.Default().(*http.Transport)
+	if !ok {
+		httpTransport = http.DefaultTransport.(*http.Transport)
+	}
+	httpTransport = httpTransport.Clone()
+	httpTransport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: httpTransport}
+}
+
+// execCredentialToken runs the given credential plugin - such as gke-gcloud-auth-plugin - and returns the token of the ExecCredential it writes on stdout.
+func execCredentialToken(ctx context.Context, command string, args, env []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, args...) //nolint: gosec // the command is defined in the kubeconfig file
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to run credential plugin %s - got stderr [%s]: %w", command, strings.TrimSpace(stderr.String()), err)
+	}
+
+	var credential struct {
+		Status struct {
+			Token string `json:"token"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &credential); err != nil {
+		return "", fmt.Errorf("failed to parse the output of credential plugin %s: %w", command, err)
+	}
+	if len(credential.Status.Token) == 0 {
+		return "", fmt.Errorf("credential plugin %s returned no token", command)
+	}
+	return credential.Status.Token, nil
+}
+
+// fileOrData returns the content of the given file - if set - or the given base64-encoded data
+func fileOrData(file, data string) ([]byte, error) {
+	if len(file) > 0 {
+		return os.ReadFile(file)
+	}
+	if len(data) > 0 {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	return nil, nil
+}
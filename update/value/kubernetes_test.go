@@ -0,0 +1,251 @@
+package value
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kubeconfigTemplate = `apiVersion: v1
+kind: Config
+current-context: test
+contexts:
+- name: test
+  context:
+    cluster: fake
+    user: octopilot
+    namespace: production
+- name: other
+  context:
+    cluster: fake
+    user: anonymous
+clusters:
+- name: fake
+  cluster:
+    server: %s
+users:
+- name: octopilot
+  user:
+    token: secret-token
+- name: anonymous
+  user: {}
+`
+
+func TestKubernetesValuerValue(t *testing.T) {
+	t.Parallel()
+
+	// fake API server, with a single deployment
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"kind":"Status","status":"Failure","message":"deployments.apps \"my-app\" is forbidden: User \"system:anonymous\" cannot get resource \"deployments\" in API group \"apps\" in the namespace \"production\"","reason":"Forbidden","code":403}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/apis/apps/v1/namespaces/production/deployments/my-app":
+			_, _ = w.Write([]byte(`{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {
+    "name": "my-app",
+    "namespace": "production",
+    "labels": {"app.kubernetes.io/version": "1.2.3"},
+    "annotations": {"example.com/git-sha": "abc123"}
+  },
+  "spec": {
+    "replicas": 3,
+    "revisionHistoryLimit": 1234567,
+    "template": {"spec": {"containers": [{"name": "app", "image": "my-app:1.2.3"}]}}
+  }
+}`))
+		case "/api/v1/namespaces/kube-system":
+			_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"kube-system","uid":"d0a1b2c3"}}`))
+		case "/apis/example.com/v1/namespaces/production/widgets/my-widget":
+			_, _ = w.Write([]byte(`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"my-widget"},"status":{"phase":"Ready"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","status":"Failure","message":"the server could not find the requested resource","reason":"NotFound","code":404}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(fmt.Sprintf(kubeconfigTemplate, server.URL)), 0600))
+
+	tests := []struct {
+		name             string
+		valuer           KubernetesValuer
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "label of a deployment in the namespace of the context",
+			valuer: KubernetesValuer{
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Namespaced: true,
+				Name:       "my-app",
+				Label:      "app.kubernetes.io/version",
+				Kubeconfig: kubeconfig,
+			},
+			expected: "1.2.3",
+		},
+		{
+			name: "annotation of a deployment",
+			valuer: KubernetesValuer{
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Namespaced: true,
+				Namespace:  "production",
+				Name:       "my-app",
+				Annotation: "example.com/git-sha",
+				Kubeconfig: kubeconfig,
+			},
+			expected: "abc123",
+		},
+		{
+			name: "field of a deployment",
+			valuer: KubernetesValuer{
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Namespaced: true,
+				Name:       "my-app",
+				Path:       "spec.template.spec.containers.0.image",
+				Kubeconfig: kubeconfig,
+			},
+			expected: "my-app:1.2.3",
+		},
+		{
+			name: "large number field of a deployment",
+			valuer: KubernetesValuer{
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Namespaced: true,
+				Name:       "my-app",
+				Path:       "spec.revisionHistoryLimit",
+				Kubeconfig: kubeconfig,
+			},
+			expected: "1234567",
+		},
+		{
+			name: "field of a cluster-scoped resource",
+			valuer: KubernetesValuer{
+				APIVersion: "v1",
+				Resource:   "namespaces",
+				Name:       "kube-system",
+				Path:       "metadata.uid",
+				Kubeconfig: kubeconfig,
+			},
+			expected: "d0a1b2c3",
+		},
+		{
+			name: "field of a custom resource",
+			valuer: KubernetesValuer{
+				APIVersion: "example.com/v1",
+				Resource:   "widgets",
+				Namespaced: true,
+				Name:       "my-widget",
+				Path:       "status.phase",
+				Kubeconfig: kubeconfig,
+			},
+			expected: "Ready",
+		},
+		{
+			name: "missing label",
+			valuer: KubernetesValuer{
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Namespaced: true,
+				Name:       "my-app",
+				Label:      "team",
+				Kubeconfig: kubeconfig,
+			},
+			expectedErrorMsg: "label team not found in deployments production/my-app",
+		},
+		{
+			name: "missing resource",
+			valuer: KubernetesValuer{
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Namespaced: true,
+				Name:       "other-app",
+				Label:      "team",
+				Kubeconfig: kubeconfig,
+			},
+			expectedErrorMsg: "failed to get deployments production/other-app: not found: the server could not find the requested resource",
+		},
+		{
+			name: "forbidden by RBAC",
+			valuer: KubernetesValuer{
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Namespaced: true,
+				Namespace:  "production",
+				Name:       "my-app",
+				Label:      "app.kubernetes.io/version",
+				Kubeconfig: kubeconfig,
+				Context:    "other",
+			},
+			expectedErrorMsg: `failed to get deployments production/my-app: forbidden - check the RBAC permissions of the kubernetes user: deployments.apps "my-app" is forbidden: User "system:anonymous" cannot get resource "deployments" in API group "apps" in the namespace "production"`,
+		},
+		{
+			name: "unknown context",
+			valuer: KubernetesValuer{
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Name:       "my-app",
+				Label:      "app.kubernetes.io/version",
+				Kubeconfig: kubeconfig,
+				Context:    "whatever",
+			},
+			expectedErrorMsg: "failed to load the kubernetes cluster configuration: context whatever not found in kubeconfig file " + kubeconfig,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := test.valuer.Value(context.Background(), "")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestKubernetesValuerCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(fmt.Sprintf(kubeconfigTemplate, server.URL)), 0600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	valuer := KubernetesValuer{
+		APIVersion: "v1",
+		Resource:   "configmaps",
+		Namespaced: true,
+		Name:       "my-config",
+		Path:       "data.version",
+		Kubeconfig: kubeconfig,
+	}
+	_, err := valuer.Value(ctx, "")
+	assert.ErrorIs(t, err, context.Canceled)
+}
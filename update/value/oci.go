@@ -0,0 +1,317 @@
+package value
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/dailymotion-oss/octopilot/internal/transport"
+)
+
+const ociScheme = "oci://"
+
+var (
+	// key="value" or key=value parameters of a WWW-Authenticate challenge
+	challengeParamRegexp = regexp.MustCompile(`([a-zA-Z_]+)=(?:"([^"]*)"|([^,\s]*))`)
+	// <url>; rel="next" link of the next page of tags
+	nextLinkRegexp = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+)
+
+// OCIValuer is a valuer that returns the highest version of an OCI artifact - such as a Helm chart stored in an OCI registry -
+// from the tags of its repository. The versions can be filtered with a semver constraint.
+type OCIValuer struct {
+	Registry   string
+	Repository string
+	Constraint *semver.Constraints
+	Username   string
+	Password   string
+	Token      string
+	// PlainHTTP uses HTTP instead of HTTPS to connect to the registry
+	PlainHTTP bool
+}
+
+func newOCIValuer(params map[string]string) (*OCIValuer, error) {
+	valuer := &OCIValuer{}
+
+	artifactURL := params["url"]
+	if len(artifactURL) == 0 {
+		return nil, errors.New("missing url parameter")
+	}
+	if !strings.HasPrefix(artifactURL, ociScheme) {
+		return nil, fmt.Errorf("invalid url %s: must start with %s, such as oci://ghcr.io/my-org/charts/my-chart", artifactURL, ociScheme)
+	}
+	registry, repository, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(artifactURL, ociScheme), "/"), "/")
+	if len(registry) == 0 || len(repository) == 0 {
+		return nil, fmt.Errorf("invalid url %s: must contain a registry and a repository, such as oci://ghcr.io/my-org/charts/my-chart", artifactURL)
+	}
+	valuer.Registry = registry
+	valuer.Repository = repository
+
+	if constraintStr := params["constraint"]; len(constraintStr) > 0 {
+		constraint, err := semver.NewConstraint(constraintStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %s: %w", constraintStr, err)
+		}
+		valuer.Constraint = constraint
+	}
+
+	valuer.Username = params["username"]
+	valuer.Password = params["password"]
+	valuer.Token = params["token"]
+	if len(valuer.Token) > 0 && len(valuer.Username) > 0 {
+		return nil, errors.New("the token and username parameters can't be used together")
+	}
+	valuer.PlainHTTP = params["plain-http"] == "true"
+
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (v OCIValuer) Value(ctx context.Context, _ string) (string, error) {
+	tags, err := v.tags(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list the tags of %s: %w", v.artifactURL(), err)
+	}
+
+	version, found := v.latestVersion(tags)
+	if !found {
+		return "", fmt.Errorf("no version of %s matching the constraint in %d tags", v.artifactURL(), len(tags))
+	}
+	return version, nil
+}
+
+// tags returns all the tags of the repository, using the "tags list" API of the OCI distribution spec - following the pagination.
+// If the registry requires authentication, the credentials are exchanged for a token - or sent with basic auth - as requested by the registry.
+func (v OCIValuer) tags(ctx context.Context) ([]string, error) {
+	var (
+		tags          []string
+		authorization string
+		challenged    bool
+	)
+	if len(v.Token) > 0 {
+		authorization = "Bearer " + v.Token
+	}
+
+	scheme := "https"
+	if v.PlainHTTP {
+		scheme = "http"
+	}
+	endpoint := &url.URL{Scheme: scheme, Host: v.Registry, Path: fmt.Sprintf("/v2/%s/tags/list", v.Repository)}
+	for endpoint != nil {
+		resp, err := v.get(ctx, endpoint.String(), authorization)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && !challenged && len(v.Token) == 0 {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			challenged = true
+			authorization, err = v.authorization(ctx, challenge)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var response struct {
+			Tags []string `json:"tags"`
+		}
+		err = decodeRegistryResponse(resp, &response)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, response.Tags...)
+
+		endpoint, err = nextPage(endpoint, resp.Header.Get("Link"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// authorization returns the value of the authorization header requested by the given WWW-Authenticate challenge of the registry
+func (v OCIValuer) authorization(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if len(v.Username) == 0 {
+			return "", errors.New("the registry requires basic authentication: missing username parameter")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(v.Username+":"+v.Password)), nil
+	case "bearer":
+		token, err := v.fetchToken(ctx, parseChallengeParams(params))
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("the registry requires authentication with an unsupported challenge %q", scheme)
+	}
+}
+
+// fetchToken returns a token to pull from the repository, retrieved from the authorization server of the registry.
+// The credentials - if any - are sent with basic auth, otherwise an anonymous token is requested.
+func (v OCIValuer) fetchToken(ctx context.Context, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || len(realm.Scheme) == 0 || len(realm.Host) == 0 {
+		return "", fmt.Errorf("the registry requires a token with an invalid realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; len(service) > 0 {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if len(scope) == 0 {
+		scope = fmt.Sprintf("repository:%s:pull", v.Repository)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	authorization := ""
+	if len(v.Username) > 0 {
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(v.Username+":"+v.Password))
+	}
+	resp, err := v.get(ctx, realm.String(), authorization)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = decodeRegistryResponse(resp, &response)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a token: %w", err)
+	}
+	if len(response.Token) > 0 {
+		return response.Token, nil
+	}
+	if len(response.AccessToken) > 0 {
+		return response.AccessToken, nil
+	}
+	return "", fmt.Errorf("failed to get a token: no token in the response from %s", endpointName(resp.Request.URL))
+}
+
+// get sends a GET request with the given authorization header.
+// The errors never contain the credentials.
+func (v OCIValuer) get(ctx context.Context, endpoint, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(authorization) > 0 {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := transport.DefaultClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to %s: %w", endpointName(req.URL), v.redact(errors.Unwrap(err)))
+	}
+	return resp, nil
+}
+
+// latestVersion returns the highest semver version matching the constraint - tags which are not semver versions are ignored.
+// Without a constraint, the pre-release versions are ignored, just like Helm does.
+// Helm stores the "+" of the build metadata as "_" in the tags, so it is converted back.
+func (v OCIValuer) latestVersion(tags []string) (string, bool) {
+	var latest *semver.Version
+	for _, tag := range tags {
+		version, err := semver.StrictNewVersion(strings.ReplaceAll(tag, "_", "+"))
+		if err != nil {
+			continue
+		}
+		if v.Constraint != nil {
+			if !v.Constraint.Check(version) {
+				continue
+			}
+		} else if len(version.Prerelease()) > 0 {
+			continue
+		}
+		if latest == nil || version.GreaterThan(latest) {
+			latest = version
+		}
+	}
+	if latest == nil {
+		return "", false
+	}
+	return latest.Original(), true
+}
+
+func (v OCIValuer) artifactURL() string {
+	return ociScheme + v.Registry + "/" + v.Repository
+}
+
+// redact removes the credentials from the given error
+func (v OCIValuer) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	for _, secret := range []string{v.Token, v.Password} {
+		if len(secret) > 0 {
+			msg = strings.ReplaceAll(msg, secret, "[REDACTED]")
+		}
+	}
+	return errors.New(msg)
+}
+
+// decodeRegistryResponse decodes the JSON body of a successful response, and closes it
+func decodeRegistryResponse(resp *http.Response, response interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("unexpected response from %s: %s", endpointName(resp.Request.URL), resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", endpointName(resp.Request.URL), err)
+	}
+	return nil
+}
+
+// endpointName returns the URL without its query - which may be long, for the pagination
+func endpointName(endpoint *url.URL) string {
+	return endpoint.Scheme + "://" + endpoint.Host + endpoint.Path
+}
+
+// parseChallengeParams returns the parameters of a WWW-Authenticate challenge, such as realm="https://auth.example.com/token",service="registry"
+func parseChallengeParams(params string) map[string]string {
+	values := map[string]string{}
+	for _, match := range challengeParamRegexp.FindAllStringSubmatch(params, -1) {
+		value := match[2]
+		if len(value) == 0 {
+			value = match[3]
+		}
+		values[strings.ToLower(match[1])] = value
+	}
+	return values
+}
+
+// nextPage returns the URL of the next page of tags, from the Link header of the response - or nil if it is the last page.
+// The next page must be on the same registry, so that the credentials are not sent elsewhere.
+func nextPage(endpoint *url.URL, link string) (*url.URL, error) {
+	matches := nextLinkRegexp.FindStringSubmatch(link)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	next, err := endpoint.Parse(matches[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid link to the next page of tags %s: %w", matches[1], err)
+	}
+	if next.Scheme != endpoint.Scheme || next.Host != endpoint.Host {
+		return nil, fmt.Errorf("invalid link to the next page of tags %s: must be on the registry %s", matches[1], endpoint.Host)
+	}
+	return next, nil
+}
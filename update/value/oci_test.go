@@ -0,0 +1,238 @@
+package value
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStubOCIRegistry returns a stub OCI registry serving the tags of 3 repositories:
+// - charts/my-chart requires a token from the /token endpoint - and serves its tags in 2 pages
+// - basic/my-chart requires basic auth
+// - public/my-chart doesn't require any authentication
+func newStubOCIRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "octopilot" || password != "secret-password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "stub-registry", r.URL.Query().Get("service"))
+			assert.Equal(t, "repository:charts/my-chart:pull", r.URL.Query().Get("scope"))
+			_, _ = w.Write([]byte(`{"token":"registry-token"}`))
+		case "/v2/charts/my-chart/tags/list":
+			if r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="stub-registry",scope="repository:charts/my-chart:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/charts/my-chart/tags/list?last=1.10.0&n=3>; rel="next"`)
+				_, _ = w.Write([]byte(`{"name":"charts/my-chart","tags":["1.2.0","1.9.3_build.5","1.10.0"]}`))
+				return
+			}
+			assert.Equal(t, "1.10.0", r.URL.Query().Get("last"))
+			_, _ = w.Write([]byte(`{"name":"charts/my-chart","tags":["2.0.0-rc.1","latest","sha256-0123456789abcdef.sig"]}`))
+		case "/v2/basic/my-chart/tags/list":
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "octopilot" || password != "secret-password" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="stub-registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"name":"basic/my-chart","tags":["0.1.0","0.2.0"]}`))
+		case "/v2/public/my-chart/tags/list":
+			_, _ = w.Write([]byte(`{"name":"public/my-chart","tags":["v3.0.0","3.1.0","3.2.0-beta.1"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOCIValuerValue(t *testing.T) {
+	t.Parallel()
+
+	server := newStubOCIRegistry(t)
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name             string
+		valuer           OCIValuer
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name: "latest version with token authentication - and pagination",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "charts/my-chart",
+				Username:   "octopilot",
+				Password:   "secret-password",
+				PlainHTTP:  true,
+			},
+			expected: "1.10.0",
+		},
+		{
+			name: "version matching a semver constraint - with build metadata",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "charts/my-chart",
+				Constraint: mustSemverConstraint(t, "~1.9"),
+				Username:   "octopilot",
+				Password:   "secret-password",
+				PlainHTTP:  true,
+			},
+			expected: "1.9.3+build.5",
+		},
+		{
+			name: "pre-release version matching a semver constraint",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "charts/my-chart",
+				Constraint: mustSemverConstraint(t, ">= 2.0.0-0"),
+				Username:   "octopilot",
+				Password:   "secret-password",
+				PlainHTTP:  true,
+			},
+			expected: "2.0.0-rc.1",
+		},
+		{
+			name: "latest version with basic authentication",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "basic/my-chart",
+				Username:   "octopilot",
+				Password:   "secret-password",
+				PlainHTTP:  true,
+			},
+			expected: "0.2.0",
+		},
+		{
+			name: "latest version of a public chart",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "public/my-chart",
+				PlainHTTP:  true,
+			},
+			expected: "3.1.0",
+		},
+		{
+			name: "no matching version",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "charts/my-chart",
+				Constraint: mustSemverConstraint(t, ">= 3"),
+				Username:   "octopilot",
+				Password:   "secret-password",
+				PlainHTTP:  true,
+			},
+			expectedErrorMsg: "no version of oci://" + registry + "/charts/my-chart matching the constraint in 6 tags",
+		},
+		{
+			name: "invalid credentials",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "charts/my-chart",
+				Username:   "octopilot",
+				Password:   "wrong-password",
+				PlainHTTP:  true,
+			},
+			expectedErrorMsg: "failed to list the tags of oci://" + registry + "/charts/my-chart: failed to get a token: unexpected response from " + server.URL + "/token: 401 Unauthorized",
+		},
+		{
+			name: "invalid token",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "charts/my-chart",
+				Token:      "secret-token",
+				PlainHTTP:  true,
+			},
+			expectedErrorMsg: "failed to list the tags of oci://" + registry + "/charts/my-chart: unexpected response from " + server.URL + "/v2/charts/my-chart/tags/list: 401 Unauthorized",
+		},
+		{
+			name: "basic authentication without credentials",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "basic/my-chart",
+				PlainHTTP:  true,
+			},
+			expectedErrorMsg: "failed to list the tags of oci://" + registry + "/basic/my-chart: the registry requires basic authentication: missing username parameter",
+		},
+		{
+			name: "unknown repository",
+			valuer: OCIValuer{
+				Registry:   registry,
+				Repository: "unknown/my-chart",
+				PlainHTTP:  true,
+			},
+			expectedErrorMsg: "failed to list the tags of oci://" + registry + "/unknown/my-chart: unexpected response from " + server.URL + "/v2/unknown/my-chart/tags/list: 404 Not Found",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := test.valuer.Value(context.Background(), "")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.NotContains(t, err.Error(), "secret-token")
+				assert.NotContains(t, err.Error(), "password")
+				assert.NotContains(t, err.Error(), "registry-token")
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOCIValuerCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	server := newStubOCIRegistry(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	valuer := OCIValuer{
+		Registry:   strings.TrimPrefix(server.URL, "http://"),
+		Repository: "charts/my-chart",
+		Username:   "octopilot",
+		Password:   "secret-password",
+		PlainHTTP:  true,
+	}
+	_, err := valuer.Value(ctx, "")
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "context canceled")
+	assert.NotContains(t, err.Error(), "secret-password")
+}
+
+func TestNextPage(t *testing.T) {
+	t.Parallel()
+
+	endpoint := &url.URL{Scheme: "https", Host: "registry.example.com", Path: "/v2/charts/my-chart/tags/list"}
+	next, err := nextPage(endpoint, `</v2/charts/my-chart/tags/list?last=1.0.0&n=100>; rel="next"`)
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry.example.com/v2/charts/my-chart/tags/list?last=1.0.0&n=100", next.String())
+
+	next, err = nextPage(endpoint, "")
+	require.NoError(t, err)
+	assert.Nil(t, next)
+
+	_, err = nextPage(endpoint, `<https://attacker.example.com/v2/charts/my-chart/tags/list?last=1.0.0>; rel="next"`)
+	assert.EqualError(t, err, "invalid link to the next page of tags https://attacker.example.com/v2/charts/my-chart/tags/list?last=1.0.0: must be on the registry registry.example.com")
+}
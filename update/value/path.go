@@ -0,0 +1,64 @@
+package value
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON decodes the given JSON content - keeping the numbers as json.Number, so that they are returned as written by valueAtPath.
+func decodeJSON(content []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid content after the top-level value")
+	}
+	return data, nil
+}
+
+// valueAtPath returns the value of the field at the given path - with a dot separator - in the given decoded JSON data.
+// Array elements are accessed by their index. Objects and arrays are returned as compact JSON.
+func valueAtPath(data interface{}, path string) (string, error) {
+	for _, key := range strings.Split(path, ".") {
+		switch elem := data.(type) {
+		case map[string]interface{}:
+			var found bool
+			if data, found = elem[key]; !found {
+				return "", fmt.Errorf("missing key %s", key)
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(elem) {
+				return "", fmt.Errorf("invalid index %s for an array of %d elements", key, len(elem))
+			}
+			data = elem[index]
+		default:
+			return "", fmt.Errorf("can't get key %s of a scalar value", key)
+		}
+	}
+
+	switch elem := data.(type) {
+	case string:
+		return elem, nil
+	case nil:
+		return "", nil
+	case json.Number:
+		return elem.String(), nil
+	case map[string]interface{}, []interface{}:
+		value, err := json.Marshal(elem)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode value: %w", err)
+		}
+		return string(value), nil
+	default:
+		return fmt.Sprint(elem), nil
+	}
+}
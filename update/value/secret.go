@@ -0,0 +1,11 @@
+package value
+
+// IsSecret returns true if the given valuer returns a secret - read from a secret store such as Google Secret Manager or Azure Key Vault.
+// Secrets must never be written outside of the updated files: in logs, plans, ...
+// A valuer returns a secret if it implements a "Sensitive() bool" method returning true - transforms return true if their child valuer does.
+func IsSecret(valuer Valuer) bool {
+	if secretValuer, ok := valuer.(interface{ Sensitive() bool }); ok {
+		return secretValuer.Sensitive()
+	}
+	return false
+}
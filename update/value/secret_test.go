@@ -0,0 +1,63 @@
+package value
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// secretStringValuer is a test valuer returning a fixed secret value
+type secretStringValuer string
+
+func (v secretStringValuer) Value(_ context.Context, _ string) (string, error) {
+	return string(v), nil
+}
+
+func (v secretStringValuer) Sensitive() bool {
+	return true
+}
+
+func TestIsSecret(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{
+			value:    "1.2.3",
+			expected: false,
+		},
+		{
+			value:    "file(path=VERSION)",
+			expected: false,
+		},
+		{
+			value:    "file(path=certs/keystore.jks,encoding=base64)",
+			expected: true,
+		},
+		{
+			value:    "gcpsecretmanager(project=my-project,secret=db-password)",
+			expected: true,
+		},
+		{
+			value:    "azurekeyvault(vault-url=https://my-vault.vault.azure.net,secret=db-password)",
+			expected: true,
+		},
+		{
+			value:    "gcpsecretmanager(project=my-project,secret=db-url) | envsubst()",
+			expected: true,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+			valuer, err := ParseValuer(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, IsSecret(valuer))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// stdin is the content of the standard input, read only once - so that multiple valuers can use it
+var stdin = newStdinReader(os.Stdin)
+
+// StdinValuer is a valuer that returns the content of the standard input - or a field extracted from its JSON content.
+type StdinValuer struct {
+	Path string
+}
+
+func newStdinValuer(params map[string]string) (*StdinValuer, error) {
+	valuer := &StdinValuer{
+		Path: strings.TrimPrefix(params["path"], "."),
+	}
+	return valuer, nil
+}
+
+// Value returns the value to replace while updating files in the given repository.
+func (v StdinValuer) Value(_ context.Context, _ string) (string, error) {
+	content, err := stdin.read()
+	if err != nil {
+		return "", err
+	}
+	if len(v.Path) == 0 {
+		return content, nil
+	}
+
+	data, err := decodeJSON([]byte(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse stdin as JSON: %w", err)
+	}
+	value, err := valueAtPath(data, v.Path)
+	if err != nil {
+		return "", fmt.Errorf("path %s not found in stdin: %w", v.Path, err)
+	}
+	return value, nil
+}
+
+// stdinReader reads the whole content of a file - the standard input - only once.
+type stdinReader struct {
+	file    *os.File
+	once    sync.Once
+	content string
+	err     error
+}
+
+func newStdinReader(file *os.File) *stdinReader {
+	return &stdinReader{file: file}
+}
+
+func (r *stdinReader) read() (string, error) {
+	r.once.Do(func() {
+		fileInfo, err := r.file.Stat()
+		if err != nil {
+			r.err = fmt.Errorf("failed to access stdin: %w", err)
+			return
+		}
+		if fileInfo.Mode()&os.ModeCharDevice != 0 {
+			r.err = errors.New("stdin is a terminal - pipe some content into octopilot to use the stdin valuer")
+			return
+		}
+		data, err := io.ReadAll(r.file)
+		if err != nil {
+			r.err = fmt.Errorf("failed to read stdin: %w", err)
+			return
+		}
+		if len(data) == 0 {
+			r.err = errors.New("stdin is empty - pipe some content into octopilot to use the stdin valuer")
+			return
+		}
+		r.content = string(data)
+	})
+	return r.content, r.err
+}
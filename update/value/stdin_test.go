@@ -0,0 +1,142 @@
+package value
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdinValuerValue(t *testing.T) {
+	tests := []struct {
+		name             string
+		stdin            string
+		valuer           StdinValuer
+		expected         string
+		expectedErrorMsg string
+	}{
+		{
+			name:     "raw content",
+			stdin:    "v1.2.3\n",
+			valuer:   StdinValuer{},
+			expected: "v1.2.3\n",
+		},
+		{
+			name:  "string field",
+			stdin: `{"image":{"tag":"v1.2.3","digest":"sha256:abc"}}`,
+			valuer: StdinValuer{
+				Path: "image.tag",
+			},
+			expected: "v1.2.3",
+		},
+		{
+			name:  "number field in an array",
+			stdin: `{"releases":[{"build":41},{"build":42}]}`,
+			valuer: StdinValuer{
+				Path: "releases.1.build",
+			},
+			expected: "42",
+		},
+		{
+			name:  "large number field",
+			stdin: `{"build":1234567,"size":12.5e3,"id":12345678901234567890}`,
+			valuer: StdinValuer{
+				Path: "build",
+			},
+			expected: "1234567",
+		},
+		{
+			name:  "object field with numbers",
+			stdin: `{"image":{"build":1234567,"id":12345678901234567890}}`,
+			valuer: StdinValuer{
+				Path: "image",
+			},
+			expected: `{"build":1234567,"id":12345678901234567890}`,
+		},
+		{
+			name:  "object field",
+			stdin: `{"image":{"tag":"v1.2.3"}}`,
+			valuer: StdinValuer{
+				Path: "image",
+			},
+			expected: `{"tag":"v1.2.3"}`,
+		},
+		{
+			name:  "missing field",
+			stdin: `{"image":{"tag":"v1.2.3"}}`,
+			valuer: StdinValuer{
+				Path: "image.digest",
+			},
+			expectedErrorMsg: "path image.digest not found in stdin: missing key digest",
+		},
+		{
+			name:  "not json",
+			stdin: "v1.2.3",
+			valuer: StdinValuer{
+				Path: "image.tag",
+			},
+			expectedErrorMsg: "failed to parse stdin as JSON: invalid character 'v' looking for beginning of value",
+		},
+		{
+			name:  "trailing content",
+			stdin: `{"version":"1.2.3"} {"version":"1.2.4"}`,
+			valuer: StdinValuer{
+				Path: "version",
+			},
+			expectedErrorMsg: "failed to parse stdin as JSON: invalid content after the top-level value",
+		},
+		{
+			name:             "empty stdin",
+			valuer:           StdinValuer{},
+			expectedErrorMsg: "stdin is empty - pipe some content into octopilot to use the stdin valuer",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			pipeStdin(t, test.stdin)
+
+			actual, err := test.valuer.Value(context.Background(), "")
+			if len(test.expectedErrorMsg) > 0 {
+				require.EqualError(t, err, test.expectedErrorMsg)
+				assert.Empty(t, actual)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestStdinValuerReadsOnce(t *testing.T) {
+	pipeStdin(t, `{"version":"1.2.3","build":42}`)
+
+	version, err := StdinValuer{Path: "version"}.Value(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", version)
+
+	build, err := StdinValuer{Path: "build"}.Value(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "42", build)
+}
+
+// pipeStdin replaces the stdin used by the valuers with a pipe containing the given content
+func pipeStdin(t *testing.T, content string) {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = writer.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	previousStdin := stdin
+	stdin = newStdinReader(reader)
+	t.Cleanup(func() {
+		stdin = previousStdin
+		reader.Close()
+	})
+}
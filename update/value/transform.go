@@ -0,0 +1,89 @@
+package value
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dailymotion-oss/octopilot/internal/parameters"
+)
+
+var (
+	// name(params) - the transform at the end of a value string, after its separator
+	transformRegexp = regexp.MustCompile(`^\s*(?P<name>[a-z]+)\((?P<params>.*)\)\s*$`)
+)
+
+// transformFactory creates a transform: a valuer which transforms - or validates - the value returned by its child valuer.
+type transformFactory func(child Valuer, params map[string]string) (Valuer, error)
+
+// transforms are all the supported transforms, indexed by their name
+var transforms = map[string]transformFactory{
+	"enum":       newEnumTransform,
+	"envsubst":   newEnvsubstTransform,
+	"jsonschema": newJSONSchemaTransform,
+}
+
+// parseTransform parses a value string ending with a transform, such as "file(path=VERSION) | enum(values=a;b)".
+// It returns false if the value string doesn't end with a transform - in which case it's not a transform.
+func parseTransform(valueStr string) (Valuer, bool, error) {
+	separator := lastTransformSeparator(valueStr)
+	if separator < 0 {
+		return nil, false, nil
+	}
+	matches := transformRegexp.FindStringSubmatch(valueStr[separator+1:])
+	if len(matches) < 3 {
+		return nil, false, nil
+	}
+	var (
+		childStr      = strings.TrimSpace(valueStr[:separator])
+		transformName = matches[1]
+		paramsStr     = matches[2]
+	)
+	factory, found := transforms[transformName]
+	if !found {
+		// a raw value - such as the "a|b(c)" regexp - may contain a "|" followed by a call:
+		// it's only an unknown transform with the documented " | " separator
+		if !strings.HasSuffix(valueStr[:separator], " ") || !strings.HasPrefix(valueStr[separator+1:], " ") {
+			return nil, false, nil
+		}
+		return nil, true, fmt.Errorf("unknown transform %s", transformName)
+	}
+
+	child, err := ParseValuer(childStr)
+	if err != nil {
+		return nil, true, err
+	}
+
+	transform, err := factory(child, parameters.Parse(paramsStr))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to create a transform instance for %s: %w", transformName, err)
+	}
+	return transform, true, nil
+}
+
+// lastTransformSeparator returns the index of the last "|" of the given value string which is outside of any parentheses - and of the quoted parameters -
+// so that a "|" in the parameters of a valuer or a transform doesn't separate a transform. It returns -1 if there is no such separator.
+func lastTransformSeparator(valueStr string) int {
+	var (
+		separator = -1
+		depth     int
+		quote     rune
+	)
+	for i, c := range valueStr {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case depth > 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == '|' && depth == 0:
+			separator = i
+		}
+	}
+	return separator
+}
@@ -10,7 +10,7 @@ import (
 
 var (
 	// name(params)
-	valueRegexp = regexp.MustCompile(`(?P<name>[a-z]+)\((?P<params>.+)\)`)
+	valueRegexp = regexp.MustCompile(`^(?P<name>[a-z]+)\((?P<params>.*)\)$`)
 )
 
 // Valuer is the interface for retrieving a value to replace while updating files.
@@ -20,7 +20,12 @@ type Valuer interface {
 }
 
 // ParseValuer parses the valuer defined as string - from the CLI for example - and returns a properly formatted valuer.
+// The value can end with a chain of transforms, such as "file(path=VERSION) | enum(values=a;b)".
 func ParseValuer(valueStr string) (Valuer, error) {
+	if transform, isTransform, err := parseTransform(valueStr); isTransform {
+		return transform, err
+	}
+
 	matches := valueRegexp.FindStringSubmatch(valueStr)
 	if len(matches) == 0 {
 		return StringValuer(valueStr), nil
@@ -31,6 +36,10 @@ func ParseValuer(valueStr string) (Valuer, error) {
 	}
 	valuerName := matches[1]
 	paramsStr := matches[2]
+	if len(paramsStr) == 0 && valuerName != "stdin" {
+		// only the stdin valuer can be used without params: anything else - such as "init()" - is a raw value
+		return StringValuer(valueStr), nil
+	}
 
 	params := parameters.Parse(paramsStr)
 
@@ -41,6 +50,24 @@ func ParseValuer(valueStr string) (Valuer, error) {
 	switch valuerName {
 	case "file":
 		valuer, err = newFileValuer(params)
+	case "checksum":
+		valuer, err = newChecksumValuer(params)
+	case "githubactions":
+		valuer, err = newGitHubActionsValuer(params)
+	case "stdin":
+		valuer, err = newStdinValuer(params)
+	case "artifact":
+		valuer, err = newArtifactValuer(params)
+	case "oci":
+		valuer, err = newOCIValuer(params)
+	case "kubernetes":
+		valuer, err = newKubernetesValuer(params)
+	case "conventionalcommits":
+		valuer, err = newConventionalCommitsValuer(params)
+	case "gcpsecretmanager":
+		valuer, err = newGCPSecretManagerValuer(params)
+	case "azurekeyvault":
+		valuer, err = newAzureKeyVaultValuer(params)
 	default:
 		return nil, fmt.Errorf("unknown valuer %s", valuerName)
 	}
@@ -3,6 +3,7 @@ package value
 import (
 	"testing"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -41,11 +42,335 @@ func TestParse(t *testing.T) {
 				Path: "/path/to/something",
 			},
 		},
+		{
+			name:  "base64-encoded file value",
+			value: "file(path=secret.bin,encoding=base64,wrap=76)",
+			expected: &FileValuer{
+				Path:     "secret.bin",
+				Encoding: "base64",
+				Wrap:     76,
+			},
+		},
+		{
+			name:             "file value with an invalid encoding",
+			value:            "file(path=secret.bin,encoding=hex)",
+			expectedErrorMsg: "failed to create a valuer instance for file: invalid encoding hex: must be base64",
+		},
 		{
 			name:             "file value without path",
 			value:            "file(path=)",
 			expectedErrorMsg: "failed to create a valuer instance for file: missing path parameter",
 		},
+		{
+			name:  "checksum value",
+			value: "checksum(files=k8s/configmap.yaml;config/*.properties)",
+			expected: &ChecksumValuer{
+				Files:     []string{"k8s/configmap.yaml", "config/*.properties"},
+				Algorithm: "sha256",
+			},
+		},
+		{
+			name:             "checksum value without files",
+			value:            "checksum(algorithm=sha512)",
+			expectedErrorMsg: "failed to create a valuer instance for checksum: missing files parameter",
+		},
+		{
+			name:             "checksum value with a file outside of the repository",
+			value:            "checksum(files=../secrets.yaml)",
+			expectedErrorMsg: "failed to create a valuer instance for checksum: invalid file ../secrets.yaml: must be relative to the root of the repository",
+		},
+		{
+			name:             "checksum value with an invalid algorithm",
+			value:            "checksum(files=configmap.yaml,algorithm=md5)",
+			expectedErrorMsg: "failed to create a valuer instance for checksum: invalid algorithm md5: must be one of sha256, sha512 or sha1",
+		},
+		{
+			name:  "github actions value",
+			value: "githubactions(field=run_id)",
+			expected: &GitHubActionsValuer{
+				Field: "run_id",
+			},
+		},
+		{
+			name:  "github actions value with default",
+			value: "githubactions(field=actor,default=octopilot)",
+			expected: &GitHubActionsValuer{
+				Field:      "actor",
+				Default:    "octopilot",
+				HasDefault: true,
+			},
+		},
+		{
+			name:             "github actions value with unknown field",
+			value:            "githubactions(field=runid)",
+			expectedErrorMsg: "failed to create a valuer instance for githubactions: unknown field runid - supported fields are: action, actor, actor_id, base_ref, event_name, head_ref, job, ref, ref_name, ref_type, repository, repository_owner, run_attempt, run_id, run_number, run_url, server_url, sha, triggering_actor, workflow, workflow_ref",
+		},
+		{
+			name:     "stdin value",
+			value:    "stdin()",
+			expected: &StdinValuer{},
+		},
+		{
+			name:     "raw value with an empty call",
+			value:    "call init() first",
+			expected: StringValuer("call init() first"),
+		},
+		{
+			name:     "raw value with an empty call of a valuer",
+			value:    "file()",
+			expected: StringValuer("file()"),
+		},
+		{
+			name:     "raw value containing a valuer",
+			value:    "see file(path=VERSION)",
+			expected: StringValuer("see file(path=VERSION)"),
+		},
+		{
+			name:     "raw value starting with a valuer",
+			value:    "file(path=VERSION) is the version",
+			expected: StringValuer("file(path=VERSION) is the version"),
+		},
+		{
+			name:     "raw value with an empty call of the checksum valuer",
+			value:    "checksum()",
+			expected: StringValuer("checksum()"),
+		},
+		{
+			name:     "raw value with an empty call of the kubernetes valuer",
+			value:    "kubernetes()",
+			expected: StringValuer("kubernetes()"),
+		},
+		{
+			name:     "raw value with an empty call of the gcpsecretmanager valuer",
+			value:    "gcpsecretmanager()",
+			expected: StringValuer("gcpsecretmanager()"),
+		},
+		{
+			name:     "raw value with an empty call of an unknown valuer",
+			value:    "whatever()",
+			expected: StringValuer("whatever()"),
+		},
+		{
+			name:             "unknown valuer with params",
+			value:            "whatever(key=value)",
+			expectedErrorMsg: "unknown valuer whatever",
+		},
+		{
+			name:  "stdin value with path",
+			value: "stdin(path=.image.tag)",
+			expected: &StdinValuer{
+				Path: "image.tag",
+			},
+		},
+		{
+			name:  "artifact value",
+			value: "artifact(url=https://repo.example.com/artifactory/,repository=libs-release,group=com.example,name=my-lib,token=secret)",
+			expected: &ArtifactValuer{
+				URL:        "https://repo.example.com/artifactory",
+				Type:       ArtifactoryRepositoryManager,
+				Repository: "libs-release",
+				Group:      "com.example",
+				Name:       "my-lib",
+				Token:      "secret",
+			},
+		},
+		{
+			name:             "artifact value without name",
+			value:            "artifact(url=https://repo.example.com/artifactory)",
+			expectedErrorMsg: "failed to create a valuer instance for artifact: missing name parameter",
+		},
+		{
+			name:             "nexus artifact value without repository",
+			value:            "artifact(url=https://nexus.example.com,type=nexus,name=my-lib)",
+			expectedErrorMsg: "failed to create a valuer instance for artifact: missing repository parameter",
+		},
+		{
+			name:  "oci value",
+			value: "oci(url=oci://registry.example.com:5000/charts/my-chart/,username=octopilot,password=secret,plain-http=true)",
+			expected: &OCIValuer{
+				Registry:   "registry.example.com:5000",
+				Repository: "charts/my-chart",
+				Username:   "octopilot",
+				Password:   "secret",
+				PlainHTTP:  true,
+			},
+		},
+		{
+			name:             "oci value without the oci scheme",
+			value:            "oci(url=https://ghcr.io/my-org/charts/my-chart)",
+			expectedErrorMsg: "failed to create a valuer instance for oci: invalid url https://ghcr.io/my-org/charts/my-chart: must start with oci://, such as oci://ghcr.io/my-org/charts/my-chart",
+		},
+		{
+			name:             "oci value without repository",
+			value:            "oci(url=oci://ghcr.io)",
+			expectedErrorMsg: "failed to create a valuer instance for oci: invalid url oci://ghcr.io: must contain a registry and a repository, such as oci://ghcr.io/my-org/charts/my-chart",
+		},
+		{
+			name:  "kubernetes value",
+			value: "kubernetes(kind=Deployment,namespace=production,name=my-app,label=app.kubernetes.io/version)",
+			expected: &KubernetesValuer{
+				Kind:       "Deployment",
+				APIVersion: "apps/v1",
+				Resource:   "deployments",
+				Namespaced: true,
+				Namespace:  "production",
+				Name:       "my-app",
+				Label:      "app.kubernetes.io/version",
+			},
+		},
+		{
+			name:             "kubernetes value with an unknown kind",
+			value:            "kubernetes(kind=Widget,name=my-widget,path=.status.phase)",
+			expectedErrorMsg: "failed to create a valuer instance for kubernetes: unknown kind Widget - use the resource and api-version parameters for custom resources",
+		},
+		{
+			name:             "kubernetes value with both label and annotation",
+			value:            "kubernetes(kind=configmap,name=my-config,label=a,annotation=b)",
+			expectedErrorMsg: "failed to create a valuer instance for kubernetes: exactly one of the label, annotation or path parameters is required",
+		},
+		{
+			name:  "gcp secret manager value",
+			value: "gcpsecretmanager(project=my-project,secret=db-password)",
+			expected: &GCPSecretManagerValuer{
+				Project: "my-project",
+				Secret:  "db-password",
+				Version: "latest",
+			},
+		},
+		{
+			name:             "gcp secret manager value with an invalid version",
+			value:            "gcpsecretmanager(project=my-project,secret=db-password,version=v1)",
+			expectedErrorMsg: "failed to create a valuer instance for gcpsecretmanager: invalid version v1: must be latest or a positive number",
+		},
+		{
+			name:  "azure key vault value",
+			value: "azurekeyvault(vault-url=https://my-vault.vault.azure.net/,secret=db-password,version=0123456789abcdef)",
+			expected: &AzureKeyVaultValuer{
+				VaultURL: "https://my-vault.vault.azure.net",
+				Secret:   "db-password",
+				Version:  "0123456789abcdef",
+			},
+		},
+		{
+			name:             "azure key vault value with an invalid vault url",
+			value:            "azurekeyvault(vault-url=my-vault,secret=db-password)",
+			expectedErrorMsg: "failed to create a valuer instance for azurekeyvault: invalid vault-url my-vault: must be an https URL, such as https://my-vault.vault.azure.net",
+		},
+		{
+			name:  "conventional commits value",
+			value: "conventionalcommits(base=0.1.0,prefix=v)",
+			expected: &ConventionalCommitsValuer{
+				Base:   semver.MustParse("0.1.0"),
+				Prefix: "v",
+			},
+		},
+		{
+			name:             "conventional commits value with an invalid base",
+			value:            "conventionalcommits(base=latest)",
+			expectedErrorMsg: "failed to create a valuer instance for conventionalcommits: invalid base latest: Invalid Semantic Version",
+		},
+		{
+			name:  "enum transform of a file value",
+			value: "file(path=ENVIRONMENT) | enum(values=dev;staging;prod,case-insensitive=true)",
+			expected: &EnumTransform{
+				Valuer: &FileValuer{
+					Path: "ENVIRONMENT",
+				},
+				Values:          []string{"dev", "staging", "prod"},
+				CaseInsensitive: true,
+			},
+		},
+		{
+			name:  "enum transform of a string value with custom separator",
+			value: "prod|enum(values=dev/prod,sep=/)",
+			expected: &EnumTransform{
+				Valuer: StringValuer("prod"),
+				Values: []string{"dev", "prod"},
+			},
+		},
+		{
+			name:             "unknown transform",
+			value:            "prod | whatever(values=prod)",
+			expectedErrorMsg: "unknown transform whatever",
+		},
+		{
+			name:     "raw value with a literal pipe followed by a call",
+			value:    "a|b(c)",
+			expected: StringValuer("a|b(c)"),
+		},
+		{
+			name:  "enum transform with a literal pipe in its values",
+			value: "file(path=PATTERN) | enum(values=a|b(c);d)",
+			expected: &EnumTransform{
+				Valuer: &FileValuer{
+					Path: "PATTERN",
+				},
+				Values: []string{"a|b(c)", "d"},
+			},
+		},
+		{
+			name:  "enum transform of a raw value with a literal pipe",
+			value: "a|b(c) | enum(values=a|b(c))",
+			expected: &EnumTransform{
+				Valuer: StringValuer("a|b(c)"),
+				Values: []string{"a|b(c)"},
+			},
+		},
+		{
+			name:  "enum transform of a valuer with a quoted pipe in its params",
+			value: "stdin(path='a | b(c)') | enum(values=x)",
+			expected: &EnumTransform{
+				Valuer: &StdinValuer{
+					Path: "a | b(c)",
+				},
+				Values: []string{"x"},
+			},
+		},
+		{
+			name:  "envsubst transform of a string value",
+			value: "${REGION}-bucket | envsubst(strict=true)",
+			expected: &EnvsubstTransform{
+				Valuer: StringValuer("${REGION}-bucket"),
+				Strict: true,
+			},
+		},
+		{
+			name:  "envsubst transform chained with an enum transform",
+			value: "file(path=BUCKET) | envsubst() | enum(values=eu-bucket;us-bucket)",
+			expected: &EnumTransform{
+				Valuer: &EnvsubstTransform{
+					Valuer: &FileValuer{
+						Path: "BUCKET",
+					},
+				},
+				Values: []string{"eu-bucket", "us-bucket"},
+			},
+		},
+		{
+			name:             "envsubst transform with invalid strict parameter",
+			value:            "$REGION | envsubst(strict=maybe)",
+			expectedErrorMsg: `failed to create a transform instance for envsubst: invalid strict parameter maybe: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+		{
+			name:  "jsonschema transform of a file value",
+			value: "file(path=config.json) | jsonschema(schema=schemas/config.json)",
+			expected: &JSONSchemaTransform{
+				Valuer: &FileValuer{
+					Path: "config.json",
+				},
+				SchemaPath: "schemas/config.json",
+			},
+		},
+		{
+			name:             "jsonschema transform without schema",
+			value:            "file(path=config.json) | jsonschema()",
+			expectedErrorMsg: "failed to create a transform instance for jsonschema: missing schema parameter",
+		},
+		{
+			name:             "enum transform without values",
+			value:            "prod | enum(sep=;)",
+			expectedErrorMsg: "failed to create a transform instance for enum: missing values parameter",
+		},
 	}
 
 	for i := range tests {
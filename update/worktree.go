@@ -0,0 +1,183 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// worktreeSnapshot is the state of the worktree of a git repository before a wrapped updater runs - so that the files it changed can be found.
+// Only the files already changed in the worktree - compared to the HEAD commit - are kept, the other ones still have their content of the HEAD commit.
+type worktreeSnapshot struct {
+	gitRepo *git.Repository
+	// the content of the files already changed, indexed by their relative path - nil if the file doesn't exist
+	files map[string]*fileContent
+}
+
+// fileContent is the content of a file, with its mode
+type fileContent struct {
+	data []byte
+	mode os.FileMode
+}
+
+// snapshotWorktree returns the snapshot of the worktree of the git repository at the given path
+func snapshotWorktree(repoPath string) (*worktreeSnapshot, error) {
+	gitRepo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository %s: %w", repoPath, err)
+	}
+	snapshot := &worktreeSnapshot{
+		gitRepo: gitRepo,
+		files:   make(map[string]*fileContent),
+	}
+	files, err := snapshot.statusFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if snapshot.files[file], err = readFileContent(repoPath, file); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// changedFiles returns the sorted list of files that have been created, modified or deleted since the snapshot
+func (s *worktreeSnapshot) changedFiles(repoPath string) ([]string, error) {
+	files, err := s.statusFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool, len(files))
+	for _, file := range files {
+		if _, found := s.files[file]; !found {
+			// the file had the content of the HEAD commit
+			changed[file] = true
+		}
+	}
+	// the files already changed may have been changed again - or reverted to their content of the HEAD commit
+	for file, original := range s.files {
+		content, err := readFileContent(repoPath, file)
+		if err != nil {
+			return nil, err
+		}
+		if !sameContent(original, content) {
+			changed[file] = true
+		}
+	}
+
+	changedFiles := make([]string, 0, len(changed))
+	for file := range changed {
+		changedFiles = append(changedFiles, file)
+	}
+	sort.Strings(changedFiles)
+	return changedFiles, nil
+}
+
+// original returns the content of the given file at the time of the snapshot - or nil if it didn't exist
+func (s *worktreeSnapshot) original(file string) (*fileContent, error) {
+	if content, found := s.files[file]; found {
+		return content, nil
+	}
+
+	head, err := s.gitRepo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	commit, err := s.gitRepo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the HEAD commit: %w", err)
+	}
+	headFile, err := commit.File(filepath.ToSlash(file))
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file %s from the HEAD commit: %w", file, err)
+	}
+	mode, err := headFile.Mode.ToOSFileMode()
+	if err != nil {
+		return nil, fmt.Errorf("invalid mode of file %s in the HEAD commit: %w", file, err)
+	}
+	reader, err := headFile.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s from the HEAD commit: %w", file, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s from the HEAD commit: %w", file, err)
+	}
+	return &fileContent{data: data, mode: mode}, nil
+}
+
+// statusFiles returns the relative paths of the files changed in the worktree - compared to the HEAD commit - including the untracked files
+func (s *worktreeSnapshot) statusFiles() ([]string, error) {
+	workTree, err := s.gitRepo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	status, err := workTree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the worktree status: %w", err)
+	}
+	var files []string
+	for filePath, fileStatus := range status {
+		if fileStatus.Worktree == git.Unmodified && fileStatus.Staging == git.Unmodified {
+			continue
+		}
+		files = append(files, filepath.FromSlash(filePath))
+	}
+	return files, nil
+}
+
+// readFileContent returns the content of the given file of the repository - or nil if it doesn't exist
+func readFileContent(repoPath, file string) (*fileContent, error) {
+	filePath := filepath.Join(repoPath, file)
+	info, err := os.Lstat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", file, err)
+	}
+	var data []byte
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read link %s: %w", file, err)
+		}
+		data = []byte(target)
+	} else if data, err = os.ReadFile(filePath); err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+	}
+	return &fileContent{data: data, mode: info.Mode()}, nil
+}
+
+// sameContent returns true if both contents - which may be nil, for files which don't exist - are the same
+func sameContent(a, b *fileContent) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return string(a.data) == string(b.data)
+}
+
+// isRegularFile returns true if the given file of the repository exists, and is a regular file - and not a link
+func isRegularFile(repoPath, file string) (bool, error) {
+	info, err := os.Lstat(filepath.Join(repoPath, file))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file %s: %w", file, err)
+	}
+	return info.Mode().IsRegular(), nil
+}
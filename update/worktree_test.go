@@ -0,0 +1,81 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktreeSnapshot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		changesBefore    map[string]string
+		changesAfter     map[string]string
+		deletedAfter     []string
+		expectedFiles    []string
+		expectedOriginal map[string]string
+	}{
+		{
+			name:          "no changes",
+			expectedFiles: []string{},
+		},
+		{
+			name:             "changed, created and deleted files",
+			changesAfter:     map[string]string{"a.txt": "a2\n", "new.txt": "new\n"},
+			deletedAfter:     []string{"b.txt"},
+			expectedFiles:    []string{"a.txt", "b.txt", "new.txt"},
+			expectedOriginal: map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+		},
+		{
+			name:             "files already changed before the snapshot",
+			changesBefore:    map[string]string{"a.txt": "a1\n", "b.txt": "b1\n", "c.txt": "c1\n"},
+			changesAfter:     map[string]string{"a.txt": "a2\n", "b.txt": "b\n", "c.txt": "c1\n"},
+			expectedFiles:    []string{"a.txt", "b.txt"},
+			expectedOriginal: map[string]string{"a.txt": "a1\n", "b.txt": "b1\n"},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoPath := t.TempDir()
+			for file, content := range map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"} {
+				require.NoError(t, os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644))
+			}
+			commitRepository(t, repoPath)
+			for file, content := range test.changesBefore {
+				require.NoError(t, os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644))
+			}
+
+			snapshot, err := snapshotWorktree(repoPath)
+			require.NoError(t, err)
+			for file, content := range test.changesAfter {
+				require.NoError(t, os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644))
+			}
+			for _, file := range test.deletedAfter {
+				require.NoError(t, os.Remove(filepath.Join(repoPath, file)))
+			}
+
+			files, err := snapshot.changedFiles(repoPath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedFiles, files)
+			for _, file := range files {
+				original, err := snapshot.original(file)
+				require.NoError(t, err)
+				expectedContent, expected := test.expectedOriginal[file]
+				if !expected {
+					assert.Nil(t, original, file)
+					continue
+				}
+				require.NotNil(t, original, file)
+				assert.Equal(t, expectedContent, string(original.data), file)
+			}
+		})
+	}
+}
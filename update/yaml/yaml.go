@@ -6,17 +6,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	"github.com/dailymotion-oss/octopilot/internal/missingkey"
+	"github.com/dailymotion-oss/octopilot/internal/monotonic"
+	"github.com/dailymotion-oss/octopilot/internal/semantic"
 	"github.com/dailymotion-oss/octopilot/internal/yaml"
 	"github.com/dailymotion-oss/octopilot/update/value"
 
 	"github.com/mikefarah/yq/v4/pkg/yqlib"
+	"github.com/sirupsen/logrus"
 	gologging "gopkg.in/op/go-logging.v1"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 func init() {
@@ -32,9 +40,20 @@ type YamlUpdater struct {
 	Style      string
 	Trim       bool
 	Indent     int
-	Valuer     value.Valuer
+	EOL        eol.Mode
+	Monotonic  monotonic.Mode
+	Strict     bool
+	// MissingKey is the strategy used for the files which don't contain the path
+	MissingKey missingkey.Strategy
+	// SemanticDiff compares the data of the original and updated files - instead of their bytes - so that formatting-only changes are ignored
+	SemanticDiff bool
+	Image        string
+	Valuer       value.Valuer
 }
 
+// defaultPodSpecPath is the path to the pod spec of a Deployment - used when updating container images
+const defaultPodSpecPath = ".spec.template.spec"
+
 // NewUpdater builds a new YAML updater from the given parameters and valuer
 func NewUpdater(params map[string]string, valuer value.Valuer) (*YamlUpdater, error) {
 	updater := &YamlUpdater{}
@@ -44,9 +63,13 @@ func NewUpdater(params map[string]string, valuer value.Valuer) (*YamlUpdater, er
 		return nil, errors.New("missing file parameter")
 	}
 
+	updater.Image = params["image"]
 	updater.Path = params["path"]
 	if len(updater.Path) == 0 {
-		return nil, errors.New("missing path parameter")
+		if len(updater.Image) == 0 {
+			return nil, errors.New("missing path parameter")
+		}
+		updater.Path = defaultPodSpecPath
 	}
 
 	updater.Indent, _ = strconv.Atoi(params["indent"])
@@ -57,6 +80,39 @@ func NewUpdater(params map[string]string, valuer value.Valuer) (*YamlUpdater, er
 	updater.AutoCreate, _ = strconv.ParseBool(params["create"])
 	updater.Trim, _ = strconv.ParseBool(params["trim"])
 	updater.Style = params["style"]
+	updater.SemanticDiff, _ = strconv.ParseBool(params["semantic-diff"])
+
+	var err error
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
+	}
+
+	updater.Monotonic, err = monotonic.ParseMode(params["monotonic"])
+	if err != nil {
+		return nil, err
+	}
+	updater.Strict, _ = strconv.ParseBool(params["strict"])
+	if len(updater.Image) > 0 && updater.Monotonic != monotonic.None {
+		return nil, errors.New("the monotonic parameter can't be used with the image parameter")
+	}
+
+	updater.MissingKey, err = missingkey.ParseStrategy(params["missing-key-strategy"])
+	if err != nil {
+		return nil, err
+	}
+	switch updater.MissingKey {
+	case missingkey.Default:
+	case missingkey.Create:
+		updater.AutoCreate = true
+	default:
+		if updater.AutoCreate {
+			return nil, fmt.Errorf("the create parameter can't be used with the %s missing-key-strategy", updater.MissingKey)
+		}
+	}
+	if len(updater.Image) > 0 && updater.MissingKey != missingkey.Default {
+		return nil, errors.New("the missing-key-strategy parameter can't be used with the image parameter")
+	}
 
 	updater.Valuer = valuer
 
@@ -70,9 +126,16 @@ func (u *YamlUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 		return false, fmt.Errorf("failed to get value: %w", err)
 	}
 
-	expression, expressionNode, err := u.yqExpression(value)
-	if err != nil {
-		return false, fmt.Errorf("failed to parse yq expression %s: %w", expression, err)
+	var (
+		expression     string
+		expressionNode *yqlib.ExpressionNode
+	)
+	if len(u.Image) == 0 {
+		// with the image mode, the expression depends on the current images, so it is built for each file
+		expression, expressionNode, err = u.yqExpression(value)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse yq expression %s: %w", expression, err)
+		}
 	}
 
 	filePaths, err := filepath.Glob(filepath.Join(repoPath, u.FilePath))
@@ -106,7 +169,46 @@ func (u *YamlUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 			return false, fmt.Errorf("failed to read file %s: %w", relFilePath, err)
 		}
 
-		reader, leadingContent, err := yaml.ExtractLeadingContentForYQ(bytes.NewReader(fileData))
+		if len(u.Image) > 0 {
+			var changedContainers int
+			expression, expressionNode, changedContainers, err = u.imageExpression(fileData, value)
+			if err != nil {
+				return false, fmt.Errorf("failed to build the image expression for file %s: %w", relFilePath, err)
+			}
+			if changedContainers == 0 {
+				continue
+			}
+			logrus.WithFields(logrus.Fields{
+				"file":       relFilePath,
+				"image":      u.Image,
+				"containers": changedContainers,
+			}).Debug("Updating container images")
+		}
+
+		// without auto-create, the yq expression doesn't create the missing path - so the skip strategy is the default behavior
+		if u.MissingKey == missingkey.Error {
+			exists, err := u.pathExists(fileData)
+			if err != nil {
+				return false, fmt.Errorf("failed to lookup path %s in file %s: %w", u.Path, relFilePath, err)
+			}
+			if !exists {
+				return false, fmt.Errorf("path %s not found in file %s (missing-key-strategy=%s)", u.Path, relFilePath, u.MissingKey)
+			}
+		}
+
+		if u.Monotonic != monotonic.None {
+			increases, err := u.valueIncreases(fileData, value)
+			if err != nil {
+				return false, fmt.Errorf("failed to compare the current value of path %s in file %s: %w", u.Path, relFilePath, err)
+			}
+			if !increases {
+				continue
+			}
+		}
+
+		// the yq lib doesn't handle CRLF line endings well, so we normalize them first
+		// they will be restored - if needed - once the file has been updated
+		reader, leadingContent, err := yaml.ExtractLeadingContentForYQ(bytes.NewReader(eol.Convert(fileData, eol.LF)))
 		if err != nil {
 			return false, fmt.Errorf("failed to extract leading content from file %s: %w", relFilePath, err)
 		}
@@ -121,12 +223,22 @@ func (u *YamlUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 		if u.Trim {
 			buffer = bytes.NewBuffer(bytes.TrimSpace(buffer.Bytes()))
 		}
+		updatedData := eol.Apply(u.EOL, fileData, buffer.Bytes())
 
-		if reflect.DeepEqual(fileData, buffer.Bytes()) {
+		if reflect.DeepEqual(fileData, updatedData) {
 			continue
 		}
+		if u.SemanticDiff {
+			equal, err := semantic.Equal(fileData, updatedData)
+			if err != nil {
+				return false, fmt.Errorf("failed to compare the data of file %s: %w", relFilePath, err)
+			}
+			if equal {
+				continue
+			}
+		}
 
-		err = os.WriteFile(filePath, buffer.Bytes(), fileInfo.Mode())
+		err = os.WriteFile(filePath, updatedData, fileInfo.Mode())
 		if err != nil {
 			return false, fmt.Errorf("failed to write file %s: %w", filePath, err)
 		}
@@ -140,28 +252,209 @@ func (u *YamlUpdater) Update(ctx context.Context, repoPath string) (bool, error)
 // Message returns the default title and body that should be used in the commits / pull requests
 func (u *YamlUpdater) Message() (title, body string) {
 	title = fmt.Sprintf("Update %s", u.FilePath)
+	if len(u.Image) > 0 {
+		body = fmt.Sprintf("Updating image `%s` of the containers at path `%s` in file(s) `%s`", u.Image, u.Path, u.FilePath)
+		return title, body
+	}
 	body = fmt.Sprintf("Updating path `%s` in file(s) `%s`", u.Path, u.FilePath)
 	return title, body
 }
 
 // String returns a string representation of the updater
 func (u *YamlUpdater) String() string {
+	if len(u.Image) > 0 {
+		return fmt.Sprintf("YAML[image=%s,path=%s,file=%s,trim=%v,indent=%v]", u.Image, u.Path, u.FilePath, u.Trim, u.Indent)
+	}
 	return fmt.Sprintf("YAML[path=%s,file=%s,style=%s,create=%v,trim=%v,indent=%v]", u.Path, u.FilePath, u.Style, u.AutoCreate, u.Trim, u.Indent)
 }
 
-func (u *YamlUpdater) yqExpression(value string) (string, *yqlib.ExpressionNode, error) {
+// valueIncreases returns true if the new value is greater than all the current values of the path in the given file content - using the monotonic mode.
+// If the path doesn't exist yet, there is nothing to compare, so the value can be written.
+// If the value doesn't increase and the strict mode is enabled, an error is returned.
+func (u *YamlUpdater) valueIncreases(fileData []byte, value string) (bool, error) {
+	docs, err := decodeDocuments(fileData)
+	if err != nil {
+		return false, err
+	}
+
+	results, err := yqlib.NewAllAtOnceEvaluator().EvaluateNodes(u.rawExpression(), docs...)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate path: %w", err)
+	}
+
+	for elem := results.Front(); elem != nil; elem = elem.Next() {
+		node := elem.Value.(*yqlib.CandidateNode).Node
+		if node.Kind != yamlv3.ScalarNode || node.Tag == "!!null" {
+			continue
+		}
+		increases, err := monotonic.IsGreater(u.Monotonic, node.Value, value)
+		if err != nil {
+			return false, err
+		}
+		if !increases {
+			if u.Strict {
+				return false, fmt.Errorf("new value %q is not greater than current value %q (monotonic=%s)", strings.TrimSpace(value), node.Value, u.Monotonic)
+			}
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// pathExists returns true if the path exists in all the documents of the given file content.
+// The yq lib creates the missing map entries while traversing a path, so a path is missing if evaluating it changes the documents - or returns nothing.
+func (u *YamlUpdater) pathExists(fileData []byte) (bool, error) {
+	docs, err := decodeDocuments(fileData)
+	if err != nil {
+		return false, err
+	}
+	before, err := encodeDocuments(docs)
+	if err != nil {
+		return false, err
+	}
+
+	results, err := yqlib.NewAllAtOnceEvaluator().EvaluateNodes(u.rawExpression(), docs...)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate path: %w", err)
+	}
+	if results.Len() == 0 {
+		return false, nil
+	}
+
+	after, err := encodeDocuments(docs)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(before, after), nil
+}
+
+// imageExpression returns the yq expression to set the tag of the images matching the image repository prefix,
+// for all the containers and init containers of the pod spec(s) in the given file content - and the number of containers changed.
+// Containers are matched by their current image, so that the other containers are left untouched.
+func (u *YamlUpdater) imageExpression(fileData []byte, tag string) (string, *yqlib.ExpressionNode, int, error) {
+	docs, err := decodeDocuments(fileData)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	results, err := yqlib.NewAllAtOnceEvaluator().EvaluateNodes(u.containersExpression()+" | .image", docs...)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("failed to evaluate path: %w", err)
+	}
+
 	var (
-		parser        = yqlib.ExpressionParser
-		rawExpression string
+		newImages         = map[string]string{}
+		changedContainers int
+	)
+	for elem := results.Front(); elem != nil; elem = elem.Next() {
+		node := elem.Value.(*yqlib.CandidateNode).Node
+		if node.Kind != yamlv3.ScalarNode {
+			continue
+		}
+		newImage, matches := replaceImageTag(node.Value, u.Image, strings.TrimSpace(tag))
+		if !matches || newImage == node.Value {
+			continue
+		}
+		newImages[node.Value] = newImage
+		changedContainers++
+	}
+	if changedContainers == 0 {
+		return "", nil, 0, nil
+	}
+
+	currentImages := make([]string, 0, len(newImages))
+	for currentImage := range newImages {
+		currentImages = append(currentImages, currentImage)
+	}
+	sort.Strings(currentImages)
+
+	expressions := make([]string, 0, len(currentImages))
+	for _, currentImage := range currentImages {
+		expressions = append(expressions, fmt.Sprintf(`(%s | select(.image == %q) | .image) ref $x | $x = %q`, u.containersExpression(), currentImage, newImages[currentImage]))
+	}
+	expression := strings.Join(expressions, " | ")
+
+	expressionNode, err := yqlib.ExpressionParser.ParseExpression(expression)
+	if err != nil {
+		return expression, nil, 0, fmt.Errorf("failed to parse yq expression %s: %w", expression, err)
+	}
+	return expression, expressionNode, changedContainers, nil
+}
+
+func (u *YamlUpdater) containersExpression() string {
+	return fmt.Sprintf("(%s) | (.containers[], .initContainers[])", u.rawExpression())
+}
+
+// replaceImageTag returns the given image with its tag - or digest - replaced by the given one, if its repository is the given prefix,
+// or is under it - such as "ghcr.io/my-org/my-app" for the "ghcr.io/my-org" prefix, but not "ghcr.io/my-org/my-app-sidecar" for the "ghcr.io/my-org/my-app" prefix.
+// A tag starting with "sha256:" is used as a digest.
+func replaceImageTag(image, repositoryPrefix, tag string) (string, bool) {
+	repository := image
+	if index := strings.Index(repository, "@"); index >= 0 {
+		repository = repository[:index]
+	}
+	if index := strings.LastIndex(repository, ":"); index > strings.LastIndex(repository, "/") {
+		repository = repository[:index]
+	}
+	repositoryPrefix = strings.TrimSuffix(repositoryPrefix, "/")
+	if repository != repositoryPrefix && !strings.HasPrefix(repository, repositoryPrefix+"/") {
+		return "", false
+	}
+	if strings.HasPrefix(tag, "sha256:") {
+		return repository + "@" + tag, true
+	}
+	return repository + ":" + tag, true
+}
+
+// decodeDocuments decodes all the YAML documents of the given file content
+func decodeDocuments(fileData []byte) ([]*yamlv3.Node, error) {
+	var (
+		decoder = yamlv3.NewDecoder(bytes.NewReader(eol.Convert(fileData, eol.LF)))
+		docs    []*yamlv3.Node
 	)
+	for {
+		var doc yamlv3.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode YAML: %w", err)
+		}
+		docs = append(docs, &doc)
+	}
+	return docs, nil
+}
+
+// encodeDocuments encodes the given YAML documents - to compare them
+func encodeDocuments(docs []*yamlv3.Node) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buffer)
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
 
-	if _, err := parser.ParseExpression(u.Path); err == nil && strings.HasPrefix(u.Path, ".") {
-		// we have a valid yq v4 expression - that starts with a dot
-		rawExpression = u.Path
-	} else {
-		// most likely an old v3 path format, let's convert it to a valid v4 path
-		rawExpression = convertYqExpressionToV4(u.Path)
+func (u *YamlUpdater) rawExpression() string {
+	if _, err := yqlib.ExpressionParser.ParseExpression(u.Path); err == nil && (strings.HasPrefix(u.Path, ".") || strings.HasPrefix(u.Path, "(")) {
+		// we have a valid yq v4 expression - that starts with a dot, or a parenthesized expression such as "(select(...) | .path)"
+		return u.Path
 	}
+	// most likely an old v3 path format, let's convert it to a valid v4 path
+	return convertYqExpressionToV4(u.Path)
+}
+
+func (u *YamlUpdater) yqExpression(value string) (string, *yqlib.ExpressionNode, error) {
+	var (
+		parser        = yqlib.ExpressionParser
+		rawExpression = u.rawExpression()
+	)
 
 	// add the assignment operator to set the new value
 	expression := fmt.Sprintf(`(%s) ref $x | $x = %q`, rawExpression, value)
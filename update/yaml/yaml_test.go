@@ -6,6 +6,9 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	"github.com/dailymotion-oss/octopilot/internal/missingkey"
+	"github.com/dailymotion-oss/octopilot/internal/monotonic"
 	"github.com/dailymotion-oss/octopilot/update/value"
 
 	"github.com/stretchr/testify/assert"
@@ -39,6 +42,54 @@ func TestNewUpdater(t *testing.T) {
 				Indent:     4,
 			},
 		},
+		{
+			name: "valid params with eol",
+			params: map[string]string{
+				"file": "values.yaml",
+				"path": "level1.level2",
+				"eol":  "crlf",
+			},
+			expected: &YamlUpdater{
+				FilePath: "values.yaml",
+				Path:     "level1.level2",
+				Indent:   2,
+				EOL:      eol.CRLF,
+			},
+		},
+		{
+			name: "valid params with monotonic guard",
+			params: map[string]string{
+				"file":      "values.yaml",
+				"path":      "version",
+				"monotonic": "semver",
+				"strict":    "true",
+			},
+			expected: &YamlUpdater{
+				FilePath:  "values.yaml",
+				Path:      "version",
+				Indent:    2,
+				Monotonic: monotonic.SemVer,
+				Strict:    true,
+			},
+		},
+		{
+			name: "invalid monotonic mode",
+			params: map[string]string{
+				"file":      "values.yaml",
+				"path":      "version",
+				"monotonic": "date",
+			},
+			expectedErrorMsg: "invalid monotonic mode date: must be one of semver, numeric or lexical",
+		},
+		{
+			name: "invalid eol",
+			params: map[string]string{
+				"file": "values.yaml",
+				"path": "level1.level2",
+				"eol":  "cr",
+			},
+			expectedErrorMsg: "invalid eol mode cr: must be one of preserve, lf or crlf",
+		},
 		{
 			name: "invalid create boolean value",
 			params: map[string]string{
@@ -55,6 +106,40 @@ func TestNewUpdater(t *testing.T) {
 				Indent:     2,
 			},
 		},
+		{
+			name: "create missing-key-strategy",
+			params: map[string]string{
+				"file":                 "values.yaml",
+				"path":                 "level1.level2",
+				"missing-key-strategy": "create",
+			},
+			expected: &YamlUpdater{
+				FilePath:   "values.yaml",
+				Path:       "level1.level2",
+				AutoCreate: true,
+				MissingKey: missingkey.Create,
+				Indent:     2,
+			},
+		},
+		{
+			name: "create with the error missing-key-strategy",
+			params: map[string]string{
+				"file":                 "values.yaml",
+				"path":                 "level1.level2",
+				"create":               "true",
+				"missing-key-strategy": "error",
+			},
+			expectedErrorMsg: "the create parameter can't be used with the error missing-key-strategy",
+		},
+		{
+			name: "invalid missing-key-strategy",
+			params: map[string]string{
+				"file":                 "values.yaml",
+				"path":                 "level1.level2",
+				"missing-key-strategy": "ignore",
+			},
+			expectedErrorMsg: "invalid missing-key-strategy ignore: must be one of skip, create or error",
+		},
 		{
 			name: "valid params with multiple files using a glob pattern",
 			params: map[string]string{
@@ -67,6 +152,28 @@ func TestNewUpdater(t *testing.T) {
 				Indent:   2,
 			},
 		},
+		{
+			name: "valid params with image",
+			params: map[string]string{
+				"file":  "deployment.yaml",
+				"image": "ghcr.io/dailymotion-oss/octopilot",
+			},
+			expected: &YamlUpdater{
+				FilePath: "deployment.yaml",
+				Path:     ".spec.template.spec",
+				Indent:   2,
+				Image:    "ghcr.io/dailymotion-oss/octopilot",
+			},
+		},
+		{
+			name: "image with monotonic guard",
+			params: map[string]string{
+				"file":      "deployment.yaml",
+				"image":     "ghcr.io/dailymotion-oss/octopilot",
+				"monotonic": "semver",
+			},
+			expectedErrorMsg: "the monotonic parameter can't be used with the image parameter",
+		},
 		{
 			name:             "nil params",
 			expectedErrorMsg: "missing file parameter",
@@ -318,6 +425,308 @@ key: value
 			expectedFiles: map[string]string{
 				"no-changes-without-auto-create.yaml": `# a simple key
 key: value
+`,
+			},
+		},
+		{
+			name: "skip the files without the key with the skip missing-key-strategy",
+			files: map[string]string{
+				"missing-key-strategy/skip/with-key.yaml":    "app:\n  version: 1.0.0\n",
+				"missing-key-strategy/skip/without-key.yaml": "other: value\n",
+			},
+			updater: &YamlUpdater{
+				FilePath:   "missing-key-strategy/skip/*.yaml",
+				Path:       "app.version",
+				MissingKey: missingkey.Skip,
+				Valuer:     value.StringValuer("1.1.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"missing-key-strategy/skip/with-key.yaml":    "app:\n    version: 1.1.0\n",
+				"missing-key-strategy/skip/without-key.yaml": "other: value\n",
+			},
+		},
+		{
+			name: "add the key to all the files with the create missing-key-strategy",
+			files: map[string]string{
+				"missing-key-strategy/create/with-key.yaml":    "app:\n  version: 1.0.0\n",
+				"missing-key-strategy/create/without-key.yaml": "other: value\n",
+			},
+			updater: &YamlUpdater{
+				FilePath:   "missing-key-strategy/create/*.yaml",
+				Path:       "app.version",
+				AutoCreate: true,
+				MissingKey: missingkey.Create,
+				Valuer:     value.StringValuer("1.1.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"missing-key-strategy/create/with-key.yaml":    "app:\n    version: 1.1.0\n",
+				"missing-key-strategy/create/without-key.yaml": "other: value\napp:\n    version: 1.1.0\n",
+			},
+		},
+		{
+			name: "fail if a file doesn't have the key with the error missing-key-strategy",
+			files: map[string]string{
+				"missing-key-strategy/error/with-key.yaml":    "app:\n  version: 1.0.0\n",
+				"missing-key-strategy/error/without-key.yaml": "other: value\n",
+			},
+			updater: &YamlUpdater{
+				FilePath:   "missing-key-strategy/error/*.yaml",
+				Path:       "app.version",
+				MissingKey: missingkey.Error,
+				Valuer:     value.StringValuer("1.1.0"),
+			},
+			expectedErrorMsg: "path app.version not found in file missing-key-strategy/error/without-key.yaml (missing-key-strategy=error)",
+		},
+		{
+			name: "update all the files with the key with the error missing-key-strategy",
+			files: map[string]string{
+				"missing-key-strategy/error-ok/first.yaml":  "app:\n  version: 1.0.0\n",
+				"missing-key-strategy/error-ok/second.yaml": "app:\n  version: ~\n",
+			},
+			updater: &YamlUpdater{
+				FilePath:   "missing-key-strategy/error-ok/*.yaml",
+				Path:       ".app.version",
+				MissingKey: missingkey.Error,
+				Valuer:     value.StringValuer("1.1.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"missing-key-strategy/error-ok/first.yaml":  "app:\n    version: 1.1.0\n",
+				"missing-key-strategy/error-ok/second.yaml": "app:\n    version: 1.1.0\n",
+			},
+		},
+		{
+			name: "preserve crlf line endings",
+			files: map[string]string{
+				"crlf.yaml": "# a simple key\r\nkey: value\r\n# another key\r\nother: value\r\n",
+			},
+			updater: &YamlUpdater{
+				FilePath: "crlf.yaml",
+				Path:     "key",
+				Indent:   2,
+				Valuer:   value.StringValuer("updated-value"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"crlf.yaml": "# a simple key\r\nkey: updated-value\r\n# another key\r\nother: value\r\n",
+			},
+		},
+		{
+			name: "no changes with crlf line endings",
+			files: map[string]string{
+				"no-changes-crlf.yaml": "# a simple key\r\nkey: value\r\n",
+			},
+			updater: &YamlUpdater{
+				FilePath: "no-changes-crlf.yaml",
+				Path:     "key",
+				Valuer:   value.StringValuer("value"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"no-changes-crlf.yaml": "# a simple key\r\nkey: value\r\n",
+			},
+		},
+		{
+			name: "convert to lf line endings",
+			files: map[string]string{
+				"crlf-to-lf.yaml": "# a simple key\r\nkey: value\r\n",
+			},
+			updater: &YamlUpdater{
+				FilePath: "crlf-to-lf.yaml",
+				Path:     "key",
+				EOL:      eol.LF,
+				Valuer:   value.StringValuer("updated-value"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"crlf-to-lf.yaml": "# a simple key\nkey: updated-value\n",
+			},
+		},
+		{
+			name: "monotonic upgrade",
+			files: map[string]string{
+				"monotonic-upgrade.yaml": `# the app version
+version: 1.9.0
+`,
+			},
+			updater: &YamlUpdater{
+				FilePath:  "monotonic-upgrade.yaml",
+				Path:      "version",
+				Monotonic: monotonic.SemVer,
+				Valuer:    value.StringValuer("1.10.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"monotonic-upgrade.yaml": `# the app version
+version: 1.10.0
+`,
+			},
+		},
+		{
+			name: "update images of all containers sharing a repository",
+			files: map[string]string{
+				"deployment-images.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: migrations
+          image: ghcr.io/dailymotion-oss/app:v1.0.0
+      containers:
+        - name: app
+          # the main container
+          image: ghcr.io/dailymotion-oss/app:v1.0.0
+        - name: proxy
+          image: envoyproxy/envoy:v1.24.0
+        - name: worker
+          image: ghcr.io/dailymotion-oss/app@sha256:0123456789abcdef
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`,
+			},
+			updater: &YamlUpdater{
+				FilePath: "deployment-images.yaml",
+				Path:     ".spec.template.spec",
+				Image:    "ghcr.io/dailymotion-oss/app",
+				Valuer:   value.StringValuer("v1.1.0\n"),
+				Indent:   2,
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"deployment-images.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: migrations
+          image: ghcr.io/dailymotion-oss/app:v1.1.0
+      containers:
+        - name: app
+          # the main container
+          image: ghcr.io/dailymotion-oss/app:v1.1.0
+        - name: proxy
+          image: envoyproxy/envoy:v1.24.0
+        - name: worker
+          image: ghcr.io/dailymotion-oss/app:v1.1.0
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`,
+			},
+		},
+		{
+			name: "no changes for images of other repositories",
+			files: map[string]string{
+				"deployment-other-images.yaml": `spec:
+  template:
+    spec:
+      containers:
+      - name: proxy
+        image: envoyproxy/envoy:v1.24.0
+`,
+			},
+			updater: &YamlUpdater{
+				FilePath: "deployment-other-images.yaml",
+				Path:     ".spec.template.spec",
+				Image:    "ghcr.io/dailymotion-oss/app",
+				Valuer:   value.StringValuer("v1.1.0"),
+				Indent:   2,
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"deployment-other-images.yaml": `spec:
+  template:
+    spec:
+      containers:
+      - name: proxy
+        image: envoyproxy/envoy:v1.24.0
+`,
+			},
+		},
+		{
+			name: "monotonic same value",
+			files: map[string]string{
+				"monotonic-same.yaml": `build: 42
+`,
+			},
+			updater: &YamlUpdater{
+				FilePath:  "monotonic-same.yaml",
+				Path:      "build",
+				Monotonic: monotonic.Numeric,
+				Valuer:    value.StringValuer("42"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"monotonic-same.yaml": `build: 42
+`,
+			},
+		},
+		{
+			name: "monotonic downgrade skipped",
+			files: map[string]string{
+				"monotonic-downgrade.yaml": `image:
+  tag: v2.0.0
+`,
+			},
+			updater: &YamlUpdater{
+				FilePath:  "monotonic-downgrade.yaml",
+				Path:      ".image.tag",
+				Monotonic: monotonic.SemVer,
+				Valuer:    value.StringValuer("v1.0.0"),
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"monotonic-downgrade.yaml": `image:
+  tag: v2.0.0
+`,
+			},
+		},
+		{
+			name: "monotonic downgrade with strict mode",
+			files: map[string]string{
+				"monotonic-downgrade-strict.yaml": `date: "2023-02-01"
+`,
+			},
+			updater: &YamlUpdater{
+				FilePath:  "monotonic-downgrade-strict.yaml",
+				Path:      "date",
+				Monotonic: monotonic.Lexical,
+				Strict:    true,
+				Valuer:    value.StringValuer("2023-01-15"),
+			},
+			expectedErrorMsg: `failed to compare the current value of path date in file monotonic-downgrade-strict.yaml: new value "2023-01-15" is not greater than current value "2023-02-01" (monotonic=lexical)`,
+		},
+		{
+			name: "monotonic with a missing path",
+			files: map[string]string{
+				"monotonic-missing.yaml": `other: value
+`,
+			},
+			updater: &YamlUpdater{
+				FilePath:   "monotonic-missing.yaml",
+				Path:       "version",
+				AutoCreate: true,
+				Indent:     2,
+				Monotonic:  monotonic.SemVer,
+				Valuer:     value.StringValuer("1.0.0"),
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"monotonic-missing.yaml": `other: value
+version: 1.0.0
 `,
 			},
 		},
@@ -463,3 +872,89 @@ func TestYqExpression(t *testing.T) {
 		})
 	}
 }
+
+func TestImageExpression(t *testing.T) {
+	t.Parallel()
+	updater := YamlUpdater{
+		Path:  ".spec.template.spec",
+		Image: "ghcr.io/dailymotion-oss/app",
+	}
+	fileData := []byte(`spec:
+  template:
+    spec:
+      initContainers:
+        - name: init
+          image: ghcr.io/dailymotion-oss/app:v1
+      containers:
+        - name: app
+          image: ghcr.io/dailymotion-oss/app:v1
+        - name: sidecar
+          image: ghcr.io/dailymotion-oss/app-sidecar:v1
+        - name: proxy
+          image: envoyproxy/envoy:v1.24.0
+`)
+
+	expression, expressionNode, changedContainers, err := updater.imageExpression(fileData, "v2")
+	require.NoError(t, err)
+	// the sidecar image is another repository, even if it starts with the same prefix
+	assert.Equal(t, 2, changedContainers)
+	assert.Equal(t, `((.spec.template.spec) | (.containers[], .initContainers[]) | select(.image == "ghcr.io/dailymotion-oss/app:v1") | .image) ref $x | $x = "ghcr.io/dailymotion-oss/app:v2"`, expression)
+	assert.NotNil(t, expressionNode)
+
+	_, _, changedContainers, err = updater.imageExpression(fileData, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, changedContainers)
+}
+
+func TestReplaceImageTag(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		image            string
+		repositoryPrefix string
+		expected         string
+	}{
+		{
+			image:            "ghcr.io/my-org/app:v1",
+			repositoryPrefix: "ghcr.io/my-org/app",
+			expected:         "ghcr.io/my-org/app:v2",
+		},
+		{
+			image:            "ghcr.io/my-org/app@sha256:0123456789abcdef",
+			repositoryPrefix: "ghcr.io/my-org/app",
+			expected:         "ghcr.io/my-org/app:v2",
+		},
+		{
+			image:            "ghcr.io/my-org/app:v1",
+			repositoryPrefix: "ghcr.io/my-org",
+			expected:         "ghcr.io/my-org/app:v2",
+		},
+		{
+			image:            "ghcr.io/my-org/app:v1",
+			repositoryPrefix: "ghcr.io/my-org/",
+			expected:         "ghcr.io/my-org/app:v2",
+		},
+		{
+			image:            "localhost:5000/app:v1",
+			repositoryPrefix: "localhost:5000/app",
+			expected:         "localhost:5000/app:v2",
+		},
+		{
+			image:            "ghcr.io/my-org/app-sidecar:v1",
+			repositoryPrefix: "ghcr.io/my-org/app",
+		},
+		{
+			image:            "ghcr.io/my-org-fork/app:v1",
+			repositoryPrefix: "ghcr.io/my-org",
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.image+" with "+test.repositoryPrefix, func(t *testing.T) {
+			t.Parallel()
+			actual, matches := replaceImageTag(test.image, test.repositoryPrefix, "v2")
+			assert.Equal(t, len(test.expected) > 0, matches)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
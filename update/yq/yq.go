@@ -12,6 +12,8 @@ import (
 	"reflect"
 	"strconv"
 
+	"github.com/dailymotion-oss/octopilot/internal/eol"
+	"github.com/dailymotion-oss/octopilot/internal/semantic"
 	"github.com/dailymotion-oss/octopilot/internal/yaml"
 	"github.com/mikefarah/yq/v4/pkg/yqlib"
 	gologging "gopkg.in/op/go-logging.v1"
@@ -31,6 +33,9 @@ type YQUpdater struct {
 	Indent       int
 	Trim         bool
 	UnwrapScalar bool
+	EOL          eol.Mode
+	// SemanticDiff compares the data of the original and updated files - instead of their bytes - so that formatting-only changes are ignored
+	SemanticDiff bool
 }
 
 // NewUpdater builds a new YQ updater from the given parameters
@@ -65,6 +70,12 @@ func NewUpdater(params map[string]string) (*YQUpdater, error) {
 
 	updater.Trim, _ = strconv.ParseBool(params["trim"])
 	updater.Output = params["output"]
+	updater.SemanticDiff, _ = strconv.ParseBool(params["semantic-diff"])
+
+	updater.EOL, err = eol.ParseMode(params["eol"])
+	if err != nil {
+		return nil, err
+	}
 
 	return updater, nil
 }
@@ -139,7 +150,9 @@ func (u *YQUpdater) Update(_ context.Context, repoPath string) (bool, error) {
 			return false, fmt.Errorf("failed to read file %s: %w", relFilePath, err)
 		}
 
-		reader, leadingContent, err := yaml.ExtractLeadingContentForYQ(bytes.NewReader(fileData))
+		// the yq lib doesn't handle CRLF line endings well, so we normalize them first
+		// they will be restored - if needed - once the file has been updated
+		reader, leadingContent, err := yaml.ExtractLeadingContentForYQ(bytes.NewReader(eol.Convert(fileData, eol.LF)))
 		if err != nil {
 			return false, fmt.Errorf("failed to extract leading content from file %s: %w", relFilePath, err)
 		}
@@ -154,10 +167,20 @@ func (u *YQUpdater) Update(_ context.Context, repoPath string) (bool, error) {
 		if u.Trim {
 			buffer = bytes.NewBuffer(bytes.TrimSpace(buffer.Bytes()))
 		}
+		buffer = bytes.NewBuffer(eol.Apply(u.EOL, fileData, buffer.Bytes()))
 
 		if reflect.DeepEqual(fileData, buffer.Bytes()) {
 			continue
 		}
+		if u.SemanticDiff {
+			equal, err := semantic.Equal(fileData, buffer.Bytes())
+			if err != nil {
+				return false, fmt.Errorf("failed to compare the data of file %s: %w", relFilePath, err)
+			}
+			if equal {
+				continue
+			}
+		}
 
 		if output != nil {
 			_, err = buffer.WriteTo(output)
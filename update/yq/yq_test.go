@@ -73,6 +73,22 @@ func TestNewUpdater(t *testing.T) {
 			},
 			expectedErrorMsg: "missing expression parameter",
 		},
+		{
+			name: "semantic diff",
+			params: map[string]string{
+				"file":          "values.yaml",
+				"expression":    "sort_keys(..)",
+				"semantic-diff": "true",
+			},
+			expected: &YQUpdater{
+				FilePath:     "values.yaml",
+				Expression:   "sort_keys(..)",
+				UnwrapScalar: true,
+				OutputFormat: yqlib.YamlOutputFormat,
+				Indent:       2,
+				SemanticDiff: true,
+			},
+		},
 		{
 			name: "invalid trim boolean value",
 			params: map[string]string{
@@ -395,6 +411,76 @@ key: value
 			expectedFiles: map[string]string{
 				"no-changes.yaml": `# a simple key
 key: value
+`,
+			},
+		},
+		{
+			name: "reordered keys with semantic diff",
+			files: map[string]string{
+				"semantic-diff-reordered.yaml": `name: app
+image:
+  tag: 1.2.3
+  repository: app
+`,
+			},
+			updater: &YQUpdater{
+				FilePath:     "semantic-diff-reordered.yaml",
+				Expression:   `sort_keys(..)`,
+				OutputFormat: yqlib.YamlOutputFormat,
+				Indent:       2,
+				SemanticDiff: true,
+			},
+			expected: false,
+			expectedFiles: map[string]string{
+				"semantic-diff-reordered.yaml": `name: app
+image:
+  tag: 1.2.3
+  repository: app
+`,
+			},
+		},
+		{
+			name: "reordered keys without semantic diff",
+			files: map[string]string{
+				"byte-diff-reordered.yaml": `name: app
+image:
+  tag: 1.2.3
+  repository: app
+`,
+			},
+			updater: &YQUpdater{
+				FilePath:     "byte-diff-reordered.yaml",
+				Expression:   `sort_keys(..)`,
+				OutputFormat: yqlib.YamlOutputFormat,
+				Indent:       2,
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"byte-diff-reordered.yaml": `image:
+  repository: app
+  tag: 1.2.3
+name: app
+`,
+			},
+		},
+		{
+			name: "updated value with semantic diff",
+			files: map[string]string{
+				"semantic-diff-updated.yaml": `name: app
+version: 1.2.3
+`,
+			},
+			updater: &YQUpdater{
+				FilePath:     "semantic-diff-updated.yaml",
+				Expression:   `.version = "1.2.4" | sort_keys(.)`,
+				OutputFormat: yqlib.YamlOutputFormat,
+				Indent:       2,
+				SemanticDiff: true,
+			},
+			expected: true,
+			expectedFiles: map[string]string{
+				"semantic-diff-updated.yaml": `name: app
+version: 1.2.4
 `,
 			},
 		},